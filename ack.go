@@ -0,0 +1,325 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Acker is implemented by PULL sockets created with WithPullAck.
+type Acker interface {
+	// Ack acknowledges the processing of msg, received from the socket:
+	// its PUSH peer then stops redelivering it.
+	Ack(msg Msg) error
+}
+
+// ackIDSize is the size of the sequence number frame prefixed to the
+// messages exchanged by PUSH and PULL sockets in acknowledgement mode, and
+// sent back by PULL sockets to acknowledge them.
+const ackIDSize = 8
+
+// ackWriter is the wpool of PUSH sockets in acknowledgement mode.
+// It sends each message to a single connection, in turn, and sends it again
+// to another connection when it is not acknowledged in time, or when its
+// connection is lost.
+type ackWriter struct {
+	ctx     context.Context
+	timeout time.Duration // delay before unacknowledged messages are sent again
+
+	mu      sync.Mutex
+	hwm     int // maximum number of unacknowledged messages
+	ws      []*Conn
+	next    int // index of the connection the next message is sent to
+	seq     uint64
+	pending map[uint64]*ackEntry
+	room    chan struct{} // closed when a message is acknowledged, or hwm raised
+
+	sem  *semaphore    // ready when a connection is live.
+	wake chan struct{} // signals the redelivery loop
+}
+
+// ackEntry is a message sent by an ackWriter that was not acknowledged yet.
+type ackEntry struct {
+	msg      Msg   // message as sent over the wire, prefixed with its sequence number
+	conn     *Conn // connection the message was last sent to, nil if lost
+	deadline time.Time
+}
+
+// newAckWriter returns an ackWriter keeping up to hwm unacknowledged
+// messages, or DefaultSendHwm if hwm is 0.
+func newAckWriter(ctx context.Context, timeout time.Duration, hwm int) *ackWriter {
+	if hwm <= 0 {
+		hwm = DefaultSendHwm
+	}
+	w := &ackWriter{
+		ctx:     ctx,
+		timeout: timeout,
+		hwm:     hwm,
+		pending: make(map[uint64]*ackEntry),
+		room:    make(chan struct{}),
+		sem:     newSemaphore(),
+		wake:    make(chan struct{}, 1),
+	}
+	go w.run()
+	return w
+}
+
+func (w *ackWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	for _, ww := range w.ws {
+		e := ww.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	w.ws = nil
+	w.mu.Unlock()
+	return err
+}
+
+func (w *ackWriter) addConn(c *Conn) {
+	w.mu.Lock()
+	w.sem.enable()
+	w.ws = append(w.ws, c)
+	w.mu.Unlock()
+	go w.listen(c)
+	// the new connection may take over messages whose connection was lost.
+	w.notify()
+}
+
+func (w *ackWriter) rmConn(c *Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cur := -1
+	for i := range w.ws {
+		if w.ws[i] == c {
+			cur = i
+			break
+		}
+	}
+	if cur < 0 {
+		return
+	}
+	w.ws = append(w.ws[:cur], w.ws[cur+1:]...)
+
+	// the messages in flight on c are sent again right away.
+	now := time.Now()
+	for _, e := range w.pending {
+		if e.conn == c {
+			e.conn = nil
+			e.deadline = now
+		}
+	}
+	w.notify()
+}
+
+func (w *ackWriter) write(ctx context.Context, msg Msg) error {
+	w.sem.lock(ctx)
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	// the frames are kept until the message is acknowledged: they must not
+	// be reused by the application in the meantime.
+	frames := make([][]byte, 0, len(msg.Frames)+1)
+	frames = append(frames, nil)
+	for _, frame := range msg.Frames {
+		frames = append(frames, append([]byte(nil), frame...))
+	}
+
+	w.mu.Lock()
+	for len(w.pending) >= w.hwm {
+		// wait for messages in flight to be acknowledged: with a done
+		// ctx, e.g. for SendNB, the message is not sent.
+		room := w.room
+		w.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		case <-room:
+		}
+		w.mu.Lock()
+	}
+	w.seq++
+	frames[0] = binary.BigEndian.AppendUint64(nil, w.seq)
+	e := &ackEntry{
+		msg:      Msg{Frames: frames, multipart: true},
+		conn:     w.pick(nil),
+		deadline: time.Now().Add(w.timeout),
+	}
+	w.pending[w.seq] = e
+	conn := e.conn
+	w.mu.Unlock()
+
+	if conn == nil {
+		// all the connections were lost: the message is sent once one
+		// comes back.
+		return nil
+	}
+	// failed sends are retried on another connection, once the reaper
+	// removed the broken one.
+	_ = conn.SendMsg(e.msg)
+	return nil
+}
+
+func (w *ackWriter) sendHWM() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.hwm
+}
+
+// setSendHWM sets the maximum number of unacknowledged messages, or
+// DefaultSendHwm if n is 0.
+func (w *ackWriter) setSendHWM(n int) {
+	if n <= 0 {
+		n = DefaultSendHwm
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hwm = n
+	// the pending writes may fit now.
+	close(w.room)
+	w.room = make(chan struct{})
+}
+
+// pick returns the connection the next message is sent to, trying not to
+// pick prev, or nil if there is no connection.
+// pick must be called with w.mu held.
+func (w *ackWriter) pick(prev *Conn) *Conn {
+	if len(w.ws) == 0 {
+		return nil
+	}
+	for range w.ws {
+		c := w.ws[w.next%len(w.ws)]
+		w.next = (w.next + 1) % len(w.ws)
+		if c != prev {
+			return c
+		}
+	}
+	return prev
+}
+
+// listen handles the acknowledgements received from c.
+func (w *ackWriter) listen(c *Conn) {
+	for {
+		msg := c.read()
+		if msg.err != nil {
+			return
+		}
+		if len(msg.Frames) == 1 && len(msg.Frames[0]) == ackIDSize {
+			seq := binary.BigEndian.Uint64(msg.Frames[0])
+			w.mu.Lock()
+			if _, ok := w.pending[seq]; ok {
+				delete(w.pending, seq)
+				close(w.room)
+				w.room = make(chan struct{})
+			}
+			w.mu.Unlock()
+		}
+		msg.Release()
+	}
+}
+
+// notify wakes the redelivery loop up.
+func (w *ackWriter) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends again the messages that were not acknowledged in time, until
+// the socket is closed.
+func (w *ackWriter) run() {
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+		timer.Reset(w.redeliver(time.Now()))
+	}
+}
+
+// redeliver sends again the messages whose deadline passed at now, and
+// returns the delay until the next deadline.
+func (w *ackWriter) redeliver(now time.Time) time.Duration {
+	type resend struct {
+		conn *Conn
+		msg  Msg
+	}
+	var (
+		sends []resend
+		next  = w.timeout
+	)
+	w.mu.Lock()
+	if len(w.ws) == 0 {
+		// wait for a connection to come back.
+		w.mu.Unlock()
+		return next
+	}
+	for _, e := range w.pending {
+		if d := e.deadline.Sub(now); d > 0 {
+			next = min(next, d)
+			continue
+		}
+		e.conn = w.pick(e.conn)
+		e.deadline = now.Add(w.timeout)
+		sends = append(sends, resend{e.conn, e.msg})
+	}
+	w.mu.Unlock()
+
+	for _, s := range sends {
+		_ = s.conn.SendMsg(s.msg)
+	}
+	return next
+}
+
+// msgAck identifies a message received by a PULL socket in
+// acknowledgement mode, for Ack.
+type msgAck struct {
+	conn *Conn  // connection the message was received from
+	id   []byte // sequence number frame of the message
+}
+
+// ackMsg strips the sequence number frame from msg, received by a PULL
+// socket in acknowledgement mode.
+func ackMsg(msg Msg) (Msg, error) {
+	if len(msg.Frames) < 1 || len(msg.Frames[0]) != ackIDSize || msg.conn == nil {
+		return msg, fmt.Errorf("zmq4: invalid acknowledgement frame in message from PUSH peer")
+	}
+	// the frame may be backed by a pooled buffer, released independently.
+	msg.ack = &msgAck{conn: msg.conn, id: append([]byte(nil), msg.Frames[0]...)}
+	msg.Frames = msg.Frames[1:]
+	return msg, nil
+}
+
+// ack acknowledges msg, received by a PULL socket in acknowledgement mode.
+func ack(msg Msg) error {
+	if msg.ack == nil {
+		return fmt.Errorf("zmq4: message cannot be acknowledged")
+	}
+	err := msg.ack.conn.SendMsg(NewMsg(msg.ack.id))
+	if err != nil {
+		return fmt.Errorf("zmq4: could not acknowledge message: %w", err)
+	}
+	return nil
+}
+
+var (
+	_ wpool     = (*ackWriter)(nil)
+	_ hwmWriter = (*ackWriter)(nil)
+)
@@ -5,82 +5,302 @@
 package zmq4
 
 import (
+	"crypto/ecdh"
 	"crypto/rand"
-	"encoding/hex"
 	"fmt"
+	"log"
+	"net"
+	"sync"
 )
 
-// Simple auth - no complex state management, just basic functions
-
-// NewCurveKeypair generates a new CURVE keypair
+// NewCurveKeypair generates a new CURVE key pair, Z85-encoded as by
+// libzmq's zmq_curve_keypair.
 func NewCurveKeypair() (publicKey, secretKey string, err error) {
-	// Simple random key generation - real implementation would use luxfi/crypto
-	secret := make([]byte, 32)
-	if _, err = rand.Read(secret); err != nil {
-		return "", "", err
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("zmq4: could not generate CURVE key pair: %w", err)
 	}
-	public := make([]byte, 32)
-	copy(public, secret)
-
-	publicKey = hex.EncodeToString(public)
-	secretKey = hex.EncodeToString(secret)
-	return publicKey, secretKey, nil
+	return Z85encode(key.PublicKey().Bytes()), Z85encode(key.Bytes()), nil
 }
 
-// AuthCurvePublic derives the public key from a secret key
+// AuthCurvePublic derives the Z85-encoded public key from a Z85-encoded
+// secret key, as libzmq's zmq_curve_public does.
 func AuthCurvePublic(secretKey string) (string, error) {
-	// Simplified - real implementation would use luxfi/crypto
-	_, err := hex.DecodeString(secretKey)
+	raw, err := Z85decode(secretKey)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("zmq4: invalid CURVE secret key: %w", err)
 	}
-	return secretKey, nil
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return "", fmt.Errorf("zmq4: invalid CURVE secret key: %w", err)
+	}
+	return Z85encode(key.PublicKey().Bytes()), nil
 }
 
-// Z85encode encodes binary data to Z85 text format
+// z85Alphabet is the alphabet of the Z85 encoding, as defined by
+// https://rfc.zeromq.org/spec/32/.
+const z85Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#"
+
+// z85Values maps the characters of z85Alphabet to their values, and the
+// other bytes to 0xff.
+var z85Values = func() (v [256]byte) {
+	for i := range v {
+		v[i] = 0xff
+	}
+	for i := 0; i < len(z85Alphabet); i++ {
+		v[z85Alphabet[i]] = byte(i)
+	}
+	return v
+}()
+
+// Z85encode encodes binary data to Z85 text format, as zmq_z85_encode does:
+// each 4 bytes are encoded as 5 characters.
+// Like zmq_z85_encode, Z85encode fails and returns an empty string if the
+// length of data is not a multiple of 4.
 func Z85encode(data []byte) string {
-	return hex.EncodeToString(data)
+	if len(data)%4 != 0 {
+		return ""
+	}
+	out := make([]byte, 0, len(data)/4*5)
+	for i := 0; i < len(data); i += 4 {
+		v := uint32(data[i])<<24 | uint32(data[i+1])<<16 | uint32(data[i+2])<<8 | uint32(data[i+3])
+		var chunk [5]byte
+		for j := 4; j >= 0; j-- {
+			chunk[j] = z85Alphabet[v%85]
+			v /= 85
+		}
+		out = append(out, chunk[:]...)
+	}
+	return string(out)
 }
 
-// Z85decode decodes Z85 text to binary data
+// Z85decode decodes Z85 text to binary data, as zmq_z85_decode does:
+// each 5 characters are decoded as 4 bytes.
+// The length of text must be a multiple of 5.
 func Z85decode(text string) ([]byte, error) {
-	return hex.DecodeString(text)
+	if len(text)%5 != 0 {
+		return nil, fmt.Errorf("zmq4: invalid Z85 text length %d: not a multiple of 5", len(text))
+	}
+	out := make([]byte, 0, len(text)/5*4)
+	for i := 0; i < len(text); i += 5 {
+		var v uint64
+		for j := i; j < i+5; j++ {
+			d := z85Values[text[j]]
+			if d == 0xff {
+				return nil, fmt.Errorf("zmq4: invalid Z85 character %q at offset %d", text[j], j)
+			}
+			v = v*85 + uint64(d)
+		}
+		if v > 0xffffffff {
+			return nil, fmt.Errorf("zmq4: invalid Z85 text %q at offset %d: value overflows 32 bits", text[i:i+5], i)
+		}
+		out = append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return out, nil
 }
 
-// Simplified auth - removed complex state management
-var authStarted bool
+// auth is the in-process ZAP handler, as specified by
+// https://rfc.zeromq.org/spec:27/ZAP/, consulted by the security mechanisms
+// of the connections accepted by the sockets while authentication is
+// started (see AuthStart).
+var auth struct {
+	mu      sync.RWMutex
+	started bool
+	verbose bool
+	allow   map[string][]string        // allowed addresses, per domain
+	deny    map[string][]string        // denied addresses, per domain
+	curve   map[string]map[string]bool // allowed Z85 CURVE public keys, per domain
+	meta    MetadataHandler
+}
 
-// AuthStart starts authentication (simplified)
+// CurveAllowAny is the CURVE public key that, added to a domain with
+// AuthCurveAdd, allows any client key.
+const CurveAllowAny = "*"
+
+// AuthStart starts authentication: the sockets then check the peers of the
+// connections they accept against the policies set by AuthAllow, AuthDeny
+// and AuthCurveAdd for their domain (see WithZAPDomain).
 func AuthStart() error {
-	if authStarted {
-		return fmt.Errorf("already started")
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	if auth.started {
+		return fmt.Errorf("zmq4: authentication already started")
 	}
-	authStarted = true
+	auth.started = true
 	return nil
 }
 
-// AuthStop stops authentication
+// AuthStop stops authentication, and clears the policies.
 func AuthStop() {
-	authStarted = false
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	auth.started = false
+	auth.verbose = false
+	auth.allow = nil
+	auth.deny = nil
+	auth.curve = nil
+	auth.meta = nil
 }
 
-// AuthSetVerbose sets verbose mode (no-op)
-func AuthSetVerbose(verbose bool) {}
+// AuthSetVerbose sets whether authentication decisions are logged.
+func AuthSetVerbose(verbose bool) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	auth.verbose = verbose
+}
 
-// AuthAllow adds allowed addresses (no-op for simplicity)
-func AuthAllow(domain string, addresses ...string) {}
+// AuthAllow allows the peers with the addresses to connect to the sockets
+// of the domain, and denies all the others.
+// Addresses are IP addresses or CIDR ranges. The "*" domain applies to all
+// domains.
+func AuthAllow(domain string, addresses ...string) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	if auth.allow == nil {
+		auth.allow = make(map[string][]string)
+	}
+	auth.allow[domain] = append(auth.allow[domain], addresses...)
+}
 
-// AuthDeny adds denied addresses (no-op for simplicity)
-func AuthDeny(domain string, addresses ...string) {}
+// AuthDeny denies the peers with the addresses to connect to the sockets of
+// the domain. Denied addresses are ignored for domains with allowed
+// addresses.
+// Addresses are IP addresses or CIDR ranges. The "*" domain applies to all
+// domains.
+func AuthDeny(domain string, addresses ...string) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	if auth.deny == nil {
+		auth.deny = make(map[string][]string)
+	}
+	auth.deny[domain] = append(auth.deny[domain], addresses...)
+}
 
-// AuthCurveAdd adds a CURVE public key (no-op for simplicity)
-func AuthCurveAdd(domain, publicKey string) {}
+// AuthCurveAdd allows the CURVE clients with the Z85-encoded public key to
+// connect to the sockets of the domain, or any client with CurveAllowAny.
+// CURVE clients are denied by default, while authentication is started.
+// The "*" domain applies to all domains.
+func AuthCurveAdd(domain, publicKey string) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	if auth.curve == nil {
+		auth.curve = make(map[string]map[string]bool)
+	}
+	if auth.curve[domain] == nil {
+		auth.curve[domain] = make(map[string]bool)
+	}
+	auth.curve[domain][publicKey] = true
+}
 
-// AuthCurveRemove removes a CURVE public key (no-op for simplicity)
-func AuthCurveRemove(domain, publicKey string) {}
+// AuthCurveRemove removes a CURVE public key added by AuthCurveAdd.
+func AuthCurveRemove(domain, publicKey string) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	delete(auth.curve[domain], publicKey)
+}
 
-// MetadataHandler simplified
+// MetadataHandler returns the metadata to attach to the connection of an
+// authenticated peer, e.g. a "User-Id" property, given the domain of the
+// socket and the address of the peer.
 type MetadataHandler func(domain, address string) map[string]string
 
-// AuthSetMetadataHandler sets the metadata handler (no-op for simplicity)
-func AuthSetMetadataHandler(handler MetadataHandler) {}
+// AuthSetMetadataHandler sets the handler returning the metadata of the
+// authenticated peers, added to the metadata of their connection.
+func AuthSetMetadataHandler(handler MetadataHandler) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	auth.meta = handler
+}
+
+// zapRequest is a ZAP request, made by a security mechanism during the
+// handshake of an accepted connection.
+type zapRequest struct {
+	domain      string
+	address     string
+	mechanism   SecurityType
+	credentials [][]byte
+}
+
+// zapAuthenticate handles req, and returns the metadata of the peer, or the
+// reason the peer is denied.
+func zapAuthenticate(req zapRequest) (map[string]string, error) {
+	auth.mu.RLock()
+	defer auth.mu.RUnlock()
+	if !auth.started || (req.mechanism == NullSecurity && req.domain == "") {
+		return nil, nil
+	}
+	meta, err := zapPolicy(req)
+	if auth.verbose {
+		switch err {
+		case nil:
+			log.Printf("zmq4: auth: %s peer %s allowed in domain %q", req.mechanism, req.address, req.domain)
+		default:
+			log.Printf("zmq4: auth: %s peer %s denied in domain %q: %v", req.mechanism, req.address, req.domain, err)
+		}
+	}
+	return meta, err
+}
+
+// zapPolicy applies the authentication policies to req.
+// zapPolicy must be called with auth.mu held.
+func zapPolicy(req zapRequest) (map[string]string, error) {
+	domains := []string{req.domain, "*"}
+	var allow, deny []string
+	for _, d := range domains {
+		allow = append(allow, auth.allow[d]...)
+		deny = append(deny, auth.deny[d]...)
+	}
+	switch {
+	case len(allow) > 0:
+		if !matchAddr(allow, req.address) {
+			return nil, fmt.Errorf("address %s not allowed", req.address)
+		}
+	case matchAddr(deny, req.address):
+		return nil, fmt.Errorf("address %s denied", req.address)
+	}
+
+	meta := make(map[string]string)
+	switch req.mechanism {
+	case PlainSecurity:
+		if len(req.credentials) > 0 {
+			meta["User-Id"] = string(req.credentials[0])
+		}
+	case CurveSecurity:
+		if len(req.credentials) == 0 {
+			return nil, fmt.Errorf("missing CURVE public key")
+		}
+		key := Z85encode(req.credentials[0])
+		allowed := false
+		for _, d := range domains {
+			allowed = allowed || auth.curve[d][key] || auth.curve[d][CurveAllowAny]
+		}
+		if !allowed {
+			return nil, fmt.Errorf("CURVE public key %s not allowed", key)
+		}
+		meta["User-Id"] = key
+	}
+	if auth.meta != nil {
+		for k, v := range auth.meta(req.domain, req.address) {
+			meta[k] = v
+		}
+	}
+	return meta, nil
+}
+
+// matchAddr returns whether addr is one of the addresses, or in one of their
+// CIDR ranges.
+func matchAddr(addrs []string, addr string) bool {
+	ip := net.ParseIP(addr)
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+		if _, ipnet, err := net.ParseCIDR(a); err == nil && ip != nil && ipnet.Contains(ip) {
+			return true
+		}
+		if aip := net.ParseIP(a); aip != nil && aip.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// BackoffPolicy describes the delays between the attempts of an operation
+// that is retried, such as dialing a peer.
+//
+// The delay before retry n (counting from 0) is Initial*Multiplier^n, capped
+// at Max, and randomized by Jitter.
+type BackoffPolicy struct {
+	Initial    time.Duration // delay before the first retry
+	Max        time.Duration // maximum delay before jitter, unbounded if zero
+	Multiplier float64       // growth factor of the delays, constant delays if <= 1
+	Jitter     float64       // fraction of the delay, in [0, 1], by which delays are randomized
+}
+
+// ConstantBackoff returns the policy retrying every d.
+func ConstantBackoff(d time.Duration) BackoffPolicy {
+	return BackoffPolicy{Initial: d}
+}
+
+// Next returns the delay to wait before retry number attempt, counting from 0.
+// With a non-zero Jitter, the delay d is picked uniformly in
+// [d*(1-Jitter), d*(1+Jitter)].
+func (p BackoffPolicy) Next(attempt int) time.Duration {
+	d := float64(p.Initial)
+	if p.Multiplier > 1 && attempt > 0 {
+		d *= math.Pow(p.Multiplier, float64(attempt))
+	}
+	if p.Max > 0 && d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	if j := min(max(p.Jitter, 0), 1); j > 0 {
+		d *= 1 - j + 2*j*rand.Float64()
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+)
+
+func TestBackoffPolicySequence(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy zmq4.BackoffPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "constant",
+			policy: zmq4.ConstantBackoff(10 * time.Millisecond),
+			want:   []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+		},
+		{
+			name:   "exponential",
+			policy: zmq4.BackoffPolicy{Initial: time.Millisecond, Multiplier: 2},
+			want:   []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond},
+		},
+		{
+			name:   "capped",
+			policy: zmq4.BackoffPolicy{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 3},
+			want:   []time.Duration{time.Millisecond, 3 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond},
+		},
+		{
+			name:   "zero",
+			policy: zmq4.BackoffPolicy{},
+			want:   []time.Duration{0, 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i, want := range tc.want {
+				if got := tc.policy.Next(i); got != want {
+					t.Fatalf("invalid delay of retry %d: got=%v, want=%v", i, got, want)
+				}
+			}
+		})
+	}
+
+	// huge delays saturate instead of overflowing.
+	p := zmq4.BackoffPolicy{Initial: time.Hour, Multiplier: 10}
+	if got := p.Next(100); got != math.MaxInt64 {
+		t.Fatalf("invalid saturated delay: got=%v, want=%v", got, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestBackoffPolicyJitter(t *testing.T) {
+	p := zmq4.BackoffPolicy{
+		Initial:    100 * time.Millisecond,
+		Max:        time.Second,
+		Multiplier: 2,
+		Jitter:     0.25,
+	}
+	for attempt, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second} {
+		lo, hi := base*3/4, base*5/4
+		distinct := make(map[time.Duration]bool)
+		for range 100 {
+			d := p.Next(attempt)
+			if d < lo || d > hi {
+				t.Fatalf("delay of retry %d out of bounds: got=%v, want in [%v, %v]", attempt, d, lo, hi)
+			}
+			distinct[d] = true
+		}
+		if len(distinct) < 2 {
+			t.Fatalf("delays of retry %d are not randomized", attempt)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// coalesceConfig configures the coalescing of the writes of a connection.
+type coalesceConfig struct {
+	delay time.Duration // maximum time written data is held back
+	size  int           // number of held back bytes triggering a flush
+}
+
+func (cfg coalesceConfig) enabled() bool {
+	return cfg.delay > 0 && cfg.size > 0
+}
+
+// coalescer is a writer holding back written data, so that consecutive
+// writes reach the underlying writer as one.
+// Data is flushed once cfg.size bytes are held back, or cfg.delay after the
+// first held back write, whichever comes first.
+type coalescer struct {
+	w   io.Writer
+	cfg coalesceConfig
+
+	mu    sync.Mutex
+	buf   []byte
+	timer *time.Timer
+	armed bool  // whether a delayed flush is scheduled
+	err   error // error of the last flush, reported by the next write
+}
+
+func newCoalescer(w io.Writer, cfg coalesceConfig) *coalescer {
+	c := &coalescer{
+		w:   w,
+		cfg: cfg,
+		buf: make([]byte, 0, cfg.size),
+	}
+	c.timer = time.AfterFunc(cfg.delay, c.delayedFlush)
+	c.timer.Stop()
+	return c
+}
+
+func (c *coalescer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err; err != nil {
+		c.err = nil
+		return 0, err
+	}
+
+	c.buf = append(c.buf, p...)
+	switch {
+	case len(c.buf) >= c.cfg.size:
+		if err := c.flushLocked(); err != nil {
+			return 0, err
+		}
+	case !c.armed:
+		c.armed = true
+		c.timer.Reset(c.cfg.delay)
+	}
+	return len(p), nil
+}
+
+// Flush writes the held back data to the underlying writer.
+func (c *coalescer) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushLocked()
+}
+
+func (c *coalescer) delayedFlush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.flushLocked(); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *coalescer) flushLocked() error {
+	if c.armed {
+		c.armed = false
+		c.timer.Stop()
+	}
+	if len(c.buf) == 0 {
+		return nil
+	}
+	_, err := c.w.Write(c.buf)
+	c.buf = c.buf[:0]
+	return err
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingWriter records the writes it receives.
+type countingWriter struct {
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (w *countingWriter) get() [][]byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([][]byte(nil), w.writes...)
+}
+
+func TestCoalescer(t *testing.T) {
+	t.Run("delay", func(t *testing.T) {
+		w := new(countingWriter)
+		c := newCoalescer(w, coalesceConfig{delay: 20 * time.Millisecond, size: 1024})
+		for _, p := range []string{"a", "bb", "ccc"} {
+			if _, err := c.Write([]byte(p)); err != nil {
+				t.Fatalf("could not write: %+v", err)
+			}
+		}
+		if got := w.get(); len(got) != 0 {
+			t.Fatalf("data written before the delay: %q", got)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(w.get()) == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := w.get(); len(got) != 1 || !bytes.Equal(got[0], []byte("abbccc")) {
+			t.Fatalf("invalid writes: %q", got)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		w := new(countingWriter)
+		c := newCoalescer(w, coalesceConfig{delay: time.Hour, size: 4})
+		for _, p := range []string{"ab", "cd", "e"} {
+			if _, err := c.Write([]byte(p)); err != nil {
+				t.Fatalf("could not write: %+v", err)
+			}
+		}
+		if got := w.get(); len(got) != 1 || !bytes.Equal(got[0], []byte("abcd")) {
+			t.Fatalf("invalid writes: %q", got)
+		}
+		if err := c.Flush(); err != nil {
+			t.Fatalf("could not flush: %+v", err)
+		}
+		if got := w.get(); len(got) != 2 || !bytes.Equal(got[1], []byte("e")) {
+			t.Fatalf("invalid writes after flush: %q", got)
+		}
+	})
+}
@@ -6,6 +6,7 @@ package zmq4
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -14,10 +15,18 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 )
 
 var ErrClosedConn = errors.New("zmq4: read/write on closed connection")
 
+// ErrPeerDisconnected is returned, wrapping the underlying error, when a
+// message could not be written because the peer went away (e.g. broken pipe
+// or connection reset).
+// Sockets that reconnect automatically (the default) start reconnecting when
+// they return this error: the send may be retried.
+var ErrPeerDisconnected = errors.New("zmq4: peer disconnected")
+
 // Conn implements the ZeroMQ Message Transport Protocol as defined
 // in https://rfc.zeromq.org/spec:23/ZMTP/.
 type Conn struct {
@@ -34,24 +43,91 @@ type Conn struct {
 
 	mu     sync.RWMutex
 	topics map[string]struct{} // set of subscribed topics
+	groups map[string]struct{} // set of joined groups, see CmdJoin
 
 	closed         int32
+	dropped        int32 // set when the socket closes the connection itself, see drop
 	onCloseErrorCB func(c *Conn)
+
+	hdr    [8]byte   // scratch space for the frame headers decoded by read
+	pooled bool      // whether small messages are decoded into pooled buffers
+	zc     *zcReader // reads the frames in place, nil unless zero-copy is enabled
+	notify func()    // wakes up the pollers of the socket, see queued
+	tracer *messageTracer
+	stats  *socketStats
+	cw     *coalescer // nil if writes are not coalesced
+
+	maxFrames int         // maximum number of frames of a received message, 0 for no limit
+	value     interface{} // application-defined value set by the accept filter
+
+	cipher FrameCipher // boxes the frames, if set by the security handshake
+	cmu    sync.Mutex  // serializes the sealing and writing of boxed frames
+
+	zapDomain string   // authentication domain of the accepting socket
+	zapMeta   Metadata // metadata of the peer, set by Authenticate
+
+	endpoint string // endpoint the connection was dialed to, or accepted on
+	dialed   bool   // whether the connection was dialed, rather than accepted
+
+	hb       heartbeat    // heartbeat configuration, set by the socket
+	wmu      sync.Mutex   // serializes the messages and the heartbeat commands written to the wire
+	lastRecv atomic.Int64 // time the last message or command was received, in Unix nanoseconds
+	peerTTL  atomic.Int64 // time to wait for traffic, announced by the PING commands of the peer
+}
+
+// SetFrameCipher makes the connection box the frames it sends, and open the
+// frames it receives, with fc.
+// It is meant to be called by Security.Handshake, once the handshake
+// commands have been exchanged: all the subsequent frames, messages and
+// commands alike, go through fc.
+func (c *Conn) SetFrameCipher(fc FrameCipher) {
+	c.cipher = fc
+}
+
+// lockCipher serializes the writers of boxed frames, so frames are written
+// in the order they were sealed. It returns the function releasing the lock.
+func (c *Conn) lockCipher() func() {
+	if c.cipher == nil {
+		return func() {}
+	}
+	c.cmu.Lock()
+	return c.cmu.Unlock
 }
 
 func (c *Conn) Close() error {
+	if c.cw != nil && !c.Closed() {
+		// best effort: the peer may be gone already.
+		_ = c.cw.Flush()
+	}
 	return c.rw.Close()
 }
 
+// wire returns the writer ZMTP messages are written to.
+func (c *Conn) wire() io.Writer {
+	if c.cw != nil {
+		return c.cw
+	}
+	return c.rw
+}
+
 func (c *Conn) Read(p []byte) (int, error) {
 	if c.Closed() {
 		return 0, ErrClosedConn
 	}
-	n, err := io.ReadFull(c.rw, p)
+	n, err := c.readFull(p)
 	c.checkIO(err)
 	return n, err
 }
 
+// readFull reads exactly len(p) bytes from the connection, with the semantics
+// of io.ReadFull.
+func (c *Conn) readFull(p []byte) (int, error) {
+	if c.zc != nil {
+		return c.zc.ReadFull(p)
+	}
+	return io.ReadFull(c.rw, p)
+}
+
 func (c *Conn) Write(p []byte) (int, error) {
 	if c.Closed() {
 		return 0, ErrClosedConn
@@ -61,10 +137,105 @@ func (c *Conn) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// ConnInfo describes a connection of a socket with a peer.
+type ConnInfo struct {
+	Remote    string       // network address of the peer
+	Identity  string       // identity of the peer
+	PeerType  SocketType   // socket type of the peer
+	Server    bool         // whether the local end is the ZMTP server, by default the end that accepted the connection
+	Mechanism SecurityType // security mechanism negotiated with the peer
+	Value     interface{}  // application-defined value set by the accept filter
+}
+
+// ConnectionLister is implemented by sockets that can describe their
+// connections.
+type ConnectionLister interface {
+	// Connections returns the live connections of the socket.
+	Connections() []ConnInfo
+}
+
+// EndpointManager is implemented by sockets that can be connected to
+// several endpoints, by calling Dial repeatedly.
+type EndpointManager interface {
+	// Endpoints returns the endpoints the socket is connected to with Dial.
+	Endpoints() []string
+
+	// Disconnect closes the connection to the endpoint ep, dialed
+	// previously, and stops reconnecting to it.
+	Disconnect(ep string) error
+}
+
+// Unbinder is implemented by sockets that can stop listening to one of
+// their end-points, without being closed.
+type Unbinder interface {
+	// Unbind stops listening to the end-point ep, and closes the
+	// connections accepted on it.
+	Unbind(ep string) error
+}
+
+// PeerReceiver is implemented by sockets that can tell which connection a
+// received message came from.
+type PeerReceiver interface {
+	// RecvFrom receives a complete message, and describes the connection
+	// it was received from.
+	RecvFrom() (Msg, ConnInfo, error)
+}
+
+// recvFrom receives a message with recv, which must keep the connection the
+// message was received from, and describes that connection.
+func recvFrom(recv func() (Msg, error)) (Msg, ConnInfo, error) {
+	msg, err := recv()
+	var info ConnInfo
+	if err == nil && msg.conn != nil {
+		info = msg.conn.info()
+	}
+	msg.conn = nil
+	return msg, info, err
+}
+
+// withoutConn drops the reference of a received message to its connection,
+// which is internal to the socket.
+func withoutConn(msg Msg, err error) (Msg, error) {
+	msg.conn = nil
+	return msg, err
+}
+
+// Mechanism returns the security mechanism negotiated with the peer during
+// the ZMTP handshake.
+func (c *Conn) Mechanism() SecurityType {
+	return c.sec.Type()
+}
+
+// info returns the description of the connection.
+func (c *Conn) info() ConnInfo {
+	info := ConnInfo{
+		Identity:  c.Peer.Meta[sysSockID],
+		PeerType:  SocketType(c.Peer.Meta[sysSockType]),
+		Server:    c.Server,
+		Mechanism: c.Mechanism(),
+		Value:     c.value,
+	}
+	if addr := c.rw.RemoteAddr(); addr != nil {
+		info.Remote = addr.String()
+	}
+	return info
+}
+
 // Open opens a ZMTP connection over rw with the given security, socket type and identity.
 // An optional onCloseErrorCB can be provided to inform the caller when this Conn is closed.
 // Open performs a complete ZMTP handshake.
+//
+// server is the ZMTP role of the local end: it is announced in the greeting
+// and selects the side of the security handshake. Sockets open the
+// connections they accept as servers and the ones they dial as clients,
+// unless created with WithAsServer.
 func Open(rw net.Conn, sec Security, sockType SocketType, sockID SocketIdentity, server bool, onCloseErrorCB func(c *Conn)) (*Conn, error) {
+	return openConn(rw, sec, sockType, sockID, server, "", onCloseErrorCB)
+}
+
+// openConn is Open for the connections of a socket whose authentication
+// domain is zapDomain (see WithZAPDomain).
+func openConn(rw net.Conn, sec Security, sockType SocketType, sockID SocketIdentity, server bool, zapDomain string, onCloseErrorCB func(c *Conn)) (*Conn, error) {
 	if rw == nil {
 		return nil, fmt.Errorf("zmq4: invalid nil read-writer")
 	}
@@ -81,7 +252,9 @@ func Open(rw net.Conn, sec Security, sockType SocketType, sockID SocketIdentity,
 		Server:         server,
 		Meta:           make(Metadata),
 		topics:         make(map[string]struct{}),
+		groups:         make(map[string]struct{}),
 		onCloseErrorCB: onCloseErrorCB,
+		zapDomain:      zapDomain,
 	}
 	conn.Meta[sysSockType] = string(conn.typ)
 	conn.Meta[sysSockID] = conn.id.String()
@@ -99,9 +272,75 @@ func Open(rw net.Conn, sec Security, sockType SocketType, sockID SocketIdentity,
 		return nil, fmt.Errorf("zmq4: could not initialize ZMTP connection: %w", err)
 	}
 
+	// the metadata from the authentication handler override the ones
+	// announced by the peer.
+	for k, v := range conn.zapMeta {
+		conn.Peer.Meta[k] = v
+	}
+
+	// the TLS handshake, if any, completed with the ZMTP greeting.
+	if id, ok := certIdentity(rw); ok {
+		conn.Peer.Meta[PeerCertIdentity] = id
+	}
+
 	return conn, nil
 }
 
+// Authenticate checks the peer of a connection accepted by a socket against
+// the authentication policies of the socket domain, while authentication is
+// started (see AuthStart), given the credentials it presented: the user name
+// and password for PLAIN, the client public key for CURVE.
+// Security mechanisms call it during the server side of their handshake,
+// and reject the peer with an ERROR command if it fails.
+// The error returned for denied peers wraps ErrAuthFailed.
+func (c *Conn) Authenticate(credentials ...[]byte) error {
+	addr := c.rw.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	meta, err := zapAuthenticate(zapRequest{
+		domain:      c.zapDomain,
+		address:     addr,
+		mechanism:   c.sec.Type(),
+		credentials: credentials,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	if len(meta) > 0 {
+		c.zapMeta = meta
+	}
+	return nil
+}
+
+// certIdentity returns the identity of the verified certificate presented by
+// the peer of a TLS connection: its subject common name or, if empty, its
+// first subject alternative name.
+func certIdentity(rw net.Conn) (string, bool) {
+	tc, ok := rw.(interface{ ConnectionState() tls.ConnectionState })
+	if !ok {
+		return "", false
+	}
+	chains := tc.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return "", false
+	}
+	cert := chains[0][0]
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName, true
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0], true
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0], true
+	case len(cert.IPAddresses) > 0:
+		return cert.IPAddresses[0].String(), true
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String(), true
+	}
+	return "", false
+}
+
 // init performs a ZMTP handshake over an io.ReadWriter
 func (conn *Conn) init(sec Security) error {
 	var err error
@@ -134,6 +373,10 @@ func (conn *Conn) greet(server bool) error {
 	send := greeting{Version: defaultVersion}
 	send.Sig.Header = sigHeader
 	send.Sig.Footer = sigFooter
+	if server && conn.sec.Type() != NullSecurity {
+		// the NULL mechanism has no server role.
+		send.Server = 1
+	}
 	kind := string(conn.sec.Type())
 	if len(kind) > len(send.Mechanism) {
 		return errSecMech
@@ -155,7 +398,11 @@ func (conn *Conn) greet(server bool) error {
 
 	peerKind := asString(recv.Mechanism[:])
 	if peerKind != kind {
-		return errBadSec
+		if peerKind == string(NullSecurity) {
+			// e.g. a peer reconnecting without the security it used to.
+			return fmt.Errorf("%w: %s peer on %s connection: downgrade rejected", errBadSec, peerKind, kind)
+		}
+		return fmt.Errorf("%w: %s peer on %s connection", errBadSec, peerKind, kind)
 	}
 
 	conn.Peer.Server, err = asBool(recv.Server)
@@ -176,11 +423,24 @@ func (c *Conn) SendCmd(name string, body []byte) error {
 	if err != nil {
 		return err
 	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
 	return c.send(true, buf, 0)
 }
 
 // SendMsg sends a ZMTP message over the wire.
 func (c *Conn) SendMsg(msg Msg) error {
+	c.wmu.Lock()
+	err := c.sendMsg(msg)
+	c.wmu.Unlock()
+	if err == nil {
+		c.tracer.emit(TraceSend, msg)
+		c.stats.record(TraceSend, msg)
+	}
+	return err
+}
+
+func (c *Conn) sendMsg(msg Msg) error {
 	if c.Closed() {
 		return ErrClosedConn
 	}
@@ -214,10 +474,9 @@ func (c *Conn) sendStream(msg Msg) error {
 	// First frame should be identity (for routing)
 	// Remaining frames are data
 	for _, frame := range msg.Frames {
-		_, err := c.rw.Write(frame)
+		_, err := c.wire().Write(frame)
 		if err != nil {
-			c.checkIO(err)
-			return err
+			return c.writeErr(err)
 		}
 	}
 	return nil
@@ -254,15 +513,6 @@ func (c *Conn) RecvMsg() (Msg, error) {
 		return msg, fmt.Errorf("zmq4: could not unmarshal ZMTP recv msg: %w", msg.err)
 	}
 
-	switch cmd.Name {
-	case CmdPing:
-		// send back a PONG immediately.
-		msg.err = c.SendCmd(CmdPong, nil)
-		if msg.err != nil {
-			return msg, msg.err
-		}
-	}
-
 	switch len(cmd.Body) {
 	case 0:
 		msg.Frames = nil
@@ -309,14 +559,67 @@ func (c *Conn) RecvCmd() (Cmd, error) {
 }
 
 func (c *Conn) sendMulti(msg Msg) error {
-	var buffers net.Buffers
+	defer c.lockCipher()()
+	buffers, err := c.appendFrames(nil, msg.Frames)
+	if err != nil {
+		return err
+	}
 
-	nframes := len(msg.Frames)
-	for i, frame := range msg.Frames {
+	if _, err := buffers.WriteTo(c.wire()); err != nil {
+		return c.writeErr(err)
+	}
+
+	return nil
+}
+
+// sendBatch sends the messages over the wire in a single coalesced write.
+func (c *Conn) sendBatch(msgs []Msg) error {
+	if c.Closed() {
+		return ErrClosedConn
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	defer c.lockCipher()()
+	var (
+		buffers = make(net.Buffers, 0, 4*len(msgs))
+		err     error
+	)
+	for _, msg := range msgs {
+		buffers, err = c.appendFrames(buffers, msg.Frames)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := buffers.WriteTo(c.wire()); err != nil {
+		return c.writeErr(err)
+	}
+
+	for _, msg := range msgs {
+		c.tracer.emit(TraceSend, msg)
+		c.stats.record(TraceSend, msg)
+	}
+	return nil
+}
+
+// appendFrames appends the wire encoding of the frames of a message to
+// buffers.
+func (c *Conn) appendFrames(buffers net.Buffers, frames [][]byte) (net.Buffers, error) {
+	nframes := len(frames)
+	for i, frame := range frames {
 		var flag byte
 		if i < nframes-1 {
 			flag ^= hasMoreBitFlag
 		}
+		if c.cipher != nil {
+			// the more flag is boxed with the frame.
+			boxed, err := c.cipher.Seal(frame, flag != 0, false)
+			if err != nil {
+				return buffers, fmt.Errorf("zmq4: could not seal frame: %w", err)
+			}
+			frame, flag = boxed, 0
+		}
 
 		size := len(frame)
 		isLong := size > 255
@@ -336,27 +639,31 @@ func (c *Conn) sendMulti(msg Msg) error {
 			hdr[1] = uint8(size)
 		}
 
-		switch c.sec.Type() {
-		case NullSecurity:
+		switch {
+		case c.sec.Type() == NullSecurity, c.cipher != nil:
 			buffers = append(buffers, hdr[:hsz], frame)
 		default:
 			var secBuf bytes.Buffer
 			if _, err := c.sec.Encrypt(&secBuf, frame); err != nil {
-				return err
+				return buffers, err
 			}
 			buffers = append(buffers, hdr[:hsz], secBuf.Bytes())
 		}
 	}
-
-	if _, err := buffers.WriteTo(c.rw); err != nil {
-		c.checkIO(err)
-		return err
-	}
-
-	return nil
+	return buffers, nil
 }
 
 func (c *Conn) send(isCommand bool, body []byte, flag byte) error {
+	if c.cipher != nil {
+		defer c.lockCipher()()
+		// the flags are boxed with the frame, sent as a plain final frame.
+		boxed, err := c.cipher.Seal(body, flag&hasMoreBitFlag != 0, isCommand)
+		if err != nil {
+			return fmt.Errorf("zmq4: could not seal frame: %w", err)
+		}
+		body, flag, isCommand = boxed, 0, false
+	}
+
 	// Long flag
 	size := len(body)
 	isLong := size > 255
@@ -381,14 +688,18 @@ func (c *Conn) send(isCommand bool, body []byte, flag byte) error {
 		hsz = 2
 		hdr[1] = uint8(size)
 	}
-	if _, err := c.rw.Write(hdr[:hsz]); err != nil {
-		c.checkIO(err)
-		return err
+	if _, err := c.wire().Write(hdr[:hsz]); err != nil {
+		return c.writeErr(err)
 	}
 
-	if _, err := c.sec.Encrypt(c.rw, body); err != nil {
-		c.checkIO(err)
-		return err
+	if c.cipher != nil {
+		if _, err := c.wire().Write(body); err != nil {
+			return c.writeErr(err)
+		}
+		return nil
+	}
+	if _, err := c.sec.Encrypt(c.wire(), body); err != nil {
+		return c.writeErr(err)
 	}
 
 	return nil
@@ -427,17 +738,55 @@ func (c *Conn) readStream() Msg {
 	return msg
 }
 
+// queued reports that a message read from c was queued for Recv, waking up
+// the pollers of the socket.
+func (c *Conn) queued() {
+	if c.notify != nil {
+		c.notify()
+	}
+}
+
 // read returns the isCommand flag, the body of the message, and optionally an error
+// The heartbeat commands are handled, rather than returned.
 func (c *Conn) read() Msg {
+	msg := c.readMsg()
+	for msg.err == nil && c.typ != Stream {
+		c.received()
+		if !msg.isCmd() && c.typ == Radio {
+			// DISH peers only send commands.
+			msg.Release()
+		} else if !msg.isCmd() || !c.handleCmd(msg) {
+			break
+		}
+		msg = c.readMsg()
+	}
+	if c.expired(msg.err) {
+		// the peer did not send anything within the TTL of its PINGs.
+		c.SetClosed()
+	}
+	if msg.err == nil && !msg.isCmd() {
+		msg.conn = c
+		if c.typ == Dish {
+			ungroup(&msg)
+		}
+		c.tracer.emit(TraceRecv, msg)
+		c.stats.record(TraceRecv, msg)
+	}
+	return msg
+}
+
+func (c *Conn) readMsg() Msg {
 	// STREAM sockets handle raw TCP data differently
 	if c.typ == Stream {
 		return c.readStream()
 	}
 
 	var (
-		header  [2]byte
-		longHdr [8]byte
+		header  = c.hdr[:2]
+		longHdr = c.hdr[:8]
 		msg     Msg
+		used    int // number of bytes of msg.buf holding frames
+		nframes int
 
 		hasMore = true
 		isCmd   = false
@@ -446,9 +795,13 @@ func (c *Conn) read() Msg {
 	for hasMore {
 
 		// Read out the header
-		_, msg.err = io.ReadFull(c.rw, header[:])
+		_, msg.err = c.readFull(header)
 		if msg.err != nil {
 			c.checkIO(msg.err)
+			if msg.err == io.EOF && msg.Frames != nil {
+				// the previous frame announced more frames.
+				msg.err = io.ErrUnexpectedEOF
+			}
 			return msg
 		}
 
@@ -457,6 +810,14 @@ func (c *Conn) read() Msg {
 		hasMore = fl.hasMore()
 		isCmd = isCmd || fl.isCommand()
 
+		nframes++
+		if c.maxFrames > 0 && nframes > c.maxFrames {
+			// the peer is misbehaving: drop the connection.
+			msg.err = fmt.Errorf("zmq4: received message has more than %d frames: %w", c.maxFrames, ErrTooManyFrames)
+			c.SetClosed()
+			return msg
+		}
+
 		// Determine the actual length of the body
 		size := uint64(header[1])
 		if fl.isLong() {
@@ -465,13 +826,13 @@ func (c *Conn) read() Msg {
 			// We already have the first byte, so assign it, and then read the rest
 			longHdr[0] = header[1]
 
-			_, msg.err = io.ReadFull(c.rw, longHdr[1:])
+			_, msg.err = c.readFull(longHdr[1:])
 			if msg.err != nil {
 				c.checkIO(msg.err)
 				return msg
 			}
 
-			size = binary.BigEndian.Uint64(longHdr[:])
+			size = binary.BigEndian.Uint64(longHdr)
 		}
 
 		if size > uint64(maxInt64) {
@@ -479,13 +840,49 @@ func (c *Conn) read() Msg {
 			return msg
 		}
 
-		body := make([]byte, size)
-		_, msg.err = io.ReadFull(c.rw, body)
+		// fast path for small user messages under NULL security: frames are
+		// decoded into a pooled buffer, handed back by Msg.Release.
+		if c.pooled && c.zc == nil && msg.Frames == nil && !isCmd && size <= smallMsgSize && c.sec.Type() == NullSecurity {
+			msg.buf = msgBufPool.Get().(*msgBuf)
+			msg.Frames = msg.buf.frames[:0]
+		}
+
+		var body []byte
+		switch {
+		case c.zc != nil && !isCmd && size <= zcChunkSize:
+			// zero-copy: the frame is left in place, in the read buffer.
+			body, msg.err = c.zc.next(int(size))
+			if msg.err == nil {
+				msg.holdChunk(c.zc.chunk)
+			}
+		case msg.buf != nil && uint64(used)+size <= smallMsgSize:
+			end := used + int(size)
+			body = msg.buf.data[used:end:end]
+			used = end
+			_, msg.err = c.readFull(body)
+		default:
+			body = make([]byte, size)
+			_, msg.err = c.readFull(body)
+		}
 		if msg.err != nil {
 			c.checkIO(msg.err)
 			return msg
 		}
 
+		if c.cipher != nil {
+			// the flags of the wire frame describe the box, not the frame.
+			var more, cmd bool
+			body, more, cmd, msg.err = c.cipher.Open(body)
+			if msg.err != nil {
+				msg.err = fmt.Errorf("zmq4: could not open frame: %w", msg.err)
+				return msg
+			}
+			hasMore = more
+			isCmd = isCmd || cmd
+			msg.Frames = append(msg.Frames, body)
+			continue
+		}
+
 		// fast path for NULL security: we bypass the bytes.Buffer allocation.
 		switch c.sec.Type() {
 		case NullSecurity: // FIXME(sbinet): also do that for non-encrypted PLAIN?
@@ -505,17 +902,23 @@ func (c *Conn) read() Msg {
 	return msg
 }
 
-func (conn *Conn) subscribe(msg Msg) {
+// subscribe applies the subscription message msg to the connection, and
+// returns whether its subscriptions changed.
+func (conn *Conn) subscribe(msg Msg) bool {
+	subscribe, topic, ok := ParseSubscription(msg.Frames[0])
+	if !ok {
+		return false
+	}
+	k := string(topic)
 	conn.mu.Lock()
-	v := msg.Frames[0]
-	k := string(v[1:])
-	switch v[0] {
-	case 0:
-		delete(conn.topics, k)
-	case 1:
+	defer conn.mu.Unlock()
+	_, had := conn.topics[k]
+	if subscribe {
 		conn.topics[k] = struct{}{}
+	} else {
+		delete(conn.topics, k)
 	}
-	conn.mu.Unlock()
+	return had != subscribe
 }
 
 func (conn *Conn) subscribed(topic string) bool {
@@ -533,6 +936,71 @@ func (conn *Conn) subscribed(topic string) bool {
 	return false
 }
 
+// handleCmd handles the command msg received from the peer if it concerns
+// the connection itself, as the heartbeats and the groups a DISH peer joins
+// do, and returns whether it did.
+func (c *Conn) handleCmd(msg Msg) bool {
+	if len(msg.Frames) != 1 {
+		return false
+	}
+	var cmd Cmd
+	if err := cmd.unmarshalZMTP(msg.Frames[0]); err != nil {
+		return false
+	}
+	switch {
+	case cmd.Name == CmdPing, cmd.Name == CmdPong:
+		c.heartbeatCmd(cmd)
+	case cmd.Name == CmdJoin && c.typ == Radio:
+		c.mu.Lock()
+		c.groups[string(cmd.Body)] = struct{}{}
+		c.mu.Unlock()
+	case cmd.Name == CmdLeave && c.typ == Radio:
+		c.mu.Lock()
+		delete(c.groups, string(cmd.Body))
+		c.mu.Unlock()
+	default:
+		return false
+	}
+	return true
+}
+
+// joined returns whether the peer joined exactly the provided group.
+func (conn *Conn) joined(group string) bool {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	_, ok := conn.groups[group]
+	return ok
+}
+
+// sendGroup sends msg to the peer as a message published to msg.Group: a
+// frame holding the group, followed by the frame of the message.
+func (c *Conn) sendGroup(msg Msg) error {
+	if c.Closed() {
+		return ErrClosedConn
+	}
+	c.wmu.Lock()
+	err := c.send(false, []byte(msg.Group), hasMoreBitFlag)
+	if err == nil {
+		err = c.sendMsg(msg)
+	}
+	c.wmu.Unlock()
+	if err == nil {
+		c.tracer.emit(TraceSend, msg)
+		c.stats.record(TraceSend, msg)
+	}
+	return err
+}
+
+// ungroup moves the group frame of a message received from a RADIO peer
+// to msg.Group. Malformed messages are left without a group.
+func ungroup(msg *Msg) {
+	if len(msg.Frames) != 2 {
+		return
+	}
+	msg.Group = string(msg.Frames[0])
+	msg.Frames = msg.Frames[1:]
+}
+
 func (conn *Conn) SetClosed() {
 	if wasClosed := atomic.CompareAndSwapInt32(&conn.closed, 0, 1); wasClosed {
 		conn.notifyOnCloseError()
@@ -543,12 +1011,20 @@ func (conn *Conn) Closed() bool {
 	return atomic.LoadInt32(&conn.closed) == 1
 }
 
+// drop closes the connection on behalf of its socket, which has removed it
+// already: the closing is not reported as a failure of the peer, i.e. the
+// socket neither emits EventDisconnected nor reconnects.
+func (conn *Conn) drop() error {
+	atomic.StoreInt32(&conn.dropped, 1)
+	return conn.Close()
+}
+
 func (conn *Conn) checkIO(err error) {
 	if err == nil {
 		return
 	}
 
-	if err == io.EOF || errors.Is(err, io.EOF) {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 		conn.SetClosed()
 		return
 	}
@@ -559,8 +1035,23 @@ func (conn *Conn) checkIO(err error) {
 	}
 }
 
+// writeErr checks the error of a write to the wire, wrapping it with
+// ErrPeerDisconnected if the peer went away.
+func (conn *Conn) writeErr(err error) error {
+	conn.checkIO(err)
+	switch {
+	case errors.Is(err, syscall.EPIPE),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, io.ErrClosedPipe):
+		conn.SetClosed()
+		return fmt.Errorf("%w: %w", ErrPeerDisconnected, err)
+	}
+	return err
+}
+
 func (conn *Conn) notifyOnCloseError() {
-	if conn.onCloseErrorCB == nil {
+	if conn.onCloseErrorCB == nil || atomic.LoadInt32(&conn.dropped) == 1 {
 		return
 	}
 	conn.onCloseErrorCB(conn)
@@ -0,0 +1,320 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// loopConn is a net.Conn endlessly serving the same wire data.
+type loopConn struct {
+	net.Conn
+	data []byte
+	pos  int
+}
+
+func (c *loopConn) Read(p []byte) (int, error) {
+	n := copy(p, c.data[c.pos:])
+	c.pos = (c.pos + n) % len(c.data)
+	return n, nil
+}
+
+// chunkConn is a net.Conn serving wire data at most chunk bytes at a time.
+type chunkConn struct {
+	net.Conn
+	data  []byte
+	chunk int
+}
+
+func (c *chunkConn) Read(p []byte) (int, error) {
+	if len(c.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), c.chunk)], c.data)
+	c.data = c.data[n:]
+	return n, nil
+}
+
+// wireFrames encodes frames as ZMTP frames, with a long header for frames
+// larger than 255 bytes.
+func wireFrames(isCmd bool, frames ...[]byte) []byte {
+	var buf []byte
+	for i, frame := range frames {
+		var fl byte
+		if i < len(frames)-1 {
+			fl |= hasMoreBitFlag
+		}
+		if isCmd {
+			fl |= isCommandBitFlag
+		}
+		if len(frame) > 255 {
+			buf = append(buf, fl|isLongBitFlag)
+			buf = binary.BigEndian.AppendUint64(buf, uint64(len(frame)))
+		} else {
+			buf = append(buf, fl, byte(len(frame)))
+		}
+		buf = append(buf, frame...)
+	}
+	return buf
+}
+
+func newLoopConn(data []byte) *Conn {
+	return &Conn{
+		typ:    Pair,
+		rw:     &loopConn{data: data},
+		sec:    nullSecurity{},
+		pooled: true,
+	}
+}
+
+func TestConnReadPooled(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 64)
+
+	t.Run("small", func(t *testing.T) {
+		c := newLoopConn(wireFrames(false, []byte{}, payload))
+		msg := c.read()
+		if msg.err != nil {
+			t.Fatalf("could not read msg: %+v", msg.err)
+		}
+		if msg.buf == nil {
+			t.Fatalf("small message not decoded into a pooled buffer")
+		}
+		if len(msg.Frames) != 2 || len(msg.Frames[0]) != 0 || !bytes.Equal(msg.Frames[1], payload) {
+			t.Fatalf("invalid msg: %v", msg)
+		}
+
+		// appending to a frame must not clobber the next one.
+		_ = append(msg.Frames[0], "clobber"...)
+		if !bytes.Equal(msg.Frames[1], payload) {
+			t.Fatalf("frame overwritten: %q", msg.Frames[1])
+		}
+
+		msg.Release()
+		if msg.Frames != nil || msg.buf != nil {
+			t.Fatalf("released msg still references its storage: %v", msg)
+		}
+		msg.Release() // no-op
+	})
+
+	t.Run("large", func(t *testing.T) {
+		c := newLoopConn(wireFrames(false, payload, payload, payload, payload, payload, payload))
+		msg := c.read()
+		if msg.err != nil {
+			t.Fatalf("could not read msg: %+v", msg.err)
+		}
+		if len(msg.Frames) != 6 {
+			t.Fatalf("invalid number of frames: got=%d, want=6", len(msg.Frames))
+		}
+		for i, frame := range msg.Frames {
+			if !bytes.Equal(frame, payload) {
+				t.Fatalf("invalid frame %d: %q", i, frame)
+			}
+		}
+		msg.Release()
+	})
+
+	t.Run("command", func(t *testing.T) {
+		c := newLoopConn(wireFrames(true, []byte("\x05READY")))
+		msg := c.read()
+		if msg.err != nil {
+			t.Fatalf("could not read msg: %+v", msg.err)
+		}
+		if msg.buf != nil {
+			t.Fatalf("command decoded into a pooled buffer")
+		}
+	})
+}
+
+func TestConnReadChunked(t *testing.T) {
+	frame := func(n int) []byte {
+		return bytes.Repeat([]byte{byte(n)}, n)
+	}
+	msgs := [][][]byte{
+		{frame(0), frame(1), frame(255)},
+		{frame(256)},
+		{frame(3), frame(70000), frame(0), frame(254)},
+		{frame(0)},
+	}
+	var data []byte
+	for _, frames := range msgs {
+		data = append(data, wireFrames(false, frames...)...)
+	}
+
+	// chunk sizes splitting the wire data within short and long headers,
+	// and within frame bodies.
+	for _, chunk := range []int{1, 2, 3, 5, 8, 9, 10, 4096} {
+		for _, pooled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("chunk=%d/pooled=%v", chunk, pooled), func(t *testing.T) {
+				c := &Conn{
+					typ:    Pair,
+					rw:     &chunkConn{data: append([]byte(nil), data...), chunk: chunk},
+					sec:    nullSecurity{},
+					pooled: pooled,
+				}
+				for i, want := range msgs {
+					msg := c.read()
+					if msg.err != nil {
+						t.Fatalf("could not read msg #%d: %+v", i, msg.err)
+					}
+					if msg.isCmd() {
+						t.Fatalf("msg #%d decoded as a command", i)
+					}
+					if !reflect.DeepEqual(msg.Frames, want) {
+						t.Fatalf("invalid msg #%d: got %d frames, want %d frames", i, len(msg.Frames), len(want))
+					}
+					msg.Release()
+				}
+				if msg := c.read(); msg.err != io.EOF {
+					t.Fatalf("invalid error at end of stream: got=%v, want=%v", msg.err, io.EOF)
+				}
+			})
+		}
+	}
+
+	// a message cut within a long header or a frame body is not delivered.
+	for _, n := range []int{3, 6, 300} {
+		c := &Conn{
+			typ: Pair,
+			rw:  &chunkConn{data: wireFrames(false, frame(1), frame(1000))[:n], chunk: 2},
+			sec: nullSecurity{},
+		}
+		if msg := c.read(); msg.err != io.ErrUnexpectedEOF {
+			t.Fatalf("truncated at %d: invalid error: got=%v, want=%v", n, msg.err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+func TestConnReadMaxFrames(t *testing.T) {
+	frames := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	for _, tc := range []struct {
+		max int
+		ok  bool
+	}{
+		{max: 0, ok: true},
+		{max: 3, ok: true},
+		{max: 2, ok: false},
+	} {
+		t.Run(fmt.Sprintf("max=%d", tc.max), func(t *testing.T) {
+			c := &Conn{
+				typ:       Pair,
+				rw:        &chunkConn{data: wireFrames(false, frames...), chunk: 4096},
+				sec:       nullSecurity{},
+				maxFrames: tc.max,
+			}
+			msg := c.read()
+			if tc.ok {
+				if msg.err != nil {
+					t.Fatalf("could not read msg: %+v", msg.err)
+				}
+				if !reflect.DeepEqual(msg.Frames, frames) {
+					t.Fatalf("invalid msg: %v", msg)
+				}
+				return
+			}
+			if !errors.Is(msg.err, ErrTooManyFrames) {
+				t.Fatalf("invalid error: got=%v, want=%v", msg.err, ErrTooManyFrames)
+			}
+			if !c.Closed() {
+				t.Fatalf("connection not closed")
+			}
+		})
+	}
+}
+
+// writeConn is a net.Conn recording the wire data written to it.
+type writeConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *writeConn) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
+
+func TestConnGroups(t *testing.T) {
+	payload := []byte{0x00, 0x01, 0x02}
+
+	// a RADIO connection sends the group, then the message.
+	w := &writeConn{}
+	radio := &Conn{typ: Radio, rw: w, sec: nullSecurity{}}
+	if err := radio.sendGroup(Msg{Frames: [][]byte{payload}, Group: "grp"}); err != nil {
+		t.Fatalf("could not send group message: %+v", err)
+	}
+	if got, want := w.buf.Bytes(), wireFrames(false, []byte("grp"), payload); !bytes.Equal(got, want) {
+		t.Fatalf("invalid wire data: got=%q, want=%q", got, want)
+	}
+
+	// a DISH connection reports the group of the messages it reads.
+	dish := &Conn{
+		typ: Dish,
+		rw:  &chunkConn{data: wireFrames(false, []byte("grp"), payload), chunk: 4096},
+		sec: nullSecurity{},
+	}
+	msg := dish.read()
+	if msg.err != nil {
+		t.Fatalf("could not read group message: %+v", msg.err)
+	}
+	if msg.Group != "grp" || !reflect.DeepEqual(msg.Frames, [][]byte{payload}) {
+		t.Fatalf("invalid group message: got=%q (group=%q)", msg.Frames, msg.Group)
+	}
+
+	// a RADIO connection applies the JOIN and LEAVE commands of its peer,
+	// and drops its messages.
+	var data []byte
+	for _, frame := range [][]byte{
+		[]byte("\x04JOINgrp"),
+		[]byte("\x04JOINold"),
+		[]byte("\x05LEAVEold"),
+	} {
+		data = append(data, wireFrames(true, frame)...)
+	}
+	data = append(data, wireFrames(false, []byte("dropped"))...)
+	radio = &Conn{
+		typ:    Radio,
+		rw:     &chunkConn{data: data, chunk: 4096},
+		sec:    nullSecurity{},
+		groups: make(map[string]struct{}),
+	}
+	if msg := radio.read(); !errors.Is(msg.err, io.EOF) {
+		t.Fatalf("invalid read: got=%v (err=%v), want=%v", msg, msg.err, io.EOF)
+	}
+	if !radio.joined("grp") || radio.joined("old") {
+		t.Fatalf("invalid groups: %q", radio.groups)
+	}
+}
+
+func BenchmarkConnRead(b *testing.B) {
+	data := wireFrames(false, []byte{}, make([]byte, 64))
+
+	b.Run("Small-64B", func(b *testing.B) {
+		c := newLoopConn(data)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			msg := c.read()
+			if msg.err != nil {
+				b.Fatal(msg.err)
+			}
+		}
+	})
+
+	b.Run("Small-64B-Release", func(b *testing.B) {
+		c := newLoopConn(data)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			msg := c.read()
+			if msg.err != nil {
+				b.Fatal(msg.err)
+			}
+			msg.Release()
+		}
+	})
+}
@@ -7,102 +7,334 @@
 
 package zmq4
 
+/*
+#cgo !windows pkg-config: libczmq libzmq libsodium
+#cgo windows LDFLAGS: -lws2_32 -liphlpapi -lrpcrt4 -lsodium -lzmq -lczmq
+
+#include "czmq.h"
+*/
+import "C"
+
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	czmq4 "github.com/luxfi/czmq/v4"
 )
 
 func NewCPair(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Pair, opts...)
+	return newCSocket(ctx, czmq4.Pair, opts...)
 }
 
 func NewCPub(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Pub, opts...)
+	return newCSocket(ctx, czmq4.Pub, opts...)
 }
 
 func NewCSub(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Sub, opts...)
+	return newCSocket(ctx, czmq4.Sub, opts...)
 }
 
 func NewCReq(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Req, opts...)
+	return newCSocket(ctx, czmq4.Req, opts...)
 }
 
 func NewCRep(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Rep, opts...)
+	return newCSocket(ctx, czmq4.Rep, opts...)
 }
 
 func NewCDealer(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Dealer, opts...)
+	return newCSocket(ctx, czmq4.Dealer, opts...)
 }
 
 func NewCRouter(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Router, opts...)
+	return newCSocket(ctx, czmq4.Router, opts...)
 }
 
 func NewCPull(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Pull, opts...)
+	return newCSocket(ctx, czmq4.Pull, opts...)
 }
 
 func NewCPush(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Push, opts...)
+	return newCSocket(ctx, czmq4.Push, opts...)
 }
 
 func NewCXPub(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.XPub, opts...)
+	return newCSocket(ctx, czmq4.XPub, opts...)
 }
 
 func NewCXSub(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.XSub, opts...)
+	return newCSocket(ctx, czmq4.XSub, opts...)
 }
 
 func NewCStream(ctx context.Context, opts ...czmq4.SockOption) Socket {
-	return newCSocket(czmq4.Stream, opts...)
+	return newCSocket(ctx, czmq4.Stream, opts...)
+}
+
+// cFallback is whether the NewC* constructors fall back to the pure-Go
+// backend when a czmq socket cannot be created.
+var cFallback atomic.Bool
+
+// SetCZMQFallback configures what the NewC* constructors return when the
+// czmq backend cannot create a socket, e.g. when libzmq fails to initialize:
+// if enable is true, they log a warning and return a pure-Go socket of the
+// same type, ignoring the czmq socket options.
+// Otherwise (the default), they return a socket whose operations fail with
+// an error wrapping ErrBackendUnavailable.
+func SetCZMQFallback(enable bool) {
+	cFallback.Store(enable)
+}
+
+// cNewSock creates a czmq socket of type ctyp.
+// It is a variable so tests can simulate an unavailable backend.
+var cNewSock = func(ctyp int, opts ...czmq4.SockOption) (*czmq4.Sock, error) {
+	// czmq4.NewSock does not report the failures of zsock_new: the global
+	// context of czmq, zsock_new creates its sockets in, is checked
+	// beforehand, and the handle of the created socket afterwards.
+	if C.zsys_init() == nil {
+		return nil, errors.New("zmq4: could not initialize the czmq context")
+	}
+	sock := czmq4.NewSock(ctyp)
+	if !cSockCreated(sock) {
+		return nil, errors.New("zmq4: zsock_new failed")
+	}
+	for _, opt := range opts {
+		opt(sock)
+	}
+	return sock, nil
+}
+
+// cSockCreated reports whether zsock_new succeeded for sock.
+// czmq4.NewSock always returns a non-nil *Sock, even when zsock_new fails
+// and leaves its zsock_t handle nil, and exposes no accessor for that
+// handle: its unexported zsockT field is inspected instead. An unknown
+// layout of czmq4.Sock is reported as a success, the failure then
+// surfacing on the first operation on the socket.
+func cSockCreated(sock *czmq4.Sock) bool {
+	h := reflect.ValueOf(sock).Elem().FieldByName("zsockT")
+	if !h.IsValid() || h.Kind() != reflect.Ptr {
+		return true
+	}
+	return !h.IsNil()
+}
+
+// pureSockets are the pure-Go constructors of the czmq socket types.
+var pureSockets = map[int]func(context.Context, ...Option) Socket{
+	czmq4.Pair:   NewPair,
+	czmq4.Pub:    NewPub,
+	czmq4.Sub:    NewSub,
+	czmq4.Req:    NewReq,
+	czmq4.Rep:    NewRep,
+	czmq4.Dealer: NewDealer,
+	czmq4.Router: NewRouter,
+	czmq4.Pull:   NewPull,
+	czmq4.Push:   NewPush,
+	czmq4.XPub:   NewXPub,
+	czmq4.XSub:   NewXSub,
+	czmq4.Stream: NewStream,
 }
 
 type csocket struct {
+	ctx  context.Context
+	ctyp int
 	sock *czmq4.Sock
 	addr net.Addr
+	done chan struct{}
+	err  error // why sock could not be created, if nil
+
+	closeOnce sync.Once // destroys sock and closes done, see Close
+
+	mu           sync.Mutex
+	sendDeadline time.Time // see SetSendDeadline
+	recvDeadline time.Time // see SetRecvDeadline
 }
 
-func newCSocket(ctyp int, opts ...czmq4.SockOption) *csocket {
-	sck := &csocket{sock: czmq4.NewSock(ctyp)}
-	for _, opt := range opts {
-		opt(sck.sock)
+func newCSocket(ctx context.Context, ctyp int, opts ...czmq4.SockOption) Socket {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sck := &csocket{ctx: ctx, ctyp: ctyp, done: make(chan struct{})}
+	sock, err := cNewSock(ctyp, opts...)
+	if err != nil {
+		sck.err = fmt.Errorf("zmq4: could not create czmq %s socket (%v): %w", sck.Type(), err, ErrBackendUnavailable)
+		if cFallback.Load() {
+			log.Printf("%v: falling back to the pure-Go backend", sck.err)
+			return pureSockets[ctyp](ctx)
+		}
+		return sck
 	}
+	sck.sock = sock
 	return sck
 }
 
 func (sck *csocket) Close() error {
-	sck.sock.Destroy()
+	first := false
+	sck.closeOnce.Do(func() {
+		first = true
+		if sck.err == nil {
+			sck.sock.Destroy()
+		}
+		close(sck.done)
+	})
+	if !first {
+		return fmt.Errorf("zmq4: socket already closed")
+	}
 	return nil
 }
 
+// Done returns a channel that is closed once the socket is destroyed by
+// Close.
+func (sck *csocket) Done() <-chan struct{} {
+	return sck.done
+}
+
+// Context returns the context the socket was created with.
+// The czmq backend does not tear the socket down when it is done:
+// Close must still be called.
+func (sck *csocket) Context() context.Context {
+	return sck.ctx
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (sck *csocket) Send(msg Msg) error {
-	return sck.sock.SendMessage(msg.Frames)
+	if sck.err != nil {
+		return sck.err
+	}
+	return sck.send(msg)
+}
+
+// send sends msg before the send deadline of the socket, if any.
+func (sck *csocket) send(msg Msg) error {
+	sck.mu.Lock()
+	deadline := sck.sendDeadline
+	sck.mu.Unlock()
+	if !deadline.IsZero() {
+		sck.sock.SetOption(czmq4.SockSetSndtimeo(cTimeout(deadline)))
+	}
+	err := sck.sock.SendMessage(msg.Frames)
+	if err != nil && !deadline.IsZero() && !time.Now().Before(deadline) {
+		return ErrTimeout
+	}
+	return err
 }
 
 // SendMulti puts the message on the outbound send queue.
 // SendMulti blocks until the message can be queued or the send deadline expires.
 // The message will be sent as a multipart message.
 func (sck *csocket) SendMulti(msg Msg) error {
+	if sck.err != nil {
+		return sck.err
+	}
+	return sck.send(msg)
+}
+
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (sck *csocket) SendNB(msg Msg) error {
+	if sck.err != nil {
+		return sck.err
+	}
+	if !sck.sock.Pollout() {
+		return ErrWouldBlock
+	}
 	return sck.sock.SendMessage(msg.Frames)
 }
 
+// Flush returns immediately: the czmq backend does not expose the state
+// of its outbound queue.
+func (sck *csocket) Flush(ctx context.Context) error {
+	return ctx.Err()
+}
+
 // Recv receives a complete message.
 func (sck *csocket) Recv() (Msg, error) {
+	if sck.err != nil {
+		return Msg{err: sck.err}, sck.err
+	}
+	sck.mu.Lock()
+	deadline := sck.recvDeadline
+	sck.mu.Unlock()
+	if !deadline.IsZero() {
+		sck.sock.SetOption(czmq4.SockSetRcvtimeo(cTimeout(deadline)))
+	}
+	frames, err := sck.sock.RecvMessage()
+	if err != nil && !deadline.IsZero() && !time.Now().Before(deadline) {
+		err = ErrTimeout
+	}
+	return Msg{Frames: frames}, err
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+// czmq never delivers the frames of a message cut short by the
+// disconnection of its peer: RecvMulti is Recv.
+func (sck *csocket) RecvMulti() (Msg, error) {
+	return sck.Recv()
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations,
+// applied as the send timeout of the czmq socket when they start.
+// A zero t clears the deadline.
+func (sck *csocket) SetSendDeadline(t time.Time) error {
+	if sck.err != nil {
+		return sck.err
+	}
+	sck.mu.Lock()
+	defer sck.mu.Unlock()
+	sck.sendDeadline = t
+	if t.IsZero() {
+		sck.sock.SetOption(czmq4.SockSetSndtimeo(-1))
+	}
+	return nil
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations,
+// applied as the receive timeout of the czmq socket when they start.
+// A zero t clears the deadline.
+func (sck *csocket) SetRecvDeadline(t time.Time) error {
+	if sck.err != nil {
+		return sck.err
+	}
+	sck.mu.Lock()
+	defer sck.mu.Unlock()
+	sck.recvDeadline = t
+	if t.IsZero() {
+		sck.sock.SetOption(czmq4.SockSetRcvtimeo(-1))
+	}
+	return nil
+}
+
+// cTimeout returns the czmq timeout, in milliseconds, of an operation
+// starting now and bound by deadline.
+func cTimeout(deadline time.Time) int {
+	return max(int(time.Until(deadline)/time.Millisecond), 0)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (sck *csocket) RecvNB() (Msg, error) {
+	if sck.err != nil {
+		return Msg{err: sck.err}, sck.err
+	}
+	if !sck.sock.Pollin() {
+		return Msg{}, ErrWouldBlock
+	}
 	frames, err := sck.sock.RecvMessage()
 	return Msg{Frames: frames}, err
 }
 
 // Listen connects a local endpoint to the Socket.
 func (sck *csocket) Listen(addr string) error {
+	if sck.err != nil {
+		return sck.err
+	}
 	port, err := sck.sock.Bind(addr)
 	if err != nil {
 		return err
@@ -113,12 +345,15 @@ func (sck *csocket) Listen(addr string) error {
 
 // Dial connects a remote endpoint to the Socket.
 func (sck *csocket) Dial(addr string) error {
+	if sck.err != nil {
+		return sck.err
+	}
 	return sck.sock.Connect(addr)
 }
 
 // Type returns the type of this Socket (PUB, SUB, ...)
 func (sck *csocket) Type() SocketType {
-	switch sck.sock.GetType() {
+	switch sck.ctyp {
 	case czmq4.Pair:
 		return Pair
 	case czmq4.Pub:
@@ -153,6 +388,14 @@ func (sck *csocket) Addr() net.Addr {
 	return sck.addr
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (sck *csocket) LastEndpoint() (string, error) {
+	if sck.err != nil {
+		return "", sck.err
+	}
+	return czmq4.LastEndpoint(sck.sock), nil
+}
+
 // Conn returns the underlying net.Conn the socket is bound to.
 func (sck *csocket) Conn() net.Conn {
 	panic("not implemented")
@@ -160,26 +403,107 @@ func (sck *csocket) Conn() net.Conn {
 
 // GetOption is used to retrieve an option for a socket.
 func (sck *csocket) GetOption(name string) (interface{}, error) {
+	if sck.err != nil {
+		return nil, sck.err
+	}
+	switch name {
+	case OptionIdentity:
+		return czmq4.Identity(sck.sock), nil
+	case OptionHWM, OptionSndHWM:
+		return czmq4.Sndhwm(sck.sock), nil
+	case OptionRcvHWM:
+		return czmq4.Rcvhwm(sck.sock), nil
+	case OptionLinger:
+		ms := czmq4.Linger(sck.sock)
+		if ms < 0 {
+			return time.Duration(-1), nil
+		}
+		return time.Duration(ms) * time.Millisecond, nil
+	case OptionReconnectIvl:
+		return time.Duration(czmq4.ReconnectIvl(sck.sock)) * time.Millisecond, nil
+	case OptionReconnectIvlMax:
+		return time.Duration(czmq4.ReconnectIvlMax(sck.sock)) * time.Millisecond, nil
+	}
 	panic("not implemented")
 }
 
 // SetOption is used to set an option for a socket.
 func (sck *csocket) SetOption(name string, value interface{}) error {
+	if sck.err != nil {
+		return sck.err
+	}
 	switch name {
 	case OptionSubscribe:
-		topic := value.(string)
+		topic, err := optionString(name, value)
+		if err != nil {
+			return err
+		}
 		sck.sock.SetOption(czmq4.SockSetSubscribe(topic))
 		return nil
 	case OptionUnsubscribe:
-		topic := value.(string)
+		topic, err := optionString(name, value)
+		if err != nil {
+			return err
+		}
 		sck.sock.SetOption(czmq4.SockSetUnsubscribe(topic))
 		return nil
+	case OptionLinger:
+		linger, err := lingerValue(value)
+		if err != nil {
+			return err
+		}
+		ms := -1
+		if linger >= 0 {
+			ms = int(linger / time.Millisecond)
+		}
+		sck.sock.SetOption(czmq4.SockSetLinger(ms))
+		return nil
+	case OptionIdentity:
+		id, err := optionValue(name, value)
+		if err != nil {
+			return err
+		}
+		sck.sock.SetOption(czmq4.SockSetIdentity(id.(string)))
+		return nil
+	case OptionReconnectIvl, OptionReconnectIvlMax:
+		v, err := optionValue(name, value)
+		if err != nil {
+			return err
+		}
+		ms := int(v.(time.Duration) / time.Millisecond)
+		if name == OptionReconnectIvl {
+			sck.sock.SetOption(czmq4.SockSetReconnectIvl(ms))
+		} else {
+			sck.sock.SetOption(czmq4.SockSetReconnectIvlMax(ms))
+		}
+		return nil
+	case OptionHWM, OptionSndHWM, OptionRcvHWM:
+		v, err := optionValue(name, value)
+		if err != nil {
+			return err
+		}
+		if name == OptionRcvHWM {
+			sck.sock.SetOption(czmq4.SockSetRcvhwm(v.(int)))
+		} else {
+			sck.sock.SetOption(czmq4.SockSetSndhwm(v.(int)))
+		}
+		return nil
 	default:
 		panic("unknown set option name [" + name + "]")
 	}
 	panic("not implemented")
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (sck *csocket) Reader() SocketReader {
+	return socketReader{sck}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (sck *csocket) Writer() SocketWriter {
+	return socketWriter{sck}
+}
+
 // CWithID configures a ZeroMQ socket identity.
 func CWithID(id SocketIdentity) czmq4.SockOption {
 	return czmq4.SockSetIdentity(string(id))
@@ -0,0 +1,99 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo
+// +build cgo
+
+package zmq4
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	czmq4 "github.com/luxfi/czmq/v4"
+)
+
+func TestCSocketBackendUnavailable(t *testing.T) {
+	defer func(f func(int, ...czmq4.SockOption) (*czmq4.Sock, error)) { cNewSock = f }(cNewSock)
+	cNewSock = func(int, ...czmq4.SockOption) (*czmq4.Sock, error) {
+		return nil, errors.New("simulated failure")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("error", func(t *testing.T) {
+		sck := NewCPush(ctx)
+		defer sck.Close()
+		if got, want := sck.Type(), Push; got != want {
+			t.Fatalf("invalid socket type: got=%v, want=%v", got, want)
+		}
+		for name, err := range map[string]error{
+			"listen": sck.Listen("tcp://127.0.0.1:0"),
+			"dial":   sck.Dial("tcp://127.0.0.1:1"),
+			"send":   sck.Send(NewMsgString("hello")),
+		} {
+			if !errors.Is(err, ErrBackendUnavailable) {
+				t.Fatalf("invalid %s error: got=%v, want=%v", name, err, ErrBackendUnavailable)
+			}
+		}
+		if _, err := sck.Recv(); !errors.Is(err, ErrBackendUnavailable) {
+			t.Fatalf("invalid recv error: got=%v, want=%v", err, ErrBackendUnavailable)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		sck := NewCPush(ctx)
+		if err := sck.Close(); err != nil {
+			t.Fatalf("could not close: %+v", err)
+		}
+		select {
+		case <-sck.Done():
+		default:
+			t.Fatalf("socket not done after Close")
+		}
+		// closing again reports an error, as the pure-Go sockets do,
+		// without panicking.
+		if err := sck.Close(); err == nil {
+			t.Fatalf("expected an error closing a closed socket")
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		SetCZMQFallback(true)
+		defer SetCZMQFallback(false)
+
+		sck := NewCPush(ctx)
+		defer sck.Close()
+		if _, ok := sck.(*pushSocket); !ok {
+			t.Fatalf("invalid fallback socket: %T", sck)
+		}
+		if err := sck.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen with fallback socket: %+v", err)
+		}
+	})
+}
+
+func TestCNewSockFailure(t *testing.T) {
+	// zsock_new fails for an unknown socket type, leaving the returned
+	// czmq4.Sock without a zsock_t handle.
+	sock, err := cNewSock(-1)
+	if err == nil {
+		sock.Destroy()
+		t.Fatalf("expected an error creating a czmq socket of unknown type")
+	}
+	if sock != nil {
+		t.Fatalf("invalid socket: got=%v, want=nil", sock)
+	}
+
+	sock, err = cNewSock(czmq4.Push)
+	if err != nil {
+		t.Fatalf("could not create czmq socket: %+v", err)
+	}
+	defer sock.Destroy()
+	if !cSockCreated(sock) {
+		t.Fatalf("created czmq socket reported as failed")
+	}
+}
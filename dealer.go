@@ -6,16 +6,35 @@ package zmq4
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"time"
 )
 
 // NewDealer returns a new DEALER ZeroMQ socket.
 // The returned socket value is initially unbound.
+//
+// DEALER sockets send and receive frames as-is: unlike REQ sockets, they
+// neither add nor strip the empty delimiter frame REP sockets expect.
+// See NewEnvelopeMsg and SplitEnvelope.
 func NewDealer(ctx context.Context, opts ...Option) Socket {
 	dealer := &dealerSocket{newSocket(ctx, Dealer, opts...)}
+	dealer.sck.self = dealer
+	if dealer.sck.sndHWM > 0 {
+		dealer.sck.w = newQWriter(dealer.sck.ctx, dealer.sck.sndHWM)
+	} else {
+		dealer.sck.w = newLBWriter(dealer.sck.ctx)
+	}
 	return dealer
 }
 
+// Broadcaster is implemented by sockets that can send a message to all their
+// peers at once, like DEALER sockets.
+type Broadcaster interface {
+	// SendAll sends the message to every connected peer.
+	SendAll(msg Msg) error
+}
+
 // dealerSocket is a DEALER ZeroMQ socket.
 type dealerSocket struct {
 	sck *socket
@@ -26,6 +45,77 @@ func (dealer *dealerSocket) Close() error {
 	return dealer.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (dealer *dealerSocket) Context() context.Context {
+	return dealer.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (dealer *dealerSocket) Done() <-chan struct{} {
+	return dealer.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (dealer *dealerSocket) MessageTrace() <-chan TraceEvent {
+	return dealer.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (dealer *dealerSocket) Stats() Stats {
+	return dealer.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (dealer *dealerSocket) Connections() []ConnInfo {
+	return dealer.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (dealer *dealerSocket) Endpoints() []string {
+	return dealer.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (dealer *dealerSocket) Disconnect(ep string) error {
+	return dealer.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (dealer *dealerSocket) Monitor(events EventType) <-chan SocketEvent {
+	return dealer.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (dealer *dealerSocket) GetMonitorChannel() <-chan SocketEvent {
+	return dealer.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (dealer *dealerSocket) MonitorDropped() uint64 {
+	return dealer.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (dealer *dealerSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return dealer.sck.recvOrEvent(ctx, dealer.Recv)
+}
+
+func (dealer *dealerSocket) events() State {
+	return dealer.sck.events()
+}
+
+func (dealer *dealerSocket) watch(fn func()) func() {
+	return dealer.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (dealer *dealerSocket) Send(msg Msg) error {
@@ -39,16 +129,83 @@ func (dealer *dealerSocket) SendMulti(msg Msg) error {
 	return dealer.sck.SendMulti(msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (dealer *dealerSocket) SendNB(msg Msg) error {
+	return dealer.sck.SendNB(msg)
+}
+
+// SendAll sends the message to every connected peer, regardless of how Send
+// distributes messages. SendAll blocks until the message was written to all
+// the peers or the send deadline expires, and returns the errors of all the
+// peers that could not be sent the message.
+func (dealer *dealerSocket) SendAll(msg Msg) error {
+	dealer.sck.mu.RLock()
+	closed := dealer.sck.isClosed
+	dealer.sck.mu.RUnlock()
+	if closed {
+		return fmt.Errorf("zmq4: socket is closed")
+	}
+	if err := dealer.sck.checkFrames(msg); err != nil {
+		return err
+	}
+
+	ctx, cancel := dealer.sck.sendContext()
+	defer cancel()
+	return dealer.sck.w.(allWriter).writeAll(ctx, msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (dealer *dealerSocket) Flush(ctx context.Context) error {
+	return dealer.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (dealer *dealerSocket) Recv() (Msg, error) {
 	return dealer.sck.Recv()
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (dealer *dealerSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(dealer.sck.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (dealer *dealerSocket) RecvNB() (Msg, error) {
+	return dealer.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (dealer *dealerSocket) RecvMulti() (Msg, error) {
+	return recvMulti(dealer.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (dealer *dealerSocket) SetSendDeadline(t time.Time) error {
+	return dealer.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (dealer *dealerSocket) SetRecvDeadline(t time.Time) error {
+	return dealer.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (dealer *dealerSocket) Listen(ep string) error {
 	return dealer.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (dealer *dealerSocket) Unbind(ep string) error {
+	return dealer.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (dealer *dealerSocket) Dial(ep string) error {
 	return dealer.sck.Dial(ep)
@@ -65,6 +222,11 @@ func (dealer *dealerSocket) Addr() net.Addr {
 	return dealer.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (dealer *dealerSocket) LastEndpoint() (string, error) {
+	return dealer.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (dealer *dealerSocket) GetOption(name string) (interface{}, error) {
 	return dealer.sck.GetOption(name)
@@ -75,6 +237,24 @@ func (dealer *dealerSocket) SetOption(name string, value interface{}) error {
 	return dealer.sck.SetOption(name, value)
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (dealer *dealerSocket) Reader() SocketReader {
+	return socketReader{dealer}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (dealer *dealerSocket) Writer() SocketWriter {
+	return socketWriter{dealer}
+}
+
 var (
-	_ Socket = (*dealerSocket)(nil)
+	_ Socket           = (*dealerSocket)(nil)
+	_ MessageTracer    = (*dealerSocket)(nil)
+	_ ConnectionLister = (*dealerSocket)(nil)
+	_ EndpointManager  = (*dealerSocket)(nil)
+	_ PeerReceiver     = (*dealerSocket)(nil)
+	_ Monitored        = (*dealerSocket)(nil)
+	_ EventReceiver    = (*dealerSocket)(nil)
+	_ StatsReporter    = (*dealerSocket)(nil)
+	_ Broadcaster      = (*dealerSocket)(nil)
 )
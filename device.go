@@ -0,0 +1,65 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import "fmt"
+
+// DeviceType is the kind of a device started with Device.
+type DeviceType int
+
+const (
+	DeviceQueue     DeviceType = iota // shared queue: ROUTER frontend, DEALER backend
+	DeviceForwarder                   // forwarder: XSUB frontend, XPUB backend
+	DeviceStreamer                    // streamer: PULL frontend, PUSH backend
+)
+
+func (kind DeviceType) String() string {
+	switch kind {
+	case DeviceQueue:
+		return "QUEUE"
+	case DeviceForwarder:
+		return "FORWARDER"
+	case DeviceStreamer:
+		return "STREAMER"
+	default:
+		return fmt.Sprintf("DeviceType(%d)", int(kind))
+	}
+}
+
+// sockets returns the types of the frontend and backend sockets of the
+// device.
+func (kind DeviceType) sockets() (frontend, backend SocketType, ok bool) {
+	switch kind {
+	case DeviceQueue:
+		return Router, Dealer, true
+	case DeviceForwarder:
+		return XSub, XPub, true
+	case DeviceStreamer:
+		return Pull, Push, true
+	default:
+		return "", "", false
+	}
+}
+
+// Device runs a device of the given kind, forwarding messages between
+// frontend and backend with Proxy, until either socket is closed.
+// Device returns an error wrapping ErrProxyWiring if the types of the
+// sockets do not match the kind of device.
+func Device(kind DeviceType, frontend, backend Socket) error {
+	if frontend == nil || backend == nil {
+		return fmt.Errorf("frontend and backend sockets are required")
+	}
+	front, back, ok := kind.sockets()
+	if !ok {
+		return fmt.Errorf("zmq4: unknown device type %v", kind)
+	}
+	if frontend.Type() != front || backend.Type() != back {
+		return fmt.Errorf(
+			"%w: %v device needs a %v frontend and a %v backend, got %v and %v",
+			ErrProxyWiring, kind, front, back, frontend.Type(), backend.Type(),
+		)
+	}
+	return Proxy(frontend, backend, nil)
+}
@@ -0,0 +1,309 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewDish returns a new DISH ZeroMQ socket.
+// The returned socket value is initially unbound.
+//
+// A DISH socket receives the messages its RADIO peers publish to the groups
+// it joined with OptionJoin, and reports their group in Msg.Group.
+func NewDish(ctx context.Context, opts ...Option) Socket {
+	dish := &dishSocket{sck: newSocket(ctx, Dish, opts...)}
+	dish.sck.self = dish
+	dish.sck.w = nil
+	dish.sck.dishGroups = dish.joined
+	dish.groups = make(map[string]int)
+	return dish
+}
+
+// dishSocket is a DISH ZeroMQ socket.
+type dishSocket struct {
+	sck *socket
+
+	mu     sync.RWMutex
+	groups map[string]int // number of joins per group
+}
+
+// Close closes the open Socket
+func (dish *dishSocket) Close() error {
+	return dish.sck.Close()
+}
+
+// Context returns the life-line of the socket.
+func (dish *dishSocket) Context() context.Context {
+	return dish.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (dish *dishSocket) Done() <-chan struct{} {
+	return dish.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (dish *dishSocket) MessageTrace() <-chan TraceEvent {
+	return dish.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (dish *dishSocket) Stats() Stats {
+	return dish.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (dish *dishSocket) Connections() []ConnInfo {
+	return dish.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (dish *dishSocket) Endpoints() []string {
+	return dish.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (dish *dishSocket) Disconnect(ep string) error {
+	return dish.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (dish *dishSocket) Monitor(events EventType) <-chan SocketEvent {
+	return dish.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (dish *dishSocket) GetMonitorChannel() <-chan SocketEvent {
+	return dish.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (dish *dishSocket) MonitorDropped() uint64 {
+	return dish.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (dish *dishSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return dish.sck.recvOrEvent(ctx, dish.Recv)
+}
+
+func (dish *dishSocket) events() State {
+	return dish.sck.events()
+}
+
+func (dish *dishSocket) watch(fn func()) func() {
+	return dish.sck.watch(fn)
+}
+
+// Send returns ErrInvalidOperation: DISH sockets can't send messages.
+// Groups are joined and left with SetOption.
+func (*dishSocket) Send(msg Msg) error {
+	return errInvalidOp(Dish, "send")
+}
+
+// SendMulti returns ErrInvalidOperation: DISH sockets can't send messages.
+func (*dishSocket) SendMulti(msg Msg) error {
+	return errInvalidOp(Dish, "send")
+}
+
+// SendNB is an invalid operation for a DISH socket.
+func (*dishSocket) SendNB(msg Msg) error {
+	return errInvalidOp(Dish, "send")
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (dish *dishSocket) Flush(ctx context.Context) error {
+	return dish.sck.Flush(ctx)
+}
+
+// Recv receives a complete message.
+func (dish *dishSocket) Recv() (Msg, error) {
+	return withoutConn(dish.recvConn())
+}
+
+// recvConn receives a complete message, still referencing the connection it was
+// received from.
+func (dish *dishSocket) recvConn() (Msg, error) {
+	return dish.filter(dish.sck.recvConn)
+}
+
+// filter receives messages with recv until one was published to a joined
+// group: the messages received before leaving their group, or without a
+// group, are dropped.
+func (dish *dishSocket) filter(recv func() (Msg, error)) (Msg, error) {
+	for {
+		msg, err := recv()
+		if err != nil {
+			return msg, err
+		}
+		dish.mu.RLock()
+		_, ok := dish.groups[msg.Group]
+		dish.mu.RUnlock()
+		if !ok {
+			msg.Release()
+			continue
+		}
+		return msg, nil
+	}
+}
+
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (dish *dishSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(dish.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (dish *dishSocket) RecvNB() (Msg, error) {
+	return withoutConn(dish.filter(dish.sck.recvConnNB))
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (dish *dishSocket) RecvMulti() (Msg, error) {
+	return recvMulti(dish.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (dish *dishSocket) SetSendDeadline(t time.Time) error {
+	return dish.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (dish *dishSocket) SetRecvDeadline(t time.Time) error {
+	return dish.sck.SetRecvDeadline(t)
+}
+
+// Listen connects a local endpoint to the Socket.
+func (dish *dishSocket) Listen(ep string) error {
+	return dish.sck.Listen(ep)
+}
+
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (dish *dishSocket) Unbind(ep string) error {
+	return dish.sck.Unbind(ep)
+}
+
+// Dial connects a remote endpoint to the Socket.
+func (dish *dishSocket) Dial(ep string) error {
+	return dish.sck.Dial(ep)
+}
+
+// Type returns the type of this Socket (PUB, SUB, ...)
+func (dish *dishSocket) Type() SocketType {
+	return dish.sck.Type()
+}
+
+// Addr returns the listener's address.
+// Addr returns nil if the socket isn't a listener.
+func (dish *dishSocket) Addr() net.Addr {
+	return dish.sck.Addr()
+}
+
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (dish *dishSocket) LastEndpoint() (string, error) {
+	return dish.sck.LastEndpoint()
+}
+
+// GetOption is used to retrieve an option for a socket.
+func (dish *dishSocket) GetOption(name string) (interface{}, error) {
+	return dish.sck.GetOption(name)
+}
+
+// SetOption is used to set an option for a socket.
+func (dish *dishSocket) SetOption(name string, value interface{}) error {
+	err := dish.sck.SetOption(name, value)
+	if err != nil {
+		return err
+	}
+
+	var (
+		cmd     string
+		changed bool
+		k, _    = optionString(name, value) // validated by sck.SetOption
+	)
+	switch name {
+	case OptionJoin:
+		cmd, changed = CmdJoin, dish.join(k, +1)
+	case OptionLeave:
+		cmd, changed = CmdLeave, dish.join(k, -1)
+	default:
+		// a socket option, set by sck.SetOption.
+		return nil
+	}
+	if !changed {
+		// the peers already know about this group.
+		return nil
+	}
+
+	dish.sck.mu.RLock()
+	defer dish.sck.mu.RUnlock()
+	for _, c := range dish.sck.conns {
+		// a closed connection is reaped, and its replacement joins the
+		// groups upon connection.
+		_ = c.SendCmd(cmd, []byte(k))
+	}
+	return nil
+}
+
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (dish *dishSocket) Reader() SocketReader {
+	return socketReader{dish}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (dish *dishSocket) Writer() SocketWriter {
+	return socketWriter{dish}
+}
+
+// join joins (delta=+1) or leaves (delta=-1) a group, and reports whether
+// the group became, or stopped being, joined. Like subscriptions, joins are
+// reference counted.
+func (dish *dishSocket) join(group string, delta int) bool {
+	dish.mu.Lock()
+	defer dish.mu.Unlock()
+	return refCount(dish.groups, group, delta)
+}
+
+// joined returns the sorted list of the groups the socket joined, to join
+// them again upon reconnection.
+func (dish *dishSocket) joined() []string {
+	dish.mu.RLock()
+	groups := make([]string, 0, len(dish.groups))
+	for group := range dish.groups {
+		groups = append(groups, group)
+	}
+	dish.mu.RUnlock()
+	sort.Strings(groups)
+	return groups
+}
+
+var (
+	_ Socket           = (*dishSocket)(nil)
+	_ MessageTracer    = (*dishSocket)(nil)
+	_ ConnectionLister = (*dishSocket)(nil)
+	_ EndpointManager  = (*dishSocket)(nil)
+	_ PeerReceiver     = (*dishSocket)(nil)
+	_ Monitored        = (*dishSocket)(nil)
+	_ EventReceiver    = (*dishSocket)(nil)
+	_ StatsReporter    = (*dishSocket)(nil)
+)
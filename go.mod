@@ -5,6 +5,7 @@ go 1.26.3
 require (
 	github.com/luxfi/czmq/v4 v4.2.2
 	go.uber.org/goleak v1.3.0
+	golang.org/x/crypto v0.48.0
 	golang.org/x/sync v0.20.0
 	golang.org/x/text v0.35.0
 )
@@ -13,4 +14,5 @@ require (
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
+	golang.org/x/sys v0.41.0 // indirect
 )
@@ -0,0 +1,72 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import "context"
+
+// SocketReader is the receiving half of a Socket.
+//
+// A SocketReader can only receive messages: it lets code that must not send
+// on a socket be handed the socket without its sending methods.
+type SocketReader interface {
+	// Recv receives a complete message.
+	Recv() (Msg, error)
+
+	// RecvNB receives a complete message if one is ready, and returns
+	// ErrWouldBlock otherwise.
+	RecvNB() (Msg, error)
+
+	// Context returns the life-line of the underlying Socket.
+	Context() context.Context
+}
+
+// SocketWriter is the sending half of a Socket.
+//
+// A SocketWriter can only send messages: it lets code that must not receive
+// from a socket be handed the socket without its receiving methods.
+type SocketWriter interface {
+	// Send puts the message on the outbound send queue.
+	Send(msg Msg) error
+
+	// SendMulti puts the message on the outbound send queue, as a
+	// multipart message.
+	SendMulti(msg Msg) error
+
+	// SendNB puts the message on the outbound send queue if it can do so
+	// without blocking, and returns ErrWouldBlock otherwise.
+	SendNB(msg Msg) error
+
+	// Flush blocks until all the messages queued for sending have been
+	// written to the connected peers, or until ctx is done.
+	Flush(ctx context.Context) error
+
+	// Context returns the life-line of the underlying Socket.
+	Context() context.Context
+}
+
+// socketReader restricts a Socket to its receiving methods.
+type socketReader struct {
+	sck Socket
+}
+
+func (r socketReader) Recv() (Msg, error)       { return r.sck.Recv() }
+func (r socketReader) RecvNB() (Msg, error)     { return r.sck.RecvNB() }
+func (r socketReader) Context() context.Context { return r.sck.Context() }
+
+// socketWriter restricts a Socket to its sending methods.
+type socketWriter struct {
+	sck Socket
+}
+
+func (w socketWriter) Send(msg Msg) error              { return w.sck.Send(msg) }
+func (w socketWriter) SendMulti(msg Msg) error         { return w.sck.SendMulti(msg) }
+func (w socketWriter) SendNB(msg Msg) error            { return w.sck.SendNB(msg) }
+func (w socketWriter) Flush(ctx context.Context) error { return w.sck.Flush(ctx) }
+func (w socketWriter) Context() context.Context        { return w.sck.Context() }
+
+var (
+	_ SocketReader = socketReader{}
+	_ SocketWriter = socketWriter{}
+)
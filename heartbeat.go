@@ -0,0 +1,113 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxHeartbeatTTL is the largest TTL a PING command can carry, in
+// deciseconds on 16 bits.
+const maxHeartbeatTTL = 0xffff * 100 * time.Millisecond
+
+// heartbeat is the ZMTP heartbeat configuration of the connections of a
+// socket, see OptionHeartbeatIvl.
+type heartbeat struct {
+	ivl     time.Duration // interval between the PING commands, 0 disables the heartbeats
+	timeout time.Duration // time to wait for traffic after a PING, 0 for ivl
+	ttl     time.Duration // time the peer waits for traffic before dropping the connection, 0 for no limit
+}
+
+// pingTimeout returns the time to wait for traffic after a PING.
+func (hb heartbeat) pingTimeout() time.Duration {
+	if hb.timeout > 0 {
+		return hb.timeout
+	}
+	return hb.ivl
+}
+
+// ping returns the body of the PING commands: the TTL, in deciseconds,
+// without context.
+func (hb heartbeat) ping() []byte {
+	body := make([]byte, 2)
+	binary.BigEndian.PutUint16(body, uint16(hb.ttl/(100*time.Millisecond)))
+	return body
+}
+
+// heartbeat sends a PING command every c.hb.ivl, and closes the connection
+// when no traffic follows it within the timeout of c.hb. It returns once the
+// connection is closed or ctx is done.
+func (c *Conn) heartbeat(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	hb := c.hb
+	ping := time.NewTicker(hb.ivl)
+	defer ping.Stop()
+	var (
+		expire = time.NewTimer(hb.pingTimeout())
+		sent   time.Time
+		armed  bool
+	)
+	expire.Stop()
+	defer expire.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ping.C:
+			if !armed {
+				sent, armed = now, true
+				expire.Reset(hb.pingTimeout())
+			}
+			if err := c.SendCmd(CmdPing, hb.ping()); err != nil {
+				return
+			}
+		case <-expire.C:
+			armed = false
+			if c.lastRecv.Load() < sent.UnixNano() {
+				// the peer is gone: the readers of the connection see it
+				// closed, and the socket reconnects.
+				c.SetClosed()
+				_ = c.rw.Close()
+				return
+			}
+		}
+	}
+}
+
+// heartbeatCmd handles the heartbeat command cmd received from the peer: a
+// PING is answered with a PONG, and its TTL bounds the time to wait for the
+// next traffic.
+func (c *Conn) heartbeatCmd(cmd Cmd) {
+	if cmd.Name != CmdPing || len(cmd.Body) < 2 {
+		return
+	}
+	ttl := time.Duration(binary.BigEndian.Uint16(cmd.Body)) * 100 * time.Millisecond
+	c.peerTTL.Store(int64(ttl))
+	// the context of the PING is echoed back.
+	_ = c.SendCmd(CmdPong, cmd.Body[2:])
+}
+
+// received records that traffic was received from the peer.
+func (c *Conn) received() {
+	if c.hb.ivl > 0 {
+		c.lastRecv.Store(time.Now().UnixNano())
+	}
+	if ttl := c.peerTTL.Load(); ttl > 0 {
+		_ = c.rw.SetReadDeadline(time.Now().Add(time.Duration(ttl)))
+	}
+}
+
+// expired returns whether err is the expiry of the TTL of the peer.
+func (c *Conn) expired(err error) bool {
+	var e net.Error
+	return c.peerTTL.Load() > 0 && errors.As(err, &e) && e.Timeout()
+}
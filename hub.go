@@ -0,0 +1,138 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultHubBuffer is the default capacity of the channels of the
+// subscriptions to a Hub.
+const defaultHubBuffer = 100
+
+// Hub distributes messages published on topics to the goroutines of a
+// process subscribed to them, with the semantics of PUB/SUB sockets: a
+// subscription receives the messages published on the topics it is a prefix
+// of, and the messages a slow subscriber has no room for are dropped.
+//
+// Unlike inproc PUB and SUB sockets, a Hub hands messages to the channels
+// of the subscriptions directly, without encoding them.
+// A Hub is safe for concurrent use.
+type Hub struct {
+	size int // capacity of the channels of the subscriptions
+
+	mu     sync.RWMutex
+	subs   map[<-chan []byte]hubSub
+	closed bool
+
+	dropped atomic.Uint64
+}
+
+// hubSub is a subscription to a Hub.
+type hubSub struct {
+	topic string
+	c     chan []byte
+}
+
+// HubOption configures a Hub.
+type HubOption func(h *Hub)
+
+// WithHubBuffer sets the capacity of the channels returned by Subscribe
+// (default 100): messages published while a channel is full are dropped.
+func WithHubBuffer(n int) HubOption {
+	return func(h *Hub) {
+		h.size = n
+	}
+}
+
+// NewHub returns a new Hub.
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{
+		size: defaultHubBuffer,
+		subs: make(map[<-chan []byte]hubSub),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Subscribe returns a channel receiving the data of the messages published
+// on the topics starting with topic. An empty topic subscribes to all the
+// messages.
+// The channel is closed by Unsubscribe, or when the hub is closed.
+func (h *Hub) Subscribe(topic string) <-chan []byte {
+	c := make(chan []byte, max(h.size, 0))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(c)
+		return c
+	}
+	h.subs[c] = hubSub{topic: topic, c: c}
+	return c
+}
+
+// Unsubscribe cancels the subscription of the channel c returned by
+// Subscribe, and closes c.
+func (h *Hub) Unsubscribe(c <-chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub, ok := h.subs[c]
+	if !ok {
+		return
+	}
+	delete(h.subs, c)
+	close(sub.c)
+}
+
+// Publish delivers data to the subscriptions to topic.
+// The subscribers share the delivered data, which must not be modified.
+// Publish does not wait for slow subscribers: the message is dropped for
+// the subscriptions whose channel is full (see Dropped).
+func (h *Hub) Publish(topic string, data []byte) error {
+	data = append([]byte(nil), data...)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return fmt.Errorf("zmq4: hub is closed")
+	}
+	for _, sub := range h.subs {
+		if !strings.HasPrefix(topic, sub.topic) {
+			continue
+		}
+		select {
+		case sub.c <- data:
+		default:
+			h.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of messages dropped because the channel of a
+// subscription was full.
+func (h *Hub) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// Close closes the hub and the channels of its subscriptions.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	for c, sub := range h.subs {
+		delete(h.subs, c)
+		close(sub.c)
+	}
+	return nil
+}
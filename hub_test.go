@@ -0,0 +1,105 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+)
+
+func TestHubFanOut(t *testing.T) {
+	hub := zmq4.NewHub()
+	defer hub.Close()
+
+	const (
+		nsubs = 8
+		nmsgs = 50
+	)
+	var (
+		wg   sync.WaitGroup
+		subs = make([]<-chan []byte, nsubs)
+	)
+	for i := range subs {
+		subs[i] = hub.Subscribe("events.")
+	}
+	other := hub.Subscribe("metrics.")
+
+	for i := range subs {
+		wg.Add(1)
+		go func(c <-chan []byte) {
+			defer wg.Done()
+			for j := 0; j < nmsgs; j++ {
+				select {
+				case data := <-c:
+					if got, want := string(data), "payload"; got != want {
+						t.Errorf("invalid data: got=%q, want=%q", got, want)
+						return
+					}
+				case <-time.After(5 * time.Second):
+					t.Errorf("timeout waiting for message #%d", j)
+					return
+				}
+			}
+		}(subs[i])
+	}
+
+	for i := 0; i < nmsgs; i++ {
+		if err := hub.Publish("events.click", []byte("payload")); err != nil {
+			t.Fatalf("could not publish: %+v", err)
+		}
+	}
+	wg.Wait()
+
+	select {
+	case data := <-other:
+		t.Fatalf("subscription to another topic received %q", data)
+	default:
+	}
+	if got := hub.Dropped(); got != 0 {
+		t.Fatalf("dropped %d messages", got)
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := zmq4.NewHub(zmq4.WithHubBuffer(1))
+
+	kept := hub.Subscribe("")
+	gone := hub.Subscribe("")
+	hub.Unsubscribe(gone)
+	if _, ok := <-gone; ok {
+		t.Fatalf("unsubscribed channel not closed")
+	}
+	hub.Unsubscribe(gone) // unsubscribing twice is a no-op.
+
+	if err := hub.Publish("topic", []byte("msg-1")); err != nil {
+		t.Fatalf("could not publish: %+v", err)
+	}
+	// the channel is full: the second message is dropped.
+	if err := hub.Publish("topic", []byte("msg-2")); err != nil {
+		t.Fatalf("could not publish: %+v", err)
+	}
+	if got := string(<-kept); got != "msg-1" {
+		t.Fatalf("invalid data: got=%q, want=%q", got, "msg-1")
+	}
+	if got, want := hub.Dropped(), uint64(1); got != want {
+		t.Fatalf("invalid dropped count: got=%d, want=%d", got, want)
+	}
+
+	if err := hub.Close(); err != nil {
+		t.Fatalf("could not close hub: %+v", err)
+	}
+	if _, ok := <-kept; ok {
+		t.Fatalf("channel not closed with the hub")
+	}
+	if err := hub.Publish("topic", nil); err == nil {
+		t.Fatalf("published to a closed hub")
+	}
+	if _, ok := <-hub.Subscribe("topic"); ok {
+		t.Fatalf("subscription to a closed hub not closed")
+	}
+}
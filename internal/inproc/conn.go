@@ -85,6 +85,10 @@ func (c *conn) read(data []byte) (int, error) {
 			return nr, io.ErrShortBuffer
 		}
 		return nr, nil
+	case <-c.localDone:
+		return 0, io.ErrClosedPipe
+	case <-c.remoteDone:
+		return 0, io.EOF
 	case <-c.rdeadline.wait():
 		return 0, timeoutError{}
 	}
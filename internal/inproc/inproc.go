@@ -13,12 +13,16 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 	"sync"
 )
 
 var (
-	mgr = contextType{db: make(map[string]*Listener)}
+	mgr = contextType{
+		db:      make(map[string]*Listener),
+		pending: make(map[string][]*pipe),
+	}
 
 	ErrClosed      = errors.New("inproc: connection closed")
 	ErrConnRefused = errors.New("inproc: connection refused")
@@ -29,9 +33,10 @@ func init() {
 }
 
 type contextType struct {
-	mu sync.Mutex
-	cv sync.Cond
-	db map[string]*Listener
+	mu      sync.Mutex
+	cv      sync.Cond
+	db      map[string]*Listener
+	pending map[string][]*pipe // connections dialed before their address was bound
 }
 
 // A Listener is an in-process listener for stream-oriented protocols.
@@ -41,8 +46,9 @@ type contextType struct {
 type Listener struct {
 	addr Addr
 
-	pipes  []*pipe
-	closed bool
+	pipes   []*pipe
+	pending []*pipe // connections dialed before Listen, to be accepted first
+	closed  bool
 }
 
 type pipe struct {
@@ -102,6 +108,12 @@ func Listen(addr string) (*Listener, error) {
 	l := &Listener{
 		addr: Addr(addr),
 	}
+	for _, p := range mgr.pending[addr] {
+		if !isClosedChan(p.p2.localDone) {
+			l.pending = append(l.pending, p)
+		}
+	}
+	delete(mgr.pending, addr)
 	mgr.db[addr] = l
 	mgr.cv.Broadcast()
 	mgr.mu.Unlock()
@@ -123,8 +135,7 @@ func (l *Listener) Close() error {
 		return nil
 	}
 	var err error
-	for i := range l.pipes {
-		p := l.pipes[i]
+	for _, p := range append(l.pipes, l.pending...) {
 		e := p.Close()
 		if e != nil && err == nil {
 			err = e
@@ -138,6 +149,12 @@ func (l *Listener) Close() error {
 // Accept waits for and returns the next connection to the listener.
 func (l *Listener) Accept() (net.Conn, error) {
 	mgr.mu.Lock()
+	if len(l.pending) > 0 && !l.closed {
+		p := l.pending[0]
+		l.pending = l.pending[1:]
+		mgr.mu.Unlock()
+		return p.p1, nil
+	}
 	p := newPipe(l.addr)
 	l.pipes = append(l.pipes, p)
 	closed := l.closed
@@ -151,6 +168,9 @@ func (l *Listener) Accept() (net.Conn, error) {
 }
 
 // Dial connects to the given address.
+// If no listener is bound to addr yet, the connection is queued until one
+// is: it is returned right away, and blocks reading and, once its buffer is
+// full, writing until the listener accepts it.
 func Dial(addr string) (net.Conn, error) {
 	mgr.mu.Lock()
 
@@ -160,8 +180,13 @@ func Dial(addr string) (net.Conn, error) {
 			ok bool
 		)
 		if l, ok = mgr.db[addr]; !ok || l == nil {
+			p := newPipe(Addr(addr))
+			pending := slices.DeleteFunc(mgr.pending[addr], func(p *pipe) bool {
+				return isClosedChan(p.p2.localDone)
+			})
+			mgr.pending[addr] = append(pending, p)
 			mgr.mu.Unlock()
-			return nil, ErrConnRefused
+			return p.p2, nil
 		}
 		if n := len(l.pipes); n != 0 {
 			p := l.pipes[n-1]
@@ -173,6 +198,14 @@ func Dial(addr string) (net.Conn, error) {
 	}
 }
 
+// Listening returns whether a listener is bound to addr.
+func Listening(addr string) bool {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	_, ok := mgr.db[addr]
+	return ok
+}
+
 // Addr represents an in-process "network" end-point address.
 type Addr string
 
@@ -171,3 +171,43 @@ func TestRW(t *testing.T) {
 		t.Fatalf("error: %+v", err)
 	}
 }
+
+func TestDialBeforeListen(t *testing.T) {
+	const ep = "inproc://dial-before-listen"
+
+	// a connection closed before the address is bound is not accepted.
+	stale, err := Dial(ep)
+	if err != nil {
+		t.Fatalf("could not dial unbound address: %+v", err)
+	}
+	stale.Close()
+
+	conn, err := Dial(ep)
+	if err != nil {
+		t.Fatalf("could not dial unbound address: %+v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("HELLO")); err != nil {
+		t.Fatalf("could not write before listen: %+v", err)
+	}
+
+	lst, err := Listen(ep)
+	if err != nil {
+		t.Fatalf("could not create server: %+v", err)
+	}
+	defer lst.Close()
+
+	srv, err := lst.Accept()
+	if err != nil {
+		t.Fatalf("could not accept connection: %+v", err)
+	}
+	defer srv.Close()
+
+	raw := make([]byte, len("HELLO"))
+	if _, err := io.ReadFull(srv, raw); err != nil {
+		t.Fatalf("could not read request: %+v", err)
+	}
+	if got, want := raw, []byte("HELLO"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid request: got=%v, want=%v", got, want)
+	}
+}
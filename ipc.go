@@ -0,0 +1,58 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ipcAbstract returns whether the ipc address addr names a socket of the
+// Linux abstract namespace, rather than a file: such names start with '@'
+// and vanish with the last socket using them.
+func ipcAbstract(addr string) bool {
+	return strings.HasPrefix(addr, "@")
+}
+
+// removeStaleIPC removes the socket file at path if it was left behind by a
+// listener that did not close cleanly, e.g. a process that crashed: binding
+// path would fail with "address already in use" otherwise.
+// Files that are not sockets, and sockets some process still listens to,
+// are left untouched.
+func removeStaleIPC(path string) {
+	if ipcAbstract(path) {
+		return
+	}
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&fs.ModeSocket == 0 {
+		return
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		_ = os.Remove(path)
+	}
+}
+
+// chmodIPC sets the permissions of the socket file of the ipc end-point
+// endpoint, listening to path, to the mode set with WithIPCFileMode.
+func (sck *socket) chmodIPC(endpoint, path string) error {
+	if sck.ipcMode == 0 || ipcAbstract(path) {
+		return nil
+	}
+	if err := os.Chmod(path, sck.ipcMode.Perm()); err != nil {
+		return fmt.Errorf("zmq4: could not set mode of %q: %w", endpoint, err)
+	}
+	return nil
+}
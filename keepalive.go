@@ -0,0 +1,77 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// tcpKeepalive is the TCP keepalive configuration of the connections of a
+// socket, see OptionTCPKeepalive.
+type tcpKeepalive struct {
+	mode     int           // 1 enables keepalive, 0 disables it, -1 keeps the defaults of the net package
+	idle     time.Duration // idle time before the first probe, 0 for the system default
+	interval time.Duration // time between the probes, 0 for the system default
+	count    int           // number of unanswered probes before the connection is dropped, 0 for the system default
+}
+
+// keepaliveConn is implemented by the connections supporting TCP keepalive,
+// e.g. *net.TCPConn.
+type keepaliveConn interface {
+	SetKeepAliveConfig(cfg net.KeepAliveConfig) error
+}
+
+// apply configures the keepalive of conn. Connections that are not TCP
+// connections, e.g. ipc or inproc ones, are left untouched.
+func (ka tcpKeepalive) apply(conn net.Conn) error {
+	if ka.mode < 0 {
+		return nil
+	}
+	if nc, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		// e.g. a *tls.Conn.
+		conn = nc.NetConn()
+	}
+	kc, ok := conn.(keepaliveConn)
+	if !ok {
+		return nil
+	}
+	// net.KeepAliveConfig leaves the negative parameters unchanged, and
+	// replaces the zero ones by its own defaults.
+	unset := func(v int64) int64 {
+		if v == 0 {
+			return -1
+		}
+		return v
+	}
+	cfg := net.KeepAliveConfig{
+		Enable:   ka.mode == 1,
+		Idle:     time.Duration(unset(int64(ka.idle))),
+		Interval: time.Duration(unset(int64(ka.interval))),
+		Count:    int(unset(int64(ka.count))),
+	}
+	if err := kc.SetKeepAliveConfig(cfg); err != nil {
+		return fmt.Errorf("zmq4: could not set TCP keepalive of %v: %w", conn.RemoteAddr(), err)
+	}
+	return nil
+}
+
+// keepaliveValue returns the value of OptionTCPKeepalive, given as a bool or
+// an int, as an int.
+func keepaliveValue(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case int:
+		if v >= -1 && v <= 1 {
+			return v, nil
+		}
+	}
+	return 0, invalidOption(OptionTCPKeepalive, value, "bool or int in [-1, 1]")
+}
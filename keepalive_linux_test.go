@@ -0,0 +1,116 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// sockoptInts returns the named socket options of the TCP connection conn.
+func sockoptInts(t *testing.T, conn net.Conn, opts map[string][2]int) map[string]int {
+	t.Helper()
+	rc, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("could not access socket: %+v", err)
+	}
+	got := make(map[string]int, len(opts))
+	var gerr error
+	err = rc.Control(func(fd uintptr) {
+		for name, opt := range opts {
+			v, err := syscall.GetsockoptInt(int(fd), opt[0], opt[1])
+			if err != nil {
+				gerr = err
+				return
+			}
+			got[name] = v
+		}
+	})
+	if err == nil {
+		err = gerr
+	}
+	if err != nil {
+		t.Fatalf("could not get socket options: %+v", err)
+	}
+	return got
+}
+
+func TestSocketTCPKeepalive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	opts := []struct {
+		name  string
+		value interface{}
+	}{
+		{OptionTCPKeepalive, true},
+		{OptionTCPKeepaliveIdle, 42 * time.Second},
+		{OptionTCPKeepaliveInterval, 7 * time.Second},
+		{OptionTCPKeepaliveCount, 3},
+	}
+
+	srv := NewPair(ctx, WithLogger(Devnull)).(*pairSocket)
+	defer srv.Close()
+	cli := NewPair(ctx, WithLogger(Devnull)).(*pairSocket)
+	defer cli.Close()
+	for _, sck := range []*pairSocket{srv, cli} {
+		for _, opt := range opts {
+			if err := sck.SetOption(opt.name, opt.value); err != nil {
+				t.Fatalf("could not set %s: %+v", opt.name, err)
+			}
+		}
+	}
+	if got, err := cli.GetOption(OptionTCPKeepalive); err != nil || got != 1 {
+		t.Fatalf("invalid %s: got=%v, want=1 (err=%v)", OptionTCPKeepalive, got, err)
+	}
+	if err := cli.SetOption(OptionTCPKeepaliveCount, -1); !errors.Is(err, ErrBadProperty) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, ErrBadProperty)
+	}
+
+	if err := srv.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	// the accepted connection is added once the handshake completed.
+	for {
+		srv.sck.mu.RLock()
+		n := len(srv.sck.conns)
+		srv.sck.mu.RUnlock()
+		if n > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("no connection accepted: %+v", ctx.Err())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	want := map[string]int{"keepalive": 1, "idle": 42, "interval": 7, "count": 3}
+	for _, sck := range []*pairSocket{srv, cli} {
+		sck.sck.mu.RLock()
+		conn := sck.sck.conns[0].rw
+		sck.sck.mu.RUnlock()
+		got := sockoptInts(t, conn, map[string][2]int{
+			"keepalive": {syscall.SOL_SOCKET, syscall.SO_KEEPALIVE},
+			"idle":      {syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE},
+			"interval":  {syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL},
+			"count":     {syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT},
+		})
+		for name, v := range want {
+			if got[name] != v {
+				t.Errorf("invalid %s keepalive %s: got=%d, want=%d", sck.sck.typ, name, got[name], v)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultMonitorBuffer is the default capacity of the monitor channel of a
+// socket.
+const defaultMonitorBuffer = 100
+
+// EventType is a set of socket lifecycle events.
+type EventType int
+
+const (
+	EventConnected       EventType = 1 << iota // connection to a peer established
+	EventConnectDelayed                        // connection attempt failed, to be retried
+	EventConnectRetried                        // connection attempt retried
+	EventListening                             // socket bound to an end-point
+	EventBindFailed                            // socket could not bind to an end-point
+	EventAccepted                              // connection from a peer accepted
+	EventAcceptFailed                          // connection from a peer could not be accepted
+	EventClosed                                // socket end-point closed
+	EventDisconnected                          // connection to a peer lost
+	EventHandshakeFailed                       // handshake with a peer failed, or peer rejected
+
+	EventAll = EventConnected | EventConnectDelayed | EventConnectRetried |
+		EventListening | EventBindFailed | EventAccepted | EventAcceptFailed |
+		EventClosed | EventDisconnected | EventHandshakeFailed
+)
+
+func (ev EventType) String() string {
+	switch ev {
+	case EventConnected:
+		return "connected"
+	case EventConnectDelayed:
+		return "connect-delayed"
+	case EventConnectRetried:
+		return "connect-retried"
+	case EventListening:
+		return "listening"
+	case EventBindFailed:
+		return "bind-failed"
+	case EventAccepted:
+		return "accepted"
+	case EventAcceptFailed:
+		return "accept-failed"
+	case EventClosed:
+		return "closed"
+	case EventDisconnected:
+		return "disconnected"
+	case EventHandshakeFailed:
+		return "handshake-failed"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(ev))
+	}
+}
+
+// SocketEvent is a socket lifecycle event.
+type SocketEvent struct {
+	Type EventType
+	Addr string // address of the peer or end-point
+	Err  error  // cause of failure events
+}
+
+// Monitored is implemented by sockets that report their lifecycle events.
+type Monitored interface {
+	// Monitor starts reporting the given events on the monitor channel of
+	// the socket, replacing the previously monitored events, and returns
+	// that channel.
+	Monitor(events EventType) <-chan SocketEvent
+
+	// GetMonitorChannel returns the monitor channel of the socket, or nil
+	// if Monitor was never called.
+	GetMonitorChannel() <-chan SocketEvent
+
+	// MonitorDropped returns the number of events dropped because the
+	// monitor channel was full.
+	MonitorDropped() uint64
+}
+
+// EventReceiver is implemented by sockets that can wait for either a message
+// or a lifecycle event, without a separate goroutine draining the monitor
+// channel.
+type EventReceiver interface {
+	// RecvOrEvent waits until a message is received or one of the events
+	// enabled with Monitor occurs, whichever comes first, or until ctx is
+	// done.
+	// Exactly one of the returned message and event is populated when the
+	// error is nil.
+	//
+	// RecvOrEvent consumes the events of the monitor channel: events read
+	// from that channel by other goroutines are not returned.
+	// If Monitor was never called, RecvOrEvent only waits for messages.
+	RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error)
+}
+
+// socketMonitor delivers the lifecycle events of a socket.
+type socketMonitor struct {
+	size  int  // capacity of the monitor channel
+	block bool // whether emitters wait for room in a full channel
+
+	mu     sync.RWMutex
+	events EventType
+	c      chan SocketEvent
+
+	dropped atomic.Uint64
+}
+
+func newSocketMonitor() *socketMonitor {
+	return &socketMonitor{size: defaultMonitorBuffer}
+}
+
+func (m *socketMonitor) enable(events EventType) <-chan SocketEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.c == nil {
+		m.c = make(chan SocketEvent, m.size)
+	}
+	m.events = events
+	return m.c
+}
+
+func (m *socketMonitor) channel() <-chan SocketEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.c
+}
+
+// emit delivers ev if it is monitored.
+// In blocking mode, emit waits for room in the channel until ctx is done.
+func (m *socketMonitor) emit(ctx context.Context, ev SocketEvent) {
+	m.mu.RLock()
+	c, events := m.c, m.events
+	m.mu.RUnlock()
+	if c == nil || events&ev.Type == 0 {
+		return
+	}
+
+	if m.block {
+		select {
+		case c <- ev:
+		case <-ctx.Done():
+			m.dropped.Add(1)
+		}
+		return
+	}
+	select {
+	case c <- ev:
+	default:
+		m.dropped.Add(1)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMonitorBuffer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("drop", func(t *testing.T) {
+		sck := newSocket(ctx, Pair, WithMonitorBuffer(4))
+		defer sck.Close()
+
+		if c := sck.GetMonitorChannel(); c != nil {
+			t.Fatalf("monitor channel before Monitor")
+		}
+		c := sck.Monitor(EventAccepted)
+		if got, want := cap(c), 4; got != want {
+			t.Fatalf("invalid monitor buffer: got=%d, want=%d", got, want)
+		}
+
+		const n = 100
+		for i := 0; i < n; i++ {
+			sck.emitEvent(EventAccepted, "peer", nil)
+			sck.emitEvent(EventDisconnected, "peer", nil) // not monitored
+		}
+		if got, want := sck.MonitorDropped(), uint64(n-4); got != want {
+			t.Fatalf("invalid dropped events: got=%d, want=%d", got, want)
+		}
+		for i := 0; i < 4; i++ {
+			if ev := <-c; ev.Type != EventAccepted || ev.Addr != "peer" {
+				t.Fatalf("invalid event: %+v", ev)
+			}
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		sck := newSocket(ctx, Pair, WithMonitorBuffer(1), WithMonitorBlocking(true))
+		defer sck.Close()
+		c := sck.Monitor(EventAll)
+
+		const n = 100
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < n; i++ {
+				sck.emitEvent(EventConnected, "peer", nil)
+			}
+		}()
+		for i := 0; i < n; i++ {
+			select {
+			case <-c:
+			case <-ctx.Done():
+				t.Fatalf("missing event %d", i)
+			}
+		}
+		<-done
+		if got := sck.MonitorDropped(); got != 0 {
+			t.Fatalf("blocking monitor dropped %d events", got)
+		}
+
+		// a blocked emitter gives up once the socket is closed.
+		sck.emitEvent(EventConnected, "peer", nil)
+		go sck.Close()
+		sck.emitEvent(EventConnected, "peer", nil)
+		if got, want := sck.MonitorDropped(), uint64(1); got != want {
+			t.Fatalf("invalid dropped events: got=%d, want=%d", got, want)
+		}
+	})
+}
+
+func TestRecvOrEvent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv := NewPair(ctx)
+	defer srv.Close()
+	if err := srv.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	cli := NewPair(ctx)
+	defer cli.Close()
+	if err := cli.Dial("tcp://" + srv.Addr().String()); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	recv := srv.(EventReceiver)
+	// the accept of the connection of cli may be reported after Dial
+	// returned: only monitor events the test emits.
+	srv.(Monitored).Monitor(EventAcceptFailed)
+
+	srv.(*pairSocket).sck.emitEvent(EventAcceptFailed, "peer", nil)
+	msg, ev, err := recv.RecvOrEvent(ctx)
+	if err != nil {
+		t.Fatalf("could not recv event: %+v", err)
+	}
+	if ev == nil || ev.Type != EventAcceptFailed || msg.Frames != nil {
+		t.Fatalf("invalid result: msg=%v, event=%+v", msg, ev)
+	}
+
+	if err := cli.Send(NewMsgString("hello")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, ev, err = recv.RecvOrEvent(ctx)
+	if err != nil {
+		t.Fatalf("could not recv msg: %+v", err)
+	}
+	if ev != nil || len(msg.Frames) != 1 || string(msg.Frames[0]) != "hello" {
+		t.Fatalf("invalid result: msg=%v, event=%+v", msg, ev)
+	}
+
+	// a message sent while waiting wakes the wait up.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = cli.Send(NewMsgString("late"))
+	}()
+	msg, ev, err = recv.RecvOrEvent(ctx)
+	if err != nil {
+		t.Fatalf("could not recv msg: %+v", err)
+	}
+	if ev != nil || len(msg.Frames) != 1 || string(msg.Frames[0]) != "late" {
+		t.Fatalf("invalid result: msg=%v, event=%+v", msg, ev)
+	}
+
+	tctx, tcancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer tcancel()
+	if _, _, err := recv.RecvOrEvent(tctx); err != context.DeadlineExceeded {
+		t.Fatalf("invalid error: got=%v, want=%v", err, context.DeadlineExceeded)
+	}
+
+	ws := &srv.(*pairSocket).sck.watchers
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if n := len(ws.fns); n != 0 {
+		t.Fatalf("socket still watched by %d waits", n)
+	}
+}
+
+// waitEvent waits for an event of type typ on the monitor channel c,
+// skipping the other events.
+func waitEvent(t *testing.T, c <-chan SocketEvent, typ EventType) SocketEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-c:
+			if ev.Type == typ {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %v event", typ)
+		}
+	}
+}
+
+func TestMonitorEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv := NewPair(ctx)
+	defer srv.Close()
+	sevents := srv.(Monitored).Monitor(EventAll)
+	if err := srv.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep := "tcp://" + srv.Addr().String()
+	if ev := waitEvent(t, sevents, EventListening); ev.Addr != "tcp://127.0.0.1:0" {
+		t.Fatalf("invalid listening end-point: %q", ev.Addr)
+	}
+
+	cli := NewPair(ctx)
+	defer cli.Close()
+	cevents := cli.(Monitored).Monitor(EventAll)
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if ev := waitEvent(t, cevents, EventConnected); ev.Addr != ep {
+		t.Fatalf("invalid connected end-point: got=%q, want=%q", ev.Addr, ep)
+	}
+	accepted := waitEvent(t, sevents, EventAccepted)
+	if accepted.Addr == "" || accepted.Err != nil {
+		t.Fatalf("invalid accepted event: %+v", accepted)
+	}
+
+	if err := cli.Close(); err != nil {
+		t.Fatalf("could not close client: %+v", err)
+	}
+	if ev := waitEvent(t, sevents, EventDisconnected); ev.Addr != accepted.Addr {
+		t.Fatalf("invalid disconnected peer: got=%q, want=%q", ev.Addr, accepted.Addr)
+	}
+
+	dup := NewPair(ctx)
+	defer dup.Close()
+	devents := dup.(Monitored).Monitor(EventAll)
+	if err := dup.Listen(ep); err == nil {
+		t.Fatalf("could listen to %q twice", ep)
+	}
+	if ev := waitEvent(t, devents, EventBindFailed); ev.Addr != ep || ev.Err == nil {
+		t.Fatalf("invalid bind-failed event: %+v", ev)
+	}
+}
+
+func TestMonitorNoDisconnectOnDrop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv := NewPair(ctx)
+	defer srv.Close()
+	sevents := srv.(Monitored).Monitor(EventAll)
+	if err := srv.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep := "tcp://" + srv.Addr().String()
+
+	cli := NewPair(ctx)
+	defer cli.Close()
+	cevents := cli.(Monitored).Monitor(EventAll)
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	waitEvent(t, cevents, EventConnected)
+	waitEvent(t, sevents, EventAccepted)
+
+	// the server loses its peer, the client closed the connection itself.
+	if err := cli.(*pairSocket).Disconnect(ep); err != nil {
+		t.Fatalf("could not disconnect: %+v", err)
+	}
+	waitEvent(t, sevents, EventDisconnected)
+	noEvent := func(c <-chan SocketEvent, typ EventType) {
+		t.Helper()
+		timeout := time.After(200 * time.Millisecond)
+		for {
+			select {
+			case ev := <-c:
+				if ev.Type == typ {
+					t.Fatalf("unexpected %v event: %+v", typ, ev)
+				}
+			case <-timeout:
+				return
+			}
+		}
+	}
+	noEvent(cevents, EventDisconnected)
+
+	// and conversely with Unbind.
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	waitEvent(t, cevents, EventConnected)
+	waitEvent(t, sevents, EventAccepted)
+	if err := srv.(*pairSocket).Unbind(ep); err != nil {
+		t.Fatalf("could not unbind: %+v", err)
+	}
+	waitEvent(t, cevents, EventDisconnected)
+	noEvent(sevents, EventDisconnected)
+}
+
+// failingListener is a net.Listener whose Accept fails until it is closed.
+type failingListener struct {
+	net.Listener
+	accepts atomic.Int32
+	closed  chan struct{}
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	l.accepts.Add(1)
+	select {
+	case <-l.closed:
+		return nil, net.ErrClosed
+	default:
+		return nil, syscall.EMFILE
+	}
+}
+
+func TestAcceptFailures(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sck := newSocket(ctx, Pair)
+	defer sck.Close()
+	events := sck.Monitor(EventAcceptFailed)
+
+	l := &failingListener{closed: make(chan struct{})}
+	bl := &boundListener{ep: "tcp://failing", l: l, done: make(chan struct{})}
+	sck.wg.Add(1)
+	go sck.accept(ctx, bl)
+
+	// a persistent error is retried with a growing delay, not in a loop.
+	time.Sleep(200 * time.Millisecond)
+	if n := l.accepts.Load(); n > 10 {
+		t.Fatalf("too many accept attempts: %d", n)
+	}
+	waitEvent(t, events, EventAcceptFailed)
+
+	// the accept loop returns once the listener is closed.
+	close(l.closed)
+	select {
+	case <-bl.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("accept loop still running after the listener was closed")
+	}
+}
@@ -6,8 +6,10 @@ package zmq4
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sync"
 )
 
 type MsgType byte
@@ -19,10 +21,24 @@ const (
 
 // Msg is a ZMTP message, possibly composed of multiple frames.
 type Msg struct {
-	Frames    [][]byte
-	Type      MsgType
+	Frames [][]byte
+	Type   MsgType
+
+	// Group is the group a message is published to by a RADIO socket, or
+	// was received from by a DISH socket. Other socket types ignore it.
+	//
+	// A RADIO socket only delivers a message to the DISH peers that joined
+	// exactly its group (see OptionJoin). As specified by ZMTP for RADIO
+	// and DISH, the message goes on the wire as two frames: the group,
+	// then the single frame of the message.
+	Group string
+
 	multipart bool
 	err       error
+	buf       *msgBuf  // pooled storage backing Frames, if any
+	chunk     *zcChunk // read buffer backing Frames, if received in zero-copy mode
+	conn      *Conn    // connection the message was received from, if any
+	ack       *msgAck  // acknowledgement of the message, if any (see Acker)
 }
 
 func NewMsg(frame []byte) Msg {
@@ -45,6 +61,175 @@ func NewMsgFromString(frames []string) Msg {
 	return msg
 }
 
+// NewMsgJSON returns a message made of a single frame holding the JSON
+// encoding of v.
+func NewMsgJSON(v interface{}) (Msg, error) {
+	frame, err := json.Marshal(v)
+	if err != nil {
+		return Msg{}, fmt.Errorf("zmq4: could not encode JSON message: %w", err)
+	}
+	return NewMsg(frame), nil
+}
+
+// JSON decodes the JSON encoding held by the first frame of the message,
+// e.g. created with NewMsgJSON, into v.
+func (msg Msg) JSON(v interface{}) error {
+	if len(msg.Frames) == 0 {
+		return fmt.Errorf("zmq4: could not decode JSON message without frames: %w", ErrBadFrame)
+	}
+	if err := json.Unmarshal(msg.Frames[0], v); err != nil {
+		return fmt.Errorf("zmq4: could not decode JSON message: %w", err)
+	}
+	return nil
+}
+
+// SendParts sends the parts on s as the frames of a single message.
+// Without parts, a message made of a single empty frame is sent, as ZeroMQ
+// messages have at least one frame.
+func SendParts(s Socket, parts ...[]byte) error {
+	if len(parts) == 0 {
+		return s.Send(NewMsg(nil))
+	}
+	return s.Send(NewMsgFrom(parts...))
+}
+
+// RecvParts receives a message on s, and returns its frames.
+// A message without frames is returned as a single empty frame, mirroring
+// SendParts.
+func RecvParts(s Socket) ([][]byte, error) {
+	msg, err := s.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Frames) == 0 {
+		return [][]byte{{}}, nil
+	}
+	return msg.Frames, nil
+}
+
+// streamChunkSize is the maximum size of the frames SendReader reads its
+// payload into.
+const streamChunkSize = 1 << 20
+
+// SendReader sends size bytes read from r on s, as a single message.
+// A negative size reads r until io.EOF.
+// The payload is read into frames of up to 1MiB, rather than into a single
+// buffer, and RecvWriter writes them out in order without concatenating
+// them.
+func SendReader(s Socket, r io.Reader, size int64) error {
+	var frames [][]byte
+	for size != 0 {
+		n := int64(streamChunkSize)
+		if size > 0 && size < n {
+			n = size
+		}
+		frame := make([]byte, n)
+		m, err := io.ReadFull(r, frame)
+		if size < 0 && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+			if m > 0 {
+				frames = append(frames, frame[:m])
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("zmq4: could not read message payload: %w", err)
+		}
+		frames = append(frames, frame)
+		if size > 0 {
+			size -= n
+		}
+	}
+	return SendParts(s, frames...)
+}
+
+// RecvWriter receives a message on s, and writes the content of its frames
+// to w. It returns the number of bytes written.
+func RecvWriter(s Socket, w io.Writer) (int64, error) {
+	msg, err := s.Recv()
+	if err != nil {
+		return 0, err
+	}
+	defer msg.Release()
+
+	var n int64
+	for _, frame := range msg.Frames {
+		m, err := w.Write(frame)
+		n += int64(m)
+		if err != nil {
+			return n, fmt.Errorf("zmq4: could not write message payload: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Release hands the storage backing the frames of a received message back
+// to the library, for reuse by subsequent receives.
+//
+// Sockets created with WithRecvBufferPool decode small messages (up to 256
+// bytes of payload, all frames included) received over a connection without
+// encryption into pooled buffers.
+// Releasing them once processed makes receiving such messages allocation-free.
+// Sockets created with WithRecvZeroCopy leave the frames of the messages
+// received over a connection without encryption in the read buffer of the
+// connection. Releasing them hands the buffer back for reuse, once all the
+// messages read into it were released.
+// Calling Release is optional: a message that is never released is simply
+// garbage collected, and Release is a no-op on any other message.
+//
+// Release must be called at most once, and neither msg nor its frames may be
+// used afterwards. This also applies to the copies of msg and to the frames
+// they share, such as a message handed to a socket with an outbound queue
+// (PUB, XPUB, REP) that may not have been written yet: Clone a message to
+// retain its content beyond Release.
+func (msg *Msg) Release() {
+	if chunk := msg.chunk; chunk != nil {
+		msg.chunk = nil
+		msg.Frames = nil
+		chunk.release()
+	}
+	buf := msg.buf
+	if buf == nil {
+		return
+	}
+	msg.buf = nil
+	msg.Frames = nil
+	clear(buf.frames[:])
+	msgBufPool.Put(buf)
+}
+
+// holdChunk makes msg reference the read buffer chunk its next frame was read
+// into, in zero-copy mode.
+func (msg *Msg) holdChunk(chunk *zcChunk) {
+	switch msg.chunk {
+	case chunk:
+		return
+	case nil:
+	default:
+		// the message straddles two chunks: its previous frames are
+		// copied out of the chunk the reader moved on from.
+		for i, frame := range msg.Frames {
+			msg.Frames[i] = bytes.Clone(frame)
+		}
+		msg.chunk.release()
+	}
+	chunk.retain()
+	msg.chunk = chunk
+}
+
+// smallMsgSize is the maximum payload size of a message decoded into a
+// pooled msgBuf.
+const smallMsgSize = 256
+
+// msgBuf is the pooled backing storage of a small received message.
+type msgBuf struct {
+	data   [smallMsgSize]byte
+	frames [4][]byte
+}
+
+var msgBufPool = sync.Pool{
+	New: func() any { return new(msgBuf) },
+}
+
 func (msg Msg) isCmd() bool {
 	return msg.Type == CmdMsg
 }
@@ -55,14 +240,16 @@ func (msg Msg) Err() error {
 
 // Bytes returns the concatenated content of all its frames.
 func (msg Msg) Bytes() []byte {
-	buf := make([]byte, 0, msg.size())
+	buf := make([]byte, 0, msg.Size())
 	for _, frame := range msg.Frames {
 		buf = append(buf, frame...)
 	}
 	return buf
 }
 
-func (msg Msg) size() int {
+// Size returns the total size of the frames of the message, in bytes.
+// The ZMTP framing written to the wire along the frames is not counted.
+func (msg Msg) Size() int {
 	n := 0
 	for _, frame := range msg.Frames {
 		n += len(frame)
@@ -70,6 +257,11 @@ func (msg Msg) size() int {
 	return n
 }
 
+// NumFrames returns the number of frames of the message.
+func (msg Msg) NumFrames() int {
+	return len(msg.Frames)
+}
+
 func (msg Msg) String() string {
 	buf := new(bytes.Buffer)
 	buf.WriteString("Msg{Frames:{")
@@ -84,7 +276,7 @@ func (msg Msg) String() string {
 }
 
 func (msg Msg) Clone() Msg {
-	o := Msg{Frames: make([][]byte, len(msg.Frames))}
+	o := Msg{Frames: make([][]byte, len(msg.Frames)), Group: msg.Group}
 	for i, frame := range msg.Frames {
 		o.Frames[i] = make([]byte, len(frame))
 		copy(o.Frames[i], frame)
@@ -92,6 +284,114 @@ func (msg Msg) Clone() Msg {
 	return o
 }
 
+// Pop returns the first frame of the message, e.g. the routing identity
+// of a message received by a ROUTER socket, and the message without it.
+// Like Clone, Pop copies the frames: msg is left untouched and the results
+// do not share memory with it.
+// Pop returns a nil frame and an empty message if msg has no frame.
+func (msg *Msg) Pop() ([]byte, Msg) {
+	if len(msg.Frames) == 0 {
+		return nil, Msg{}
+	}
+	o := msg.Clone()
+	return o.Frames[0], Msg{Frames: o.Frames[1:], Group: o.Group}
+}
+
+// PushFront returns a copy of the message, as made by Clone, with frame
+// prepended, e.g. the routing identity of the peer a ROUTER socket sends the
+// message to.
+func (msg Msg) PushFront(frame []byte) Msg {
+	o := Msg{Frames: make([][]byte, 1, len(msg.Frames)+1), Group: msg.Group}
+	o.Frames[0] = append([]byte{}, frame...)
+	o.Frames = append(o.Frames, msg.Clone().Frames...)
+	return o
+}
+
+// NewEnvelopeMsg returns a message made of the routing identities, an empty
+// delimiter frame and the body frames, as exchanged in request-reply patterns:
+//
+//	[identity]...[empty][body]...
+//
+// REQ sockets add the delimiter themselves and ROUTER sockets add (and strip)
+// the identity of the peer a message comes from (is sent to), but DEALER
+// sockets send and receive their frames as-is: a DEALER talking to a REP
+// socket, possibly through ROUTER brokers, must send NewEnvelopeMsg(nil, body...)
+// and receives replies as [empty][body]...
+func NewEnvelopeMsg(ids [][]byte, body ...[]byte) Msg {
+	frames := make([][]byte, 0, len(ids)+1+len(body))
+	frames = append(frames, ids...)
+	frames = append(frames, []byte{})
+	frames = append(frames, body...)
+	return NewMsgFrom(frames...)
+}
+
+// SplitEnvelope splits a message built by NewEnvelopeMsg into its routing
+// identities and its body, around the first empty delimiter frame.
+// ok is false if the message has no delimiter frame.
+func SplitEnvelope(msg Msg) (ids, body [][]byte, ok bool) {
+	for i, frame := range msg.Frames {
+		if len(frame) == 0 {
+			return msg.Frames[:i], msg.Frames[i+1:], true
+		}
+	}
+	return nil, nil, false
+}
+
+// NewTopicMsg returns a message made of a topic frame followed by a payload
+// frame, as published on PUB sockets: SUB sockets match their subscriptions
+// against the topic frame only, so the payload never needs to be parsed to
+// find the topic.
+func NewTopicMsg(topic string, payload []byte) Msg {
+	return NewMsgFrom([]byte(topic), payload)
+}
+
+// TopicPayload splits a message built by NewTopicMsg into its topic and its
+// payload.
+// ok is false if the message is not made of exactly two frames.
+func (msg Msg) TopicPayload() (topic, payload []byte, ok bool) {
+	if len(msg.Frames) != 2 {
+		return nil, nil, false
+	}
+	return msg.Frames[0], msg.Frames[1], true
+}
+
+// SubscribeFrame returns the frame a SUB or XSUB socket sends to subscribe to
+// topic: a 0x01 byte followed by the topic.
+func SubscribeFrame(topic []byte) []byte {
+	return subscriptionFrame(1, topic)
+}
+
+// UnsubscribeFrame returns the frame a SUB or XSUB socket sends to
+// unsubscribe from topic: a 0x00 byte followed by the topic.
+func UnsubscribeFrame(topic []byte) []byte {
+	return subscriptionFrame(0, topic)
+}
+
+func subscriptionFrame(v byte, topic []byte) []byte {
+	frame := make([]byte, 0, 1+len(topic))
+	frame = append(frame, v)
+	return append(frame, topic...)
+}
+
+// ParseSubscription parses a frame built by SubscribeFrame or
+// UnsubscribeFrame, as received by a XPUB socket.
+// ParseSubscription reports whether the frame is a subscription (rather than
+// an unsubscription) and its topic, which aliases frame.
+// ok is false if frame is not a subscription frame.
+func ParseSubscription(frame []byte) (subscribe bool, topic []byte, ok bool) {
+	if len(frame) == 0 {
+		return false, nil, false
+	}
+	switch frame[0] {
+	case 0:
+		return false, frame[1:], true
+	case 1:
+		return true, frame[1:], true
+	default:
+		return false, nil, false
+	}
+}
+
 // Cmd is a ZMTP Cmd as per:
 //
 //	https://rfc.zeromq.org/spec:23/ZMTP/#formal-grammar
@@ -129,11 +429,17 @@ func (cmd *Cmd) marshalZMTP() ([]byte, error) {
 // ZMTP commands as per:
 //
 //	https://rfc.zeromq.org/spec:23/ZMTP/#commands
+//
+// and, for the JOIN and LEAVE commands of the DISH sockets:
+//
+//	https://rfc.zeromq.org/spec:37/ZMTP/
 const (
 	CmdCancel      = "CANCEL"
 	CmdError       = "ERROR"
 	CmdHello       = "HELLO"
 	CmdInitiate    = "INITIATE"
+	CmdJoin        = "JOIN"
+	CmdLeave       = "LEAVE"
 	CmdPing        = "PING"
 	CmdPong        = "PONG"
 	CmdReady       = "READY"
@@ -6,14 +6,22 @@ package zmq4
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	errgrp "github.com/luxfi/zmq/v4/internal/errgroup"
 	"golang.org/x/sync/errgroup"
 )
 
 // rpool is the interface that reads ZMQ messages from a pool of connections.
+//
+// read completes if it can do so without blocking even when ctx is already
+// done, and so does the write of the wpools that are flushers: this is how
+// RecvNB and SendNB are implemented.
 type rpool interface {
 	io.Closer
 
@@ -31,23 +39,44 @@ type wpool interface {
 	write(ctx context.Context, msg Msg) error
 }
 
+// flusher is implemented by wpools that hand messages off to background
+// writers, and can thus wait for those messages to reach the wire.
+type flusher interface {
+	flush(ctx context.Context) error
+}
+
+// queuer is implemented by rpools that queue received messages until they
+// are read, and by wpools that queue messages until they are written.
+type queuer interface {
+	// queued returns the number of messages ready to be read, or waiting
+	// to be written.
+	queued() int
+}
+
+// recvConfig configures how received messages are handed off to Recv.
+type recvConfig struct {
+	qsize    int  // capacity of the receive queue
+	dispatch bool // whether connections are fair-queued by a dispatcher
+}
+
 // qreader is a queued-message reader.
 type qreader struct {
 	ctx context.Context
 	mu  sync.RWMutex
 	rs  []*Conn
 	c   chan Msg
+	d   *dispatcher // nil if connections hand messages off directly
 
 	sem *semaphore // ready when a connection is live.
 }
 
-func newQReader(ctx context.Context) *qreader {
-	const qrsize = 10
-	return &qreader{
+func newQReader(ctx context.Context, cfg recvConfig) *qreader {
+	q := &qreader{
 		ctx: ctx,
-		c:   make(chan Msg, qrsize),
 		sem: newSemaphore(),
 	}
+	q.c, q.d = newRecvQueue(ctx, cfg)
+	return q
 }
 
 func (q *qreader) Close() error {
@@ -87,27 +116,67 @@ func (q *qreader) rmConn(r *Conn) {
 	}
 }
 
+func (q *qreader) queued() int {
+	if q.d != nil {
+		// q.c is the unbuffered output of the dispatcher.
+		return q.d.queued()
+	}
+	return len(q.c)
+}
+
 func (q *qreader) read(ctx context.Context, msg *Msg) error {
 	q.sem.lock(ctx)
+	if err := recvMsg(ctx, q.c, msg); err != nil {
+		return err
+	}
+	return msg.err
+}
+
+// recvMsg receives a message from c, or returns the error of ctx once it is
+// done. A ready message is received even if ctx is done.
+func recvMsg(ctx context.Context, c <-chan Msg, msg *Msg) error {
+	select {
+	case *msg = <-c:
+		return nil
+	default:
+	}
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case *msg = <-q.c:
+	case *msg = <-c:
+		return nil
 	}
-	return msg.err
 }
 
 func (q *qreader) listen(ctx context.Context, r *Conn) {
 	defer q.rmConn(r)
 	defer r.Close()
 
+	in := q.c
+	if q.d != nil {
+		in = q.d.add()
+		defer q.d.done(in)
+	}
+
 	for {
 		msg := r.read()
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			q.c <- msg
+			if msg.err != nil && atomic.LoadInt32(&r.dropped) == 1 {
+				// the socket closed the connection itself: the peer
+				// did not fail.
+				return
+			}
+			q.d.push()
+			select {
+			case in <- msg:
+				q.d.notify()
+				r.queued()
+			case <-ctx.Done():
+				return
+			}
 			if msg.err != nil {
 				return
 			}
@@ -115,11 +184,17 @@ func (q *qreader) listen(ctx context.Context, r *Conn) {
 	}
 }
 
+// errNoConn is returned by the load-balancing writes that find no connection
+// left to write to: all the peers went away.
+var errNoConn = fmt.Errorf("zmq4: no connections available: %w", ErrPeerDisconnected)
+
 type mwriter struct {
-	ctx context.Context
-	mu  sync.Mutex
-	ws  []*Conn
-	sem *semaphore
+	ctx  context.Context
+	mu   sync.Mutex
+	ws   []*Conn
+	sem  *semaphore
+	lb   bool // whether each message is written to a single connection, in turn
+	next int  // index of the connection the next message is written to, if lb
 }
 
 func newMWriter(ctx context.Context) *mwriter {
@@ -129,6 +204,14 @@ func newMWriter(ctx context.Context) *mwriter {
 	}
 }
 
+// newLBWriter returns the wpool of the sockets load-balancing their messages
+// across their connections, as PUSH and DEALER do.
+func newLBWriter(ctx context.Context) *mwriter {
+	w := newMWriter(ctx)
+	w.lb = true
+	return w
+}
+
 func (w *mwriter) Close() error {
 	w.mu.Lock()
 	var err error
@@ -168,19 +251,372 @@ func (mw *mwriter) rmConn(w *Conn) {
 
 func (w *mwriter) write(ctx context.Context, msg Msg) error {
 	w.sem.lock(ctx)
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
 	grp, _ := errgrp.WithContext(ctx)
-	w.mu.Lock()
-	for i := range w.ws {
-		ww := w.ws[i]
+	if w.lb {
 		grp.Go(func() error {
-			return ww.SendMsg(msg)
+			return w.writeNext(msg)
 		})
+	} else {
+		for _, ww := range w.conns() {
+			grp.Go(func() error {
+				return ww.SendMsg(msg)
+			})
+		}
 	}
 	err := grp.Wait()
+	if err != nil && w.ctx.Err() != nil {
+		// connections were torn down with the socket.
+		return w.ctx.Err()
+	}
+	return err
+}
+
+// conns returns a copy of the connections, so messages can be written to
+// them without holding w.mu: a stalled peer must not block the other
+// writes, nor the connections being added and removed.
+func (w *mwriter) conns() []*Conn {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.ws)
+}
+
+// writeNext writes msg to the next connection in turn, and fails over to
+// the following ones if the write fails.
+func (w *mwriter) writeNext(msg Msg) error {
+	w.mu.Lock()
+	ws := make([]*Conn, 0, len(w.ws))
+	for i := range w.ws {
+		ws = append(ws, w.ws[(w.next+i)%len(w.ws)])
+	}
+	if len(w.ws) > 0 {
+		w.next = (w.next + 1) % len(w.ws)
+	}
 	w.mu.Unlock()
+
+	if len(ws) == 0 {
+		// all the connections were lost since the first one was added.
+		return errNoConn
+	}
+	var err error
+	for _, ww := range ws {
+		if err = ww.SendMsg(msg); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// writeAll writes msg to every connection, and returns the errors of all
+// the connections that failed.
+func (w *mwriter) writeAll(ctx context.Context, msg Msg) error {
+	w.sem.lock(ctx)
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var (
+		ws   = w.conns()
+		wg   sync.WaitGroup
+		errs = make([]error, len(ws))
+	)
+	for i, ww := range ws {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ww.SendMsg(msg); err != nil {
+				errs[i] = fmt.Errorf("zmq4: could not send to %v: %w", ww.rw.RemoteAddr(), err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := w.ctx.Err(); err != nil {
+		// connections were torn down with the socket.
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// connAwaiter is implemented by the wpools failing the messages sent
+// without a connection, which can be told to wait for one being dialed.
+type connAwaiter interface {
+	awaitConn()
+}
+
+// hwmWriter is implemented by the wpools holding messages up to a high water
+// mark: queued for their connections, or unacknowledged (see ackWriter).
+type hwmWriter interface {
+	// sendHWM returns the capacity of the queue of each connection, or
+	// the maximum number of unacknowledged messages.
+	sendHWM() int
+	// setSendHWM sets the capacity of the queues of the connections
+	// added next, or the maximum number of unacknowledged messages.
+	setSendHWM(n int)
+}
+
+// allWriter is implemented by the wpools that can write a message to all
+// their connections, whatever the way write distributes messages.
+type allWriter interface {
+	writeAll(ctx context.Context, msg Msg) error
+}
+
+// qwriter is the wpool of the PUSH and DEALER sockets created with
+// WithSendHWM. It queues each message for a single connection, in turn,
+// and background goroutines write the queues to the connections: write
+// blocks once the queues of all the connections are full.
+type qwriter struct {
+	ctx context.Context
+	hwm atomic.Int64 // capacity of the queues of the connections added next
+
+	mu     sync.Mutex
+	qs     []connQueue
+	next   int           // index of the queue the next message is pushed to
+	err    error         // error of the last failed background write
+	room   chan struct{} // closed when a queue may have room, or a connection was added
+	closed bool
+
+	out *outbox
+}
+
+// connQueue is the queue of messages waiting to be written to a connection.
+type connQueue struct {
+	conn *Conn
+	c    chan Msg
+}
+
+func newQWriter(ctx context.Context, hwm int) *qwriter {
+	w := &qwriter{
+		ctx:  ctx,
+		room: make(chan struct{}),
+		out:  newOutbox(),
+	}
+	w.hwm.Store(int64(hwm))
+	return w
+}
+
+func (w *qwriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for _, q := range w.qs {
+		if e := q.conn.Close(); e != nil && err == nil {
+			err = e
+		}
+		close(q.c)
+	}
+	w.qs = nil
+	w.closed = true
 	return err
 }
 
+func (w *qwriter) addConn(c *Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		_ = c.Close()
+		return
+	}
+	q := connQueue{conn: c, c: make(chan Msg, w.hwm.Load())}
+	w.qs = append(w.qs, q)
+	go w.run(q)
+	w.wake()
+}
+
+func (w *qwriter) rmConn(c *Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, q := range w.qs {
+		if q.conn == c {
+			w.qs = append(w.qs[:i], w.qs[i+1:]...)
+			close(q.c)
+			return
+		}
+	}
+}
+
+// run writes the messages of q to its connection, until q is closed.
+func (w *qwriter) run(q connQueue) {
+	for msg := range q.c {
+		if err := q.conn.SendMsg(msg); err != nil {
+			w.mu.Lock()
+			w.err = err
+			w.mu.Unlock()
+		}
+		w.out.done()
+		w.mu.Lock()
+		w.wake()
+		w.mu.Unlock()
+	}
+}
+
+// wake wakes the writers waiting for room up.
+// wake must be called with w.mu held.
+func (w *qwriter) wake() {
+	close(w.room)
+	w.room = make(chan struct{})
+}
+
+func (w *qwriter) write(ctx context.Context, msg Msg) error {
+	for {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		room, err := w.push(msg)
+		if room == nil || err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-room:
+		}
+	}
+}
+
+// push pushes msg to the next queue with room, in turn. If all the queues
+// are full, push returns the channel closed once one of them may have room.
+// The error of a failed background write is returned, in place of pushing
+// msg, by the push that follows it.
+func (w *qwriter) push(msg Msg) (chan struct{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.err; err != nil {
+		w.err = nil
+		return nil, err
+	}
+	for range w.qs {
+		q := w.qs[w.next%len(w.qs)]
+		w.next = (w.next + 1) % len(w.qs)
+		w.out.add()
+		select {
+		case q.c <- msg:
+			return nil, nil
+		default:
+			w.out.done()
+		}
+	}
+	return w.room, nil
+}
+
+// writeAll pushes msg to the queues of all the connections, waiting for
+// the full ones to have room.
+func (w *qwriter) writeAll(ctx context.Context, msg Msg) error {
+	w.mu.Lock()
+	pending := make([]*Conn, 0, len(w.qs))
+	for _, q := range w.qs {
+		pending = append(pending, q.conn)
+	}
+	w.mu.Unlock()
+
+	for len(pending) > 0 {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		left := pending[:0]
+		for _, c := range pending {
+			i := slices.IndexFunc(w.qs, func(q connQueue) bool { return q.conn == c })
+			if i < 0 {
+				continue // connection lost in the meantime.
+			}
+			w.out.add()
+			select {
+			case w.qs[i].c <- msg:
+			default:
+				w.out.done()
+				left = append(left, c)
+			}
+		}
+		pending = left
+		room := w.room
+		w.mu.Unlock()
+		if len(pending) == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-room:
+		}
+	}
+	return nil
+}
+
+func (w *qwriter) flush(ctx context.Context) error {
+	return w.out.wait(ctx)
+}
+
+// queued returns the number of messages waiting to be written to the
+// connections.
+func (w *qwriter) queued() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := 0
+	for _, q := range w.qs {
+		n += len(q.c)
+	}
+	return n
+}
+
+func (w *qwriter) sendHWM() int {
+	return int(w.hwm.Load())
+}
+
+func (w *qwriter) setSendHWM(n int) {
+	w.hwm.Store(int64(n))
+}
+
+// outbox tracks the number of messages handed off to background writers
+// that have not been written to a connection yet.
+type outbox struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // closed when n == 0
+}
+
+func newOutbox() *outbox {
+	idle := make(chan struct{})
+	close(idle)
+	return &outbox{idle: idle}
+}
+
+func (o *outbox) add() {
+	o.mu.Lock()
+	if o.n == 0 {
+		o.idle = make(chan struct{})
+	}
+	o.n++
+	o.mu.Unlock()
+}
+
+func (o *outbox) done() {
+	o.mu.Lock()
+	o.n--
+	if o.n == 0 {
+		close(o.idle)
+	}
+	o.mu.Unlock()
+}
+
+// wait blocks until all the messages queued so far have been written
+// or ctx is done.
+func (o *outbox) wait(ctx context.Context) error {
+	o.mu.Lock()
+	idle := o.idle
+	o.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-idle:
+		return nil
+	}
+}
+
 type semaphore struct {
 	ready chan struct{}
 }
@@ -208,6 +644,140 @@ func (sem *semaphore) lock(ctx context.Context) {
 }
 
 var (
-	_ rpool = (*qreader)(nil)
-	_ wpool = (*mwriter)(nil)
+	_ rpool     = (*qreader)(nil)
+	_ wpool     = (*mwriter)(nil)
+	_ allWriter = (*mwriter)(nil)
+	_ wpool     = (*qwriter)(nil)
+	_ flusher   = (*qwriter)(nil)
+	_ queuer    = (*qwriter)(nil)
+	_ hwmWriter = (*qwriter)(nil)
+	_ allWriter = (*qwriter)(nil)
 )
+
+// newRecvQueue creates the channel received messages are handed off to Recv
+// through, and the dispatcher feeding it if cfg asks for one.
+//
+// Without a dispatcher, the connection readers push messages to a queue of
+// cfg.qsize messages, in arrival order.
+// With a dispatcher, each connection reader pushes to its own queue of
+// cfg.qsize messages and the dispatcher hands them off to Recv, one
+// connection at a time.
+func newRecvQueue(ctx context.Context, cfg recvConfig) (chan Msg, *dispatcher) {
+	if !cfg.dispatch {
+		return make(chan Msg, cfg.qsize), nil
+	}
+	d := &dispatcher{
+		ctx:   ctx,
+		qsize: cfg.qsize,
+		out:   make(chan Msg),
+		ready: make(chan struct{}, 1),
+	}
+	return d.out, d
+}
+
+// dispatcher fair-queues the messages received from multiple connections:
+// it hands off to Recv one message of each connection in turn, so a busy peer
+// can not starve the others.
+type dispatcher struct {
+	ctx   context.Context
+	qsize int
+	out   chan Msg
+	ready chan struct{} // signaled when an input has been pushed to or closed
+	once  sync.Once     // starts the dispatching goroutine
+
+	mu  sync.Mutex
+	ins []chan Msg // copied on write
+
+	n atomic.Int64 // messages pushed to the inputs, and not handed off yet
+}
+
+// add registers a new input queue.
+func (d *dispatcher) add() chan Msg {
+	d.once.Do(func() { go d.run(d.ctx) })
+	in := make(chan Msg, d.qsize)
+	d.mu.Lock()
+	d.ins = append(d.ins[:len(d.ins):len(d.ins)], in)
+	d.mu.Unlock()
+	return in
+}
+
+// done closes an input queue.
+// The dispatcher drops it once its pending messages have been handed off.
+func (d *dispatcher) done(in chan Msg) {
+	close(in)
+	d.notify()
+}
+
+// push counts a message about to be pushed to an input queue.
+// It is counted beforehand so queued never misses a message being moved from
+// its input queue to Recv.
+func (d *dispatcher) push() {
+	if d == nil {
+		return
+	}
+	d.n.Add(1)
+}
+
+// queued returns the number of messages in the input queues, or held by the
+// dispatcher until Recv takes them.
+func (d *dispatcher) queued() int {
+	return int(d.n.Load())
+}
+
+// notify wakes the dispatcher up.
+func (d *dispatcher) notify() {
+	if d == nil {
+		return
+	}
+	select {
+	case d.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dispatcher) drop(in chan Msg) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ins := make([]chan Msg, 0, len(d.ins))
+	for _, c := range d.ins {
+		if c != in {
+			ins = append(ins, c)
+		}
+	}
+	d.ins = ins
+}
+
+func (d *dispatcher) run(ctx context.Context) {
+	for {
+		d.mu.Lock()
+		ins := d.ins
+		d.mu.Unlock()
+
+		idle := true
+		for _, in := range ins {
+			select {
+			case msg, ok := <-in:
+				if !ok {
+					d.drop(in)
+					continue
+				}
+				idle = false
+				select {
+				case d.out <- msg:
+					d.n.Add(-1)
+				case <-ctx.Done():
+					return
+				}
+			default:
+			}
+		}
+
+		if idle {
+			select {
+			case <-d.ready:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2020-2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package networking
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Codec compresses message payloads.
+// Codecs other than GzipCodec, e.g. snappy, are plugged in by implementing
+// Codec.
+type Codec interface {
+	// ID is the byte prefixed to the payloads compressed by the codec.
+	// IDs 0x00 to 0x1f are reserved to the codecs of this package, and
+	// '{' is the first byte of uncompressed messages.
+	ID() byte
+
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// BufferCompressor is implemented by codecs compressing payloads into a
+// caller-provided buffer, which lets Broadcast reuse its buffers.
+type BufferCompressor interface {
+	// CompressTo appends the compressed data to buf.
+	CompressTo(buf *bytes.Buffer, data []byte) error
+}
+
+const gzipCodecID = 0x01
+
+// gzipWriters are the pools of gzip writers, indexed by compression level
+// minus gzip.HuffmanOnly.
+var gzipWriters [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+// GzipCodec compresses payloads with gzip.
+type GzipCodec struct {
+	// Level is the gzip compression level (gzip.DefaultCompression if 0).
+	Level int
+}
+
+// ID implements Codec.
+func (GzipCodec) ID() byte { return gzipCodecID }
+
+// Compress implements Codec.
+func (c GzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.CompressTo(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CompressTo implements BufferCompressor.
+// The gzip writers are pooled and reused across calls.
+func (c GzipCodec) CompressTo(buf *bytes.Buffer, data []byte) error {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return fmt.Errorf("invalid gzip compression level %d", level)
+	}
+	pool := &gzipWriters[level-gzip.HuffmanOnly]
+	w, _ := pool.Get().(*gzip.Writer)
+	if w == nil {
+		var err error
+		if w, err = gzip.NewWriterLevel(buf, level); err != nil {
+			return err
+		}
+	} else {
+		w.Reset(buf)
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	w.Reset(io.Discard) // do not retain buf.
+	pool.Put(w)
+	return nil
+}
+
+// Decompress implements Codec.
+func (GzipCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// compress compresses data with codec, prefixed with the codec ID.
+func compress(codec Codec, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := compressTo(codec, &buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressTo appends data, compressed with codec and prefixed with the codec
+// ID, to buf.
+func compressTo(codec Codec, buf *bytes.Buffer, data []byte) error {
+	if codec.ID() == '{' {
+		return fmt.Errorf("invalid codec ID %#x", codec.ID())
+	}
+	buf.WriteByte(codec.ID())
+	if bc, ok := codec.(BufferCompressor); ok {
+		return bc.CompressTo(buf, data)
+	}
+	out, err := codec.Compress(data)
+	if err != nil {
+		return err
+	}
+	buf.Write(out)
+	return nil
+}
+
+// decompress returns the uncompressed payload of data.
+// Payloads without a codec prefix are returned as-is.
+func (t *Transport) decompress(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] == '{' {
+		return data, nil
+	}
+	var codec Codec
+	switch id := data[0]; {
+	case t.config.CompressBroadcasts != nil && t.config.CompressBroadcasts.ID() == id:
+		codec = t.config.CompressBroadcasts
+	case id == gzipCodecID:
+		codec = GzipCodec{}
+	default:
+		return nil, fmt.Errorf("unknown codec ID %#x", id)
+	}
+	return codec.Decompress(data[1:])
+}
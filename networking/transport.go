@@ -6,6 +6,7 @@
 package networking
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -36,6 +37,8 @@ type Transport struct {
 	msgReceived atomic.Uint64
 	msgDropped  atomic.Uint64
 
+	bufs sync.Pool // *encodeBuffer reused by Broadcast
+
 	stopCh chan struct{}
 	wg     sync.WaitGroup
 }
@@ -48,6 +51,34 @@ type Config struct {
 	MaxRetries  int           // Default: 3
 	RetryDelay  time.Duration // Default: 100ms
 	BufferSize  int           // Default: 1000
+
+	// Backoff is the policy of the delays between the attempts of
+	// SendWithRetry and BroadcastWithRetry, and between the attempts of
+	// the sockets to dial peers.
+	// Default: delays doubling from RetryDelay for the retried sends, and
+	// the default policy of the sockets for dials.
+	Backoff zmq4.BackoffPolicy
+
+	// MaxPooledBuffer is the capacity, in bytes, above which the buffers
+	// used to serialize broadcasts are dropped instead of being reused.
+	// Default: 64KiB. Buffers are never reused if negative.
+	MaxPooledBuffer int
+
+	// CompressBroadcasts is the codec compressing the payloads sent by
+	// Broadcast. Broadcasts are sent uncompressed if nil.
+	// Compressed payloads are prefixed with the ID of their codec, so peers
+	// receive both compressed and uncompressed messages: peers must know
+	// the codec (GzipCodec is always known) to decompress them.
+	CompressBroadcasts Codec
+}
+
+const defaultMaxPooledBuffer = 64 << 10
+
+// encodeBuffer holds the buffers a broadcast is serialized into.
+type encodeBuffer struct {
+	json bytes.Buffer
+	enc  *json.Encoder
+	out  bytes.Buffer // compressed payload
 }
 
 // Message represents a network message
@@ -74,6 +105,8 @@ func DefaultConfig(nodeID string, basePort int) Config {
 		MaxRetries:  3,
 		RetryDelay:  100 * time.Millisecond,
 		BufferSize:  1000,
+
+		MaxPooledBuffer: defaultMaxPooledBuffer,
 	}
 }
 
@@ -92,6 +125,9 @@ func New(ctx context.Context, config Config) *Transport {
 	if config.BufferSize == 0 {
 		config.BufferSize = 1000
 	}
+	if config.MaxPooledBuffer == 0 {
+		config.MaxPooledBuffer = defaultMaxPooledBuffer
+	}
 
 	tCtx, cancel := context.WithCancel(ctx)
 	return &Transport{
@@ -115,7 +151,7 @@ func (t *Transport) Start() error {
 	}
 
 	// SUB socket for receiving broadcasts
-	t.sub = zmq4.NewSub(t.ctx)
+	t.sub = zmq4.NewSub(t.ctx, t.socketOptions()...)
 	t.sub.SetOption(zmq4.OptionSubscribe, "")
 
 	// ROUTER socket for direct messages
@@ -181,7 +217,7 @@ func (t *Transport) ConnectPeerWithAddress(peerID, address string, port int) err
 	}
 
 	// Create dealer for direct messages
-	dealer := zmq4.NewDealer(t.ctx, zmq4.WithID(zmq4.SocketIdentity(t.nodeID)))
+	dealer := zmq4.NewDealer(t.ctx, append(t.socketOptions(), zmq4.WithID(zmq4.SocketIdentity(t.nodeID)))...)
 
 	routerAddr := fmt.Sprintf("tcp://%s:%d", address, port+1000)
 	if err := dealer.Dial(routerAddr); err != nil {
@@ -220,13 +256,45 @@ func (t *Transport) Broadcast(msg *Message) error {
 	msg.From = t.nodeID
 	msg.Timestamp = time.Now().UnixNano()
 
-	data, err := json.Marshal(msg)
-	if err != nil {
+	buf := t.getBuffer()
+	defer t.putBuffer(buf)
+
+	if err := buf.enc.Encode(msg); err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
+	data := buf.json.Bytes()
+	data = data[:len(data)-1] // trailing newline added by Encode
+	if codec := t.config.CompressBroadcasts; codec != nil {
+		if err := compressTo(codec, &buf.out, data); err != nil {
+			return fmt.Errorf("failed to compress message: %w", err)
+		}
+		data = buf.out.Bytes()
+	}
 
+	// the PUB socket queues the message: it must not share the buffers.
 	t.msgSent.Add(1)
-	return t.pub.Send(zmq4.NewMsg(data))
+	return t.pub.Send(zmq4.NewMsg(bytes.Clone(data)))
+}
+
+// getBuffer returns a buffer to serialize a broadcast into.
+func (t *Transport) getBuffer() *encodeBuffer {
+	if buf, ok := t.bufs.Get().(*encodeBuffer); ok {
+		return buf
+	}
+	buf := new(encodeBuffer)
+	buf.enc = json.NewEncoder(&buf.json)
+	return buf
+}
+
+// putBuffer hands buf back for reuse, unless it grew too large.
+func (t *Transport) putBuffer(buf *encodeBuffer) {
+	limit := t.config.MaxPooledBuffer
+	if limit < 0 || buf.json.Cap() > limit || buf.out.Cap() > limit {
+		return
+	}
+	buf.json.Reset()
+	buf.out.Reset()
+	t.bufs.Put(buf)
 }
 
 // Send sends a direct message to a specific peer
@@ -243,49 +311,75 @@ func (t *Transport) Send(peerID string, msg *Message) error {
 	msg.To = peerID
 	msg.Timestamp = time.Now().UnixNano()
 
-	data, err := json.Marshal(msg)
+	zmsg, err := zmq4.NewMsgJSON(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
 	t.msgSent.Add(1)
-	return dealer.Send(zmq4.NewMsg(data))
+	return dealer.Send(zmsg)
 }
 
 // SendWithRetry sends a message with retry logic
 func (t *Transport) SendWithRetry(peerID string, msg *Message) error {
-	var lastErr error
+	return t.retry(func() error { return t.Send(peerID, msg) })
+}
+
+// BroadcastWithRetry broadcasts a message with retry logic
+func (t *Transport) BroadcastWithRetry(msg *Message) error {
+	return t.retry(func() error { return t.Broadcast(msg) })
+}
 
+// retry calls op up to MaxRetries times, until it succeeds, waiting between
+// the attempts as configured by the backoff policy.
+func (t *Transport) retry(op func() error) error {
+	var (
+		lastErr error
+		backoff = t.backoff()
+	)
 	for i := 0; i < t.config.MaxRetries; i++ {
-		if err := t.Send(peerID, msg); err == nil {
+		if lastErr = op(); lastErr == nil {
 			return nil
-		} else {
-			lastErr = err
-			if i < t.config.MaxRetries-1 {
-				time.Sleep(time.Duration(i+1) * t.config.RetryDelay)
-			}
+		}
+		if i < t.config.MaxRetries-1 {
+			time.Sleep(backoff.Next(i))
 		}
 	}
 
 	return fmt.Errorf("failed after %d retries: %w", t.config.MaxRetries, lastErr)
 }
 
-// BroadcastWithRetry broadcasts a message with retry logic
-func (t *Transport) BroadcastWithRetry(msg *Message) error {
-	var lastErr error
+// backoff returns the policy of the retried sends.
+func (t *Transport) backoff() zmq4.BackoffPolicy {
+	if t.config.Backoff != (zmq4.BackoffPolicy{}) {
+		return t.config.Backoff
+	}
+	return zmq4.BackoffPolicy{Initial: t.config.RetryDelay, Multiplier: 2}
+}
 
-	for i := 0; i < t.config.MaxRetries; i++ {
-		if err := t.Broadcast(msg); err == nil {
-			return nil
-		} else {
-			lastErr = err
-			if i < t.config.MaxRetries-1 {
-				time.Sleep(time.Duration(i+1) * t.config.RetryDelay)
-			}
-		}
+// socketOptions returns the options of the sockets dialing peers.
+func (t *Transport) socketOptions() []zmq4.Option {
+	if t.config.Backoff == (zmq4.BackoffPolicy{}) {
+		return nil
 	}
+	return []zmq4.Option{zmq4.WithBackoff(t.config.Backoff)}
+}
 
-	return fmt.Errorf("failed after %d retries: %w", t.config.MaxRetries, lastErr)
+// Flush blocks until all queued broadcasts and direct messages have been
+// written to the peers, or until ctx is done.
+func (t *Transport) Flush(ctx context.Context) error {
+	if err := t.pub.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush pub socket: %w", err)
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for peerID, dealer := range t.dealers {
+		if err := dealer.Flush(ctx); err != nil {
+			return fmt.Errorf("failed to flush dealer to %s: %w", peerID, err)
+		}
+	}
+	return nil
 }
 
 // RegisterHandler registers a message handler for a specific type
@@ -311,6 +405,22 @@ func (t *Transport) GetPeers() []string {
 	return peers
 }
 
+// IsPeerConnected reports whether the connection to the direct messages
+// socket of a peer is established, i.e. its handshake completed and the
+// peer did not disconnect since.
+// Unlike GetPeers, it reflects the actual state of the connection: a peer
+// that went down is reported as disconnected until it is reconnected.
+func (t *Transport) IsPeerConnected(peerID string) bool {
+	t.mu.RLock()
+	dealer, ok := t.dealers[peerID]
+	t.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	conns, ok := dealer.(zmq4.ConnectionLister)
+	return ok && len(conns.Connections()) > 0
+}
+
 // GetNodeID returns this node's ID
 func (t *Transport) GetNodeID() string {
 	return t.nodeID
@@ -321,6 +431,48 @@ func (t *Transport) GetMetrics() (sent, received, dropped uint64) {
 	return t.msgSent.Load(), t.msgReceived.Load(), t.msgDropped.Load()
 }
 
+// SocketStats holds the traffic counters and queue depths of a socket of the
+// transport.
+type SocketStats struct {
+	zmq4.Stats
+
+	// HWM is the maximum number of messages queued for sending per peer,
+	// or 0 if the socket has no send queue.
+	HWM int
+}
+
+// SocketStats returns the stats of the sockets of the transport, keyed by
+// role: "pub", "sub", "router", and "dealer/<peer ID>" for the dealers
+// connected to each peer.
+// Comparing the SendQueued messages of the "pub" socket to its HWM tells
+// whether broadcasts are backing up.
+func (t *Transport) SocketStats() map[string]SocketStats {
+	stats := make(map[string]SocketStats)
+	add := func(role string, sck zmq4.Socket) {
+		r, ok := sck.(zmq4.StatsReporter)
+		if !ok {
+			return
+		}
+		s := SocketStats{Stats: r.Stats()}
+		if hwm, err := sck.GetOption(zmq4.OptionHWM); err == nil {
+			s.HWM, _ = hwm.(int)
+		}
+		stats[role] = s
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for role, sck := range map[string]zmq4.Socket{"pub": t.pub, "sub": t.sub, "router": t.router} {
+		if sck != nil {
+			add(role, sck)
+		}
+	}
+	for peerID, dealer := range t.dealers {
+		add("dealer/"+peerID, dealer)
+	}
+	return stats
+}
+
 // subLoop processes broadcast messages
 func (t *Transport) subLoop() {
 	defer t.wg.Done()
@@ -377,6 +529,12 @@ func (t *Transport) routerLoop() {
 
 // processMessage handles incoming messages
 func (t *Transport) processMessage(data []byte) {
+	data, err := t.decompress(data)
+	if err != nil {
+		t.msgDropped.Add(1)
+		return
+	}
+
 	var message Message
 	if err := json.Unmarshal(data, &message); err != nil {
 		// Silently drop malformed messages
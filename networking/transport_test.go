@@ -0,0 +1,304 @@
+// Copyright (C) 2020-2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package networking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+)
+
+// freeBasePort returns a base port such that the PUB (base) and ROUTER
+// (base+1000) ports of a Transport are free.
+func freeBasePort(t testing.TB) int {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not find a free port: %+v", err)
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		if port+1000 > 65535 {
+			continue
+		}
+		l, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port+1000)))
+		if err != nil {
+			continue
+		}
+		l.Close()
+		return port
+	}
+	t.Fatalf("could not find a free base port")
+	return 0
+}
+
+func TestTransportCompressedBroadcast(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg := DefaultConfig("sender", freeBasePort(t))
+	cfg.CompressBroadcasts = GzipCodec{}
+	sender := New(ctx, cfg)
+	if err := sender.Start(); err != nil {
+		t.Fatalf("could not start sender: %+v", err)
+	}
+	defer sender.Stop()
+
+	// the receiver does not compress its own broadcasts.
+	receiver := New(ctx, DefaultConfig("receiver", freeBasePort(t)))
+	if err := receiver.Start(); err != nil {
+		t.Fatalf("could not start receiver: %+v", err)
+	}
+	defer receiver.Stop()
+
+	got := make(chan *Message, 16)
+	receiver.RegisterHandler("block", func(msg *Message) { got <- msg })
+	if err := receiver.ConnectPeer("sender", cfg.BasePort); err != nil {
+		t.Fatalf("could not connect to sender: %+v", err)
+	}
+
+	data, _ := json.Marshal(strings.Repeat("consensus ", 1000))
+	msg := &Message{Type: "block", Height: 42, Data: data}
+
+	raw, _ := json.Marshal(msg)
+	wire, err := compress(cfg.CompressBroadcasts, raw)
+	if err != nil {
+		t.Fatalf("could not compress: %+v", err)
+	}
+	if wire[0] != gzipCodecID || len(wire) >= len(raw)/10 {
+		t.Fatalf("payload not compressed: %d bytes -> %d bytes", len(raw), len(wire))
+	}
+
+	// the subscription may not have reached the sender yet: broadcast
+	// until the message is received.
+	for {
+		if err := sender.Broadcast(msg); err != nil {
+			t.Fatalf("could not broadcast: %+v", err)
+		}
+		select {
+		case m := <-got:
+			if m.From != "sender" || m.Height != 42 || !bytes.Equal(m.Data, data) {
+				t.Fatalf("invalid message: %+v", m)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatalf("compressed broadcast not received")
+		}
+	}
+}
+
+func TestTransportConcurrentBroadcast(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, codec := range []Codec{nil, GzipCodec{}} {
+		t.Run(fmt.Sprintf("codec=%T", codec), func(t *testing.T) {
+			cfg := DefaultConfig("sender", freeBasePort(t))
+			cfg.CompressBroadcasts = codec
+			sender := New(ctx, cfg)
+			if err := sender.Start(); err != nil {
+				t.Fatalf("could not start sender: %+v", err)
+			}
+			defer sender.Stop()
+
+			receiver := New(ctx, DefaultConfig("receiver", freeBasePort(t)))
+			if err := receiver.Start(); err != nil {
+				t.Fatalf("could not start receiver: %+v", err)
+			}
+			defer receiver.Stop()
+
+			payload := func(height uint64) json.RawMessage {
+				data, _ := json.Marshal(strings.Repeat(strconv.FormatUint(height, 10), int(height%64)+1))
+				return data
+			}
+
+			got := make(chan *Message, 1024)
+			receiver.RegisterHandler("block", func(msg *Message) { got <- msg })
+			if err := receiver.ConnectPeer("sender", cfg.BasePort); err != nil {
+				t.Fatalf("could not connect to sender: %+v", err)
+			}
+			// wait for the subscription to reach the sender.
+		wait:
+			for {
+				if err := sender.Broadcast(&Message{Type: "block", Data: payload(0)}); err != nil {
+					t.Fatalf("could not broadcast: %+v", err)
+				}
+				select {
+				case <-got:
+					break wait
+				case <-time.After(50 * time.Millisecond):
+				case <-ctx.Done():
+					t.Fatalf("broadcast not received")
+				}
+			}
+
+			const (
+				nsenders = 8
+				nmsgs    = 50
+			)
+			var wg sync.WaitGroup
+			for i := 0; i < nsenders; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 1; j <= nmsgs; j++ {
+						height := uint64(i*nmsgs + j)
+						msg := &Message{Type: "block", Height: height, Data: payload(height)}
+						if err := sender.Broadcast(msg); err != nil {
+							t.Errorf("could not broadcast: %+v", err)
+							return
+						}
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			seen := make(map[uint64]bool)
+			for len(seen) < nsenders*nmsgs {
+				select {
+				case m := <-got:
+					if m.Height == 0 {
+						continue // late subscription probe.
+					}
+					if !bytes.Equal(m.Data, payload(m.Height)) {
+						t.Fatalf("invalid payload for height %d: %s", m.Height, m.Data)
+					}
+					seen[m.Height] = true
+				case <-ctx.Done():
+					t.Fatalf("received %d broadcasts, want %d", len(seen), nsenders*nmsgs)
+				}
+			}
+		})
+	}
+}
+
+func TestTransportDecompress(t *testing.T) {
+	tr := New(context.Background(), DefaultConfig("node", 0))
+
+	plain := []byte(`{"type":"ping"}`)
+	if got, err := tr.decompress(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("uncompressed payload altered: %q (err=%v)", got, err)
+	}
+
+	wire, err := compress(GzipCodec{}, plain)
+	if err != nil {
+		t.Fatalf("could not compress: %+v", err)
+	}
+	if got, err := tr.decompress(wire); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("invalid decompressed payload: %q (err=%v)", got, err)
+	}
+
+	if _, err := tr.decompress([]byte{0x1f, 'x'}); err == nil {
+		t.Fatalf("expected an error for an unknown codec")
+	}
+}
+
+func TestTransportIsPeerConnected(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg := DefaultConfig("peer", freeBasePort(t))
+	peer := New(ctx, cfg)
+	if err := peer.Start(); err != nil {
+		t.Fatalf("could not start peer: %+v", err)
+	}
+	node := New(ctx, DefaultConfig("node", freeBasePort(t)))
+	if err := node.Start(); err != nil {
+		t.Fatalf("could not start node: %+v", err)
+	}
+	defer node.Stop()
+
+	if node.IsPeerConnected("peer") {
+		t.Fatalf("unknown peer reported connected")
+	}
+	if err := node.ConnectPeer("peer", cfg.BasePort); err != nil {
+		t.Fatalf("could not connect to peer: %+v", err)
+	}
+	if !node.IsPeerConnected("peer") {
+		t.Fatalf("peer reported disconnected")
+	}
+
+	peer.Stop()
+	for node.IsPeerConnected("peer") {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("stopped peer still reported connected")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if got := node.GetPeers(); len(got) != 1 || got[0] != "peer" {
+		t.Fatalf("invalid peers: %q", got)
+	}
+}
+
+func TestTransportSocketStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg := DefaultConfig("peer", freeBasePort(t))
+	peer := New(ctx, cfg)
+	if err := peer.Start(); err != nil {
+		t.Fatalf("could not start peer: %+v", err)
+	}
+	defer peer.Stop()
+	node := New(ctx, DefaultConfig("node", freeBasePort(t)))
+	if err := node.Start(); err != nil {
+		t.Fatalf("could not start node: %+v", err)
+	}
+	defer node.Stop()
+	if err := node.ConnectPeer("peer", cfg.BasePort); err != nil {
+		t.Fatalf("could not connect to peer: %+v", err)
+	}
+
+	stats := node.SocketStats()
+	for _, role := range []string{"pub", "sub", "router", "dealer/peer"} {
+		if _, ok := stats[role]; !ok {
+			t.Fatalf("missing stats of the %q socket: %v", role, stats)
+		}
+	}
+	if got, want := len(stats), 4; got != want {
+		t.Fatalf("invalid number of sockets: got=%d, want=%d", got, want)
+	}
+	if got, want := stats["pub"].HWM, zmq4.DefaultSendHwm; got != want {
+		t.Fatalf("invalid pub HWM: got=%d, want=%d", got, want)
+	}
+}
+
+func BenchmarkTransportBroadcast(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data, _ := json.Marshal(strings.Repeat("consensus ", 100))
+	for _, codec := range []Codec{nil, GzipCodec{}} {
+		b.Run(fmt.Sprintf("codec=%T", codec), func(b *testing.B) {
+			cfg := DefaultConfig("sender", freeBasePort(b))
+			cfg.CompressBroadcasts = codec
+			sender := New(ctx, cfg)
+			if err := sender.Start(); err != nil {
+				b.Fatalf("could not start sender: %+v", err)
+			}
+			defer sender.Stop()
+
+			msg := &Message{Type: "block", Height: 42, Data: data}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := sender.Broadcast(msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
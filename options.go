@@ -5,13 +5,18 @@
 package zmq4
 
 import (
+	"crypto/tls"
+	"fmt"
+	"os"
 	"time"
 )
 
 // Option configures a ZeroMQ socket - ONE way to configure
 type Option func(s *socket)
 
-// WithID sets socket identity
+// WithID sets socket identity.
+// Sockets created without one announce an empty identity, and are assigned a
+// transient identity by the ROUTER sockets they connect with.
 func WithID(id SocketIdentity) Option {
 	return func(s *socket) {
 		s.id = id
@@ -19,6 +24,11 @@ func WithID(id SocketIdentity) Option {
 }
 
 // WithSecurity sets security mechanism (default: NULL)
+//
+// The mechanism applies to all the connections of the socket: the ZMTP
+// greeting rejects peers offering another one. In particular, a peer that
+// connected with CURVE or PLAIN cannot reconnect with NULL, whatever its
+// identity.
 func WithSecurity(sec Security) Option {
 	return func(s *socket) {
 		if sec == nil {
@@ -28,10 +38,228 @@ func WithSecurity(sec Security) Option {
 	}
 }
 
-// WithTimeout sets socket timeout
+// WithZAPDomain sets the authentication domain of the socket, selecting the
+// policies set by AuthAllow, AuthDeny and AuthCurveAdd that apply to the
+// peers of the connections it accepts, while authentication is started.
+// As in libzmq, the peers of NULL sockets are only authenticated when the
+// domain is set.
+func WithZAPDomain(domain string) Option {
+	return func(s *socket) {
+		s.zapDomain = domain
+	}
+}
+
+// WithAsServer sets the ZMTP role of the socket on all its connections,
+// whether accepted or dialed. By default, the end that accepted a connection
+// is its ZMTP server and the end that dialed it its client.
+//
+// The role is announced in the ZMTP greeting and selects the side of the
+// security handshake the socket performs, e.g. the PLAIN server validating
+// the credentials of its client. It is the server parameter of Open.
+// The peers of a connection must agree on their roles: WithAsServer(true)
+// on a dialing socket requires WithAsServer(false) on the listening one.
+func WithAsServer(server bool) Option {
+	return func(s *socket) {
+		s.asServer = &server
+	}
+}
+
+// WithTimeout sets the timeout of the send and receive operations of the
+// socket: they fail with ErrTimeout when they cannot complete in time.
+// A zero timeout makes them block until they complete.
+// By default, sends time out after 5 minutes and receives block.
 func WithTimeout(timeout time.Duration) Option {
 	return func(s *socket) {
 		s.timeout = timeout
+		s.recvTimeout = timeout
+	}
+}
+
+// WithLinger sets how long Close waits for the messages queued for sending
+// to be written to the connected peers.
+// A zero linger (the default) discards pending messages on Close, and a
+// negative linger waits indefinitely.
+// The linger can also be changed at runtime with OptionLinger.
+func WithLinger(linger time.Duration) Option {
+	return func(s *socket) {
+		s.linger = linger
+	}
+}
+
+// lingerValue converts the value of OptionLinger to a duration: either a
+// time.Duration or a number of milliseconds, a negative value meaning
+// infinite linger.
+func lingerValue(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case int:
+		if v < 0 {
+			return -1, nil
+		}
+		return time.Duration(v) * time.Millisecond, nil
+	default:
+		return 0, invalidOption(OptionLinger, value, "time.Duration or int")
+	}
+}
+
+// invalidOption reports an invalid value for the named option, e.g.
+// "zmq4: HWM expects non-negative int, got string ("10")".
+func invalidOption(name string, value interface{}, want string) error {
+	return fmt.Errorf("zmq4: %s expects %s, got %T (%#v): %w", name, want, value, value, ErrBadProperty)
+}
+
+// optionString returns the string or []byte value of the named option as a
+// string.
+func optionString(name string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case SocketIdentity:
+		return string(v), nil
+	default:
+		return "", invalidOption(name, value, "string or []byte")
+	}
+}
+
+// optionValue validates the value of the named option, and returns it in its
+// canonical form.
+func optionValue(name string, value interface{}) (interface{}, error) {
+	switch name {
+	case OptionSubscribe, OptionUnsubscribe, OptionXPubWelcomeMsg:
+		return optionString(name, value)
+	case OptionJoin, OptionLeave:
+		group, err := optionString(name, value)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(group); n == 0 || n > maxGroupLen {
+			return nil, fmt.Errorf("zmq4: invalid %s option length %d, want 1 to %d bytes: %w", name, n, maxGroupLen, ErrBadProperty)
+		}
+		return group, nil
+	case OptionIdentity:
+		id, err := optionString(name, value)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(id); n == 0 || n > 255 {
+			return nil, fmt.Errorf("zmq4: invalid %s option length %d, want 1 to 255 bytes: %w", name, n, ErrBadProperty)
+		}
+		return id, nil
+	case OptionHWM, OptionSndHWM, OptionRcvHWM:
+		if hwm, ok := value.(int); ok && hwm >= 0 {
+			return hwm, nil
+		}
+		return nil, invalidOption(name, value, "non-negative int")
+	case OptionTimeout:
+		if timeout, ok := value.(time.Duration); ok && timeout >= 0 {
+			return timeout, nil
+		}
+		return nil, invalidOption(name, value, "non-negative time.Duration")
+	case OptionReconnectIvl:
+		if ivl, ok := value.(time.Duration); ok && ivl > 0 {
+			return ivl, nil
+		}
+		return nil, invalidOption(name, value, "positive time.Duration")
+	case OptionReconnectIvlMax:
+		if ivl, ok := value.(time.Duration); ok && ivl >= 0 {
+			return ivl, nil
+		}
+		return nil, invalidOption(name, value, "non-negative time.Duration")
+	case OptionLinger:
+		return lingerValue(value)
+	case OptionRouterMandatory, OptionRouterHandover, OptionXPubVerbose, OptionXPubManual,
+		OptionReqRelaxed, OptionReqCorrelate:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+		return nil, invalidOption(name, value, "bool")
+	case OptionTCPKeepalive:
+		return keepaliveValue(value)
+	case OptionTCPKeepaliveIdle, OptionTCPKeepaliveInterval, OptionHeartbeatIvl, OptionHeartbeatTimeout:
+		if d, ok := value.(time.Duration); ok && d >= 0 {
+			return d, nil
+		}
+		return nil, invalidOption(name, value, "non-negative time.Duration")
+	case OptionTCPKeepaliveCount:
+		if n, ok := value.(int); ok && n >= 0 {
+			return n, nil
+		}
+		return nil, invalidOption(name, value, "non-negative int")
+	case OptionHeartbeatTTL:
+		if d, ok := value.(time.Duration); ok && d >= 0 && d <= maxHeartbeatTTL {
+			return d, nil
+		}
+		return nil, invalidOption(name, value, "time.Duration in [0, 6553.5s]")
+	default:
+		return value, nil
+	}
+}
+
+// WithWriteCoalesce configures the socket to coalesce the messages written
+// to each peer: written data is held back until maxBytes bytes are pending or
+// for at most maxDelay, and then written in a single system call.
+// This improves the throughput of bursts of small messages, at the cost of up
+// to maxDelay of added latency.
+// Coalescing is off by default, and when maxDelay or maxBytes is not
+// positive.
+func WithWriteCoalesce(maxDelay time.Duration, maxBytes int) Option {
+	return func(s *socket) {
+		s.coalesce = coalesceConfig{delay: maxDelay, size: maxBytes}
+	}
+}
+
+// DeadLetterHandler is called with the routing identity and the body of a
+// message a ROUTER or STREAM socket could not route to any connected peer.
+type DeadLetterHandler func(identity []byte, msg Msg)
+
+// WithDeadLetterHandler sets the handler of the messages a ROUTER or STREAM
+// socket cannot route, e.g. because their peer disconnected.
+// Such messages are otherwise silently dropped: either way, Send does not
+// report an error for them.
+//
+// The handler is called from Send, once the message was rejected, and may
+// send on the socket.
+func WithDeadLetterHandler(h DeadLetterHandler) Option {
+	return func(s *socket) {
+		s.deadLetter = h
+	}
+}
+
+// AcceptFilter is called with each connection accepted by a socket, once the
+// ZMTP handshake completed. It returns an application-defined value attached
+// to the connection (see ConnInfo.Value and PeerReceiver), or an error to
+// reject the connection.
+type AcceptFilter func(info ConnInfo) (interface{}, error)
+
+// WithAcceptFilter sets the filter of the connections accepted by the socket.
+// The filter is called from the accepting goroutine: the socket does not
+// accept other connections until it returns.
+func WithAcceptFilter(f AcceptFilter) Option {
+	return func(s *socket) {
+		s.acceptFilter = f
+	}
+}
+
+// WithMonitorBuffer sets the capacity of the monitor channel of the socket
+// (100 by default). Events are dropped when the channel is full, unless the
+// monitor is blocking (see WithMonitorBlocking).
+func WithMonitorBuffer(n int) Option {
+	return func(s *socket) {
+		if n >= 0 {
+			s.monitor.size = n
+		}
+	}
+}
+
+// WithMonitorBlocking configures whether the socket waits for room in a full
+// monitor channel, rather than dropping events.
+// A blocking monitor must be drained: the socket stalls until it is.
+func WithMonitorBlocking(block bool) Option {
+	return func(s *socket) {
+		s.monitor.block = block
 	}
 }
 
@@ -40,31 +268,508 @@ func WithLogger(logger interface{}) Option {
 	return func(s *socket) {}
 }
 
-// WithDialerRetry is a no-op for compatibility
+// WithDialerRetry configures the socket to retry failed dials every retry,
+// up to the number of retries set by WithDialerMaxRetries.
+// It is a shorthand for WithBackoff(ConstantBackoff(retry)).
 func WithDialerRetry(retry time.Duration) Option {
+	return WithBackoff(ConstantBackoff(retry))
+}
+
+// WithBackoff configures the delays between the attempts to dial a peer,
+// when Dial fails and when the socket reconnects (see
+// WithAutomaticReconnect), up to the number of retries set by
+// WithDialerMaxRetries.
+// The default is to retry every 250ms.
+func WithBackoff(policy BackoffPolicy) Option {
 	return func(s *socket) {
-		s.retry = retry
+		s.backoff = policy
 	}
 }
 
-// WithDialerMaxRetries is a no-op for compatibility
+// WithDialerMaxRetries configures how many times a failed dial is retried,
+// by Dial and when the socket reconnects (see WithAutomaticReconnect).
+// A value of -1 retries until the socket is closed.
 func WithDialerMaxRetries(maxRetries int) Option {
 	return func(s *socket) {
 		s.maxRetries = maxRetries
 	}
 }
 
-// WithAutomaticReconnect is a no-op for compatibility
+// WithAutomaticReconnect configures whether the socket dials its endpoints
+// again when their connection is lost, e.g. when the peer restarts.
+// The dials are retried as configured by WithBackoff and
+// WithDialerMaxRetries. The reconnected peer receives the identity of the
+// socket and, for SUB and XSUB sockets, their subscriptions.
+// The default is to reconnect.
 func WithAutomaticReconnect(auto bool) Option {
 	return func(s *socket) {
 		s.autoReconnect = auto
 	}
 }
 
+// WithRawEnvelope configures REQ and REP sockets to hand the routing
+// envelope to the application instead of managing it.
+//
+// A raw REP socket delivers the complete frame stack received from its peer:
+//
+//	[identity-0]...[identity-n][empty-delimiter][payload-0]...[payload-m]
+//
+// where the identity frames are only present when the request went through
+// ROUTER sockets. Send on a raw REP socket writes the message as-is, so it
+// must carry the envelope, to the peer of the last received message.
+//
+// A raw REQ socket does not prepend the empty delimiter frame on Send and
+// does not strip the first frame on Recv.
+//
+// Other socket types ignore this option.
+func WithRawEnvelope(raw bool) Option {
+	return func(s *socket) {
+		s.rawEnvelope = raw
+	}
+}
+
+// WithRecvBufferPool configures whether small messages are received into
+// pooled buffers, to be handed back with Msg.Release.
+//
+// With a pool, receiving messages of up to 256 bytes of payload (all frames
+// included) over connections without encryption does not allocate, provided
+// the application releases the messages once processed.
+// Received messages then reference their pooled buffer: see Msg.Release for
+// the lifetime of their frames.
+func WithRecvBufferPool(enable bool) Option {
+	return func(s *socket) {
+		s.recvPool = enable
+	}
+}
+
+// WithRecvZeroCopy configures whether the frames of the received messages are
+// left in place in the read buffers of the connections, rather than copied
+// into storage of their own.
+//
+// By default, each frame is copied out of the stream read from the peer.
+// With zero-copy, connections without encryption read the stream into
+// pooled 64 KiB buffers, and the frames of up to 64 KiB are sub-slices of
+// the buffer they were read into: receiving a frame then neither copies nor
+// allocates it. Messages of any size benefit, unlike WithRecvBufferPool,
+// which zero-copy supersedes.
+//
+// A buffer is handed back to the pool, to be overwritten by subsequent
+// reads, once all the messages referencing it were released: see
+// Msg.Release for the lifetime of their frames. Frames stay valid, and are
+// not modified by the library, until their message is released. A message
+// that is never released is garbage collected, but keeps its whole buffer
+// out of the pool, and alive, for as long as one of its frames is used:
+// Clone the messages, or the frames, retained for long.
+func WithRecvZeroCopy(enable bool) Option {
+	return func(s *socket) {
+		s.recvZeroCopy = enable
+	}
+}
+
+// WithRecvQueueSize configures the capacity of the queue holding the
+// messages received from the peers until they are delivered by Recv.
+// The default is 10 messages. Negative values are ignored.
+//
+// A small queue, down to 0 where each message is handed off directly from
+// the connection reader to Recv, keeps the messages waiting in the socket
+// fresh and pushes back on the peers sooner, at the cost of throughput: the
+// connection readers stall whenever the application is not in Recv.
+// A larger queue absorbs bursts, at the cost of the latency of the messages
+// queued behind them.
+func WithRecvQueueSize(n int) Option {
+	return func(s *socket) {
+		if n >= 0 {
+			s.recv.qsize = n
+		}
+	}
+}
+
+// WithRecvHWM configures the receive high water mark of the socket: the
+// maximum number of messages queued once received, until they are
+// delivered by Recv. It is the capacity of the receive queue, see
+// WithRecvQueueSize: once it is full, the socket stops reading from its
+// connections, which pushes back on the peers.
+func WithRecvHWM(n int) Option {
+	return WithRecvQueueSize(n)
+}
+
+// WithSendHWM configures the send high water mark of the socket: the
+// maximum number of messages queued for each connection.
+// Values lower than 1 are ignored.
+//
+// PUSH and DEALER sockets created with WithSendHWM queue their messages,
+// written to the connections by background goroutines, instead of writing
+// them within Send: Send blocks once the queues of all the connections are
+// full, until there is room again or the send deadline expires, and SendNB
+// returns ErrWouldBlock. The errors of the background writes are returned
+// by the Send calls that follow them. PUSH sockets created with
+// WithPullAck bound the number of unacknowledged messages instead, to
+// DefaultSendHwm by default.
+//
+// PUB and XPUB sockets drop the messages for the subscribers whose queue
+// is full. Their default is DefaultSendHwm.
+func WithSendHWM(n int) Option {
+	return func(s *socket) {
+		if n > 0 {
+			s.sndHWM = n
+		}
+	}
+}
+
+// WithRecvDispatcher configures whether the messages received from
+// multiple peers are fair-queued by a dispatcher goroutine.
+//
+// By default, connection readers hand messages off to Recv directly, in
+// arrival order: this has the lowest latency, but a busy peer can fill the
+// receive queue and delay the messages of the other peers.
+// With a dispatcher, each connection has its own receive queue (see
+// WithRecvQueueSize) and Recv delivers one message of each peer in turn, at
+// the cost of an extra goroutine hand-off per message.
+//
+// The dispatcher applies to PAIR, DEALER, ROUTER, PULL, SUB, XSUB and STREAM
+// sockets. Other socket types ignore this option.
+func WithRecvDispatcher(enable bool) Option {
+	return func(s *socket) {
+		s.recv.dispatch = enable
+	}
+}
+
+// WithDrainOnUnsubscribe configures whether a SUB socket drops the messages
+// that are no longer subscribed to upon OptionUnsubscribe.
+//
+// By default, messages received before the unsubscription reaches the
+// publishers are still delivered, including the ones already sitting in the
+// receive queue. With this option, Recv only delivers messages matching an
+// active subscription at the time of the Recv call: queued messages that
+// only matched the removed subscription are discarded.
+//
+// Other socket types ignore this option.
+func WithDrainOnUnsubscribe(drain bool) Option {
+	return func(s *socket) {
+		s.drainUnsub = drain
+	}
+}
+
+// WithSubscriptionHWM configures the maximum number of messages, typically
+// subscription frames, an XSUB socket queues per publisher.
+// The default is DefaultSendHwm. Values lower than 1 are ignored.
+//
+// XSUB messages are written to each publisher in order by a background
+// goroutine. When the queue of a publisher is full, Send blocks until there
+// is room again or the send deadline expires: subscription frames are never
+// dropped, and a publisher slow to process them pushes back on the sender.
+//
+// Other socket types ignore this option.
+func WithSubscriptionHWM(n int) Option {
+	return func(s *socket) {
+		if n > 0 {
+			s.subHWM = n
+		}
+	}
+}
+
+// WithMessageTrace configures whether the socket traces the messages it
+// exchanges with its peers. Tracing is off by default.
+//
+// When enabled, a TraceEvent (direction, number of frames, size and time) is
+// emitted for every message written to or read from a peer, available from
+// the channel returned by the MessageTracer interface the socket implements.
+// A message published to several peers is traced once per peer.
+// Payloads are not copied, but tracing still adds a per-message overhead:
+// it is meant for debugging message flows.
+//
+// Events are dropped when the trace channel is full: it must be drained by
+// the application. The channel is never closed.
+func WithMessageTrace(enable bool) Option {
+	return func(s *socket) {
+		s.tracer = nil
+		if enable {
+			s.tracer = newMessageTracer()
+		}
+	}
+}
+
+// WithFrameSizeHistogram configures whether the socket records the
+// distribution of the sizes of the frames it exchanges with its peers in its
+// Stats (see Stats.FrameSizes).
+func WithFrameSizeHistogram(enable bool) Option {
+	return func(s *socket) {
+		s.stats.enableHistogram(enable)
+	}
+}
+
+// WithPortRange restricts the ports TCP end-points with an ephemeral port,
+// e.g. "tcp://host:0", listen to: Listen picks the first free port between
+// min and max, inclusive, and fails if none is free.
+func WithPortRange(min, max int) Option {
+	return func(s *socket) {
+		s.portMin, s.portMax = min, max
+	}
+}
+
+// WithListenBacklog sets the maximum length of the queue of the connections
+// the kernel completed but the socket did not accept yet, for the end-points
+// it listens to (default: the system maximum, as chosen by the net package).
+// Connections beyond it are dropped or refused, e.g. when many clients
+// reconnect at once after a broker restart.
+// The kernel caps n to its own limit (net.core.somaxconn on Linux,
+// kern.ipc.somaxconn on BSDs and macOS), which may need to be raised too.
+// The backlog is ignored by the transports without kernel sockets, e.g.
+// inproc, and on platforms where it cannot be set (see transport.SetBacklog).
+func WithListenBacklog(n int) Option {
+	return func(s *socket) {
+		s.backlog = n
+	}
+}
+
+// WithIPCFileMode sets the permissions of the socket files created by the
+// ipc:// end-points the socket listens to, e.g. 0o660 to let the members of
+// a group connect. The default is to keep the permissions set by the
+// process umask.
+// The permissions are applied right after binding: a peer may connect in
+// between. They do not apply to the names of the Linux abstract namespace,
+// starting with '@' (e.g. "ipc://@name"), which have no file, and which
+// are not supported on other systems.
+// Socket files are removed when the socket stops listening to them, and
+// stale ones left by a process that did not close its socket are replaced.
+func WithIPCFileMode(mode os.FileMode) Option {
+	return func(s *socket) {
+		s.ipcMode = mode
+	}
+}
+
+// WithPullAck enables the acknowledgement mode of PUSH and PULL sockets,
+// making a reliable work queue: PULL sockets must acknowledge each received
+// message once processed (see Acker), and PUSH sockets send again to another
+// peer the messages that were not acknowledged after timeout, or whose peer
+// disconnected.
+//
+// In acknowledgement mode, PUSH sockets send each message to a single peer,
+// in turn, and messages are prefixed with a sequence number frame on the
+// wire: both ends must be created with WithPullAck.
+// Messages are delivered at least once: a slow peer acknowledging a message
+// after timeout may have it processed twice.
+//
+// PUSH sockets keep up to their send high water mark (see WithSendHWM,
+// DefaultSendHwm by default) of unacknowledged messages: Send then blocks
+// until a message is acknowledged, and SendNB returns ErrWouldBlock.
+func WithPullAck(timeout time.Duration) Option {
+	return func(s *socket) {
+		s.ackTimeout = timeout
+	}
+}
+
+// WithMaxFrames sets the maximum number of frames of the messages a socket
+// sends and receives (default 1<<20).
+// Sending a larger message fails with ErrTooManyFrames, and a peer sending
+// one is disconnected.
+// A value <= 0 disables the limit.
+func WithMaxFrames(n int) Option {
+	return func(s *socket) {
+		s.maxFrames = max(n, 0)
+	}
+}
+
+// WithOnReconnect sets a callback invoked with the socket after each
+// connection it dials, including the first one and the automatic
+// reconnections (see WithAutomaticReconnect), once the connection is ready
+// to send messages.
+//
+// It lets stateful protocols replay the registration a peer expects, e.g.
+// a worker announcing itself to a broker.
+// An error returned by the callback is logged, and returned by Dial: the
+// connection is kept.
+func WithOnReconnect(f func(sock Socket) error) Option {
+	return func(s *socket) {
+		s.onReconnect = f
+	}
+}
+
+// WithRequirePeerIdentity configures whether the socket rejects the peers
+// that do not announce an explicit identity (see WithID and OptionIdentity),
+// instead of assigning them a transient one.
+// Such peers are disconnected right after the handshake, and reported as
+// EventHandshakeFailed on the monitor channel of the socket.
+func WithRequirePeerIdentity(require bool) Option {
+	return func(s *socket) {
+		s.requirePeerID = require
+	}
+}
+
+// WithCertIdentityRouting configures whether peers connected over TLS with
+// a verified certificate are identified by the identity of that certificate
+// (see PeerCertIdentity), instead of the identity they announce.
+// ROUTER sockets then route messages by certificate identity.
+func WithCertIdentityRouting(enable bool) Option {
+	return func(s *socket) {
+		s.certRouting = enable
+	}
+}
+
+// WithTLS sets the TLS configuration of the tls:// end-points of the socket.
+// Listening sockets present the certificates of cfg to the peers, and
+// verify their certificates as set by its ClientAuth and ClientCAs.
+// Dialing sockets verify the certificate of the peer against its RootCAs,
+// or the system roots if nil, for its ServerName, or the host of the
+// end-point if empty.
+// Security mechanisms set with WithSecurity run on top of TLS.
+func WithTLS(cfg *tls.Config) Option {
+	return func(s *socket) {
+		s.tls = cfg
+	}
+}
+
 // Socket option constants - only essential ones
 const (
 	OptionSubscribe   = "SUBSCRIBE"
 	OptionUnsubscribe = "UNSUBSCRIBE"
-	OptionHWM         = "HWM"
-	OptionIdentity    = "IDENTITY"
+
+	// OptionHWM sets the send high water mark of the socket, as a
+	// non-negative int. It is an alias of OptionSndHWM.
+	OptionHWM = "HWM"
+
+	// OptionSndHWM sets the maximum number of messages the socket queues
+	// per connection, as a non-negative int. It only resizes the queues of
+	// the connections established afterwards. See WithSendHWM.
+	// PUSH and DEALER sockets only queue their messages when created with
+	// WithSendHWM, or WithPullAck: SetOption returns an error wrapping
+	// ErrBadProperty otherwise.
+	OptionSndHWM = "SNDHWM"
+
+	// OptionRcvHWM reports the maximum number of messages the socket
+	// queues once received, until they are delivered by Recv. It can only
+	// be set when the socket is created, with WithRecvHWM.
+	OptionRcvHWM = "RCVHWM"
+
+	// OptionIdentity sets the identity announced by the socket to the peers
+	// it connects with, as a string or a []byte of 1 to 255 bytes.
+	// The identity can only be changed while the socket has no connection:
+	// SetOption returns an error wrapping ErrIdentityChange otherwise.
+	OptionIdentity = "IDENTITY"
+
+	// OptionTimeout sets the send and receive timeout of the socket, as a
+	// non-negative time.Duration: 0 clears the timeout, and the operations
+	// block until they complete. See WithTimeout.
+	OptionTimeout = "TIMEOUT"
+
+	// OptionLinger sets how long Close waits for pending messages, as a
+	// time.Duration or an int number of milliseconds (-1 waits
+	// indefinitely). See WithLinger.
+	OptionLinger = "LINGER"
+
+	// OptionReconnectIvl sets the delay before the first attempt to dial
+	// a peer again, as a positive time.Duration: it is the Initial delay of
+	// the BackoffPolicy of the socket (see WithBackoff).
+	OptionReconnectIvl = "RECONNECT_IVL"
+
+	// OptionReconnectIvlMax sets the maximum delay between the attempts
+	// to dial a peer again, as a time.Duration. A positive value makes the
+	// delays double from OptionReconnectIvl up to it, unless the
+	// BackoffPolicy of the socket already grows them; zero makes them
+	// constant.
+	OptionReconnectIvlMax = "RECONNECT_IVL_MAX"
+
+	// OptionTCPKeepalive configures the TCP keepalive of the connections
+	// the socket establishes afterwards, as a bool or an int: 1 (true)
+	// enables it, 0 (false) disables it, and -1, the default, keeps the
+	// defaults of the net package, which enables it with probes every 15s.
+	// The other OptionTCPKeepalive options are only applied when it is
+	// enabled explicitly. Non-TCP connections are not affected.
+	OptionTCPKeepalive = "TCP_KEEPALIVE"
+
+	// OptionTCPKeepaliveIdle sets how long a connection stays idle before
+	// the first keepalive probe, as a time.Duration, rounded to the second
+	// on most platforms. Zero keeps the system default.
+	OptionTCPKeepaliveIdle = "TCP_KEEPALIVE_IDLE"
+
+	// OptionTCPKeepaliveInterval sets the delay between two keepalive
+	// probes, as a time.Duration. Zero keeps the system default.
+	// It can not be tuned on Windows versions older than Windows 10 1709,
+	// where it is ignored.
+	OptionTCPKeepaliveInterval = "TCP_KEEPALIVE_INTVL"
+
+	// OptionTCPKeepaliveCount sets how many probes may go unanswered
+	// before the connection is dropped, as an int. Zero keeps the system
+	// default.
+	// It can not be tuned on Windows versions older than Windows 10 1703,
+	// nor on Solaris and illumos, where it is ignored.
+	OptionTCPKeepaliveCount = "TCP_KEEPALIVE_CNT"
+
+	// OptionHeartbeatIvl sets the interval between the ZMTP PING commands
+	// sent to the peers, as a time.Duration. A connection on which nothing
+	// is received within OptionHeartbeatTimeout of a PING is closed, as a
+	// disconnection of the peer (see EventDisconnected). Zero, the
+	// default, disables the heartbeats.
+	OptionHeartbeatIvl = "HEARTBEAT_IVL"
+
+	// OptionHeartbeatTimeout sets how long to wait for traffic after a
+	// PING before closing the connection, as a time.Duration. Zero, the
+	// default, waits for OptionHeartbeatIvl.
+	OptionHeartbeatTimeout = "HEARTBEAT_TIMEOUT"
+
+	// OptionHeartbeatTTL sets the time to live announced to the peers in
+	// the PING commands, as a time.Duration rounded down to a tenth of a
+	// second, up to 6553.5s: a peer closes the connection when it receives
+	// nothing for that long. Zero, the default, announces no limit.
+	OptionHeartbeatTTL = "HEARTBEAT_TTL"
+
+	// OptionRouterMandatory configures whether a ROUTER socket fails to
+	// send the messages routed to an identity that is not connected, as a
+	// bool. Send then returns an error wrapping ErrHostUnreachable right
+	// away, even when the socket has no connection at all, instead of
+	// dropping the message (see WithDeadLetterHandler). It is off by
+	// default.
+	OptionRouterMandatory = "ROUTER_MANDATORY"
+
+	// OptionRouterHandover configures whether a peer connecting to a
+	// ROUTER socket with the identity of a live connection takes it over,
+	// as a bool: the former connection is closed, and the messages routed
+	// to the identity are sent to the new one. This lets a peer with a
+	// fixed identity reconnect before its former connection is found dead.
+	// It is off by default.
+	OptionRouterHandover = "ROUTER_HANDOVER"
+
+	// OptionReqRelaxed configures whether a REQ socket may send a request
+	// before it received the reply to the last one, as a bool: the reply to
+	// the last request is then discarded, and the new request may be sent
+	// to another peer. By default, the socket is strict, and Send fails
+	// with ErrFSM until the reply is received. Set OptionReqCorrelate too,
+	// to discard the late replies of a peer the last request was resent to.
+	OptionReqRelaxed = "REQ_RELAXED"
+
+	// OptionReqCorrelate configures whether a REQ socket prefixes its
+	// requests with an id, as a bool, and only receives the replies
+	// carrying the id of the last request. It is off by default, and does
+	// not apply to sockets created with WithRawEnvelope.
+	OptionReqCorrelate = "REQ_CORRELATE"
+
+	// OptionXPubVerbose configures whether a XPUB socket receives all the
+	// subscription messages of its subscribers, as a bool. By default,
+	// Recv only returns the first subscription to a topic and its last
+	// unsubscription, so that a Proxy subscribes upstream once per topic.
+	OptionXPubVerbose = "XPUB_VERBOSE"
+
+	// OptionXPubManual configures whether the application decides which
+	// subscriptions a XPUB socket honors, as a bool. Recv then returns all
+	// the subscription messages without applying them, and setting
+	// OptionSubscribe or OptionUnsubscribe on the XPUB socket applies the
+	// subscription to the subscriber of the last subscription message
+	// received. It is off by default.
+	OptionXPubManual = "XPUB_MANUAL"
+
+	// OptionXPubWelcomeMsg sets the message a XPUB socket sends to each
+	// subscriber upon its first subscription, before the subscription
+	// applies, as a string or a []byte. Unsubscriptions do not trigger it,
+	// and the welcome message is not filtered by topic: the subscriber
+	// receives it whatever it subscribed to. An empty message disables it,
+	// which is the default.
+	OptionXPubWelcomeMsg = "XPUB_WELCOME_MSG"
+
+	// OptionJoin and OptionLeave make a DISH socket join or leave a group,
+	// as a string or a []byte of 1 to 255 bytes, with the ZMTP JOIN and
+	// LEAVE commands. The messages published to a group by a RADIO socket
+	// (see Msg.Group) are matched exactly against the joined groups.
+	// Other socket types reject these options.
+	OptionJoin  = "JOIN"
+	OptionLeave = "LEAVE"
 )
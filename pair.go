@@ -7,12 +7,14 @@ package zmq4
 import (
 	"context"
 	"net"
+	"time"
 )
 
 // NewPair returns a new PAIR ZeroMQ socket.
 // The returned socket value is initially unbound.
 func NewPair(ctx context.Context, opts ...Option) Socket {
 	pair := &pairSocket{newSocket(ctx, Pair, opts...)}
+	pair.sck.self = pair
 	return pair
 }
 
@@ -26,6 +28,77 @@ func (pair *pairSocket) Close() error {
 	return pair.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (pair *pairSocket) Context() context.Context {
+	return pair.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (pair *pairSocket) Done() <-chan struct{} {
+	return pair.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (pair *pairSocket) MessageTrace() <-chan TraceEvent {
+	return pair.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (pair *pairSocket) Stats() Stats {
+	return pair.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (pair *pairSocket) Connections() []ConnInfo {
+	return pair.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (pair *pairSocket) Endpoints() []string {
+	return pair.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (pair *pairSocket) Disconnect(ep string) error {
+	return pair.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (pair *pairSocket) Monitor(events EventType) <-chan SocketEvent {
+	return pair.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (pair *pairSocket) GetMonitorChannel() <-chan SocketEvent {
+	return pair.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (pair *pairSocket) MonitorDropped() uint64 {
+	return pair.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (pair *pairSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return pair.sck.recvOrEvent(ctx, pair.Recv)
+}
+
+func (pair *pairSocket) events() State {
+	return pair.sck.events()
+}
+
+func (pair *pairSocket) watch(fn func()) func() {
+	return pair.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (pair *pairSocket) Send(msg Msg) error {
@@ -39,16 +112,63 @@ func (pair *pairSocket) SendMulti(msg Msg) error {
 	return pair.sck.SendMulti(msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (pair *pairSocket) SendNB(msg Msg) error {
+	return pair.sck.SendNB(msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (pair *pairSocket) Flush(ctx context.Context) error {
+	return pair.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (pair *pairSocket) Recv() (Msg, error) {
 	return pair.sck.Recv()
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (pair *pairSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(pair.sck.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (pair *pairSocket) RecvNB() (Msg, error) {
+	return pair.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (pair *pairSocket) RecvMulti() (Msg, error) {
+	return recvMulti(pair.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (pair *pairSocket) SetSendDeadline(t time.Time) error {
+	return pair.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (pair *pairSocket) SetRecvDeadline(t time.Time) error {
+	return pair.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (pair *pairSocket) Listen(ep string) error {
 	return pair.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (pair *pairSocket) Unbind(ep string) error {
+	return pair.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (pair *pairSocket) Dial(ep string) error {
 	return pair.sck.Dial(ep)
@@ -65,6 +185,11 @@ func (pair *pairSocket) Addr() net.Addr {
 	return pair.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (pair *pairSocket) LastEndpoint() (string, error) {
+	return pair.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (pair *pairSocket) GetOption(name string) (interface{}, error) {
 	return pair.sck.GetOption(name)
@@ -75,6 +200,23 @@ func (pair *pairSocket) SetOption(name string, value interface{}) error {
 	return pair.sck.SetOption(name, value)
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (pair *pairSocket) Reader() SocketReader {
+	return socketReader{pair}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (pair *pairSocket) Writer() SocketWriter {
+	return socketWriter{pair}
+}
+
 var (
-	_ Socket = (*pairSocket)(nil)
+	_ Socket           = (*pairSocket)(nil)
+	_ MessageTracer    = (*pairSocket)(nil)
+	_ ConnectionLister = (*pairSocket)(nil)
+	_ EndpointManager  = (*pairSocket)(nil)
+	_ PeerReceiver     = (*pairSocket)(nil)
+	_ Monitored        = (*pairSocket)(nil)
+	_ EventReceiver    = (*pairSocket)(nil)
+	_ StatsReporter    = (*pairSocket)(nil)
 )
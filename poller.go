@@ -0,0 +1,388 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// State is a set of socket events.
+type State int
+
+const (
+	Readable State = 1 << iota // a message can be received without blocking
+	Writable                   // the socket has peers to send messages to
+)
+
+func (s State) String() string {
+	switch s {
+	case 0:
+		return "none"
+	case Readable:
+		return "readable"
+	case Writable:
+		return "writable"
+	case Readable | Writable:
+		return "readable|writable"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// pollable is implemented by sockets whose events can be polled.
+type pollable interface {
+	events() State
+
+	// watch registers fn, called once the events of the socket may have
+	// changed, until the returned function is called.
+	watch(fn func()) (unwatch func())
+}
+
+// waiter waits for the events of a set of sockets to change: the watchers
+// of the sockets interrupt its waits.
+type waiter interface {
+	// wait waits up to d, or indefinitely if d is negative, for an
+	// interrupt, and reports whether one happened.
+	wait(d time.Duration) bool
+
+	// interrupt makes the pending wait return, or the next one if none is
+	// pending. It may be called concurrently with wait.
+	interrupt()
+}
+
+// chanWaiter is a waiter whose interrupts are buffered in a channel.
+type chanWaiter chan struct{}
+
+func newChanWaiter() chanWaiter {
+	return make(chanWaiter, 1)
+}
+
+func (w chanWaiter) wait(d time.Duration) bool {
+	if d < 0 {
+		<-w
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (w chanWaiter) interrupt() {
+	select {
+	case w <- struct{}{}:
+	default:
+	}
+}
+
+// watchers are the functions called once the events of a socket may have
+// changed: a message was queued for Recv, a connection was added or
+// removed, or the socket was closed.
+type watchers struct {
+	mu   sync.Mutex
+	fns  map[int]func()
+	next int
+}
+
+// add registers fn until the returned function is called.
+func (ws *watchers) add(fn func()) func() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.fns == nil {
+		ws.fns = make(map[int]func())
+	}
+	id := ws.next
+	ws.next++
+	ws.fns[id] = fn
+	return func() {
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		delete(ws.fns, id)
+	}
+}
+
+func (ws *watchers) notify() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for _, fn := range ws.fns {
+		fn()
+	}
+}
+
+// waitSet waits for the events of a set of sockets to change: it watches the
+// sockets, whose events change as messages are queued for Recv and as
+// connections come and go.
+type waitSet struct {
+	w waiter
+
+	mu      sync.Mutex
+	watches map[Socket]func() // watched sockets, and their unwatch function
+}
+
+func newWaitSet() *waitSet {
+	return &waitSet{
+		w:       newChanWaiter(),
+		watches: make(map[Socket]func()),
+	}
+}
+
+// update watches the sockets of items, in place of the previous ones.
+// Sockets that cannot be polled are skipped.
+func (ws *waitSet) update(items []PollItem) {
+	socks := make(map[Socket]bool, len(items))
+	for _, item := range items {
+		if _, ok := item.Socket.(pollable); ok {
+			socks[item.Socket] = true
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for sock, unwatch := range ws.watches {
+		if !socks[sock] {
+			unwatch()
+			delete(ws.watches, sock)
+		}
+	}
+	for sock := range socks {
+		if _, ok := ws.watches[sock]; !ok {
+			ws.watches[sock] = sock.(pollable).watch(ws.w.interrupt)
+		}
+	}
+}
+
+// close stops watching the sockets.
+func (ws *waitSet) close() {
+	ws.update(nil)
+}
+
+// PollItem is a socket polled for a set of events.
+// In the results of a poll, Events holds the events that occurred.
+type PollItem struct {
+	Socket Socket
+	Events State
+}
+
+// Poller polls a set of sockets for events.
+type Poller struct {
+	mu    sync.Mutex
+	items []PollItem
+
+	ws   *waitSet   // waits for the events of the items to change
+	busy sync.Mutex // held by the Poll waiting with ws
+}
+
+// NewPoller returns a new, empty, poller.
+//
+// The poller watches its sockets until Close is called, or until the poller
+// is garbage collected.
+func NewPoller() *Poller {
+	p := &Poller{ws: newWaitSet()}
+	runtime.AddCleanup(p, func(ws *waitSet) { ws.close() }, p.ws)
+	return p
+}
+
+// Add registers sock, polled for events, and returns its index in the poller.
+func (p *Poller) Add(sock Socket, events State) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items = append(p.items, PollItem{Socket: sock, Events: events})
+	p.ws.update(p.items)
+	return len(p.items) - 1
+}
+
+// Remove unregisters the socket at index id.
+// The indices of the sockets registered after it are shifted down by one.
+func (p *Poller) Remove(id int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if id < 0 || id >= len(p.items) {
+		return fmt.Errorf("zmq4: invalid poller index %d", id)
+	}
+	p.items = append(p.items[:id], p.items[id+1:]...)
+	p.ws.update(p.items)
+	return nil
+}
+
+// Close releases the resources of the poller.
+// The poller must not be used afterwards.
+func (p *Poller) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items = nil
+	p.ws.close()
+	return nil
+}
+
+// Poll waits for at least one of the registered sockets to be ready for the
+// events it is polled for, and returns the ready sockets.
+// A zero timeout polls the sockets once, without blocking.
+// A negative timeout waits indefinitely.
+// Poll returns an empty slice if no socket was ready before the timeout, and
+// an error if one of the sockets is closed while waiting.
+//
+// REQ sockets are reported as Readable once the peer their last request was
+// sent to has replied.
+func (p *Poller) Poll(timeout time.Duration) ([]PollItem, error) {
+	items, n, err := p.poll(timeout)
+	if err != nil {
+		return nil, err
+	}
+	ready := make([]PollItem, 0, n)
+	for _, item := range items {
+		if item.Events != 0 {
+			ready = append(ready, item)
+		}
+	}
+	return ready, nil
+}
+
+// PollAll is like Poll but returns all the registered sockets, in
+// registration order, with the events that occurred (possibly none).
+func (p *Poller) PollAll(timeout time.Duration) ([]PollItem, error) {
+	items, _, err := p.poll(timeout)
+	return items, err
+}
+
+// poll polls the registered sockets, waiting with the wait set of the
+// poller unless another Poll is already using it.
+// The wait is interrupted once one of the sockets is closed, and poll then
+// returns the error of the context of that socket, as WaitReadable does.
+func (p *Poller) poll(timeout time.Duration) ([]PollItem, int, error) {
+	items := p.snapshot()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	for _, item := range items {
+		sctx := item.Socket.Context()
+		defer context.AfterFunc(sctx, func() { cancel(sctx.Err()) })()
+	}
+
+	var (
+		out []PollItem
+		n   int
+		err error
+	)
+	if p.busy.TryLock() {
+		out, n, err = pollWith(ctx, p.ws, items, timeout)
+		p.busy.Unlock()
+	} else {
+		out, n, err = poll(ctx, items, timeout)
+	}
+	if err != nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	return out, n, err
+}
+
+// WaitReadable waits up to timeout for sock to have a message ready to be
+// received, and reports whether it has.
+// A zero timeout checks sock once, without blocking.
+// A negative timeout waits indefinitely.
+// WaitReadable returns an error if sock is closed while waiting.
+func WaitReadable(sock Socket, timeout time.Duration) (bool, error) {
+	items := []PollItem{{Socket: sock, Events: Readable}}
+	_, n, err := poll(sock.Context(), items, timeout)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (p *Poller) snapshot() []PollItem {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PollItem(nil), p.items...)
+}
+
+// poll waits up to timeout for one of the items to be ready, or until ctx is
+// done. It returns the items with the events that occurred, and the number of
+// ready items.
+//
+// poll is meant for one-shot waits: loops polling the same sockets over and
+// over keep a wait set of their own, and call pollWith.
+func poll(ctx context.Context, items []PollItem, timeout time.Duration) ([]PollItem, int, error) {
+	if err := checkPollable(items); err != nil {
+		return nil, 0, err
+	}
+	if out, n := pollOnce(items); n > 0 || timeout == 0 {
+		return out, n, nil
+	}
+	ws := newWaitSet()
+	defer ws.close()
+	return pollWith(ctx, ws, items, timeout)
+}
+
+// pollWith is like poll, waiting with ws between two checks of the items.
+//
+// Between two checks, pollWith blocks until the events of one of the sockets
+// may have changed, the timeout expires, or ctx is done.
+func pollWith(ctx context.Context, ws *waitSet, items []PollItem, timeout time.Duration) ([]PollItem, int, error) {
+	if err := checkPollable(items); err != nil {
+		return nil, 0, err
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	stop := context.AfterFunc(ctx, ws.w.interrupt)
+	defer stop()
+
+	for {
+		// the sockets are watched before they are checked: no change may
+		// be missed in-between.
+		ws.update(items)
+		out, n := pollOnce(items)
+		if n > 0 || timeout == 0 {
+			return out, n, nil
+		}
+
+		wait := time.Duration(-1)
+		if timeout > 0 {
+			wait = time.Until(deadline)
+			if wait <= 0 {
+				return out, 0, nil
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		ws.w.wait(wait)
+	}
+}
+
+// checkPollable returns an error if one of the sockets of items cannot be
+// polled.
+func checkPollable(items []PollItem) error {
+	for _, item := range items {
+		if _, ok := item.Socket.(pollable); !ok {
+			return fmt.Errorf("zmq4: %v socket cannot be polled", item.Socket.Type())
+		}
+	}
+	return nil
+}
+
+// pollOnce checks the sockets of items, and returns the items with the
+// events that occurred, and the number of ready items.
+// The sockets must be pollable.
+func pollOnce(items []PollItem) ([]PollItem, int) {
+	out := make([]PollItem, len(items))
+	n := 0
+	for i, item := range items {
+		out[i] = PollItem{Socket: item.Socket, Events: item.Socket.(pollable).events() & item.Events}
+		if out[i].Events != 0 {
+			n++
+		}
+	}
+	return out, n
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChanWaiter(t *testing.T) {
+	w := newChanWaiter()
+	if w.wait(10 * time.Millisecond) {
+		t.Fatalf("woken up without interrupt")
+	}
+
+	// an interrupt wakes up the pending wait, or else the next one, once.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		w.interrupt()
+	}()
+	start := time.Now()
+	if !w.wait(-1) {
+		t.Fatalf("not woken up by interrupt")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("woken up too late: %v", elapsed)
+	}
+	w.interrupt()
+	w.interrupt()
+	if !w.wait(5 * time.Second) {
+		t.Fatalf("interrupt not kept for the next wait")
+	}
+	if w.wait(10 * time.Millisecond) {
+		t.Fatalf("woken up twice by the same interrupts")
+	}
+}
+
+// countingWaiter counts the waits of a waiter.
+type countingWaiter struct {
+	waiter
+	waits atomic.Int32
+}
+
+func (w *countingWaiter) wait(d time.Duration) bool {
+	w.waits.Add(1)
+	return w.waiter.wait(d)
+}
+
+func TestPollerWaitSet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep := "tcp://" + l.Addr().String()
+	l.Close()
+
+	pull := NewPull(ctx)
+	defer pull.Close()
+	push := NewPush(ctx)
+	defer push.Close()
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	p := NewPoller()
+	defer p.Close()
+	w := &countingWaiter{waiter: p.ws.w}
+	p.ws.w = w
+
+	// the sockets are watched as they are added and removed.
+	watches := func() int {
+		p.ws.mu.Lock()
+		defer p.ws.mu.Unlock()
+		return len(p.ws.watches)
+	}
+	p.Add(pull, Readable)
+	if n := watches(); n != 1 {
+		t.Fatalf("invalid number of watched sockets: got=%d, want=1", n)
+	}
+	if err := p.Remove(0); err != nil {
+		t.Fatalf("could not remove socket: %+v", err)
+	}
+	if n := watches(); n != 0 {
+		t.Fatalf("socket still watched: %d", n)
+	}
+	p.Add(pull, Readable)
+
+	// a poll without timeout blocks until the socket is ready, rather than
+	// checking it periodically.
+	const delay = 200 * time.Millisecond
+	go func() {
+		time.Sleep(delay)
+		if err := push.Dial(ep); err != nil {
+			return
+		}
+		_ = push.Send(NewMsgString("data"))
+	}()
+	start := time.Now()
+	ready, err := p.Poll(-1)
+	if err != nil {
+		t.Fatalf("could not poll: %+v", err)
+	}
+	if len(ready) != 1 || ready[0].Events != Readable {
+		t.Fatalf("invalid poll results: %v", ready)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("poll returned too early: %v", elapsed)
+	}
+	// the waits are woken up by the new connection and the message being
+	// queued: not by a periodic timeout.
+	if n := w.waits.Load(); n > 10 {
+		t.Fatalf("too many waits: %d", n)
+	}
+}
+
+func TestPollUnwatches(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	items := []PollItem{{Socket: pull, Events: Readable}}
+
+	// one-shot polls stop watching the socket once they return.
+	for range 3 {
+		if _, n, err := poll(ctx, items, 5*time.Millisecond); err != nil || n != 0 {
+			t.Fatalf("invalid poll: n=%d, err=%+v", n, err)
+		}
+	}
+	sck := pull.(*pullSocket).sck
+	sck.watchers.mu.Lock()
+	n := len(sck.watchers.fns)
+	sck.watchers.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("socket still watched by %d polls", n)
+	}
+}
@@ -165,6 +165,12 @@ const (
 	sysSockID   = "Identity"
 )
 
+// PeerCertIdentity is the name of the peer metadata property holding the
+// identity of the verified certificate the peer presented during a TLS
+// handshake.
+// This property is set locally: it is never exchanged with the peer.
+const PeerCertIdentity = "Cert-Identity"
+
 // Metadata is describing a Conn's metadata information.
 type Metadata map[string]string
 
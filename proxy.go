@@ -5,48 +5,502 @@
 package zmq4
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 )
 
+// ErrProxyWiring is returned by ProxyWithPeerCheck when a peer of the proxy
+// is not of an expected socket type.
+var ErrProxyWiring = errors.New("zmq4: invalid proxy wiring")
+
+// ProxyPeers lists the socket types the peers of each side of a proxy are
+// expected to advertise. An empty list accepts any peer compatible with the
+// socket of that side.
+type ProxyPeers struct {
+	Frontend []SocketType
+	Backend  []SocketType
+}
+
 // Proxy starts a proxy that forwards messages between frontend and backend.
-// This is the ONLY way to create a proxy - no complex variations.
-func Proxy(frontend, backend Socket) error {
+// If capture is not nil, a copy of each message forwarded in either
+// direction is also sent to capture, e.g. a PUB socket to observe the
+// traffic.
+//
+// Proxy is ProxyContext with a background context: it returns nil once
+// either socket is closed, or the error that stopped forwarding messages.
+// Sockets that cannot be polled (see Poller) are proxied until either of
+// them fails.
+func Proxy(frontend, backend, capture Socket) error {
 	if frontend == nil || backend == nil {
 		return fmt.Errorf("frontend and backend sockets are required")
 	}
+	if !canPoll(frontend, backend) {
+		return <-proxy(frontend, backend, 2, nil, capture)
+	}
+	return proxyContext(context.Background(), frontend, backend, capture, nil)
+}
 
-	errChan := make(chan error, 2)
+// Direction is the direction a message travels through a proxy.
+type Direction int
 
-	// Frontend to backend
-	go func() {
-		for {
-			msg, err := frontend.Recv()
+const (
+	FrontendToBackend Direction = iota // message received from the frontend
+	BackendToFrontend                  // message received from the backend
+)
+
+func (dir Direction) String() string {
+	switch dir {
+	case FrontendToBackend:
+		return "frontend->backend"
+	case BackendToFrontend:
+		return "backend->frontend"
+	default:
+		return fmt.Sprintf("Direction(%d)", int(dir))
+	}
+}
+
+// ProxyTransform is like Proxy, but passes each message through fn before
+// forwarding it: fn returns the message to forward, e.g. with an added
+// header frame or re-encoded, or false to drop it.
+// fn is called concurrently for the two directions.
+//
+// ProxyTransform is ProxyTransformContext with a background context.
+// Sockets that cannot be polled (see Poller) are proxied until either of
+// them fails.
+func ProxyTransform(frontend, backend Socket, fn func(dir Direction, msg Msg) (Msg, bool)) error {
+	if frontend == nil || backend == nil {
+		return fmt.Errorf("frontend and backend sockets are required")
+	}
+	if fn == nil {
+		return fmt.Errorf("zmq4: proxy transform function is required")
+	}
+	if !canPoll(frontend, backend) {
+		return <-proxy(frontend, backend, 2, fn, nil)
+	}
+	return proxyContext(context.Background(), frontend, backend, nil, fn)
+}
+
+// ProxyTransformContext is like ProxyContext, but passes each message
+// through fn before forwarding it, as ProxyTransform does.
+// Both directions stop together: ProxyTransformContext returns once neither
+// of them forwards messages anymore.
+func ProxyTransformContext(ctx context.Context, frontend, backend Socket, fn func(dir Direction, msg Msg) (Msg, bool)) error {
+	if frontend == nil || backend == nil {
+		return fmt.Errorf("frontend and backend sockets are required")
+	}
+	if fn == nil {
+		return fmt.Errorf("zmq4: proxy transform function is required")
+	}
+	for _, sck := range []Socket{frontend, backend} {
+		if _, ok := sck.(pollable); !ok {
+			return fmt.Errorf("zmq4: proxy %v socket cannot be polled", sck.Type())
+		}
+	}
+	return proxyContext(ctx, frontend, backend, nil, fn)
+}
+
+// ProxyContext forwards messages between frontend and backend until ctx is
+// done, and then returns ctx.Err(), or until either socket is closed, and
+// then returns nil.
+// The sockets must be pollable (see Poller).
+//
+// ProxyContext returns once the message being forwarded, if any, has been
+// sent, and its goroutines have exited.
+func ProxyContext(ctx context.Context, frontend, backend Socket) error {
+	if frontend == nil || backend == nil {
+		return fmt.Errorf("frontend and backend sockets are required")
+	}
+	for _, sck := range []Socket{frontend, backend} {
+		if _, ok := sck.(pollable); !ok {
+			return fmt.Errorf("zmq4: proxy %v socket cannot be polled", sck.Type())
+		}
+	}
+	return proxyContext(ctx, frontend, backend, nil, nil)
+}
+
+// canPoll returns whether all the sockets are pollable.
+func canPoll(scks ...Socket) bool {
+	for _, sck := range scks {
+		if _, ok := sck.(pollable); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// proxyContext implements ProxyContext, passing the messages through
+// transform, if not nil, and copying the forwarded messages to capture, if
+// not nil.
+func proxyContext(ctx context.Context, frontend, backend, capture Socket, transform func(Direction, Msg) (Msg, bool)) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(frontend.Context(), cancel)()
+	defer context.AfterFunc(backend.Context(), cancel)()
+
+	errc := make(chan error, 2)
+	go func() { errc <- forward(ctx, FrontendToBackend, frontend, backend, transform, capture) }()
+	go func() { errc <- forward(ctx, BackendToFrontend, backend, frontend, transform, capture) }()
+
+	err := <-errc
+	cancel()
+	if err2 := <-errc; err == nil {
+		err = err2
+	}
+	if err == nil && !anyClosed(frontend, backend) {
+		err = parent.Err()
+	}
+	return err
+}
+
+// anyClosed returns whether one of the sockets is closed.
+func anyClosed(scks ...Socket) bool {
+	for _, sck := range scks {
+		if sck.Context().Err() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Commands accepted by the control socket of ProxySteerable.
+const (
+	ProxyPause     = "PAUSE"     // stop forwarding messages
+	ProxyResume    = "RESUME"    // resume forwarding messages
+	ProxyTerminate = "TERMINATE" // stop the proxy
+)
+
+// ProxySteerable is like Proxy, but is steered by the single-frame commands
+// received on control:
+//   - ProxyPause stops forwarding messages, which stay queued in the
+//     sockets until forwarding resumes,
+//   - ProxyResume resumes forwarding messages,
+//   - ProxyTerminate stops the proxy: ProxySteerable then returns nil.
+//
+// Other messages received on control are ignored.
+// capture may be nil. The frontend, backend and control sockets must be
+// pollable (see Poller).
+// ProxySteerable also returns nil once one of the sockets is closed.
+func ProxySteerable(frontend, backend, capture, control Socket) error {
+	if frontend == nil || backend == nil {
+		return fmt.Errorf("frontend and backend sockets are required")
+	}
+	if control == nil {
+		return fmt.Errorf("zmq4: proxy control socket is required")
+	}
+	for _, sck := range []Socket{frontend, backend, control} {
+		if _, ok := sck.(pollable); !ok {
+			return fmt.Errorf("zmq4: proxy %v socket cannot be polled", sck.Type())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer context.AfterFunc(frontend.Context(), cancel)()
+	defer context.AfterFunc(backend.Context(), cancel)()
+	defer context.AfterFunc(control.Context(), cancel)()
+
+	var (
+		paused bool
+		sides  = [...]struct{ src, dst Socket }{{frontend, backend}, {backend, frontend}}
+		ws     = newWaitSet()
+	)
+	defer ws.close()
+	for {
+		items := []PollItem{{Socket: control, Events: Readable}}
+		if !paused {
+			for _, side := range sides {
+				items = append(items, PollItem{Socket: side.src, Events: Readable})
+			}
+		}
+		ready, _, err := pollWith(ctx, ws, items, -1)
+		if err != nil {
+			return nil
+		}
+
+		if ready[0].Events != 0 {
+			msg, err := control.Recv()
 			if err != nil {
-				errChan <- err
-				return
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("zmq4: could not receive proxy command: %w", err)
 			}
-			if err := backend.Send(msg); err != nil {
-				errChan <- err
-				return
+			if len(msg.Frames) == 1 {
+				switch string(msg.Frames[0]) {
+				case ProxyPause:
+					paused = true
+				case ProxyResume:
+					paused = false
+				case ProxyTerminate:
+					return nil
+				}
 			}
+			continue
 		}
-	}()
 
-	// Backend to frontend
-	go func() {
-		for {
-			msg, err := backend.Recv()
+		for i, item := range ready[1:] {
+			if item.Events == 0 {
+				continue
+			}
+			msg, err := sides[i].src.Recv()
+			if err == nil {
+				err = sendCaptured(sides[i].dst, capture, msg)
+			}
 			if err != nil {
-				errChan <- err
-				return
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
 			}
-			if err := frontend.Send(msg); err != nil {
-				errChan <- err
-				return
+		}
+	}
+}
+
+// forward forwards the messages received from src to dst until ctx is done,
+// or either socket is closed. Messages are passed through transform, if not
+// nil, and copied to capture, if not nil.
+func forward(ctx context.Context, dir Direction, src, dst Socket, transform func(Direction, Msg) (Msg, bool), capture Socket) error {
+	stopped := func() bool {
+		return ctx.Err() != nil || src.Context().Err() != nil || dst.Context().Err() != nil
+	}
+	items := []PollItem{{Socket: src, Events: Readable}}
+	ws := newWaitSet()
+	defer ws.close()
+	for {
+		if _, _, err := pollWith(ctx, ws, items, -1); err != nil {
+			return nil
+		}
+		msg, err := src.Recv()
+		if err != nil {
+			if stopped() {
+				return nil
+			}
+			return err
+		}
+		if transform != nil {
+			var ok bool
+			msg, ok = transform(dir, msg)
+			if !ok {
+				continue
 			}
 		}
+		if err := sendCaptured(dst, capture, msg); err != nil {
+			if stopped() {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// ProxyHandle controls a proxy started with StartProxy.
+type ProxyHandle struct {
+	front, back  Socket
+	closeSockets bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error // error of ProxyContext, valid once done is closed
+
+	once     sync.Once
+	closeErr error
+}
+
+// StartProxy runs ProxyContext(ctx, frontend, backend) in the background,
+// and returns a handle to stop it.
+// If closeSockets is true, closing the handle also closes both sockets.
+func StartProxy(ctx context.Context, frontend, backend Socket, closeSockets bool) *ProxyHandle {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &ProxyHandle{
+		front:        frontend,
+		back:         backend,
+		closeSockets: closeSockets,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+	}
+	go func() {
+		defer close(h.done)
+		err := ProxyContext(ctx, frontend, backend)
+		if err != ctx.Err() {
+			h.err = err
+		}
 	}()
+	return h
+}
+
+// Done returns a channel that is closed once the proxy has stopped.
+func (h *ProxyHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait waits for the proxy to stop, and returns the error that stopped it,
+// or nil if it was stopped by Close, its context, or the closing of one of
+// its sockets.
+func (h *ProxyHandle) Wait() error {
+	<-h.done
+	return h.err
+}
 
-	// Wait for first error
-	return <-errChan
+// Close stops the proxy and waits for it to return.
+// If the handle was created with closeSockets, Close then closes the
+// frontend, so no new message enters the proxy, and then the backend.
+// Close returns the errors of closing the sockets; it can be called multiple
+// times.
+func (h *ProxyHandle) Close() error {
+	h.once.Do(func() {
+		h.cancel()
+		<-h.done
+		if !h.closeSockets {
+			return
+		}
+		var errs []error
+		if h.front != nil {
+			if err := h.front.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("zmq4: could not close proxy frontend: %w", err))
+			}
+		}
+		if h.back != nil {
+			if err := h.back.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("zmq4: could not close proxy backend: %w", err))
+			}
+		}
+		h.closeErr = errors.Join(errs...)
+	})
+	return h.closeErr
+}
+
+// ProxyWithPeerCheck is like Proxy, but also checks the Socket-Type
+// advertised by the peers of the frontend and backend sockets as they
+// connect. It returns an error wrapping ErrProxyWiring, naming the offending
+// peer, as soon as one of them is not of an expected type, once the proxy
+// has stopped forwarding messages. It returns nil once either socket is
+// closed.
+//
+// Both sockets must be pollable (see Poller) and implement ConnectionLister.
+func ProxyWithPeerCheck(frontend, backend Socket, peers ProxyPeers) error {
+	if frontend == nil || backend == nil {
+		return fmt.Errorf("frontend and backend sockets are required")
+	}
+	front, ok := frontend.(ConnectionLister)
+	if !ok {
+		return fmt.Errorf("zmq4: proxy frontend %T does not list its connections", frontend)
+	}
+	back, ok := backend.(ConnectionLister)
+	if !ok {
+		return fmt.Errorf("zmq4: proxy backend %T does not list its connections", backend)
+	}
+	for _, sck := range []Socket{frontend, backend} {
+		if _, ok := sck.(pollable); !ok {
+			return fmt.Errorf("zmq4: proxy %v socket cannot be polled", sck.Type())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- proxyContext(ctx, frontend, backend, nil, nil) }()
+
+	// the peers are checked again whenever the connections of either
+	// socket may have changed.
+	changed := newChanWaiter()
+	for _, sck := range []Socket{frontend, backend} {
+		defer sck.(pollable).watch(changed.interrupt)()
+	}
+	for {
+		err := checkPeers("frontend", front, peers.Frontend)
+		if err == nil {
+			err = checkPeers("backend", back, peers.Backend)
+		}
+		if err != nil {
+			// stop forwarding between the misconfigured peers, and wait
+			// for the relays to exit.
+			cancel()
+			<-errc
+			return err
+		}
+		select {
+		case err := <-errc:
+			return err
+		case <-changed:
+		}
+	}
+}
+
+// checkPeers checks the type of the peers of one side of a proxy.
+func checkPeers(side string, sck ConnectionLister, want []SocketType) error {
+	if len(want) == 0 {
+		return nil
+	}
+	for _, conn := range sck.Connections() {
+		if !hasSocketType(want, conn.PeerType) {
+			return fmt.Errorf(
+				"%w: %s peer %s is a %s socket, want one of %v",
+				ErrProxyWiring, side, conn.Remote, conn.PeerType, want,
+			)
+		}
+	}
+	return nil
+}
+
+func hasSocketType(types []SocketType, typ SocketType) bool {
+	for _, v := range types {
+		if v == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// proxy forwards messages between frontend and backend, and reports the
+// forwarding errors on the returned channel, of capacity n.
+// Messages are passed through transform, if not nil, and copied to
+// capture, if not nil.
+func proxy(frontend, backend Socket, n int, transform func(Direction, Msg) (Msg, bool), capture Socket) chan error {
+	errChan := make(chan error, n)
+	go relay(FrontendToBackend, frontend, backend, transform, capture, errChan)
+	go relay(BackendToFrontend, backend, frontend, transform, capture, errChan)
+	return errChan
+}
+
+// relay forwards the messages received from src to dst, until it fails.
+func relay(dir Direction, src, dst Socket, transform func(Direction, Msg) (Msg, bool), capture Socket, errChan chan<- error) {
+	for {
+		msg, err := src.Recv()
+		if err != nil {
+			errChan <- err
+			return
+		}
+		if transform != nil {
+			var ok bool
+			msg, ok = transform(dir, msg)
+			if !ok {
+				continue
+			}
+		}
+		if err := sendCaptured(dst, capture, msg); err != nil {
+			errChan <- err
+			return
+		}
+	}
+}
+
+// sendCaptured sends msg to dst and, if capture is not nil, a copy of msg to
+// capture.
+func sendCaptured(dst, capture Socket, msg Msg) error {
+	var cp Msg
+	if capture != nil {
+		cp = msg.Clone()
+	}
+	if err := dst.Send(msg); err != nil {
+		return err
+	}
+	if capture != nil {
+		if err := capture.Send(cp); err != nil {
+			return fmt.Errorf("zmq4: could not send to proxy capture: %w", err)
+		}
+	}
+	return nil
 }
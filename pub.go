@@ -6,10 +6,11 @@ package zmq4
 
 import (
 	"context"
-	"fmt"
 	"net"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const (
@@ -22,12 +23,29 @@ type Topics interface {
 	Topics() []string
 }
 
+// TopicMsg is a message published on a topic.
+type TopicMsg struct {
+	Topic   []byte
+	Payload []byte
+}
+
+// BatchSender is an interface that wraps the SendBatch method.
+type BatchSender interface {
+	// SendBatch puts a batch of messages on the outbound send queue.
+	// Each message is sent as a two-frame message: its topic then its
+	// payload.
+	// The messages of a batch are written to each peer in a single
+	// coalesced write, and count as one message against the high water mark.
+	SendBatch(msgs []TopicMsg) error
+}
+
 // NewPub returns a new PUB ZeroMQ socket.
 // The returned socket value is initially unbound.
 func NewPub(ctx context.Context, opts ...Option) Socket {
 	pub := &pubSocket{sck: newSocket(ctx, Pub, opts...)}
-	pub.sck.w = newPubMWriter(pub.sck.ctx)
-	pub.sck.r = newPubQReader(pub.sck.ctx)
+	pub.sck.self = pub
+	pub.sck.w = newPubMWriter(pub.sck.ctx, pub.sck.sndHWM)
+	pub.sck.r = newPubQReader(pub.sck.ctx, pub.sck.recv.qsize)
 	return pub
 }
 
@@ -41,10 +59,84 @@ func (pub *pubSocket) Close() error {
 	return pub.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (pub *pubSocket) Context() context.Context {
+	return pub.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (pub *pubSocket) Done() <-chan struct{} {
+	return pub.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (pub *pubSocket) MessageTrace() <-chan TraceEvent {
+	return pub.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (pub *pubSocket) Stats() Stats {
+	return pub.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (pub *pubSocket) Connections() []ConnInfo {
+	return pub.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (pub *pubSocket) Endpoints() []string {
+	return pub.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (pub *pubSocket) Disconnect(ep string) error {
+	return pub.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (pub *pubSocket) Monitor(events EventType) <-chan SocketEvent {
+	return pub.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (pub *pubSocket) GetMonitorChannel() <-chan SocketEvent {
+	return pub.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (pub *pubSocket) MonitorDropped() uint64 {
+	return pub.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (pub *pubSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return pub.sck.recvOrEvent(ctx, pub.Recv)
+}
+
+func (pub *pubSocket) events() State {
+	return pub.sck.events()
+}
+
+func (pub *pubSocket) watch(fn func()) func() {
+	return pub.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (pub *pubSocket) Send(msg Msg) error {
-	ctx, cancel := context.WithTimeout(pub.sck.ctx, pub.sck.Timeout())
+	if err := pub.sck.checkFrames(msg); err != nil {
+		return err
+	}
+	ctx, cancel := pub.sck.sendContext()
 	defer cancel()
 	return pub.sck.w.write(ctx, msg)
 }
@@ -53,23 +145,79 @@ func (pub *pubSocket) Send(msg Msg) error {
 // SendMulti blocks until the message can be queued or the send deadline expires.
 // The message will be sent as a multipart message.
 func (pub *pubSocket) SendMulti(msg Msg) error {
+	if err := pub.sck.checkFrames(msg); err != nil {
+		return err
+	}
 	msg.multipart = true
-	ctx, cancel := context.WithTimeout(pub.sck.ctx, pub.sck.Timeout())
+	ctx, cancel := pub.sck.sendContext()
 	defer cancel()
 	return pub.sck.w.write(ctx, msg)
 }
 
+// SendNB puts the message on the outbound send queue.
+// Like Send, SendNB never blocks: it drops the message for the subscribers
+// whose queue is full.
+func (pub *pubSocket) SendNB(msg Msg) error {
+	if err := pub.sck.checkFrames(msg); err != nil {
+		return err
+	}
+	return pub.sck.trySend(msg)
+}
+
+// SendBatch puts a batch of messages on the outbound send queue.
+// SendBatch blocks until the batch can be queued or the send deadline expires.
+func (pub *pubSocket) SendBatch(msgs []TopicMsg) error {
+	ctx, cancel := pub.sck.sendContext()
+	defer cancel()
+	return pub.sck.w.(*pubMWriter).writeBatch(ctx, msgs)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (pub *pubSocket) Flush(ctx context.Context) error {
+	return pub.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (*pubSocket) Recv() (Msg, error) {
-	msg := Msg{err: fmt.Errorf("zmq4: PUB sockets can't recv messages")}
+	msg := Msg{err: errInvalidOp(Pub, "receive")}
+	return msg, msg.err
+}
+
+// RecvNB is an invalid operation for a PUB socket.
+func (*pubSocket) RecvNB() (Msg, error) {
+	msg := Msg{err: errInvalidOp(Pub, "receive")}
 	return msg, msg.err
 }
 
+// RecvMulti receives a complete multipart message, atomically.
+func (pub *pubSocket) RecvMulti() (Msg, error) {
+	return recvMulti(pub.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (pub *pubSocket) SetSendDeadline(t time.Time) error {
+	return pub.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (pub *pubSocket) SetRecvDeadline(t time.Time) error {
+	return pub.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (pub *pubSocket) Listen(ep string) error {
 	return pub.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (pub *pubSocket) Unbind(ep string) error {
+	return pub.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (pub *pubSocket) Dial(ep string) error {
 	return pub.sck.Dial(ep)
@@ -86,6 +234,11 @@ func (pub *pubSocket) Addr() net.Addr {
 	return pub.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (pub *pubSocket) LastEndpoint() (string, error) {
+	return pub.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (pub *pubSocket) GetOption(name string) (interface{}, error) {
 	return pub.sck.GetOption(name)
@@ -93,23 +246,17 @@ func (pub *pubSocket) GetOption(name string) (interface{}, error) {
 
 // SetOption is used to set an option for a socket.
 func (pub *pubSocket) SetOption(name string, value interface{}) error {
-	err := pub.sck.SetOption(name, value)
-	if err != nil {
-		return err
-	}
-
-	if name != OptionHWM {
-		return ErrBadProperty
-	}
+	return pub.sck.SetOption(name, value)
+}
 
-	hwm, ok := value.(int)
-	if !ok {
-		return ErrBadProperty
-	}
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (pub *pubSocket) Reader() SocketReader {
+	return socketReader{pub}
+}
 
-	w := pub.sck.w.(*pubMWriter)
-	w.hwm.Store(int64(hwm))
-	return nil
+// Writer returns a handle restricted to the sending methods of the socket.
+func (pub *pubSocket) Writer() SocketWriter {
+	return socketWriter{pub}
 }
 
 // Topics returns the sorted list of topics a socket is subscribed to.
@@ -126,14 +273,27 @@ type pubQReader struct {
 	c  chan Msg
 
 	sem *semaphore // ready when a connection is live.
+
+	// forward is whether subscription messages are also handed off to
+	// Recv, as XPUB does, once applied to their connection.
+	forward bool
+	// subs counts the subscribers of each topic when forwarding: only the
+	// first subscription and the last unsubscription of a topic are
+	// forwarded, unless verbose.
+	subs    map[string]int
+	verbose bool  // whether all the subscription messages are forwarded, see OptionXPubVerbose
+	manual  bool  // whether the subscriptions are applied by the application, see OptionXPubManual
+	last    *Conn // connection of the last subscription message received, in manual mode
+
+	w *pubMWriter // writer welcoming the new subscribers, see OptionXPubWelcomeMsg
 }
 
-func newPubQReader(ctx context.Context) *pubQReader {
-	const qrsize = 10
+func newPubQReader(ctx context.Context, qsize int) *pubQReader {
 	return &pubQReader{
-		ctx: ctx,
-		c:   make(chan Msg, qrsize),
-		sem: newSemaphore(),
+		ctx:  ctx,
+		c:    make(chan Msg, qsize),
+		sem:  newSemaphore(),
+		subs: make(map[string]int),
 	}
 }
 
@@ -173,13 +333,26 @@ func (q *pubQReader) rmConn(r *Conn) {
 	if cur >= 0 {
 		q.rs = append(q.rs[:cur], q.rs[cur+1:]...)
 	}
+	if q.last == r {
+		q.last = nil
+	}
+}
+
+func (q *pubQReader) queued() int {
+	return len(q.c)
 }
 
 func (q *pubQReader) read(ctx context.Context, msg *Msg) error {
 	q.sem.lock(ctx)
-	select {
-	case <-ctx.Done():
-	case *msg = <-q.c:
+	if err := recvMsg(ctx, q.c, msg); err != nil {
+		return err
+	}
+	if msg.conn != nil && q.forward && q.topic(*msg) {
+		q.mu.Lock()
+		if q.manual {
+			q.last = msg.conn
+		}
+		q.mu.Unlock()
 	}
 	return msg.err
 }
@@ -187,6 +360,9 @@ func (q *pubQReader) read(ctx context.Context, msg *Msg) error {
 func (q *pubQReader) listen(ctx context.Context, r *Conn) {
 	defer q.rmConn(r)
 	defer r.Close()
+	if q.forward {
+		defer q.unsubscribeAll(ctx, r)
+	}
 
 	for {
 		msg := r.read()
@@ -199,40 +375,179 @@ func (q *pubQReader) listen(ctx context.Context, r *Conn) {
 			}
 			switch {
 			case q.topic(msg):
-				r.subscribe(msg)
+				if !q.apply(r, msg) {
+					msg.Release()
+					break
+				}
+				// the subscription applies even if the application does
+				// not keep up with forwarding it.
+				select {
+				case q.c <- msg:
+					r.queued()
+				default:
+					msg.Release()
+				}
 			default:
-				q.c <- msg
+				select {
+				case q.c <- msg:
+					r.queued()
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}
 }
 
-func (q *pubQReader) topic(msg Msg) bool {
-	if len(msg.Frames) != 1 {
+// apply applies the subscription message msg received from r, unless in
+// manual mode, and returns whether it is handed off to Recv.
+func (q *pubQReader) apply(r *Conn, msg Msg) bool {
+	if !q.forward {
+		r.subscribe(msg)
 		return false
 	}
-	frame := msg.Frames[0]
-	if len(frame) == 0 {
+	if subscribe, _, _ := ParseSubscription(msg.Frames[0]); subscribe && q.w != nil {
+		// the welcome message is queued before the subscription applies.
+		q.w.sendWelcome(r)
+	}
+	q.mu.RLock()
+	manual := q.manual
+	q.mu.RUnlock()
+	if manual {
+		return true
+	}
+	return q.forwarded(msg, r.subscribe(msg))
+}
+
+// forwarded counts the subscription message msg, that changed the
+// subscriptions of its connection or not, and returns whether it is handed
+// off to Recv.
+func (q *pubQReader) forwarded(msg Msg, changed bool) bool {
+	subscribe, topic, _ := ParseSubscription(msg.Frames[0])
+	k := string(topic)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := q.subs[k]
+	if changed {
+		if subscribe {
+			n++
+		} else {
+			n--
+		}
+		if n > 0 {
+			q.subs[k] = n
+		} else {
+			delete(q.subs, k)
+		}
+	}
+	if q.verbose {
+		return true
+	}
+	return changed && (subscribe && n == 1 || !subscribe && n == 0)
+}
+
+// unsubscribeAll drops the subscriptions of the disconnected connection r,
+// and forwards the unsubscription of the topics no other connection is
+// subscribed to.
+func (q *pubQReader) unsubscribeAll(ctx context.Context, r *Conn) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.mu.Lock()
+	topics := make([]string, 0, len(r.topics))
+	for k := range r.topics {
+		topics = append(topics, k)
+	}
+	r.mu.Unlock()
+	sort.Strings(topics)
+
+	for _, k := range topics {
+		q.mu.Lock()
+		n := q.subs[k] - 1
+		if n > 0 {
+			q.subs[k] = n
+		} else {
+			delete(q.subs, k)
+		}
+		q.mu.Unlock()
+		if n > 0 {
+			continue
+		}
+		select {
+		case q.c <- NewMsg(UnsubscribeFrame([]byte(k))):
+		default:
+		}
+	}
+}
+
+// setManual sets whether the subscriptions are applied by the application.
+func (q *pubQReader) setManual(v bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.manual = v
+	q.last = nil
+}
+
+// subscribeLast applies the subscription message msg to the connection of
+// the last subscription message received, if any.
+func (q *pubQReader) subscribeLast(msg Msg) {
+	q.mu.RLock()
+	r := q.last
+	q.mu.RUnlock()
+	if r == nil {
+		return
+	}
+	q.forwarded(msg, r.subscribe(msg))
+}
+
+// setVerbose sets whether all the subscription messages are forwarded.
+func (q *pubQReader) setVerbose(v bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.verbose = v
+}
+
+func (q *pubQReader) topic(msg Msg) bool {
+	if len(msg.Frames) != 1 {
 		return false
 	}
-	topic := frame[0]
-	return topic == 0 || topic == 1
+	_, _, ok := ParseSubscription(msg.Frames[0])
+	return ok
+}
+
+// pubItem is a message, or a batch of messages, queued for a subscriber.
+type pubItem struct {
+	msg     Msg
+	batch   []Msg
+	welcome bool // whether msg is the welcome message, sent unfiltered
 }
 
 type pubMWriter struct {
 	ctx         context.Context
 	mu          sync.RWMutex
-	subscribers map[*Conn]chan Msg
+	subscribers map[*Conn]chan pubItem
+	out         *outbox
+
+	welcome  []byte             // welcome message of the subscribers, see OptionXPubWelcomeMsg
+	welcomed map[*Conn]struct{} // subscribers the welcome message was queued for
+	groups   bool               // whether messages are matched by group rather than topic, as RADIO does
 
 	hwm atomic.Int64
 }
 
-func newPubMWriter(ctx context.Context) *pubMWriter {
+// newPubMWriter returns a pubMWriter queuing up to hwm messages per
+// subscriber, or DefaultSendHwm if hwm is 0.
+func newPubMWriter(ctx context.Context, hwm int) *pubMWriter {
 	p := &pubMWriter{
 		ctx:         ctx,
-		subscribers: map[*Conn]chan Msg{},
+		subscribers: map[*Conn]chan pubItem{},
+		out:         newOutbox(),
+		welcomed:    map[*Conn]struct{}{},
 	}
-	p.hwm.Store(DefaultSendHwm)
+	if hwm <= 0 {
+		hwm = DefaultSendHwm
+	}
+	p.hwm.Store(int64(hwm))
 	return p
 }
 
@@ -252,18 +567,46 @@ func (mw *pubMWriter) addConn(w *Conn) {
 	mw.mu.Lock()
 	defer mw.mu.Unlock()
 
-	c := make(chan Msg, mw.hwm.Load())
+	if mw.subscribers == nil {
+		// writer already closed.
+		_ = w.Close()
+		return
+	}
+
+	c := make(chan pubItem, mw.hwm.Load())
 	mw.subscribers[w] = c
 	go func() {
+		var batch []Msg
 		for {
-			msg, ok := <-c
+			item, ok := <-c
 			if !ok {
 				break
 			}
-			topic := string(msg.Frames[0])
-			if w.subscribed(topic) {
+			msg := item.msg
+			switch {
+			case item.welcome:
 				_ = w.SendMsg(msg)
+			case item.batch != nil:
+				batch = batch[:0]
+				for _, msg := range item.batch {
+					if w.subscribed(string(msg.Frames[0])) {
+						batch = append(batch, msg)
+					}
+				}
+				if len(batch) > 0 {
+					_ = w.sendBatch(batch)
+				}
+			case mw.groups:
+				if w.joined(msg.Group) {
+					_ = w.sendGroup(msg)
+				}
+			default:
+				topic := string(msg.Frames[0])
+				if w.subscribed(topic) {
+					_ = w.SendMsg(msg)
+				}
 			}
+			mw.out.done()
 		}
 	}()
 }
@@ -277,26 +620,109 @@ func (mw *pubMWriter) rmConn(w *Conn) {
 		delete(mw.subscribers, w)
 		close(channel)
 	}
+	delete(mw.welcomed, w)
+}
+
+// setWelcome sets the welcome message of the subscribers. An empty message
+// disables it.
+func (mw *pubMWriter) setWelcome(msg []byte) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	mw.welcome = msg
+}
+
+// sendWelcome queues the welcome message for the subscriber w, once.
+func (mw *pubMWriter) sendWelcome(w *Conn) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	c, ok := mw.subscribers[w]
+	if !ok || len(mw.welcome) == 0 {
+		return
+	}
+	if _, ok := mw.welcomed[w]; ok {
+		return
+	}
+	mw.welcomed[w] = struct{}{}
+	mw.out.add()
+	select {
+	case c <- pubItem{msg: NewMsg(mw.welcome), welcome: true}:
+	default: // the channel is full: the welcome message is discarded
+		mw.out.done()
+	}
 }
 
 func (w *pubMWriter) write(ctx context.Context, msg Msg) error {
+	return w.enqueue(ctx, pubItem{msg: msg})
+}
+
+func (w *pubMWriter) writeBatch(ctx context.Context, msgs []TopicMsg) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	batch := make([]Msg, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = NewMsgFrom(msg.Topic, msg.Payload)
+	}
+	return w.enqueue(ctx, pubItem{batch: batch})
+}
+
+func (w *pubMWriter) enqueue(ctx context.Context, item pubItem) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
 	for _, channel := range w.subscribers {
+		w.out.add()
 		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case channel <- msg: // proceeds to default case if the channel is full (msg will be discarded)
-		default:
+		case channel <- item:
+		default: // the channel is full: the item is discarded
+			w.out.done()
 		}
 	}
 	return nil
 }
 
+func (w *pubMWriter) sendHWM() int {
+	return int(w.hwm.Load())
+}
+
+func (w *pubMWriter) setSendHWM(n int) {
+	w.hwm.Store(int64(n))
+}
+
+func (w *pubMWriter) flush(ctx context.Context) error {
+	return w.out.wait(ctx)
+}
+
+// queued returns the number of messages waiting to be written to the
+// subscribers.
+func (w *pubMWriter) queued() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	n := 0
+	for _, c := range w.subscribers {
+		n += len(c)
+	}
+	return n
+}
+
 var (
-	_ rpool  = (*pubQReader)(nil)
-	_ wpool  = (*pubMWriter)(nil)
-	_ Socket = (*pubSocket)(nil)
-	_ Topics = (*pubSocket)(nil)
+	_ rpool            = (*pubQReader)(nil)
+	_ wpool            = (*pubMWriter)(nil)
+	_ flusher          = (*pubMWriter)(nil)
+	_ queuer           = (*pubMWriter)(nil)
+	_ hwmWriter        = (*pubMWriter)(nil)
+	_ Socket           = (*pubSocket)(nil)
+	_ MessageTracer    = (*pubSocket)(nil)
+	_ ConnectionLister = (*pubSocket)(nil)
+	_ EndpointManager  = (*pubSocket)(nil)
+	_ Monitored        = (*pubSocket)(nil)
+	_ EventReceiver    = (*pubSocket)(nil)
+	_ StatsReporter    = (*pubSocket)(nil)
+	_ Topics           = (*pubSocket)(nil)
+	_ BatchSender      = (*pubSocket)(nil)
 )
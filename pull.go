@@ -6,14 +6,15 @@ package zmq4
 
 import (
 	"context"
-	"fmt"
 	"net"
+	"time"
 )
 
 // NewPull returns a new PULL ZeroMQ socket.
 // The returned socket value is initially unbound.
 func NewPull(ctx context.Context, opts ...Option) Socket {
 	pull := &pullSocket{newSocket(ctx, Pull, opts...)}
+	pull.sck.self = pull
 	pull.sck.w = nil
 	return pull
 }
@@ -28,22 +29,151 @@ func (pull *pullSocket) Close() error {
 	return pull.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (pull *pullSocket) Context() context.Context {
+	return pull.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (pull *pullSocket) Done() <-chan struct{} {
+	return pull.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (pull *pullSocket) MessageTrace() <-chan TraceEvent {
+	return pull.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (pull *pullSocket) Stats() Stats {
+	return pull.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (pull *pullSocket) Connections() []ConnInfo {
+	return pull.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (pull *pullSocket) Endpoints() []string {
+	return pull.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (pull *pullSocket) Disconnect(ep string) error {
+	return pull.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (pull *pullSocket) Monitor(events EventType) <-chan SocketEvent {
+	return pull.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (pull *pullSocket) GetMonitorChannel() <-chan SocketEvent {
+	return pull.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (pull *pullSocket) MonitorDropped() uint64 {
+	return pull.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (pull *pullSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return pull.sck.recvOrEvent(ctx, pull.Recv)
+}
+
+func (pull *pullSocket) events() State {
+	return pull.sck.events()
+}
+
+func (pull *pullSocket) watch(fn func()) func() {
+	return pull.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (*pullSocket) Send(msg Msg) error {
-	return fmt.Errorf("zmq4: PULL sockets can't send messages")
+	return errInvalidOp(Pull, "send")
 }
 
 // SendMulti puts the message on the outbound send queue.
 // SendMulti blocks until the message can be queued or the send deadline expires.
 // The message will be sent as a multipart message.
 func (pull *pullSocket) SendMulti(msg Msg) error {
-	return fmt.Errorf("zmq4: PULL sockets can't send messages")
+	return errInvalidOp(Pull, "send")
+}
+
+// SendNB is an invalid operation for a PULL socket.
+func (*pullSocket) SendNB(msg Msg) error {
+	return errInvalidOp(Pull, "send")
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (pull *pullSocket) Flush(ctx context.Context) error {
+	return pull.sck.Flush(ctx)
 }
 
 // Recv receives a complete message.
 func (pull *pullSocket) Recv() (Msg, error) {
-	return pull.sck.Recv()
+	return withoutConn(pull.recv(pull.sck.recvConn))
+}
+
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (pull *pullSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(func() (Msg, error) {
+		return pull.recv(pull.sck.recvConn)
+	})
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (pull *pullSocket) RecvNB() (Msg, error) {
+	return withoutConn(pull.recv(pull.sck.recvConnNB))
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (pull *pullSocket) RecvMulti() (Msg, error) {
+	return recvMulti(pull.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (pull *pullSocket) SetSendDeadline(t time.Time) error {
+	return pull.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (pull *pullSocket) SetRecvDeadline(t time.Time) error {
+	return pull.sck.SetRecvDeadline(t)
+}
+
+// recv receives a message with recv and, in acknowledgement mode, strips
+// the sequence number frame Ack sends back to the peer.
+func (pull *pullSocket) recv(recv func() (Msg, error)) (Msg, error) {
+	msg, err := recv()
+	if err != nil || pull.sck.ackTimeout <= 0 {
+		return msg, err
+	}
+	return ackMsg(msg)
+}
+
+// Ack acknowledges the processing of msg, received from the socket created
+// with WithPullAck: its PUSH peer then stops redelivering it.
+func (pull *pullSocket) Ack(msg Msg) error {
+	return ack(msg)
 }
 
 // Listen connects a local endpoint to the Socket.
@@ -51,6 +181,12 @@ func (pull *pullSocket) Listen(ep string) error {
 	return pull.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (pull *pullSocket) Unbind(ep string) error {
+	return pull.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (pull *pullSocket) Dial(ep string) error {
 	return pull.sck.Dial(ep)
@@ -67,6 +203,11 @@ func (pull *pullSocket) Addr() net.Addr {
 	return pull.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (pull *pullSocket) LastEndpoint() (string, error) {
+	return pull.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (pull *pullSocket) GetOption(name string) (interface{}, error) {
 	return pull.sck.GetOption(name)
@@ -77,6 +218,24 @@ func (pull *pullSocket) SetOption(name string, value interface{}) error {
 	return pull.sck.SetOption(name, value)
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (pull *pullSocket) Reader() SocketReader {
+	return socketReader{pull}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (pull *pullSocket) Writer() SocketWriter {
+	return socketWriter{pull}
+}
+
 var (
-	_ Socket = (*pullSocket)(nil)
+	_ Socket           = (*pullSocket)(nil)
+	_ MessageTracer    = (*pullSocket)(nil)
+	_ ConnectionLister = (*pullSocket)(nil)
+	_ EndpointManager  = (*pullSocket)(nil)
+	_ PeerReceiver     = (*pullSocket)(nil)
+	_ Acker            = (*pullSocket)(nil)
+	_ Monitored        = (*pullSocket)(nil)
+	_ EventReceiver    = (*pullSocket)(nil)
+	_ StatsReporter    = (*pullSocket)(nil)
 )
@@ -6,15 +6,24 @@ package zmq4
 
 import (
 	"context"
-	"fmt"
 	"net"
+	"time"
 )
 
 // NewPush returns a new PUSH ZeroMQ socket.
 // The returned socket value is initially unbound.
 func NewPush(ctx context.Context, opts ...Option) Socket {
 	push := &pushSocket{newSocket(ctx, Push, opts...)}
+	push.sck.self = push
 	push.sck.r = nil
+	switch {
+	case push.sck.ackTimeout > 0:
+		push.sck.w = newAckWriter(push.sck.ctx, push.sck.ackTimeout, push.sck.sndHWM)
+	case push.sck.sndHWM > 0:
+		push.sck.w = newQWriter(push.sck.ctx, push.sck.sndHWM)
+	default:
+		push.sck.w = newLBWriter(push.sck.ctx)
+	}
 	return push
 }
 
@@ -28,6 +37,77 @@ func (push *pushSocket) Close() error {
 	return push.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (push *pushSocket) Context() context.Context {
+	return push.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (push *pushSocket) Done() <-chan struct{} {
+	return push.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (push *pushSocket) MessageTrace() <-chan TraceEvent {
+	return push.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (push *pushSocket) Stats() Stats {
+	return push.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (push *pushSocket) Connections() []ConnInfo {
+	return push.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (push *pushSocket) Endpoints() []string {
+	return push.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (push *pushSocket) Disconnect(ep string) error {
+	return push.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (push *pushSocket) Monitor(events EventType) <-chan SocketEvent {
+	return push.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (push *pushSocket) GetMonitorChannel() <-chan SocketEvent {
+	return push.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (push *pushSocket) MonitorDropped() uint64 {
+	return push.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (push *pushSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return push.sck.recvOrEvent(ctx, push.Recv)
+}
+
+func (push *pushSocket) events() State {
+	return push.sck.events()
+}
+
+func (push *pushSocket) watch(fn func()) func() {
+	return push.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (push *pushSocket) Send(msg Msg) error {
@@ -41,9 +121,43 @@ func (push *pushSocket) SendMulti(msg Msg) error {
 	return push.sck.SendMulti(msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (push *pushSocket) SendNB(msg Msg) error {
+	return push.sck.SendNB(msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (push *pushSocket) Flush(ctx context.Context) error {
+	return push.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (*pushSocket) Recv() (Msg, error) {
-	return Msg{}, fmt.Errorf("zmq4: PUSH sockets can't recv messages")
+	return Msg{}, errInvalidOp(Push, "receive")
+}
+
+// RecvNB is an invalid operation for a PUSH socket.
+func (*pushSocket) RecvNB() (Msg, error) {
+	return Msg{}, errInvalidOp(Push, "receive")
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (push *pushSocket) RecvMulti() (Msg, error) {
+	return recvMulti(push.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (push *pushSocket) SetSendDeadline(t time.Time) error {
+	return push.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (push *pushSocket) SetRecvDeadline(t time.Time) error {
+	return push.sck.SetRecvDeadline(t)
 }
 
 // Listen connects a local endpoint to the Socket.
@@ -51,6 +165,12 @@ func (push *pushSocket) Listen(ep string) error {
 	return push.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (push *pushSocket) Unbind(ep string) error {
+	return push.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (push *pushSocket) Dial(ep string) error {
 	return push.sck.Dial(ep)
@@ -67,6 +187,11 @@ func (push *pushSocket) Addr() net.Addr {
 	return push.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (push *pushSocket) LastEndpoint() (string, error) {
+	return push.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (push *pushSocket) GetOption(name string) (interface{}, error) {
 	return push.sck.GetOption(name)
@@ -77,6 +202,22 @@ func (push *pushSocket) SetOption(name string, value interface{}) error {
 	return push.sck.SetOption(name, value)
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (push *pushSocket) Reader() SocketReader {
+	return socketReader{push}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (push *pushSocket) Writer() SocketWriter {
+	return socketWriter{push}
+}
+
 var (
-	_ Socket = (*pushSocket)(nil)
+	_ Socket           = (*pushSocket)(nil)
+	_ MessageTracer    = (*pushSocket)(nil)
+	_ ConnectionLister = (*pushSocket)(nil)
+	_ EndpointManager  = (*pushSocket)(nil)
+	_ Monitored        = (*pushSocket)(nil)
+	_ EventReceiver    = (*pushSocket)(nil)
+	_ StatsReporter    = (*pushSocket)(nil)
 )
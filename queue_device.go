@@ -0,0 +1,144 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"fmt"
+	"sync"
+)
+
+// QueueDevice is a request-reply broker built on two ROUTER sockets,
+// like the classic ZeroMQ queue (load-balancing) broker.
+//
+// Clients (REQ sockets) connect to the frontend and workers (REQ sockets)
+// connect to the backend.
+// A worker announces itself by sending any message (e.g. "READY") and then
+// receives requests, one at a time: each reply it sends is routed back to the
+// client that issued the request and marks the worker as ready again.
+// Requests received while all workers are busy are queued until a worker
+// becomes available.
+//
+// Workers never see the client envelope: the device keeps track of which
+// client each busy worker is serving.
+type QueueDevice struct {
+	front Socket
+	back  Socket
+
+	mu       sync.Mutex
+	idle     []string            // identities of the workers waiting for a request
+	inflight map[string][][]byte // worker identity -> routing identities of the client being served
+	pending  *Queue              // requests waiting for an idle worker
+}
+
+// NewQueueDevice creates a new queue device routing requests from the
+// frontend ROUTER socket to the backend ROUTER socket.
+func NewQueueDevice(frontend, backend Socket) (*QueueDevice, error) {
+	if frontend == nil || backend == nil {
+		return nil, fmt.Errorf("zmq4: frontend and backend sockets are required")
+	}
+	if frontend.Type() != Router || backend.Type() != Router {
+		return nil, fmt.Errorf(
+			"zmq4: queue device requires ROUTER sockets (frontend=%v, backend=%v)",
+			frontend.Type(), backend.Type(),
+		)
+	}
+	return &QueueDevice{
+		front:    frontend,
+		back:     backend,
+		inflight: make(map[string][][]byte),
+		pending:  NewQueue(),
+	}, nil
+}
+
+// Run routes requests and replies between the frontend and the backend.
+// Run blocks until one of the sockets fails, e.g. when it is closed, and
+// returns that error.
+func (dev *QueueDevice) Run() error {
+	errc := make(chan error, 2)
+	go func() { errc <- dev.serveFrontend() }()
+	go func() { errc <- dev.serveBackend() }()
+	return <-errc
+}
+
+// Busy returns the number of workers currently processing a request.
+func (dev *QueueDevice) Busy() int {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return len(dev.inflight)
+}
+
+func (dev *QueueDevice) serveFrontend() error {
+	for {
+		msg, err := dev.front.Recv()
+		if err != nil {
+			return fmt.Errorf("zmq4: queue device frontend: %w", err)
+		}
+		if _, _, ok := SplitEnvelope(msg); !ok {
+			// not a request: there is no way to reply to it.
+			continue
+		}
+
+		dev.mu.Lock()
+		if len(dev.idle) == 0 {
+			dev.pending.Push(msg)
+			dev.mu.Unlock()
+			continue
+		}
+		worker := dev.idle[0]
+		dev.idle = dev.idle[1:]
+		err = dev.dispatch(worker, msg)
+		dev.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (dev *QueueDevice) serveBackend() error {
+	for {
+		msg, err := dev.back.Recv()
+		if err != nil {
+			return fmt.Errorf("zmq4: queue device backend: %w", err)
+		}
+		wids, body, ok := SplitEnvelope(msg)
+		if !ok || len(wids) == 0 {
+			continue
+		}
+		worker := string(wids[0])
+
+		dev.mu.Lock()
+		if cids, busy := dev.inflight[worker]; busy {
+			delete(dev.inflight, worker)
+			reply := NewEnvelopeMsg(cids, body...)
+			if err := dev.front.Send(reply); err != nil {
+				dev.mu.Unlock()
+				return fmt.Errorf("zmq4: queue device could not route reply: %w", err)
+			}
+		}
+		// the worker is ready for a new request.
+		err = nil
+		if req, ok := dev.pending.Peek(); ok {
+			dev.pending.Pop()
+			err = dev.dispatch(worker, req)
+		} else {
+			dev.idle = append(dev.idle, worker)
+		}
+		dev.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch sends the client request to the worker and records it as in-flight.
+// dispatch must be called with dev.mu held.
+func (dev *QueueDevice) dispatch(worker string, req Msg) error {
+	cids, body, _ := SplitEnvelope(req)
+	if err := dev.back.Send(NewEnvelopeMsg([][]byte{[]byte(worker)}, body...)); err != nil {
+		return fmt.Errorf("zmq4: queue device could not dispatch request: %w", err)
+	}
+	dev.inflight[worker] = cids
+	return nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// maxGroupLen is the maximum length of a group, as in libzmq.
+const maxGroupLen = 255
+
+// ErrInvalidGroup is returned when sending a message without a group, or
+// with a group longer than 255 bytes, on a RADIO socket.
+var ErrInvalidGroup = errors.New("zmq4: invalid group")
+
+// NewRadio returns a new RADIO ZeroMQ socket.
+// The returned socket value is initially unbound.
+//
+// A RADIO socket publishes each message to its group (see Msg.Group): the
+// message is only delivered to the DISH peers that joined exactly that
+// group, and it is dropped for the peers whose queue is full, as PUB
+// sockets do.
+func NewRadio(ctx context.Context, opts ...Option) Socket {
+	radio := &radioSocket{newSocket(ctx, Radio, opts...)}
+	radio.sck.self = radio
+	w := newPubMWriter(radio.sck.ctx, radio.sck.sndHWM)
+	w.groups = true
+	radio.sck.w = w
+	return radio
+}
+
+// radioSocket is a RADIO ZeroMQ socket.
+type radioSocket struct {
+	sck *socket
+}
+
+// Close closes the open Socket
+func (radio *radioSocket) Close() error {
+	return radio.sck.Close()
+}
+
+// Context returns the life-line of the socket.
+func (radio *radioSocket) Context() context.Context {
+	return radio.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (radio *radioSocket) Done() <-chan struct{} {
+	return radio.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (radio *radioSocket) MessageTrace() <-chan TraceEvent {
+	return radio.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (radio *radioSocket) Stats() Stats {
+	return radio.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (radio *radioSocket) Connections() []ConnInfo {
+	return radio.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (radio *radioSocket) Endpoints() []string {
+	return radio.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (radio *radioSocket) Disconnect(ep string) error {
+	return radio.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (radio *radioSocket) Monitor(events EventType) <-chan SocketEvent {
+	return radio.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (radio *radioSocket) GetMonitorChannel() <-chan SocketEvent {
+	return radio.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (radio *radioSocket) MonitorDropped() uint64 {
+	return radio.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (radio *radioSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return radio.sck.recvOrEvent(ctx, radio.Recv)
+}
+
+func (radio *radioSocket) events() State {
+	return radio.sck.events()
+}
+
+func (radio *radioSocket) watch(fn func()) func() {
+	return radio.sck.watch(fn)
+}
+
+// Send publishes the single-frame message to its group, see Msg.Group.
+// Send blocks until the message can be queued or the send deadline expires.
+func (radio *radioSocket) Send(msg Msg) error {
+	if err := checkGroupMsg(msg); err != nil {
+		return err
+	}
+	return radio.sck.Send(msg)
+}
+
+// SendMulti returns ErrInvalidOperation: RADIO messages have a single frame.
+func (*radioSocket) SendMulti(msg Msg) error {
+	return errInvalidOp(Radio, "send multipart messages")
+}
+
+// SendNB publishes the single-frame message to its group, see Msg.Group.
+// Like Send, SendNB never blocks: it drops the message for the peers whose
+// queue is full.
+func (radio *radioSocket) SendNB(msg Msg) error {
+	if err := checkGroupMsg(msg); err != nil {
+		return err
+	}
+	return radio.sck.trySend(msg)
+}
+
+// checkGroupMsg returns an error if msg can not be published by a RADIO
+// socket.
+func checkGroupMsg(msg Msg) error {
+	if n := len(msg.Group); n == 0 || n > maxGroupLen {
+		return fmt.Errorf("zmq4: RADIO message group of %d bytes, want 1 to %d: %w", n, maxGroupLen, ErrInvalidGroup)
+	}
+	if n := len(msg.Frames); n != 1 {
+		return fmt.Errorf("zmq4: RADIO message has %d frames, want 1: %w", n, ErrTooManyFrames)
+	}
+	return nil
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (radio *radioSocket) Flush(ctx context.Context) error {
+	return radio.sck.Flush(ctx)
+}
+
+// Recv returns ErrInvalidOperation: RADIO sockets can't receive messages.
+func (*radioSocket) Recv() (Msg, error) {
+	return Msg{}, errInvalidOp(Radio, "receive")
+}
+
+// RecvNB is an invalid operation for a RADIO socket.
+func (*radioSocket) RecvNB() (Msg, error) {
+	return Msg{}, errInvalidOp(Radio, "receive")
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (radio *radioSocket) RecvMulti() (Msg, error) {
+	return recvMulti(radio.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (radio *radioSocket) SetSendDeadline(t time.Time) error {
+	return radio.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (radio *radioSocket) SetRecvDeadline(t time.Time) error {
+	return radio.sck.SetRecvDeadline(t)
+}
+
+// Listen connects a local endpoint to the Socket.
+func (radio *radioSocket) Listen(ep string) error {
+	return radio.sck.Listen(ep)
+}
+
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (radio *radioSocket) Unbind(ep string) error {
+	return radio.sck.Unbind(ep)
+}
+
+// Dial connects a remote endpoint to the Socket.
+func (radio *radioSocket) Dial(ep string) error {
+	return radio.sck.Dial(ep)
+}
+
+// Type returns the type of this Socket (PUB, SUB, ...)
+func (radio *radioSocket) Type() SocketType {
+	return radio.sck.Type()
+}
+
+// Addr returns the listener's address.
+// Addr returns nil if the socket isn't a listener.
+func (radio *radioSocket) Addr() net.Addr {
+	return radio.sck.Addr()
+}
+
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (radio *radioSocket) LastEndpoint() (string, error) {
+	return radio.sck.LastEndpoint()
+}
+
+// GetOption is used to retrieve an option for a socket.
+func (radio *radioSocket) GetOption(name string) (interface{}, error) {
+	return radio.sck.GetOption(name)
+}
+
+// SetOption is used to set an option for a socket.
+func (radio *radioSocket) SetOption(name string, value interface{}) error {
+	return radio.sck.SetOption(name, value)
+}
+
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (radio *radioSocket) Reader() SocketReader {
+	return socketReader{radio}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (radio *radioSocket) Writer() SocketWriter {
+	return socketWriter{radio}
+}
+
+var (
+	_ Socket           = (*radioSocket)(nil)
+	_ MessageTracer    = (*radioSocket)(nil)
+	_ ConnectionLister = (*radioSocket)(nil)
+	_ EndpointManager  = (*radioSocket)(nil)
+	_ Monitored        = (*radioSocket)(nil)
+	_ EventReceiver    = (*radioSocket)(nil)
+	_ StatsReporter    = (*radioSocket)(nil)
+)
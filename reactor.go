@@ -0,0 +1,673 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Reactor dispatches socket events to handlers, by default from a single
+// goroutine. With WithReactorWorkers, handlers are called from a bounded
+// pool of goroutines instead, while the handler calls of a given socket
+// still never overlap and occur in order.
+//
+// Events are level-triggered: a handler registered for Readable events is
+// called again as long as its socket has messages ready to be received.
+//
+// The reactor also fires timers, see AddTimer, and dispatches the values
+// received from Go channels, see AddChannel.
+type Reactor struct {
+	mu      sync.Mutex
+	sockets []reactorSocket
+	chans   []reactorChannel
+	timers  []*reactorTimer
+	timerID uint64 // identifier of the last timer added
+	workers int
+
+	busy map[Socket]bool    // sockets whose handler is being called by a worker
+	wake context.CancelFunc // interrupts the poll of the running reactor, see interrupt
+
+	relay    *channelRelay      // receives from the channels for the running reactor
+	received chan receivedValue // value received by the relay, not dispatched yet
+	freed    chan struct{}      // signals the relay that received was dispatched
+
+	stopCh  chan struct{}
+	stopped bool
+}
+
+// ReactorOption configures a Reactor.
+type ReactorOption func(r *Reactor)
+
+// WithReactorWorkers makes the reactor call handlers from a pool of n
+// goroutines, so slow handlers of a socket do not delay the events of the
+// other sockets. A socket is not polled while its handler is being called,
+// so the handler calls of a socket are serialized.
+// With n <= 0, the default, handlers are called from the goroutine running
+// the reactor.
+func WithReactorWorkers(n int) ReactorOption {
+	return func(r *Reactor) {
+		r.workers = n
+	}
+}
+
+type reactorSocket struct {
+	sock    Socket
+	events  State
+	handler func(State) error
+}
+
+// reactorChannel is a channel registered with AddChannel.
+type reactorChannel struct {
+	ch      <-chan interface{}
+	handler func(interface{})
+}
+
+// receivedValue is a value received from a registered channel.
+type receivedValue struct {
+	ch <-chan interface{}
+	v  interface{}
+}
+
+// channelRelay receives the values of the registered channels while the
+// reactor runs, see relayChannels.
+type channelRelay struct {
+	reload chan chan struct{} // requests to look the channels up again, acknowledged by closing the sent channel
+	done   chan struct{}      // closed once the relay exits
+}
+
+// sync makes the relay look the registered channels up again, and waits for
+// it to do so, unless it exits first.
+func (cr *channelRelay) sync() {
+	if cr == nil {
+		return
+	}
+	ack := make(chan struct{})
+	select {
+	case cr.reload <- ack:
+	case <-cr.done:
+		return
+	}
+	select {
+	case <-ack:
+	case <-cr.done:
+	}
+}
+
+// reactorTimer is a timer registered with AddTimer.
+type reactorTimer struct {
+	id       uint64
+	interval time.Duration
+	times    int       // firings left, or 0 to fire until removed
+	next     time.Time // when the timer is due
+	handler  func()
+}
+
+// newReactorWaitSet returns the wait set of a reactor run.
+// It is a variable so tests can count the waits of the reactor.
+var newReactorWaitSet = newWaitSet
+
+// NewReactor returns a new reactor, without any socket.
+func NewReactor(opts ...ReactorOption) *Reactor {
+	r := &Reactor{
+		busy:     make(map[Socket]bool),
+		received: make(chan receivedValue, 1),
+		freed:    make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// AddSocket registers the handler to call with the events that occurred on
+// sock, among the requested events.
+// AddSocket replaces any previous registration of sock.
+func (r *Reactor) AddSocket(sock Socket, events State, handler func(State)) {
+	r.AddSocketErr(sock, events, noError(handler))
+}
+
+// AddSocketErr is like AddSocket, for a handler that can fail: the reactor
+// stops once the handler returns a non-nil error, and Run returns it.
+func (r *Reactor) AddSocketErr(sock Socket, events State, handler func(State) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.interrupt()
+	if i := r.index(sock); i >= 0 {
+		r.sockets[i] = reactorSocket{sock, events, handler}
+		return
+	}
+	r.sockets = append(r.sockets, reactorSocket{sock, events, handler})
+}
+
+// noError adapts a handler that cannot fail.
+func noError(handler func(State)) func(State) error {
+	if handler == nil {
+		return nil
+	}
+	return func(events State) error {
+		handler(events)
+		return nil
+	}
+}
+
+// RemoveSocket unregisters sock.
+func (r *Reactor) RemoveSocket(sock Socket) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(sock); i >= 0 {
+		r.sockets = append(r.sockets[:i], r.sockets[i+1:]...)
+		r.interrupt()
+	}
+}
+
+// Replace atomically replaces the registration of old with a registration of
+// sock, so no event of sock can be dispatched before old is removed, nor
+// missed after.
+// A handler of old that is being called when Replace is called may still
+// complete after Replace returns.
+func (r *Reactor) Replace(old, sock Socket, events State, handler func(State)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.index(old)
+	if i < 0 {
+		return fmt.Errorf("zmq4: socket to replace is not registered with the reactor")
+	}
+	if j := r.index(sock); j >= 0 && j != i {
+		return fmt.Errorf("zmq4: replacement socket is already registered with the reactor")
+	}
+	r.sockets[i] = reactorSocket{sock, events, noError(handler)}
+	r.interrupt()
+	return nil
+}
+
+// AddChannel registers the handler to call with the values received from
+// ch, so application events are dispatched by the same loop as the socket
+// events, as soon as they are received. A closed channel is unregistered.
+// AddChannel replaces any previous registration of ch.
+//
+// Channel handlers are called from the goroutine running the reactor, even
+// with WithReactorWorkers. The reactor receives the next value from its
+// channels once the previous one is dispatched.
+func (r *Reactor) AddChannel(ch <-chan interface{}, handler func(interface{})) {
+	r.mu.Lock()
+	for i, rc := range r.chans {
+		if rc.ch == ch {
+			r.chans[i].handler = handler
+			r.mu.Unlock()
+			return
+		}
+	}
+	r.chans = append(r.chans, reactorChannel{ch, handler})
+	relay := r.relay
+	r.mu.Unlock()
+	relay.sync()
+}
+
+// RemoveChannel unregisters ch: once RemoveChannel returns, no value is
+// received from ch anymore. A value received from ch but not dispatched
+// yet is dropped.
+func (r *Reactor) RemoveChannel(ch <-chan interface{}) {
+	r.mu.Lock()
+	removed := r.removeChannel(ch)
+	relay := r.relay
+	r.mu.Unlock()
+	if removed {
+		relay.sync()
+	}
+}
+
+// removeChannel unregisters ch, and reports whether it was registered.
+// removeChannel must be called with r.mu held.
+func (r *Reactor) removeChannel(ch <-chan interface{}) bool {
+	for i, rc := range r.chans {
+		if rc.ch == ch {
+			r.chans = append(r.chans[:i], r.chans[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// AddTimer registers the handler to call every interval, times times, and
+// returns the identifier of the timer for RemoveTimer.
+// With times == 0, the timer fires until it is removed.
+//
+// Timer handlers are called from the goroutine running the reactor, even
+// with WithReactorWorkers.
+//
+// AddTimer panics, without registering the timer, if interval <= 0 or
+// times < 0: like the arguments of time.NewTicker, they are programming
+// errors rather than runtime conditions.
+func (r *Reactor) AddTimer(interval time.Duration, times int, handler func()) uint64 {
+	if interval <= 0 {
+		panic(fmt.Sprintf("zmq4: non-positive interval %v for Reactor.AddTimer", interval))
+	}
+	if times < 0 {
+		panic(fmt.Sprintf("zmq4: negative times %d for Reactor.AddTimer", times))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timerID++
+	r.timers = append(r.timers, &reactorTimer{
+		id:       r.timerID,
+		interval: interval,
+		times:    times,
+		next:     time.Now().Add(interval),
+		handler:  handler,
+	})
+	r.interrupt()
+	return r.timerID
+}
+
+// RemoveTimer unregisters the timer with the given identifier.
+// Removing an unknown or expired timer is a no-op.
+func (r *Reactor) RemoveTimer(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, t := range r.timers {
+		if t.id == id {
+			r.timers = append(r.timers[:i], r.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// index returns the index of sock in the registered sockets, or -1.
+// index must be called with r.mu held.
+func (r *Reactor) index(sock Socket) int {
+	for i, rs := range r.sockets {
+		if rs.sock == sock {
+			return i
+		}
+	}
+	return -1
+}
+
+// Run dispatches events until Stop is called, polling fails, or a handler
+// registered with AddSocketErr fails.
+// Run returns nil once stopped, and the error of the handler otherwise.
+func (r *Reactor) Run() error {
+	return r.RunContext(context.Background())
+}
+
+// RunContext dispatches events until ctx is done, Stop is called, polling
+// fails, or a handler registered with AddSocketErr fails.
+// RunContext returns nil once stopped, ctx.Err() when ctx is done, and the
+// error of the handler when it failed.
+func (r *Reactor) RunContext(ctx context.Context) error {
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-r.stopCh:
+			cancel()
+		case <-pctx.Done():
+		}
+	}()
+
+	// the sockets are polled over and over: the run keeps its wait set.
+	ws := newReactorWaitSet()
+	defer ws.close()
+
+	relay := &channelRelay{reload: make(chan chan struct{}), done: make(chan struct{})}
+	r.mu.Lock()
+	r.relay = relay
+	r.mu.Unlock()
+	go r.relayChannels(pctx, relay)
+	defer func() {
+		cancel()
+		<-relay.done
+		r.mu.Lock()
+		r.relay = nil
+		r.mu.Unlock()
+	}()
+
+	if r.workers > 0 {
+		return r.runWorkers(ctx, pctx, cancel, ws)
+	}
+
+	for {
+		wctx, wake := r.pollContext(pctx)
+		timeout := r.dispatch()
+		// sockets may be added or removed while polling: the poll is then
+		// interrupted, and the registered sockets looked up again.
+		ready, _, err := pollWith(wctx, ws, r.items(), timeout)
+		wake()
+		if err != nil {
+			if pctx.Err() == nil {
+				continue
+			}
+			return r.exit(ctx, err)
+		}
+		for _, item := range ready {
+			if item.Events == 0 {
+				continue
+			}
+			if h := r.handler(item.Socket); h != nil {
+				if err := h(item.Events); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// pollContext returns the context of the next poll of the running reactor,
+// done with pctx or once the poll is interrupted, see interrupt.
+func (r *Reactor) pollContext(pctx context.Context) (context.Context, context.CancelFunc) {
+	wctx, wake := context.WithCancel(pctx)
+	r.mu.Lock()
+	r.wake = wake
+	r.mu.Unlock()
+	return wctx, wake
+}
+
+// interrupt wakes the poll of the running reactor up, so it looks the
+// registered sockets, channels and timers up again.
+// interrupt must be called with r.mu held.
+func (r *Reactor) interrupt() {
+	if r.wake != nil {
+		r.wake()
+	}
+}
+
+// reactorTask is a handler call dispatched to a worker.
+type reactorTask struct {
+	sock    Socket
+	events  State
+	handler func(State) error
+}
+
+// runWorkers dispatches events to r.workers goroutines, until pctx is done
+// or a handler fails, which cancels pctx. The sockets are polled with ws.
+// It returns once the handlers being called return.
+func (r *Reactor) runWorkers(ctx, pctx context.Context, cancel context.CancelFunc, ws *waitSet) (err error) {
+	var (
+		wg    sync.WaitGroup
+		tasks = make(chan reactorTask)
+		once  sync.Once
+		herr  error // first error of a handler
+	)
+	defer func() {
+		close(tasks)
+		wg.Wait()
+		if herr != nil {
+			err = herr
+		}
+	}()
+	for range r.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				if err := task.handler(task.events); err != nil {
+					once.Do(func() { herr = err })
+					cancel()
+				}
+				r.done(task.sock)
+			}
+		}()
+	}
+
+	for {
+		wctx, wake := r.pollContext(pctx)
+		timeout := r.dispatch()
+		// busy sockets are left out, and polled again once their handler
+		// returns and wakes the poll up.
+		ready, _, err := pollWith(wctx, ws, r.idleItems(), timeout)
+		wake()
+		if err != nil {
+			if pctx.Err() == nil {
+				continue
+			}
+			return r.exit(ctx, err)
+		}
+		for _, item := range ready {
+			if item.Events == 0 {
+				continue
+			}
+			h := r.handler(item.Socket)
+			if h == nil {
+				continue
+			}
+			r.mu.Lock()
+			r.busy[item.Socket] = true
+			r.mu.Unlock()
+			select {
+			case tasks <- reactorTask{item.Socket, item.Events, h}:
+			case <-pctx.Done():
+				return r.exit(ctx, pctx.Err())
+			}
+		}
+	}
+}
+
+// done marks the handler call of sock as complete, and wakes the poll up so
+// sock is polled again.
+func (r *Reactor) done(sock Socket) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.busy, sock)
+	r.interrupt()
+}
+
+// exit returns the error RunContext returns when polling failed with err.
+func (r *Reactor) exit(ctx context.Context, err error) error {
+	select {
+	case <-r.stopCh:
+		return nil
+	default:
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Stop stops the reactor: Run and RunContext return once the handler being
+// called, if any, returns.
+// Stopping a stopped reactor is a no-op.
+func (r *Reactor) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.stopCh)
+	}
+}
+
+// dispatch fires the timers that are due and dispatches the value received
+// from the channels, if any, and returns how long to poll the sockets for
+// next.
+func (r *Reactor) dispatch() time.Duration {
+	r.fireTimers()
+	r.dispatchChannel()
+	return r.pollTimeout()
+}
+
+// dispatchChannel calls the handler of the channel the relay received a
+// value from, if any.
+func (r *Reactor) dispatchChannel() {
+	select {
+	case rv := <-r.received:
+		// the relay receives the next value while the handler is called,
+		// and then interrupts the poll.
+		select {
+		case r.freed <- struct{}{}:
+		default:
+		}
+		if h := r.channelHandler(rv.ch); h != nil {
+			h(rv.v)
+		}
+	default:
+	}
+}
+
+// relayChannels receives the values of the registered channels until ctx is
+// done, and hands them to the loop of the reactor one at a time: a value is
+// received once the previous one is dispatched, and the poll of the reactor
+// is interrupted for each value.
+// A value not dispatched when the reactor stops is dispatched by its next
+// run.
+func (r *Reactor) relayChannels(ctx context.Context, cr *channelRelay) {
+	defer close(cr.done)
+
+	var chans []<-chan interface{}
+	load := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		chans = chans[:0]
+		for _, rc := range r.chans {
+			chans = append(chans, rc.ch)
+		}
+	}
+	load()
+
+	// a signal left by a previous run does not free the value received by
+	// this one.
+	select {
+	case <-r.freed:
+	default:
+	}
+	pending := len(r.received) > 0
+
+	for {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cr.reload)},
+		}
+		if pending {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(r.freed)})
+		} else {
+			for _, ch := range chans {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+			}
+		}
+
+		i, v, ok := reflect.Select(cases)
+		switch {
+		case i == 0:
+			return
+		case i == 1:
+			load()
+			close(v.Interface().(chan struct{}))
+		case pending:
+			pending = false
+		case !ok:
+			// a closed channel is unregistered.
+			r.mu.Lock()
+			r.removeChannel(chans[i-2])
+			r.mu.Unlock()
+			load()
+		default:
+			r.received <- receivedValue{chans[i-2], v.Interface()}
+			pending = true
+			r.mu.Lock()
+			r.interrupt()
+			r.mu.Unlock()
+		}
+	}
+}
+
+// channelHandler returns the handler currently registered for ch, if any.
+func (r *Reactor) channelHandler(ch <-chan interface{}) func(interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rc := range r.chans {
+		if rc.ch == ch {
+			return rc.handler
+		}
+	}
+	return nil
+}
+
+// pollTimeout returns how long to poll the sockets for: until the next timer
+// is due, or indefinitely if there is no timer.
+func (r *Reactor) pollTimeout() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timeout := time.Duration(-1)
+	now := time.Now()
+	for _, t := range r.timers {
+		if left := max(t.next.Sub(now), 0); timeout < 0 || left < timeout {
+			timeout = left
+		}
+	}
+	return timeout
+}
+
+// fireTimers calls the handlers of the timers that are due, and removes the
+// timers that fired their last time.
+func (r *Reactor) fireTimers() {
+	var (
+		now = time.Now()
+		due []func()
+	)
+	r.mu.Lock()
+	timers := r.timers[:0]
+	for _, t := range r.timers {
+		if now.Before(t.next) {
+			timers = append(timers, t)
+			continue
+		}
+		due = append(due, t.handler)
+		t.next = now.Add(t.interval)
+		if t.times > 0 {
+			t.times--
+			if t.times == 0 {
+				continue
+			}
+		}
+		timers = append(timers, t)
+	}
+	clear(r.timers[len(timers):])
+	r.timers = timers
+	r.mu.Unlock()
+
+	// handlers are called without the lock, so they can add or remove
+	// timers.
+	for _, h := range due {
+		h()
+	}
+}
+
+func (r *Reactor) items() []PollItem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	items := make([]PollItem, len(r.sockets))
+	for i, rs := range r.sockets {
+		items[i] = PollItem{Socket: rs.sock, Events: rs.events}
+	}
+	return items
+}
+
+// idleItems returns the poll items of the sockets whose handler is not
+// being called.
+func (r *Reactor) idleItems() []PollItem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	items := make([]PollItem, 0, len(r.sockets))
+	for _, rs := range r.sockets {
+		if !r.busy[rs.sock] {
+			items = append(items, PollItem{Socket: rs.sock, Events: rs.events})
+		}
+	}
+	return items
+}
+
+// handler returns the handler currently registered for sock, if any.
+func (r *Reactor) handler(sock Socket) func(State) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(sock); i >= 0 {
+		return r.sockets[i].handler
+	}
+	return nil
+}
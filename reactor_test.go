@@ -0,0 +1,94 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReactorIdle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w := make(chan *countingWaiter, 1)
+	defer func(f func() *waitSet) { newReactorWaitSet = f }(newReactorWaitSet)
+	newReactorWaitSet = func() *waitSet {
+		ws := newWaitSet()
+		cw := &countingWaiter{waiter: ws.w}
+		ws.w = cw
+		w <- cw
+		return ws
+	}
+
+	pull := NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	r := NewReactor()
+	r.AddSocket(pull, Readable, func(State) {})
+	values := make(chan interface{})
+	received := make(chan interface{}, 1)
+	r.AddChannel(values, func(v interface{}) { received <- v })
+	errc := make(chan error, 1)
+	go func() { errc <- r.RunContext(ctx) }()
+	defer func() {
+		r.Stop()
+		if err := <-errc; err != nil {
+			t.Errorf("could not run reactor: %+v", err)
+		}
+	}()
+	cw := <-w
+
+	// an idle reactor without timers blocks until an event occurs, rather
+	// than waking up periodically, even with channels registered.
+	time.Sleep(200 * time.Millisecond)
+	if n := cw.waits.Load(); n > 2 {
+		t.Fatalf("idle reactor woke up %d times", n)
+	}
+
+	// a value sent to a registered channel interrupts the wait.
+	values <- "value"
+	select {
+	case v := <-received:
+		if v != "value" {
+			t.Fatalf("invalid channel value: got=%v, want=%q", v, "value")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("channel value not dispatched")
+	}
+
+	// a socket added while the reactor blocks is polled right away.
+	rep := NewRep(ctx)
+	defer rep.Close()
+	if err := rep.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	req := NewReq(ctx)
+	defer req.Close()
+	if err := req.Dial("tcp://" + rep.Addr().String()); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := req.Send(NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if ok, err := WaitReadable(rep, 5*time.Second); err != nil || !ok {
+		t.Fatalf("request not received: ok=%v, err=%v", ok, err)
+	}
+	called := make(chan struct{}, 1)
+	r.AddSocket(rep, Readable, func(State) {
+		if _, err := rep.Recv(); err == nil {
+			called <- struct{}{}
+		}
+	})
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatalf("handler of the added socket not called")
+	}
+}
@@ -9,15 +9,22 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 )
 
 // NewRep returns a new REP ZeroMQ socket.
 // The returned socket value is initially unbound.
+//
+// Recv and Send must alternate, starting with Recv: they fail with ErrFSM
+// otherwise.
 func NewRep(ctx context.Context, opts ...Option) Socket {
 	rep := &repSocket{newSocket(ctx, Rep, opts...)}
+	rep.sck.self = rep
 	sharedState := newRepState()
 	rep.sck.w = newRepWriter(rep.sck.ctx, sharedState)
-	rep.sck.r = newRepReader(rep.sck.ctx, sharedState)
+	r := newRepReader(rep.sck.ctx, sharedState, rep.sck.recv.qsize)
+	r.raw = rep.sck.rawEnvelope
+	rep.sck.r = r
 	return rep
 }
 
@@ -31,10 +38,84 @@ func (rep *repSocket) Close() error {
 	return rep.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (rep *repSocket) Context() context.Context {
+	return rep.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (rep *repSocket) Done() <-chan struct{} {
+	return rep.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (rep *repSocket) MessageTrace() <-chan TraceEvent {
+	return rep.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (rep *repSocket) Stats() Stats {
+	return rep.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (rep *repSocket) Connections() []ConnInfo {
+	return rep.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (rep *repSocket) Endpoints() []string {
+	return rep.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (rep *repSocket) Disconnect(ep string) error {
+	return rep.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (rep *repSocket) Monitor(events EventType) <-chan SocketEvent {
+	return rep.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (rep *repSocket) GetMonitorChannel() <-chan SocketEvent {
+	return rep.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (rep *repSocket) MonitorDropped() uint64 {
+	return rep.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (rep *repSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return rep.sck.recvOrEvent(ctx, rep.Recv)
+}
+
+func (rep *repSocket) events() State {
+	return rep.sck.events()
+}
+
+func (rep *repSocket) watch(fn func()) func() {
+	return rep.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (rep *repSocket) Send(msg Msg) error {
-	ctx, cancel := context.WithTimeout(rep.sck.ctx, rep.sck.Timeout())
+	if err := rep.sck.checkFrames(msg); err != nil {
+		return err
+	}
+	ctx, cancel := rep.sck.sendContext()
 	defer cancel()
 	return rep.sck.w.write(ctx, msg)
 }
@@ -43,26 +124,82 @@ func (rep *repSocket) Send(msg Msg) error {
 // SendMulti blocks until the message can be queued or the send deadline expires.
 // The message will be sent as a multipart message.
 func (rep *repSocket) SendMulti(msg Msg) error {
+	if err := rep.sck.checkFrames(msg); err != nil {
+		return err
+	}
 	msg.multipart = true
-	ctx, cancel := context.WithTimeout(rep.sck.ctx, rep.sck.Timeout())
+	ctx, cancel := rep.sck.sendContext()
 	defer cancel()
 	return rep.sck.w.write(ctx, msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (rep *repSocket) SendNB(msg Msg) error {
+	return rep.sck.SendNB(msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (rep *repSocket) Flush(ctx context.Context) error {
+	return rep.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (rep *repSocket) Recv() (Msg, error) {
-	ctx, cancel := context.WithCancel(rep.sck.ctx)
+	return withoutConn(rep.recvConn())
+}
+
+// recvConn receives a complete message, still referencing the connection it was
+// received from.
+func (rep *repSocket) recvConn() (Msg, error) {
+	ctx, cancel := rep.sck.recvContext()
 	defer cancel()
 	var msg Msg
 	err := rep.sck.r.read(ctx, &msg)
 	return msg, err
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (rep *repSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(rep.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (rep *repSocket) RecvNB() (Msg, error) {
+	return rep.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (rep *repSocket) RecvMulti() (Msg, error) {
+	return recvMulti(rep.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (rep *repSocket) SetSendDeadline(t time.Time) error {
+	return rep.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (rep *repSocket) SetRecvDeadline(t time.Time) error {
+	return rep.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (rep *repSocket) Listen(ep string) error {
 	return rep.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (rep *repSocket) Unbind(ep string) error {
+	return rep.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (rep *repSocket) Dial(ep string) error {
 	return rep.sck.Dial(ep)
@@ -79,6 +216,11 @@ func (rep *repSocket) Addr() net.Addr {
 	return rep.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (rep *repSocket) LastEndpoint() (string, error) {
+	return rep.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (rep *repSocket) GetOption(name string) (interface{}, error) {
 	return rep.sck.GetOption(name)
@@ -89,6 +231,16 @@ func (rep *repSocket) SetOption(name string, value interface{}) error {
 	return rep.sck.SetOption(name, value)
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (rep *repSocket) Reader() SocketReader {
+	return socketReader{rep}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (rep *repSocket) Writer() SocketWriter {
+	return socketWriter{rep}
+}
+
 type repMsg struct {
 	conn *Conn
 	msg  Msg
@@ -97,6 +249,7 @@ type repMsg struct {
 type repReader struct {
 	ctx   context.Context
 	state *repState
+	raw   bool // whether the envelope is handed to the application
 
 	mu    sync.Mutex
 	conns []*Conn
@@ -104,8 +257,7 @@ type repReader struct {
 	msgCh chan repMsg
 }
 
-func newRepReader(ctx context.Context, state *repState) *repReader {
-	const qsize = 10
+func newRepReader(ctx context.Context, state *repState, qsize int) *repReader {
 	return &repReader{
 		ctx:   ctx,
 		msgCh: make(chan repMsg, qsize),
@@ -136,21 +288,47 @@ func (r *repReader) rmConn(conn *Conn) {
 	}
 }
 
+func (r *repReader) queued() int {
+	return len(r.msgCh)
+}
+
 func (r *repReader) read(ctx context.Context, msg *Msg) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	if r.state.replying() {
+		return fmt.Errorf("zmq4: REP socket cannot receive a request before replying to the last one: %w", ErrFSM)
+	}
+	var repMsg repMsg
 	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case repMsg := <-r.msgCh:
-		if repMsg.msg.err != nil {
-			return repMsg.msg.err
-		}
-		pre, innerMsg := splitReq(repMsg.msg)
-		if pre == nil {
-			return fmt.Errorf("zmq4: invalid REP message")
+	case repMsg = <-r.msgCh:
+	default:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case repMsg = <-r.msgCh:
 		}
-		*msg = innerMsg
-		r.state.Set(repMsg.conn, pre)
 	}
+	if repMsg.msg.err != nil {
+		return repMsg.msg.err
+	}
+	if r.raw {
+		*msg = repMsg.msg
+		r.state.Set(repMsg.conn, nil)
+		return nil
+	}
+	pre, innerMsg := splitReq(repMsg.msg)
+	if pre == nil {
+		return fmt.Errorf("zmq4: invalid REP message")
+	}
+	if buf := repMsg.msg.buf; buf != nil {
+		// the envelope outlives the request, which may be released
+		// before the reply is sent.
+		pre = NewMsgFrom(pre...).Clone().Frames
+		innerMsg.buf = buf
+	}
+	*msg = innerMsg
+	r.state.Set(repMsg.conn, pre)
 	return nil
 }
 
@@ -167,7 +345,12 @@ func (r *repReader) listen(ctx context.Context, conn *Conn) {
 			if msg.err != nil {
 				return
 			}
-			r.msgCh <- repMsg{conn, msg}
+			select {
+			case r.msgCh <- repMsg{conn, msg}:
+				conn.queued()
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 }
@@ -187,17 +370,18 @@ func (r *repReader) Close() error {
 	return err
 }
 
+// splitReq splits a request into its envelope, up to and including the first
+// empty delimiter frame, and its body, which may contain empty frames.
 func splitReq(envelope Msg) (preamble [][]byte, msg Msg) {
-	for i, frame := range envelope.Frames {
-		if len(frame) != 0 {
-			continue
-		}
-		preamble = envelope.Frames[:i+1]
-		if i+1 < len(envelope.Frames) {
-			msg = NewMsgFrom(envelope.Frames[i+1:]...)
-		}
+	ids, body, ok := SplitEnvelope(envelope)
+	if !ok {
+		return nil, msg
 	}
-	return
+	preamble = envelope.Frames[:len(ids)+1]
+	if len(body) > 0 {
+		msg = NewMsgFrom(body...)
+	}
+	return preamble, msg
 }
 
 type repSendPayload struct {
@@ -214,6 +398,7 @@ type repWriter struct {
 	conns []*Conn
 
 	sendCh chan repSendPayload
+	out    *outbox
 }
 
 func (r repSendPayload) buildReplyMsg() Msg {
@@ -228,6 +413,7 @@ func newRepWriter(ctx context.Context, state *repState) *repWriter {
 		ctx:    ctx,
 		state:  state,
 		sendCh: make(chan repSendPayload),
+		out:    newOutbox(),
 	}
 	go r.run()
 	return r
@@ -256,17 +442,38 @@ func (r *repWriter) rmConn(conn *Conn) {
 }
 
 func (r *repWriter) write(ctx context.Context, msg Msg) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	if !r.state.replying() {
+		return fmt.Errorf("zmq4: REP socket cannot reply without a request pending: %w", ErrFSM)
+	}
 	conn, preamble := r.state.Get()
+	r.out.add()
+	payload := repSendPayload{conn, preamble, msg}
+	select {
+	case r.sendCh <- payload:
+		r.state.replied()
+		return nil
+	default:
+	}
 	select {
 	case <-ctx.Done():
+		r.out.done()
 		return ctx.Err()
 	case <-r.ctx.Done(): // repWriter.run() terminates on this, sendCh <- will not complete
+		r.out.done()
 		return r.ctx.Err()
-	case r.sendCh <- repSendPayload{conn, preamble, msg}:
+	case r.sendCh <- payload:
+		r.state.replied()
 		return nil
 	}
 }
 
+func (r *repWriter) flush(ctx context.Context) error {
+	return r.out.wait(ctx)
+}
+
 func (r *repWriter) run() {
 	for {
 		select {
@@ -277,6 +484,7 @@ func (r *repWriter) run() {
 				return
 			}
 			r.sendPayload(payload)
+			r.out.done()
 		}
 	}
 }
@@ -295,7 +503,8 @@ func (r *repWriter) sendPayload(payload repSendPayload) {
 }
 
 func (r *repWriter) Close() error {
-	close(r.sendCh)
+	// r.sendCh is left open: writers may still be selecting on it.
+	// the run goroutine exits once r.ctx is done.
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -314,6 +523,7 @@ type repState struct {
 	mu       sync.Mutex
 	conn     *Conn
 	preamble [][]byte // includes delimiter
+	pending  bool     // whether the last request received awaits its reply
 }
 
 func newRepState() *repState {
@@ -328,13 +538,37 @@ func (r *repState) Get() (conn *Conn, preamble [][]byte) {
 	return
 }
 
+// Set records that a request was received from conn, with the envelope pre.
 func (r *repState) Set(conn *Conn, pre [][]byte) {
 	r.mu.Lock()
 	r.conn = conn
 	r.preamble = pre
+	r.pending = true
+	r.mu.Unlock()
+}
+
+// replying returns whether the last request received awaits its reply.
+func (r *repState) replying() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending
+}
+
+// replied records that the reply to the last request was sent.
+func (r *repState) replied() {
+	r.mu.Lock()
+	r.pending = false
 	r.mu.Unlock()
 }
 
 var (
-	_ Socket = (*repSocket)(nil)
+	_ Socket           = (*repSocket)(nil)
+	_ MessageTracer    = (*repSocket)(nil)
+	_ ConnectionLister = (*repSocket)(nil)
+	_ EndpointManager  = (*repSocket)(nil)
+	_ PeerReceiver     = (*repSocket)(nil)
+	_ Monitored        = (*repSocket)(nil)
+	_ EventReceiver    = (*repSocket)(nil)
+	_ StatsReporter    = (*repSocket)(nil)
+	_ flusher          = (*repWriter)(nil)
 )
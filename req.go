@@ -5,19 +5,30 @@
 package zmq4
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 )
 
 // NewReq returns a new REQ ZeroMQ socket.
 // The returned socket value is initially unbound.
+//
+// Send and Recv must alternate, starting with Send: they fail with ErrFSM
+// otherwise, unless OptionReqRelaxed is set.
 func NewReq(ctx context.Context, opts ...Option) Socket {
 	state := &reqState{}
 	req := &reqSocket{newSocket(ctx, Req, opts...), state}
-	req.sck.r = newReqReader(req.sck.ctx, state)
-	req.sck.w = newReqWriter(req.sck.ctx, state)
+	req.sck.self = req
+	r := newReqReader(req.sck.ctx, state)
+	r.raw = req.sck.rawEnvelope
+	w := newReqWriter(req.sck.ctx, state)
+	w.raw = req.sck.rawEnvelope
+	req.sck.r = r
+	req.sck.w = w
 	return req
 }
 
@@ -32,10 +43,84 @@ func (req *reqSocket) Close() error {
 	return req.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (req *reqSocket) Context() context.Context {
+	return req.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (req *reqSocket) Done() <-chan struct{} {
+	return req.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (req *reqSocket) MessageTrace() <-chan TraceEvent {
+	return req.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (req *reqSocket) Stats() Stats {
+	return req.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (req *reqSocket) Connections() []ConnInfo {
+	return req.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (req *reqSocket) Endpoints() []string {
+	return req.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (req *reqSocket) Disconnect(ep string) error {
+	return req.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (req *reqSocket) Monitor(events EventType) <-chan SocketEvent {
+	return req.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (req *reqSocket) GetMonitorChannel() <-chan SocketEvent {
+	return req.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (req *reqSocket) MonitorDropped() uint64 {
+	return req.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (req *reqSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return req.sck.recvOrEvent(ctx, req.Recv)
+}
+
+func (req *reqSocket) events() State {
+	return req.sck.events()
+}
+
+func (req *reqSocket) watch(fn func()) func() {
+	return req.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (req *reqSocket) Send(msg Msg) error {
-	ctx, cancel := context.WithTimeout(req.sck.ctx, req.sck.Timeout())
+	if err := req.sck.checkFrames(msg); err != nil {
+		return err
+	}
+	ctx, cancel := req.sck.sendContext()
 	defer cancel()
 	return req.sck.w.write(ctx, msg)
 }
@@ -44,26 +129,82 @@ func (req *reqSocket) Send(msg Msg) error {
 // SendMulti blocks until the message can be queued or the send deadline expires.
 // The message will be sent as a multipart message.
 func (req *reqSocket) SendMulti(msg Msg) error {
+	if err := req.sck.checkFrames(msg); err != nil {
+		return err
+	}
 	msg.multipart = true
-	ctx, cancel := context.WithTimeout(req.sck.ctx, req.sck.Timeout())
+	ctx, cancel := req.sck.sendContext()
 	defer cancel()
 	return req.sck.w.write(ctx, msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (req *reqSocket) SendNB(msg Msg) error {
+	return req.sck.SendNB(msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (req *reqSocket) Flush(ctx context.Context) error {
+	return req.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (req *reqSocket) Recv() (Msg, error) {
-	ctx, cancel := context.WithCancel(req.sck.ctx)
+	return withoutConn(req.recvConn())
+}
+
+// recvConn receives a complete message, still referencing the connection it was
+// received from.
+func (req *reqSocket) recvConn() (Msg, error) {
+	ctx, cancel := req.sck.recvContext()
 	defer cancel()
 	var msg Msg
 	err := req.sck.r.read(ctx, &msg)
 	return msg, err
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (req *reqSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(req.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (req *reqSocket) RecvNB() (Msg, error) {
+	return req.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (req *reqSocket) RecvMulti() (Msg, error) {
+	return recvMulti(req.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (req *reqSocket) SetSendDeadline(t time.Time) error {
+	return req.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (req *reqSocket) SetRecvDeadline(t time.Time) error {
+	return req.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (req *reqSocket) Listen(ep string) error {
 	return req.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (req *reqSocket) Unbind(ep string) error {
+	return req.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (req *reqSocket) Dial(ep string) error {
 	return req.sck.Dial(ep)
@@ -80,6 +221,11 @@ func (req *reqSocket) Addr() net.Addr {
 	return req.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (req *reqSocket) LastEndpoint() (string, error) {
+	return req.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (req *reqSocket) GetOption(name string) (interface{}, error) {
 	return req.sck.GetOption(name)
@@ -87,35 +233,102 @@ func (req *reqSocket) GetOption(name string) (interface{}, error) {
 
 // SetOption is used to set an option for a socket.
 func (req *reqSocket) SetOption(name string, value interface{}) error {
-	return req.sck.SetOption(name, value)
+	err := req.sck.SetOption(name, value)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case OptionReqRelaxed:
+		req.state.mu.Lock()
+		req.state.relaxed = value.(bool)
+		req.state.mu.Unlock()
+	case OptionReqCorrelate:
+		req.state.mu.Lock()
+		req.state.correlate = value.(bool)
+		req.state.mu.Unlock()
+	}
+	return nil
+}
+
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (req *reqSocket) Reader() SocketReader {
+	return socketReader{req}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (req *reqSocket) Writer() SocketWriter {
+	return socketWriter{req}
 }
 
 type reqWriter struct {
+	ctx      context.Context
 	mu       sync.Mutex
 	conns    []*Conn
 	nextConn int
 	state    *reqState
+	raw      bool       // whether the application provides the envelope
+	sem      *semaphore // ready unless a connection is awaited, see awaitConn
 }
 
 func newReqWriter(ctx context.Context, state *reqState) *reqWriter {
+	sem := newSemaphore()
+	sem.enable()
 	return &reqWriter{
+		ctx:   ctx,
 		state: state,
+		sem:   sem,
+	}
+}
+
+// awaitConn makes the requests sent while the socket has no connection wait
+// for the one being dialed, instead of failing.
+func (r *reqWriter) awaitConn() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.conns) == 0 {
+		r.sem = newSemaphore()
 	}
 }
 
 func (r *reqWriter) write(ctx context.Context, msg Msg) error {
-	msg.Frames = append([][]byte{nil}, msg.Frames...)
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	id, err := r.state.begin()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	sem := r.sem
+	r.mu.Unlock()
+	sem.lock(ctx)
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !r.raw {
+		envelope := [][]byte{nil}
+		if id != nil {
+			envelope = [][]byte{id, nil}
+		}
+		msg.Frames = append(envelope, msg.Frames...)
+	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	var err error
+	if len(r.conns) == 0 {
+		// all the connections were lost since the first one was added.
+		return errNoConn
+	}
 	for i := 0; i < len(r.conns); i++ {
-		cur := i + r.nextConn%len(r.conns)
+		cur := (r.nextConn + i) % len(r.conns)
 		conn := r.conns[cur]
 		err = conn.SendMsg(msg)
 		if err == nil {
-			r.nextConn = cur + 1%len(r.conns)
-			r.state.Set(conn)
+			r.nextConn = (cur + 1) % len(r.conns)
+			r.state.Set(conn, id)
 			return nil
 		}
 	}
@@ -124,6 +337,7 @@ func (r *reqWriter) write(ctx context.Context, msg Msg) error {
 
 func (r *reqWriter) addConn(c *Conn) {
 	r.mu.Lock()
+	r.sem.enable()
 	r.conns = append(r.conns, c)
 	r.mu.Unlock()
 }
@@ -161,55 +375,190 @@ func (r *reqWriter) Close() error {
 	return err
 }
 
+// reqReader reads the replies to the requests of a REQ socket.
+// Each connection is read by its own goroutine, which holds the last message
+// received from the peer until it is read: a reply is known to be ready
+// before Recv is called.
 type reqReader struct {
+	ctx   context.Context
 	state *reqState
+	raw   bool // whether the envelope is handed to the application
+
+	mu      sync.Mutex
+	replies map[*Conn]chan Msg
 }
 
 func newReqReader(ctx context.Context, state *reqState) *reqReader {
 	return &reqReader{
-		state: state,
+		ctx:     ctx,
+		state:   state,
+		replies: make(map[*Conn]chan Msg),
 	}
 }
 
-func (r *reqReader) addConn(c *Conn) {}
-func (r *reqReader) rmConn(c *Conn)  {}
+func (r *reqReader) addConn(c *Conn) {
+	replies := make(chan Msg, 1)
+	r.mu.Lock()
+	r.replies[c] = replies
+	r.mu.Unlock()
+	go r.listen(c, replies)
+}
+
+func (r *reqReader) rmConn(c *Conn) {
+	r.mu.Lock()
+	delete(r.replies, c)
+	r.mu.Unlock()
+}
+
+// listen reads the messages of c until it fails.
+func (r *reqReader) listen(c *Conn, replies chan Msg) {
+	for {
+		msg := c.read()
+		select {
+		case replies <- msg:
+			c.queued()
+		case <-r.ctx.Done():
+			return
+		}
+		if msg.err != nil {
+			return
+		}
+	}
+}
 
 func (r *reqReader) Close() error {
 	return nil
 }
 
-func (r *reqReader) read(ctx context.Context, msg *Msg) error {
+// queued returns the number of replies ready to be read from the peer the
+// last request was sent to.
+func (r *reqReader) queued() int {
+	if replies := r.pending(); replies != nil {
+		return len(replies)
+	}
+	return 0
+}
+
+// pending returns the replies of the peer the last request was sent to.
+func (r *reqReader) pending() chan Msg {
 	curConn := r.state.Get()
 	if curConn == nil {
-		return fmt.Errorf("zmq4: no connections available")
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.replies[curConn]
+}
+
+func (r *reqReader) read(ctx context.Context, msg *Msg) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
 	}
-	*msg = curConn.read()
-	if msg.err != nil {
-		return msg.err
+	id, err := r.state.awaited()
+	if err != nil {
+		return err
 	}
-	if len(msg.Frames) > 1 {
-		msg.Frames = msg.Frames[1:]
+	replies := r.pending()
+	if replies == nil {
+		return fmt.Errorf("zmq4: no connections available")
 	}
+	for {
+		if err := recvMsg(ctx, replies, msg); err != nil {
+			return err
+		}
+		if msg.err != nil {
+			if err := r.ctx.Err(); err != nil {
+				// the connection was torn down with the socket.
+				return err
+			}
+			return msg.err
+		}
+		if r.raw {
+			break
+		}
+		if id != nil {
+			if len(msg.Frames) < 2 || !bytes.Equal(msg.Frames[0], id) {
+				// the reply to a previous request, resent since.
+				msg.Release()
+				continue
+			}
+			msg.Frames = msg.Frames[1:]
+		}
+		if len(msg.Frames) > 1 {
+			msg.Frames = msg.Frames[1:]
+		}
+		break
+	}
+	r.state.replied()
 	return nil
 }
 
+// reqState is the state of the requests of a REQ socket.
 type reqState struct {
-	mu       sync.Mutex
-	lastConn *Conn
+	mu        sync.Mutex
+	lastConn  *Conn
+	waiting   bool   // whether the reply to the last request is awaited
+	relaxed   bool   // whether a request may be sent before the reply to the last one, see OptionReqRelaxed
+	correlate bool   // whether the replies are matched with the requests by id, see OptionReqCorrelate
+	id        uint32 // id of the last request
+	idFrame   []byte // id frame of the last request, nil if not correlated
+}
+
+// begin returns the id frame of the request about to be sent, nil if the
+// requests are not correlated, or ErrFSM if the reply to the last request
+// is awaited.
+func (r *reqState) begin() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.waiting && !r.relaxed {
+		return nil, fmt.Errorf("zmq4: REQ socket cannot send a request before receiving the reply to the last one: %w", ErrFSM)
+	}
+	if !r.correlate {
+		return nil, nil
+	}
+	id := make([]byte, 4)
+	binary.BigEndian.PutUint32(id, r.id+1)
+	return id, nil
 }
 
-func (r *reqState) Set(conn *Conn) {
+// Set records that the request with the id frame id was sent to conn.
+func (r *reqState) Set(conn *Conn, id []byte) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.lastConn = conn
+	r.waiting = true
+	r.idFrame = id
+	if id != nil {
+		r.id = binary.BigEndian.Uint32(id)
+	}
+}
+
+// awaited returns the id frame of the request whose reply is awaited, nil
+// if the requests are not correlated, or ErrFSM if no reply is awaited.
+func (r *reqState) awaited() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.waiting {
+		return nil, fmt.Errorf("zmq4: REQ socket cannot receive without a request pending: %w", ErrFSM)
+	}
+	return r.idFrame, nil
+}
+
+// replied records that the reply to the last request was received.
+func (r *reqState) replied() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waiting = false
 }
 
-// Reset resets the state iff c matches the resident connection
+// Reset resets the state iff c matches the resident connection: the reply
+// of a lost peer is no longer awaited.
 func (r *reqState) Reset(c *Conn) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if r.lastConn == c {
 		r.lastConn = nil
+		r.waiting = false
 	}
 }
 
@@ -220,5 +569,15 @@ func (r *reqState) Get() *Conn {
 }
 
 var (
-	_ Socket = (*reqSocket)(nil)
+	_ Socket           = (*reqSocket)(nil)
+	_ MessageTracer    = (*reqSocket)(nil)
+	_ ConnectionLister = (*reqSocket)(nil)
+	_ EndpointManager  = (*reqSocket)(nil)
+	_ PeerReceiver     = (*reqSocket)(nil)
+	_ Monitored        = (*reqSocket)(nil)
+	_ EventReceiver    = (*reqSocket)(nil)
+	_ StatsReporter    = (*reqSocket)(nil)
+	_ rpool            = (*reqReader)(nil)
+	_ queuer           = (*reqReader)(nil)
+	_ connAwaiter      = (*reqWriter)(nil)
 )
@@ -7,18 +7,26 @@ package zmq4
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
 // NewRouter returns a new ROUTER ZeroMQ socket.
 // The returned socket value is initially unbound.
+//
+// Received messages are prefixed with the identity of the peer they come
+// from, and messages are sent to the peer identified by their first frame,
+// which is stripped. Other frames, including the empty delimiter frame of
+// requests (see NewEnvelopeMsg), are left untouched.
 func NewRouter(ctx context.Context, opts ...Option) Socket {
 	router := &routerSocket{newSocket(ctx, Router, opts...)}
-	router.sck.r = newRouterQReader(router.sck.ctx)
-	router.sck.w = newRouterMWriter(router.sck.ctx)
+	router.sck.self = router
+	router.sck.r = newRouterQReader(router.sck.ctx, router.sck.recv)
+	router.sck.w = newRouterMWriter(router.sck.ctx, router.sck.deadLetter)
 	return router
 }
 
@@ -27,15 +35,97 @@ type routerSocket struct {
 	sck *socket
 }
 
+// RouterSender is implemented by ROUTER sockets, which can frame the
+// messages they send as their peers expect.
+type RouterSender interface {
+	// SendTo sends msg to the peer with the identity, prefixed with the
+	// empty delimiter frame if the peer is a REQ or REP socket.
+	SendTo(identity []byte, msg Msg) error
+}
+
 // Close closes the open Socket
 func (router *routerSocket) Close() error {
 	return router.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (router *routerSocket) Context() context.Context {
+	return router.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (router *routerSocket) Done() <-chan struct{} {
+	return router.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (router *routerSocket) MessageTrace() <-chan TraceEvent {
+	return router.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (router *routerSocket) Stats() Stats {
+	return router.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (router *routerSocket) Connections() []ConnInfo {
+	return router.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (router *routerSocket) Endpoints() []string {
+	return router.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (router *routerSocket) Disconnect(ep string) error {
+	return router.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (router *routerSocket) Monitor(events EventType) <-chan SocketEvent {
+	return router.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (router *routerSocket) GetMonitorChannel() <-chan SocketEvent {
+	return router.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (router *routerSocket) MonitorDropped() uint64 {
+	return router.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (router *routerSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return router.sck.recvOrEvent(ctx, router.Recv)
+}
+
+func (router *routerSocket) events() State {
+	return router.sck.events()
+}
+
+func (router *routerSocket) watch(fn func()) func() {
+	return router.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (router *routerSocket) Send(msg Msg) error {
-	ctx, cancel := context.WithTimeout(router.sck.ctx, router.sck.Timeout())
+	if err := router.sck.checkFrames(msg); err != nil {
+		return err
+	}
+	ctx, cancel := router.sck.sendContext()
 	defer cancel()
 	return router.sck.w.write(ctx, msg)
 }
@@ -48,16 +138,80 @@ func (router *routerSocket) SendMulti(msg Msg) error {
 	return router.Send(msg)
 }
 
+// SendTo puts msg, routed to the peer with the identity, on the outbound
+// send queue, as Send does.
+// The envelope is built from the socket type the peer announced: REQ and
+// REP peers expect the empty delimiter frame before the body of msg, DEALER
+// and ROUTER peers do not.
+// Like Send, SendTo drops messages for unknown peers (see
+// WithDeadLetterHandler), unless OptionRouterMandatory is set.
+func (router *routerSocket) SendTo(identity []byte, msg Msg) error {
+	frames := make([][]byte, 0, 2+len(msg.Frames))
+	frames = append(frames, identity)
+	if typ, ok := router.sck.w.(*routerMWriter).peerType(identity); ok && (typ == Req || typ == Rep) {
+		frames = append(frames, []byte{})
+	}
+	frames = append(frames, msg.Frames...)
+	return router.Send(Msg{Frames: frames, multipart: msg.multipart})
+}
+
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (router *routerSocket) SendNB(msg Msg) error {
+	return router.sck.SendNB(msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (router *routerSocket) Flush(ctx context.Context) error {
+	return router.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (router *routerSocket) Recv() (Msg, error) {
 	return router.sck.Recv()
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (router *routerSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(router.sck.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (router *routerSocket) RecvNB() (Msg, error) {
+	return router.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (router *routerSocket) RecvMulti() (Msg, error) {
+	return recvMulti(router.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (router *routerSocket) SetSendDeadline(t time.Time) error {
+	return router.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (router *routerSocket) SetRecvDeadline(t time.Time) error {
+	return router.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (router *routerSocket) Listen(ep string) error {
 	return router.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (router *routerSocket) Unbind(ep string) error {
+	return router.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (router *routerSocket) Dial(ep string) error {
 	return router.sck.Dial(ep)
@@ -74,6 +228,11 @@ func (router *routerSocket) Addr() net.Addr {
 	return router.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (router *routerSocket) LastEndpoint() (string, error) {
+	return router.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (router *routerSocket) GetOption(name string) (interface{}, error) {
 	return router.sck.GetOption(name)
@@ -81,7 +240,29 @@ func (router *routerSocket) GetOption(name string) (interface{}, error) {
 
 // SetOption is used to set an option for a socket.
 func (router *routerSocket) SetOption(name string, value interface{}) error {
-	return router.sck.SetOption(name, value)
+	err := router.sck.SetOption(name, value)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case OptionRouterMandatory:
+		router.sck.w.(*routerMWriter).setMandatory(value.(bool))
+	case OptionRouterHandover:
+		router.sck.mu.Lock()
+		router.sck.handover = value.(bool)
+		router.sck.mu.Unlock()
+	}
+	return nil
+}
+
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (router *routerSocket) Reader() SocketReader {
+	return socketReader{router}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (router *routerSocket) Writer() SocketWriter {
+	return socketWriter{router}
 }
 
 // routerQReader is a queued-message reader.
@@ -91,17 +272,18 @@ type routerQReader struct {
 	mu sync.RWMutex
 	rs []*Conn
 	c  chan Msg
+	d  *dispatcher // nil if connections hand messages off directly
 
 	sem *semaphore // ready when a connection is live.
 }
 
-func newRouterQReader(ctx context.Context) *routerQReader {
-	const qrsize = 10
-	return &routerQReader{
+func newRouterQReader(ctx context.Context, cfg recvConfig) *routerQReader {
+	q := &routerQReader{
 		ctx: ctx,
-		c:   make(chan Msg, qrsize),
 		sem: newSemaphore(),
 	}
+	q.c, q.d = newRecvQueue(ctx, cfg)
+	return q
 }
 
 func (q *routerQReader) Close() error {
@@ -142,12 +324,14 @@ func (q *routerQReader) rmConn(r *Conn) {
 	}
 }
 
+func (q *routerQReader) queued() int {
+	return len(q.c)
+}
+
 func (q *routerQReader) read(ctx context.Context, msg *Msg) error {
 	q.sem.lock(ctx)
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case *msg = <-q.c:
+	if err := recvMsg(ctx, q.c, msg); err != nil {
+		return err
 	}
 	return msg.err
 }
@@ -156,6 +340,12 @@ func (q *routerQReader) listen(ctx context.Context, r *Conn) {
 	defer q.rmConn(r)
 	defer r.Close()
 
+	in := q.c
+	if q.d != nil {
+		in = q.d.add()
+		defer q.d.done(in)
+	}
+
 	id := []byte(r.Peer.Meta[sysSockID])
 	for {
 		msg := r.read()
@@ -167,22 +357,31 @@ func (q *routerQReader) listen(ctx context.Context, r *Conn) {
 				return
 			}
 			msg.Frames = append([][]byte{id}, msg.Frames...)
-			q.c <- msg
+			select {
+			case in <- msg:
+				q.d.notify()
+				r.queued()
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 }
 
 type routerMWriter struct {
-	ctx context.Context
-	mu  sync.Mutex
-	ws  []*Conn
-	sem *semaphore
+	ctx       context.Context
+	mu        sync.Mutex
+	ws        []*Conn
+	sem       *semaphore
+	dead      DeadLetterHandler
+	mandatory bool // whether messages to unknown peers fail, see OptionRouterMandatory
 }
 
-func newRouterMWriter(ctx context.Context) *routerMWriter {
+func newRouterMWriter(ctx context.Context, dead DeadLetterHandler) *routerMWriter {
 	return &routerMWriter{
-		ctx: ctx,
-		sem: newSemaphore(),
+		ctx:  ctx,
+		sem:  newSemaphore(),
+		dead: dead,
 	}
 }
 
@@ -223,29 +422,78 @@ func (mw *routerMWriter) rmConn(w *Conn) {
 	}
 }
 
+// setMandatory sets whether messages to unknown peers fail, rather than
+// being dropped.
+func (w *routerMWriter) setMandatory(v bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.mandatory = v
+}
+
+// peerType returns the socket type of the peer with the identity.
+func (w *routerMWriter) peerType(id []byte) (SocketType, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, ww := range w.ws {
+		if bytes.Equal([]byte(ww.Peer.Meta[sysSockID]), id) {
+			return SocketType(ww.Peer.Meta[sysSockType]), true
+		}
+	}
+	return "", false
+}
+
 func (w *routerMWriter) write(ctx context.Context, msg Msg) error {
-	w.sem.lock(ctx)
+	w.mu.Lock()
+	mandatory := w.mandatory
+	w.mu.Unlock()
+	if !mandatory {
+		// mandatory routing fails right away when no peer is connected.
+		w.sem.lock(ctx)
+	}
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
 	grp, _ := errgroup.WithContext(ctx)
 	w.mu.Lock()
 	id := msg.Frames[0]
 	dmsg := NewMsgFrom(msg.Frames[1:]...)
+	routed := false
 	for i := range w.ws {
 		ww := w.ws[i]
 		pid := []byte(ww.Peer.Meta[sysSockID])
 		if !bytes.Equal(pid, id) {
 			continue
 		}
+		routed = true
 		grp.Go(func() error {
 			return ww.SendMsg(dmsg)
 		})
 	}
 	err := grp.Wait()
 	w.mu.Unlock()
+	if !routed && mandatory {
+		return fmt.Errorf("zmq4: no peer with identity %q: %w", id, ErrHostUnreachable)
+	}
+	if !routed && w.dead != nil {
+		// outside of the lock: the handler may send on the socket.
+		w.dead(id, dmsg)
+	}
+	if err != nil && w.ctx.Err() != nil {
+		return w.ctx.Err()
+	}
 	return err
 }
 
 var (
-	_ rpool  = (*routerQReader)(nil)
-	_ wpool  = (*routerMWriter)(nil)
-	_ Socket = (*routerSocket)(nil)
+	_ rpool            = (*routerQReader)(nil)
+	_ wpool            = (*routerMWriter)(nil)
+	_ Socket           = (*routerSocket)(nil)
+	_ MessageTracer    = (*routerSocket)(nil)
+	_ ConnectionLister = (*routerSocket)(nil)
+	_ EndpointManager  = (*routerSocket)(nil)
+	_ PeerReceiver     = (*routerSocket)(nil)
+	_ RouterSender     = (*routerSocket)(nil)
+	_ Monitored        = (*routerSocket)(nil)
+	_ EventReceiver    = (*routerSocket)(nil)
+	_ StatsReporter    = (*routerSocket)(nil)
 )
@@ -5,10 +5,15 @@
 package zmq4
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
 
+// ErrAuthFailed is returned, wrapped, when the peer rejected the security
+// handshake with an ERROR command, e.g. because of invalid credentials.
+var ErrAuthFailed = errors.New("zmq4: authentication failed")
+
 // Security is an interface for ZMTP security mechanisms
 type Security interface {
 	// Type returns the security mechanism type.
@@ -29,6 +34,21 @@ type Security interface {
 	Decrypt(w io.Writer, data []byte) (int, error)
 }
 
+// FrameCipher boxes the frames exchanged over a connection once its security
+// handshake completed, for mechanisms such as CURVE that send each frame as
+// an encrypted MESSAGE command of its own.
+// Security mechanisms install it with Conn.SetFrameCipher during their
+// handshake.
+type FrameCipher interface {
+	// Seal returns the wire frame boxing frame, with its more and command
+	// flags. Seal is called for each frame, in the order they are written.
+	Seal(frame []byte, more, command bool) ([]byte, error)
+
+	// Open returns the frame boxed in a wire frame sealed by the peer,
+	// with its more and command flags.
+	Open(wire []byte) (frame []byte, more, command bool, err error)
+}
+
 // SecurityType denotes types of ZMTP security mechanisms
 type SecurityType string
 
@@ -68,9 +88,15 @@ func (nullSecurity) Handshake(conn *Conn, server bool) error {
 		return fmt.Errorf("zmq4: could not marshal metadata: %w", err)
 	}
 
-	err = conn.SendCmd(CmdReady, raw)
-	if err != nil {
-		return fmt.Errorf("zmq4: could not send metadata to peer: %w", err)
+	// servers only answer the READY of the client once it has been
+	// authenticated. Peers that both claim the server role exchange their
+	// READY unauthenticated, as the NULL mechanism lets them.
+	auth := server && !conn.Peer.Server
+	if !auth {
+		err = conn.SendCmd(CmdReady, raw)
+		if err != nil {
+			return fmt.Errorf("zmq4: could not send metadata to peer: %w", err)
+		}
 	}
 
 	cmd, err := conn.RecvCmd()
@@ -78,10 +104,30 @@ func (nullSecurity) Handshake(conn *Conn, server bool) error {
 		return fmt.Errorf("zmq4: could not recv metadata from peer: %w", err)
 	}
 
-	if cmd.Name != CmdReady {
+	switch cmd.Name {
+	case CmdReady:
+	case CmdError:
+		reason := cmd.Body
+		if len(reason) > 0 && int(reason[0]) == len(reason)-1 {
+			reason = reason[1:]
+		}
+		return fmt.Errorf("zmq4: peer rejected the handshake (%q): %w", reason, ErrAuthFailed)
+	default:
 		return ErrBadCmd
 	}
 
+	if auth {
+		if err := conn.Authenticate(); err != nil {
+			const reason = "authentication failed"
+			_ = conn.SendCmd(CmdError, append([]byte{byte(len(reason))}, reason...))
+			return err
+		}
+		err = conn.SendCmd(CmdReady, raw)
+		if err != nil {
+			return fmt.Errorf("zmq4: could not send metadata to peer: %w", err)
+		}
+	}
+
 	err = conn.Peer.Meta.UnmarshalZMTP(cmd.Body)
 	if err != nil {
 		return fmt.Errorf("zmq4: could not unmarshal peer metadata: %w", err)
@@ -0,0 +1,427 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package curve provides the ZeroMQ CURVE security mechanism as specified by:
+// https://rfc.zeromq.org/spec:25/ZMTP-CURVE/
+// https://rfc.zeromq.org/spec:26/CURVEZMQ/
+package curve
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/luxfi/zmq/v4"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	keySize     = 32
+	helloSize   = 2 + 72 + keySize + 8 + 64 + box.Overhead
+	cookieSize  = 16 + 2*keySize + secretbox.Overhead
+	welcomeSize = 16 + keySize + cookieSize + box.Overhead
+	vouchSize   = 16 + 2*keySize + box.Overhead
+)
+
+// security implements the CURVE security mechanism.
+type security struct {
+	server    bool
+	public    [keySize]byte // long-term public key of this end
+	secret    [keySize]byte // long-term secret key of this end
+	serverKey [keySize]byte // long-term public key of the server, for clients
+}
+
+// NewServerSecurity returns a value that implements the CURVE security
+// mechanism for sockets acting as CURVE servers, with their long-term secret
+// key.
+//
+// Keys are either 32 raw bytes or their 40 characters Z85 encoding, as
+// produced by libzmq and zmq4.NewCurveKeypair.
+func NewServerSecurity(secret string) (zmq4.Security, error) {
+	sec := &security{server: true}
+	if err := parseKey(&sec.secret, secret); err != nil {
+		return nil, fmt.Errorf("security/curve: invalid server secret key: %w", err)
+	}
+	pub, err := curve25519.X25519(sec.secret[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("security/curve: invalid server secret key: %w", err)
+	}
+	copy(sec.public[:], pub)
+	return sec, nil
+}
+
+// NewClientSecurity returns a value that implements the CURVE security
+// mechanism for sockets acting as CURVE clients, with the long-term public
+// key of the server they connect to, and their own long-term key pair.
+//
+// Keys are either 32 raw bytes or their 40 characters Z85 encoding, as
+// produced by libzmq and zmq4.NewCurveKeypair.
+func NewClientSecurity(serverPublic, public, secret string) (zmq4.Security, error) {
+	sec := &security{server: false}
+	for _, k := range []struct {
+		dst  *[keySize]byte
+		src  string
+		name string
+	}{
+		{&sec.serverKey, serverPublic, "server public"},
+		{&sec.public, public, "client public"},
+		{&sec.secret, secret, "client secret"},
+	} {
+		if err := parseKey(k.dst, k.src); err != nil {
+			return nil, fmt.Errorf("security/curve: invalid %s key: %w", k.name, err)
+		}
+	}
+	return sec, nil
+}
+
+// parseKey decodes a raw or Z85-encoded key into dst.
+func parseKey(dst *[keySize]byte, key string) error {
+	switch len(key) {
+	case keySize:
+		copy(dst[:], key)
+	case keySize * 5 / 4:
+		raw, err := zmq4.Z85decode(key)
+		if err != nil {
+			return err
+		}
+		copy(dst[:], raw)
+	default:
+		return fmt.Errorf("invalid key length %d", len(key))
+	}
+	return nil
+}
+
+// Type returns the security mechanism type.
+func (security) Type() zmq4.SecurityType {
+	return zmq4.CurveSecurity
+}
+
+// Handshake implements the ZMTP security handshake according to
+// this security mechanism.
+// see:
+//
+//	https://rfc.zeromq.org/spec:23/ZMTP/
+//	https://rfc.zeromq.org/spec:25/ZMTP-CURVE/
+//	https://rfc.zeromq.org/spec:26/CURVEZMQ/
+func (sec *security) Handshake(conn *zmq4.Conn, server bool) error {
+	switch {
+	case server != sec.server && server:
+		return fmt.Errorf("security/curve: CURVE client cannot act as the ZMTP server")
+	case server != sec.server:
+		return fmt.Errorf("security/curve: CURVE server cannot act as the ZMTP client")
+	case server:
+		return sec.serverHandshake(conn)
+	default:
+		return sec.clientHandshake(conn)
+	}
+}
+
+func (sec *security) clientHandshake(conn *zmq4.Conn) error {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("security/curve: could not generate transient key pair: %w", err)
+	}
+	var nonce uint64 = 1
+
+	hello := make([]byte, 0, helloSize)
+	hello = append(hello, 1, 0) // version 1.0
+	hello = append(hello, make([]byte, 72)...)
+	hello = append(hello, pub[:]...)
+	hello = binary.BigEndian.AppendUint64(hello, nonce)
+	hello = box.Seal(hello, make([]byte, 64), shortNonce("CurveZMQHELLO---", nonce), &sec.serverKey, priv)
+	nonce++
+	if err := conn.SendCmd(zmq4.CmdHello, hello); err != nil {
+		return fmt.Errorf("security/curve: could not send HELLO to server: %w", err)
+	}
+
+	welcome, err := recvCmd(conn, zmq4.CmdWelcome)
+	if err != nil {
+		return err
+	}
+	if len(welcome) != welcomeSize {
+		return fmt.Errorf("security/curve: invalid WELCOME size %d", len(welcome))
+	}
+	content, ok := box.Open(nil, welcome[16:], longNonce("WELCOME-", welcome[:16]), &sec.serverKey, priv)
+	if !ok {
+		return fmt.Errorf("security/curve: could not open WELCOME box")
+	}
+	var serverTransient [keySize]byte
+	copy(serverTransient[:], content[:keySize])
+	cookie := content[keySize:]
+
+	var key [keySize]byte
+	box.Precompute(&key, &serverTransient, priv)
+
+	vouchNonce := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, vouchNonce); err != nil {
+		return fmt.Errorf("security/curve: could not generate vouch nonce: %w", err)
+	}
+	vouch := append(vouchNonce, box.Seal(nil, append(pub[:], sec.serverKey[:]...), longNonce("VOUCH---", vouchNonce), &serverTransient, &sec.secret)...)
+
+	meta, err := conn.Meta.MarshalZMTP()
+	if err != nil {
+		return fmt.Errorf("security/curve: could not serialize metadata: %w", err)
+	}
+	plain := make([]byte, 0, keySize+vouchSize+len(meta))
+	plain = append(plain, sec.public[:]...)
+	plain = append(plain, vouch...)
+	plain = append(plain, meta...)
+
+	initiate := make([]byte, 0, cookieSize+8+box.Overhead+len(plain))
+	initiate = append(initiate, cookie...)
+	initiate = binary.BigEndian.AppendUint64(initiate, nonce)
+	initiate = box.SealAfterPrecomputation(initiate, plain, shortNonce("CurveZMQINITIATE", nonce), &key)
+	nonce++
+	if err := conn.SendCmd(zmq4.CmdInitiate, initiate); err != nil {
+		return fmt.Errorf("security/curve: could not send INITIATE to server: %w", err)
+	}
+
+	ready, err := recvCmd(conn, zmq4.CmdReady)
+	if err != nil {
+		return err
+	}
+	if len(ready) < 8+box.Overhead {
+		return fmt.Errorf("security/curve: invalid READY size %d", len(ready))
+	}
+	peerNonce := binary.BigEndian.Uint64(ready)
+	meta, ok = box.OpenAfterPrecomputation(nil, ready[8:], shortNonce("CurveZMQREADY---", peerNonce), &key)
+	if !ok {
+		return fmt.Errorf("security/curve: could not open READY box")
+	}
+	if err := conn.Peer.Meta.UnmarshalZMTP(meta); err != nil {
+		return fmt.Errorf("security/curve: could not unmarshal peer metadata: %w", err)
+	}
+
+	conn.SetFrameCipher(&cipher{
+		key:   key,
+		send:  "CurveZMQMESSAGEC",
+		recv:  "CurveZMQMESSAGES",
+		nonce: nonce,
+		peer:  peerNonce,
+	})
+	return nil
+}
+
+func (sec *security) serverHandshake(conn *zmq4.Conn) error {
+	hello, err := recvCmd(conn, zmq4.CmdHello)
+	if err != nil {
+		return err
+	}
+	if len(hello) != helloSize {
+		return sendError(conn, fmt.Errorf("security/curve: invalid HELLO size %d", len(hello)))
+	}
+	if hello[0] != 1 || hello[1] != 0 {
+		return sendError(conn, fmt.Errorf("security/curve: unsupported CURVE version %d.%d", hello[0], hello[1]))
+	}
+	var clientTransient [keySize]byte
+	copy(clientTransient[:], hello[74:])
+	helloNonce := binary.BigEndian.Uint64(hello[74+keySize:])
+	if _, ok := box.Open(nil, hello[74+keySize+8:], shortNonce("CurveZMQHELLO---", helloNonce), &clientTransient, &sec.secret); !ok {
+		return sendError(conn, fmt.Errorf("security/curve: could not open HELLO box"))
+	}
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("security/curve: could not generate transient key pair: %w", err)
+	}
+
+	// the cookie lets a stateless server recover its transient key from the
+	// INITIATE command: it is only checked here.
+	var (
+		cookieKey [keySize]byte
+		nonces    = make([]byte, 32)
+	)
+	if _, err := io.ReadFull(rand.Reader, cookieKey[:]); err != nil {
+		return fmt.Errorf("security/curve: could not generate cookie key: %w", err)
+	}
+	if _, err := io.ReadFull(rand.Reader, nonces); err != nil {
+		return fmt.Errorf("security/curve: could not generate nonces: %w", err)
+	}
+	cookieNonce, welcomeNonce := nonces[:16], nonces[16:]
+	cookie := append(cookieNonce, secretbox.Seal(nil, append(clientTransient[:], priv[:]...), longNonce("COOKIE--", cookieNonce), &cookieKey)...)
+
+	welcome := append(welcomeNonce, box.Seal(nil, append(pub[:], cookie...), longNonce("WELCOME-", welcomeNonce), &clientTransient, &sec.secret)...)
+	if err := conn.SendCmd(zmq4.CmdWelcome, welcome); err != nil {
+		return fmt.Errorf("security/curve: could not send WELCOME to client: %w", err)
+	}
+
+	initiate, err := recvCmd(conn, zmq4.CmdInitiate)
+	if err != nil {
+		return err
+	}
+	if len(initiate) < cookieSize+8+box.Overhead+keySize+vouchSize {
+		return sendError(conn, fmt.Errorf("security/curve: invalid INITIATE size %d", len(initiate)))
+	}
+	content, ok := secretbox.Open(nil, initiate[16:cookieSize], longNonce("COOKIE--", initiate[:16]), &cookieKey)
+	if !ok || !bytes.Equal(content[:keySize], clientTransient[:]) || !bytes.Equal(content[keySize:], priv[:]) {
+		return sendError(conn, fmt.Errorf("security/curve: invalid INITIATE cookie"))
+	}
+
+	var key [keySize]byte
+	box.Precompute(&key, &clientTransient, priv)
+	peerNonce := binary.BigEndian.Uint64(initiate[cookieSize:])
+	if peerNonce <= helloNonce {
+		return sendError(conn, fmt.Errorf("security/curve: invalid INITIATE nonce"))
+	}
+	plain, ok := box.OpenAfterPrecomputation(nil, initiate[cookieSize+8:], shortNonce("CurveZMQINITIATE", peerNonce), &key)
+	if !ok {
+		return sendError(conn, fmt.Errorf("security/curve: could not open INITIATE box"))
+	}
+
+	var clientKey [keySize]byte
+	copy(clientKey[:], plain[:keySize])
+	vouch := plain[keySize : keySize+vouchSize]
+	vouched, ok := box.Open(nil, vouch[16:], longNonce("VOUCH---", vouch[:16]), &clientKey, priv)
+	if !ok || !bytes.Equal(vouched[:keySize], clientTransient[:]) || !bytes.Equal(vouched[keySize:], sec.public[:]) {
+		return sendError(conn, fmt.Errorf("security/curve: invalid INITIATE vouch"))
+	}
+	if err := conn.Authenticate(clientKey[:]); err != nil {
+		return sendError(conn, fmt.Errorf("security/curve: could not authenticate client: %w", err))
+	}
+	if err := conn.Peer.Meta.UnmarshalZMTP(plain[keySize+vouchSize:]); err != nil {
+		return sendError(conn, fmt.Errorf("security/curve: could not unmarshal peer metadata: %w", err))
+	}
+
+	meta, err := conn.Meta.MarshalZMTP()
+	if err != nil {
+		return sendError(conn, fmt.Errorf("security/curve: could not serialize metadata: %w", err))
+	}
+	var nonce uint64 = 1
+	ready := binary.BigEndian.AppendUint64(make([]byte, 0, 8+box.Overhead+len(meta)), nonce)
+	ready = box.SealAfterPrecomputation(ready, meta, shortNonce("CurveZMQREADY---", nonce), &key)
+	nonce++
+	if err := conn.SendCmd(zmq4.CmdReady, ready); err != nil {
+		return fmt.Errorf("security/curve: could not send READY to client: %w", err)
+	}
+
+	conn.SetFrameCipher(&cipher{
+		key:   key,
+		send:  "CurveZMQMESSAGES",
+		recv:  "CurveZMQMESSAGEC",
+		nonce: nonce,
+		peer:  peerNonce,
+	})
+	return nil
+}
+
+// recvCmd receives the body of the named command, reporting the reason of
+// an ERROR command sent instead.
+func recvCmd(conn *zmq4.Conn, name string) ([]byte, error) {
+	cmd, err := conn.RecvCmd()
+	if err != nil {
+		return nil, fmt.Errorf("security/curve: could not receive %s: %w", name, err)
+	}
+	switch cmd.Name {
+	case name:
+		return cmd.Body, nil
+	case zmq4.CmdError:
+		reason := cmd.Body
+		if len(reason) > 0 && int(reason[0]) == len(reason)-1 {
+			reason = reason[1:]
+		}
+		return nil, fmt.Errorf("security/curve: peer rejected the handshake (%q): %w", reason, zmq4.ErrAuthFailed)
+	default:
+		return nil, fmt.Errorf("security/curve: expected %s command, got %s", name, cmd.Name)
+	}
+}
+
+// sendError tells the client why its handshake failed, and returns err.
+func sendError(conn *zmq4.Conn, err error) error {
+	const reason = "invalid handshake"
+	_ = conn.SendCmd(zmq4.CmdError, append([]byte{byte(len(reason))}, reason...))
+	return err
+}
+
+// shortNonce returns the nonce made of the 16 bytes prefix and the 8 bytes
+// counter n.
+func shortNonce(prefix string, n uint64) *[24]byte {
+	var nonce [24]byte
+	copy(nonce[:], prefix)
+	binary.BigEndian.PutUint64(nonce[16:], n)
+	return &nonce
+}
+
+// longNonce returns the nonce made of the 8 bytes prefix and 16 random bytes.
+func longNonce(prefix string, random []byte) *[24]byte {
+	var nonce [24]byte
+	copy(nonce[:], prefix)
+	copy(nonce[8:], random)
+	return &nonce
+}
+
+// Encrypt writes the encrypted form of data to w.
+// The frames exchanged after the handshake are boxed by the frame cipher
+// the handshake installs on the connection: Encrypt only passes the
+// handshake commands through.
+func (security) Encrypt(w io.Writer, data []byte) (int, error) {
+	return w.Write(data)
+}
+
+// Decrypt writes the decrypted form of data to w.
+// See Encrypt.
+func (security) Decrypt(w io.Writer, data []byte) (int, error) {
+	return w.Write(data)
+}
+
+// cipher boxes the frames of a connection as CURVE MESSAGE commands.
+type cipher struct {
+	key   [keySize]byte // shared key of the transient key pairs
+	send  string        // nonce prefix of the boxes sent
+	recv  string        // nonce prefix of the boxes received
+	nonce uint64        // short nonce of the next box sent
+	peer  uint64        // short nonce of the last box received
+}
+
+const (
+	flagMore    = 0x01
+	flagCommand = 0x02
+)
+
+// messageCmd is the name of the MESSAGE command, prefixed by its length.
+const messageCmd = "\x07MESSAGE"
+
+func (c *cipher) Seal(frame []byte, more, command bool) ([]byte, error) {
+	var flags byte
+	if more {
+		flags |= flagMore
+	}
+	if command {
+		flags |= flagCommand
+	}
+	plain := make([]byte, 1+len(frame))
+	plain[0] = flags
+	copy(plain[1:], frame)
+
+	out := make([]byte, 0, len(messageCmd)+8+box.Overhead+len(plain))
+	out = append(out, messageCmd...)
+	out = binary.BigEndian.AppendUint64(out, c.nonce)
+	out = box.SealAfterPrecomputation(out, plain, shortNonce(c.send, c.nonce), &c.key)
+	c.nonce++
+	return out, nil
+}
+
+func (c *cipher) Open(wire []byte) ([]byte, bool, bool, error) {
+	if len(wire) < len(messageCmd)+8+box.Overhead+1 || string(wire[:len(messageCmd)]) != messageCmd {
+		return nil, false, false, fmt.Errorf("security/curve: invalid MESSAGE command")
+	}
+	n := binary.BigEndian.Uint64(wire[len(messageCmd):])
+	if n <= c.peer {
+		return nil, false, false, fmt.Errorf("security/curve: replayed MESSAGE nonce %d", n)
+	}
+	plain, ok := box.OpenAfterPrecomputation(nil, wire[len(messageCmd)+8:], shortNonce(c.recv, n), &c.key)
+	if !ok {
+		return nil, false, false, fmt.Errorf("security/curve: could not open MESSAGE box")
+	}
+	c.peer = n
+	return plain[1:], plain[0]&flagMore != 0, plain[0]&flagCommand != 0, nil
+}
+
+var (
+	_ zmq4.Security    = (*security)(nil)
+	_ zmq4.FrameCipher = (*cipher)(nil)
+)
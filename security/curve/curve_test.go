@@ -0,0 +1,375 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curve_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+	"github.com/luxfi/zmq/v4/security/curve"
+	"golang.org/x/sync/errgroup"
+)
+
+// newKeys returns a Z85-encoded CURVE key pair.
+func newKeys(t *testing.T) (public, secret string) {
+	t.Helper()
+	public, secret, err := zmq4.NewCurveKeypair()
+	if err != nil {
+		t.Fatalf("could not generate key pair: %+v", err)
+	}
+	return public, secret
+}
+
+func TestHandshakeReqRep(t *testing.T) {
+	serverPub, serverSec := newKeys(t)
+	clientPub, clientSec := newKeys(t)
+
+	ssec, err := curve.NewServerSecurity(serverSec)
+	if err != nil {
+		t.Fatalf("could not create server security: %+v", err)
+	}
+	csec, err := curve.NewClientSecurity(serverPub, clientPub, clientSec)
+	if err != nil {
+		t.Fatalf("could not create client security: %+v", err)
+	}
+	if got, want := csec.Type(), zmq4.CurveSecurity; got != want {
+		t.Fatalf("got=%v, want=%v", got, want)
+	}
+
+	ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+
+	rep := zmq4.NewRep(ctx, zmq4.WithSecurity(ssec))
+	defer rep.Close()
+	req := zmq4.NewReq(ctx, zmq4.WithSecurity(csec))
+	defer req.Close()
+
+	if err := rep.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := req.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	var grp errgroup.Group
+	grp.Go(func() error {
+		for i := 0; i < 3; i++ {
+			msg, err := rep.Recv()
+			if err != nil {
+				return fmt.Errorf("could not recv request: %w", err)
+			}
+			if err := rep.Send(zmq4.NewMsgFrom(append(msg.Frames, []byte("pong"))...)); err != nil {
+				return fmt.Errorf("could not send reply: %w", err)
+			}
+		}
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		// a large frame checks the long frame encoding of the boxes.
+		big := bytes.Repeat([]byte{byte(i)}, 1000)
+		if err := req.Send(zmq4.NewMsgFrom([]byte("ping"), big)); err != nil {
+			t.Fatalf("could not send request: %+v", err)
+		}
+		msg, err := req.Recv()
+		if err != nil {
+			t.Fatalf("could not recv reply: %+v", err)
+		}
+		want := zmq4.NewMsgFrom([]byte("ping"), big, []byte("pong"))
+		if got, want := msg.String(), want.String(); got != want {
+			t.Fatalf("invalid reply:\ngot= %q\nwant=%q", got, want)
+		}
+	}
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("error: %+v", err)
+	}
+
+	conns := req.(zmq4.ConnectionLister).Connections()
+	if len(conns) != 1 || conns[0].Mechanism != zmq4.CurveSecurity {
+		t.Fatalf("invalid connections: %+v", conns)
+	}
+	if got, want := conns[0].PeerType, zmq4.Rep; got != want {
+		t.Fatalf("invalid peer type from the READY metadata: got=%v, want=%v", got, want)
+	}
+}
+
+func TestHandshakeWrongServerKey(t *testing.T) {
+	_, serverSec := newKeys(t)
+	otherPub, _ := newKeys(t)
+	clientPub, clientSec := newKeys(t)
+
+	ssec, err := curve.NewServerSecurity(serverSec)
+	if err != nil {
+		t.Fatalf("could not create server security: %+v", err)
+	}
+	csec, err := curve.NewClientSecurity(otherPub, clientPub, clientSec)
+	if err != nil {
+		t.Fatalf("could not create client security: %+v", err)
+	}
+
+	ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+
+	rep := zmq4.NewRep(ctx, zmq4.WithSecurity(ssec))
+	defer rep.Close()
+	req := zmq4.NewReq(ctx, zmq4.WithSecurity(csec))
+	defer req.Close()
+
+	if err := rep.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	err = req.Dial(ep)
+	if err == nil {
+		t.Fatalf("expected the handshake with the wrong server key to fail")
+	}
+	if !errors.Is(err, zmq4.ErrAuthFailed) {
+		t.Fatalf("invalid error: got=%+v, want=%v", err, zmq4.ErrAuthFailed)
+	}
+}
+
+func TestHandshakeAuthCurveKeys(t *testing.T) {
+	serverPub, serverSec := newKeys(t)
+	clientPub, clientSec := newKeys(t)
+
+	if err := zmq4.AuthStart(); err != nil {
+		t.Fatalf("could not start authentication: %+v", err)
+	}
+	defer zmq4.AuthStop()
+
+	ssec, err := curve.NewServerSecurity(serverSec)
+	if err != nil {
+		t.Fatalf("could not create server security: %+v", err)
+	}
+	csec, err := curve.NewClientSecurity(serverPub, clientPub, clientSec)
+	if err != nil {
+		t.Fatalf("could not create client security: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		setup func()
+		allow bool
+	}{
+		{name: "unknown", setup: func() {}, allow: false},
+		{name: "added", setup: func() { zmq4.AuthCurveAdd("curve", clientPub) }, allow: true},
+		{name: "removed", setup: func() { zmq4.AuthCurveRemove("curve", clientPub) }, allow: false},
+		{name: "any", setup: func() { zmq4.AuthCurveAdd("*", zmq4.CurveAllowAny) }, allow: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup()
+
+			ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+			defer timeout()
+
+			ep := must(EndPoint("tcp"))
+
+			rep := zmq4.NewRep(ctx, zmq4.WithSecurity(ssec), zmq4.WithZAPDomain("curve"))
+			defer rep.Close()
+			req := zmq4.NewReq(ctx, zmq4.WithSecurity(csec), zmq4.WithDialerMaxRetries(0))
+			defer req.Close()
+
+			if err := rep.Listen(ep); err != nil {
+				t.Fatalf("could not listen: %+v", err)
+			}
+			err := req.Dial(ep)
+			switch {
+			case tc.allow && err != nil:
+				t.Fatalf("could not dial: %+v", err)
+			case !tc.allow && !errors.Is(err, zmq4.ErrAuthFailed):
+				t.Fatalf("invalid error: got=%+v, want=%v", err, zmq4.ErrAuthFailed)
+			}
+		})
+	}
+}
+
+func TestHandshakeDowngrade(t *testing.T) {
+	serverPub, serverSec := newKeys(t)
+	clientPub, clientSec := newKeys(t)
+	ssec, err := curve.NewServerSecurity(serverSec)
+	if err != nil {
+		t.Fatalf("could not create server security: %+v", err)
+	}
+	csec, err := curve.NewClientSecurity(serverPub, clientPub, clientSec)
+	if err != nil {
+		t.Fatalf("could not create client security: %+v", err)
+	}
+
+	ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+
+	pull := zmq4.NewPull(ctx, zmq4.WithSecurity(ssec))
+	defer pull.Close()
+	events := pull.(zmq4.Monitored).Monitor(zmq4.EventHandshakeFailed)
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	id := zmq4.WithID(zmq4.SocketIdentity("peer"))
+	push := zmq4.NewPush(ctx, zmq4.WithSecurity(csec), id)
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := push.Send(zmq4.NewMsgString("secured")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	push.Close()
+
+	// the same peer reconnecting without security is rejected.
+	null := zmq4.NewPush(ctx, id, zmq4.WithDialerMaxRetries(0))
+	defer null.Close()
+	if err := null.Dial(ep); err == nil {
+		t.Fatalf("expected the NULL reconnection to fail")
+	}
+	for {
+		select {
+		case ev := <-events:
+			if ev.Err != nil && strings.Contains(ev.Err.Error(), "downgrade rejected") {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatalf("downgrade not reported")
+		}
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	pub, sec := newKeys(t)
+	for _, tc := range []struct {
+		name string
+		err  error
+	}{
+		{"short", func() error { _, err := curve.NewServerSecurity("short"); return err }()},
+		{"z85", func() error { _, err := curve.NewServerSecurity(strings.Repeat("~", 40)); return err }()},
+		{"client", func() error { _, err := curve.NewClientSecurity(pub, pub, sec[:39]); return err }()},
+	} {
+		if tc.err == nil {
+			t.Fatalf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+// recorder records the bytes written to a connection.
+type recorder struct {
+	net.Conn
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	r.buf.Write(p)
+	r.mu.Unlock()
+	return r.Conn.Write(p)
+}
+
+func TestEncryptedWire(t *testing.T) {
+	serverPub, serverSec := newKeys(t)
+	clientPub, clientSec := newKeys(t)
+	ssec, err := curve.NewServerSecurity(serverSec)
+	if err != nil {
+		t.Fatalf("could not create server security: %+v", err)
+	}
+	csec, err := curve.NewClientSecurity(serverPub, clientPub, clientSec)
+	if err != nil {
+		t.Fatalf("could not create client security: %+v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	defer l.Close()
+
+	var (
+		grp    errgroup.Group
+		server *zmq4.Conn
+		wire   *recorder
+	)
+	grp.Go(func() error {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		server, err = zmq4.Open(conn, ssec, zmq4.Pull, nil, true, nil)
+		return err
+	})
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	wire = &recorder{Conn: conn}
+	client, err := zmq4.Open(wire, csec, zmq4.Push, nil, false, nil)
+	if err != nil {
+		t.Fatalf("could not open client connection: %+v", err)
+	}
+	defer client.Close()
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("could not open server connection: %+v", err)
+	}
+	defer server.Close()
+
+	secret := []byte("the secret payload")
+	if err := client.SendMsg(zmq4.NewMsgFrom(secret, []byte("second frame"))); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := server.RecvMsg()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if len(msg.Frames) != 2 || !bytes.Equal(msg.Frames[0], secret) || string(msg.Frames[1]) != "second frame" {
+		t.Fatalf("invalid message: %q", msg.Frames)
+	}
+
+	wire.mu.Lock()
+	defer wire.mu.Unlock()
+	if bytes.Contains(wire.buf.Bytes(), secret) {
+		t.Fatalf("payload sent in clear text")
+	}
+	if !bytes.Contains(wire.buf.Bytes(), []byte("\x07MESSAGE")) {
+		t.Fatalf("payload not sent as MESSAGE commands")
+	}
+}
+
+func must(str string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+func EndPoint(transport string) (string, error) {
+	switch transport {
+	case "tcp":
+		addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+		if err != nil {
+			return "", fmt.Errorf("could not resolve TCP address: %w", err)
+		}
+		l, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return "", fmt.Errorf("could not listen to TCP addr=%q: %w", addr, err)
+		}
+		defer l.Close()
+		return fmt.Sprintf("tcp://%s", l.Addr()), nil
+	default:
+		panic("invalid transport: [" + transport + "]")
+	}
+}
@@ -38,9 +38,15 @@ func (security) Handshake(conn *zmq4.Conn, server bool) error {
 		return fmt.Errorf("security/null: could not marshal metadata: %w", err)
 	}
 
-	err = conn.SendCmd(zmq4.CmdReady, raw)
-	if err != nil {
-		return fmt.Errorf("security/null: could not send metadata to peer: %w", err)
+	// servers only answer the READY of the client once it has been
+	// authenticated. Peers that both claim the server role exchange their
+	// READY unauthenticated, as the NULL mechanism lets them.
+	auth := server && !conn.Peer.Server
+	if !auth {
+		err = conn.SendCmd(zmq4.CmdReady, raw)
+		if err != nil {
+			return fmt.Errorf("security/null: could not send metadata to peer: %w", err)
+		}
 	}
 
 	cmd, err := conn.RecvCmd()
@@ -48,10 +54,30 @@ func (security) Handshake(conn *zmq4.Conn, server bool) error {
 		return fmt.Errorf("security/null: could not recv metadata from peer: %w", err)
 	}
 
-	if cmd.Name != zmq4.CmdReady {
+	switch cmd.Name {
+	case zmq4.CmdReady:
+	case zmq4.CmdError:
+		reason := cmd.Body
+		if len(reason) > 0 && int(reason[0]) == len(reason)-1 {
+			reason = reason[1:]
+		}
+		return fmt.Errorf("security/null: peer rejected the handshake (%q): %w", reason, zmq4.ErrAuthFailed)
+	default:
 		return zmq4.ErrBadCmd
 	}
 
+	if auth {
+		if err := conn.Authenticate(); err != nil {
+			const reason = "authentication failed"
+			_ = conn.SendCmd(zmq4.CmdError, append([]byte{byte(len(reason))}, reason...))
+			return fmt.Errorf("security/null: could not authenticate client: %w", err)
+		}
+		err = conn.SendCmd(zmq4.CmdReady, raw)
+		if err != nil {
+			return fmt.Errorf("security/null: could not send metadata to peer: %w", err)
+		}
+	}
+
 	err = conn.Peer.Meta.UnmarshalZMTP(cmd.Body)
 	if err != nil {
 		return fmt.Errorf("security/null: could not unmarshal peer metadata: %w", err)
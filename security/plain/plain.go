@@ -17,11 +17,34 @@ import (
 type security struct {
 	user []byte
 	pass []byte
+
+	client bool                         // whether the security can act as a PLAIN client
+	server bool                         // whether the security can act as a PLAIN server
+	verify func(user, pass string) bool // validates the credentials of clients, if set
 }
 
-// Security returns a value that implements the PLAIN security mechanism
+// Security returns a value that implements the PLAIN security mechanism.
+// As a client, it sends the user/pass credentials. As a server, it accepts
+// any credentials.
 func Security(user, pass string) zmq4.Security {
-	return &security{[]byte(user), []byte(pass)}
+	return &security{user: []byte(user), pass: []byte(pass), client: true, server: true}
+}
+
+// NewServerSecurity returns a value that implements the PLAIN security
+// mechanism for sockets acting as PLAIN servers: the handshakes of the
+// clients whose credentials verify rejects fail with an ERROR command, and
+// their connections are closed.
+func NewServerSecurity(verify func(user, pass string) bool) zmq4.Security {
+	return &security{server: true, verify: verify}
+}
+
+// NewClientSecurity returns a value that implements the PLAIN security
+// mechanism for sockets acting as PLAIN clients, with the user/pass
+// credentials.
+// Dial fails with an error wrapping zmq4.ErrAuthFailed if the server rejects
+// the credentials.
+func NewClientSecurity(user, pass string) zmq4.Security {
+	return &security{user: []byte(user), pass: []byte(pass), client: true}
 }
 
 // Type returns the security mechanism type.
@@ -37,6 +60,13 @@ func (security) Type() zmq4.SecurityType {
 //	https://rfc.zeromq.org/spec:24/ZMTP-PLAIN/
 //	https://rfc.zeromq.org/spec:25/ZMTP-CURVE/
 func (sec *security) Handshake(conn *zmq4.Conn, server bool) error {
+	switch {
+	case server && !sec.server:
+		return fmt.Errorf("security/plain: PLAIN client cannot act as the ZMTP server")
+	case !server && !sec.client:
+		return fmt.Errorf("security/plain: PLAIN server cannot act as the ZMTP client")
+	}
+
 	switch {
 	case server:
 		cmd, err := conn.RecvCmd()
@@ -48,12 +78,19 @@ func (sec *security) Handshake(conn *zmq4.Conn, server bool) error {
 			return fmt.Errorf("security/plain: expected HELLO command")
 		}
 
-		// FIXME(sbinet): perform a real authentication
-		err = validateHello(cmd.Body)
+		user, pass, err := parseHello(cmd.Body)
 		if err != nil {
-			_ = conn.SendCmd(zmq4.CmdError, []byte("invalid")) // FIXME(sbinet) correct ERROR reason
+			_ = conn.SendCmd(zmq4.CmdError, errorReason("invalid HELLO"))
 			return fmt.Errorf("security/plain: could not authenticate client: %w", err)
 		}
+		if sec.verify != nil && !sec.verify(user, pass) {
+			_ = conn.SendCmd(zmq4.CmdError, errorReason("invalid username or password"))
+			return fmt.Errorf("security/plain: could not authenticate client %q: invalid credentials", user)
+		}
+		if err := conn.Authenticate([]byte(user), []byte(pass)); err != nil {
+			_ = conn.SendCmd(zmq4.CmdError, errorReason("authentication failed"))
+			return fmt.Errorf("security/plain: could not authenticate client %q: %w", user, err)
+		}
 
 		err = conn.SendCmd(zmq4.CmdWelcome, nil)
 		if err != nil {
@@ -72,7 +109,7 @@ func (sec *security) Handshake(conn *zmq4.Conn, server bool) error {
 
 		raw, err := conn.Meta.MarshalZMTP()
 		if err != nil {
-			_ = conn.SendCmd(zmq4.CmdError, []byte("invalid")) // FIXME(sbinet) correct ERROR reason
+			_ = conn.SendCmd(zmq4.CmdError, errorReason("internal error"))
 			return fmt.Errorf("security/plain: could not serialize metadata: %w", err)
 		}
 
@@ -97,14 +134,13 @@ func (sec *security) Handshake(conn *zmq4.Conn, server bool) error {
 		if err != nil {
 			return fmt.Errorf("security/plain: could not receive WELCOME from server: %w", err)
 		}
-		if cmd.Name != zmq4.CmdWelcome {
-			_ = conn.SendCmd(zmq4.CmdError, []byte("invalid command")) // FIXME(sbinet) correct ERROR reason
-			return fmt.Errorf("security/plain: expected a WELCOME command from server: %w", err)
+		if err := checkCmd(cmd, zmq4.CmdWelcome); err != nil {
+			return err
 		}
 
 		raw, err := conn.Meta.MarshalZMTP()
 		if err != nil {
-			_ = conn.SendCmd(zmq4.CmdError, []byte("internal error")) // FIXME(sbinet) correct ERROR reason
+			_ = conn.SendCmd(zmq4.CmdError, errorReason("internal error"))
 			return fmt.Errorf("security/plain: could not serialize metadata: %w", err)
 		}
 
@@ -117,18 +153,14 @@ func (sec *security) Handshake(conn *zmq4.Conn, server bool) error {
 		if err != nil {
 			return fmt.Errorf("security/plain: could not receive READY from server: %w", err)
 		}
-		if cmd.Name != zmq4.CmdReady {
-			_ = conn.SendCmd(zmq4.CmdError, []byte("invalid command")) // FIXME(sbinet) correct ERROR reason
-			return fmt.Errorf("security/plain: expected a READY command from server: %w", err)
+		if err := checkCmd(cmd, zmq4.CmdReady); err != nil {
+			return err
 		}
 
 		err = conn.Peer.Meta.UnmarshalZMTP(cmd.Body)
 		if err != nil {
 			return fmt.Errorf("security/plain: could not unmarshal peer metadata: %w", err)
 		}
-
-		sec.user = nil
-		sec.pass = nil
 	}
 	return nil
 }
@@ -143,16 +175,48 @@ func (security) Decrypt(w io.Writer, data []byte) (int, error) {
 	return w.Write(data)
 }
 
-// validateHello validates the user/passwd credentials.
-func validateHello(body []byte) error {
-	//	n := int(body[0])
-	//	user := body[1 : 1+n]
-	//	body = body[1+n:]
-	//	n = int(body[0])
-	//	pass := body[1 : 1+n]
-	//	body = body[1+n:]
-	//	log.Printf("user=%q, pass=%q, body=%q", user, pass, body)
-	return nil
+// checkCmd checks the server sent the named command, reporting the reason
+// of an ERROR command sent instead.
+func checkCmd(cmd zmq4.Cmd, name string) error {
+	switch cmd.Name {
+	case name:
+		return nil
+	case zmq4.CmdError:
+		reason := cmd.Body
+		if len(reason) > 0 && int(reason[0]) == len(reason)-1 {
+			reason = reason[1:]
+		}
+		return fmt.Errorf("security/plain: server rejected the handshake (%q): %w", reason, zmq4.ErrAuthFailed)
+	default:
+		return fmt.Errorf("security/plain: expected a %s command from server, got %s", name, cmd.Name)
+	}
+}
+
+// errorReason returns the body of an ERROR command with the reason.
+func errorReason(reason string) []byte {
+	return append([]byte{byte(len(reason))}, reason...)
+}
+
+// parseHello returns the user/passwd credentials of a HELLO command.
+func parseHello(body []byte) (user, pass string, err error) {
+	field := func() (string, error) {
+		if len(body) == 0 || len(body) < 1+int(body[0]) {
+			return "", fmt.Errorf("invalid HELLO body")
+		}
+		v := string(body[1 : 1+int(body[0])])
+		body = body[1+int(body[0]):]
+		return v, nil
+	}
+	if user, err = field(); err != nil {
+		return "", "", err
+	}
+	if pass, err = field(); err != nil {
+		return "", "", err
+	}
+	if len(body) != 0 {
+		return "", "", fmt.Errorf("invalid HELLO body")
+	}
+	return user, pass, nil
 }
 
 var (
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -102,6 +103,125 @@ func TestHandshakeReqRep(t *testing.T) {
 	}
 }
 
+func TestHandshakeAsServer(t *testing.T) {
+	ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+
+	// the REP socket dials out, e.g. through a NAT, but remains the PLAIN
+	// server validating the credentials of the REQ socket listening for it.
+	req := zmq4.NewReq(ctx, zmq4.WithSecurity(plain.Security("user", "secret")), zmq4.WithAsServer(false))
+	defer req.Close()
+	rep := zmq4.NewRep(ctx, zmq4.WithSecurity(plain.Security("user", "secret")), zmq4.WithAsServer(true))
+	defer rep.Close()
+
+	if err := req.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := rep.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		sck    zmq4.Socket
+		server bool
+	}{
+		{req, false},
+		{rep, true},
+	} {
+		conns := tc.sck.(zmq4.ConnectionLister).Connections()
+		for len(conns) == 0 {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("%v socket not connected", tc.sck.Type())
+			case <-time.After(time.Millisecond):
+			}
+			conns = tc.sck.(zmq4.ConnectionLister).Connections()
+		}
+		if got, want := conns[0].Server, tc.server; got != want {
+			t.Fatalf("invalid %v role: got server=%v, want=%v", tc.sck.Type(), got, want)
+		}
+		if got, want := conns[0].Mechanism, zmq4.PlainSecurity; got != want {
+			t.Fatalf("invalid %v mechanism: got=%v, want=%v", tc.sck.Type(), got, want)
+		}
+	}
+
+	if err := req.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send request: %+v", err)
+	}
+	msg, err := rep.Recv()
+	if err != nil {
+		t.Fatalf("could not recv request: %+v", err)
+	}
+	if got, want := msg.String(), zmq4.NewMsgString("ping").String(); got != want {
+		t.Fatalf("invalid request: got=%q, want=%q", got, want)
+	}
+	if err := rep.Send(zmq4.NewMsgString("pong")); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	msg, err = req.Recv()
+	if err != nil {
+		t.Fatalf("could not recv reply: %+v", err)
+	}
+	if got, want := msg.String(), zmq4.NewMsgString("pong").String(); got != want {
+		t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+	}
+}
+
+func TestHandshakeCredentials(t *testing.T) {
+	ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+
+	verify := func(user, pass string) bool {
+		return user == "user" && pass == "secret"
+	}
+	rep := zmq4.NewRep(ctx, zmq4.WithSecurity(plain.NewServerSecurity(verify)))
+	defer rep.Close()
+	if err := rep.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	// invalid credentials are rejected.
+	bad := zmq4.NewReq(ctx, zmq4.WithSecurity(plain.NewClientSecurity("user", "guess")), zmq4.WithDialerMaxRetries(0))
+	defer bad.Close()
+	err := bad.Dial(ep)
+	if !errors.Is(err, zmq4.ErrAuthFailed) {
+		t.Fatalf("invalid error: got=%+v, want=%v", err, zmq4.ErrAuthFailed)
+	}
+
+	// valid credentials are accepted.
+	req := zmq4.NewReq(ctx, zmq4.WithSecurity(plain.NewClientSecurity("user", "secret")))
+	defer req.Close()
+	if err := req.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := req.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send request: %+v", err)
+	}
+	msg, err := rep.Recv()
+	if err != nil {
+		t.Fatalf("could not recv request: %+v", err)
+	}
+	if got, want := msg.String(), zmq4.NewMsgString("ping").String(); got != want {
+		t.Fatalf("invalid request: got=%q, want=%q", got, want)
+	}
+
+	// only the accepted client is connected.
+	if got := len(rep.(zmq4.ConnectionLister).Connections()); got != 1 {
+		t.Fatalf("invalid number of connections: got=%d, want=1", got)
+	}
+
+	// a server-only security cannot dial as a client.
+	srv := zmq4.NewReq(ctx, zmq4.WithSecurity(plain.NewServerSecurity(verify)), zmq4.WithDialerMaxRetries(0))
+	defer srv.Close()
+	if err := srv.Dial(ep); err == nil {
+		t.Fatalf("expected a PLAIN server to fail dialing as a client")
+	}
+}
+
 func must(str string, err error) string {
 	if err != nil {
 		panic(err)
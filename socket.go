@@ -6,44 +6,150 @@ package zmq4
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/luxfi/zmq/v4/internal/inproc"
+	"github.com/luxfi/zmq/v4/transport"
 )
 
 const (
 	defaultRetry      = 250 * time.Millisecond
 	defaultTimeout    = 5 * time.Minute
 	defaultMaxRetries = 10
+	defaultMaxFrames  = 1 << 20
+	defaultRecvQueue  = 10
 )
 
+// acceptBackoff is the delay between the attempts of a listener failing to
+// accept connections, e.g. because the process ran out of file descriptors.
+var acceptBackoff = BackoffPolicy{Initial: 5 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
 var (
 	errInvalidAddress = errors.New("zmq4: invalid address")
 
 	ErrBadProperty = errors.New("zmq4: bad property")
+
+	// ErrInvalidOperation is returned when sending on a socket type that can
+	// only receive (SUB, PULL) or receiving on one that can only send
+	// (PUB, PUSH).
+	ErrInvalidOperation = errors.New("zmq4: invalid operation for socket type")
+
+	// ErrFSM is returned when a REQ socket sends a request before it
+	// received the reply to the previous one, or receives without a
+	// request pending, and when a REP socket receives a request before it
+	// replied to the previous one, or replies without a request pending
+	// (see OptionReqRelaxed).
+	ErrFSM = errors.New("zmq4: operation cannot be accomplished in current state")
+
+	// ErrIdentityChange is returned when setting OptionIdentity on a socket
+	// with live connections.
+	ErrIdentityChange = errors.New("zmq4: cannot change the identity of a connected socket")
+
+	// ErrPeerIdentity is reported when a peer without an explicit identity
+	// is rejected by a socket created with WithRequirePeerIdentity.
+	ErrPeerIdentity = errors.New("zmq4: peer did not announce an identity")
+
+	// ErrTooManyFrames is returned when sending a message with more frames
+	// than allowed by WithMaxFrames, and reported when a peer sends one.
+	ErrTooManyFrames = errors.New("zmq4: too many frames in message")
+
+	// ErrBackendUnavailable is returned by the operations of the sockets
+	// created by the NewC* constructors when the czmq backend could not
+	// create them.
+	ErrBackendUnavailable = errors.New("zmq4: backend unavailable")
+
+	// ErrTimeout is returned by the send and receive operations that could
+	// not complete before the timeout of the socket, see WithTimeout.
+	// It is context.DeadlineExceeded, which they returned before ErrTimeout
+	// was introduced.
+	ErrTimeout = context.DeadlineExceeded
+
+	// ErrHostUnreachable is returned when a ROUTER socket set with
+	// OptionRouterMandatory sends a message to an identity that is not
+	// connected.
+	ErrHostUnreachable = errors.New("zmq4: host unreachable")
+
+	// ErrWouldBlock is returned by RecvNB when no message is ready to be
+	// received, and by SendNB when the message can not be queued without
+	// blocking.
+	ErrWouldBlock = errors.New("zmq4: operation would block")
 )
 
-// socketMonitor is a no-op monitor for compatibility
-type socketMonitor struct{}
+// checkFrames returns an error if msg has more frames than allowed.
+func (sck *socket) checkFrames(msg Msg) error {
+	if sck.maxFrames > 0 && len(msg.Frames) > sck.maxFrames {
+		return fmt.Errorf("zmq4: message has %d frames, limit is %d: %w", len(msg.Frames), sck.maxFrames, ErrTooManyFrames)
+	}
+	return nil
+}
+
+// errInvalidOp reports that sockets of type typ can not perform op.
+func errInvalidOp(typ SocketType, op string) error {
+	return fmt.Errorf("zmq4: %s sockets cannot %s: %w", typ, op, ErrInvalidOperation)
+}
 
 // socket implements the ZeroMQ socket interface
 type socket struct {
-	ep            string // socket end-point
+	dialed        []string // endpoints connected to by Dial, see Endpoints
+	lastEndpoint  string   // end-point last listened to or dialed, see LastEndpoint
 	typ           SocketType
 	id            SocketIdentity
-	retry         time.Duration
+	backoff       BackoffPolicy
 	maxRetries    int
 	sec           Security
+	zapDomain     string // authentication domain, see WithZAPDomain
 	log           *log.Logger
 	subTopics     func() []string
+	dishGroups    func() []string // groups to join again upon reconnection, see OptionJoin
 	autoReconnect bool
-	timeout       time.Duration
+	timeout       time.Duration // timeout of the send operations, 0 to block until they complete
+	recvTimeout   time.Duration // timeout of the receive operations, 0 to block until they complete
+	sendDeadline  time.Time     // deadline of the send operations, see SetSendDeadline
+	recvDeadline  time.Time     // deadline of the receive operations, see SetRecvDeadline
+	linger        time.Duration // how long Close waits for pending messages, if < 0 forever
+	maxFrames     int           // maximum number of frames per message, 0 for no limit
+	// inclusive range of ports the ephemeral TCP end-points listen to, see
+	// WithPortRange. The range is unset if portMin is 0.
+	portMin       int
+	portMax       int
+	backlog       int           // length of the accept queue of the listeners, 0 for the system maximum
+	keepalive     tcpKeepalive  // TCP keepalive of the connections
+	heartbeat     heartbeat     // ZMTP heartbeats of the connections, see OptionHeartbeatIvl
+	tls           *tls.Config   // TLS configuration of the tls:// end-points, see WithTLS
+	ipcMode       os.FileMode   // permissions of the socket files of the ipc:// end-points, 0 for the default
+	ackTimeout    time.Duration // redelivery delay of unacknowledged PUSH messages, 0 if acknowledgements are disabled
+	rawEnvelope   bool          // whether REQ/REP expose the routing envelope
+	recvPool      bool          // whether small messages are received into pooled buffers
+	recvZeroCopy  bool          // whether frames are received in place, see WithRecvZeroCopy
+	watchers      watchers      // pollers waiting for the events of the socket to change
+	recv          recvConfig
+	drainUnsub    bool // whether SUB drops queued messages no longer subscribed to
+	subHWM        int  // maximum number of messages queued per publisher by XSUB
+	sndHWM        int  // maximum number of messages queued per connection, see WithSendHWM
+	tracer        *messageTracer
+	stats         *socketStats
+	certRouting   bool // whether peers are identified by their TLS certificate
+	requirePeerID bool // whether peers must announce an identity
+	handover      bool // whether ROUTER peers take over the identity of a live connection, see OptionRouterHandover
+	coalesce      coalesceConfig
+	deadLetter    DeadLetterHandler       // called with the messages ROUTER and STREAM sockets cannot route
+	acceptFilter  AcceptFilter            // called with each accepted connection
+	asServer      *bool                   // ZMTP role of the socket, derived from Listen and Dial if nil
+	onReconnect   func(sock Socket) error // called after each dialed connection
+
+	self Socket // socket wrapping this one, handed to callbacks
 
 	mu    sync.RWMutex
 	conns []*Conn // ZMTP connections
@@ -52,16 +158,23 @@ type socket struct {
 
 	props map[string]interface{} // properties of this socket
 
-	ctx      context.Context // life-line of socket
-	cancel   context.CancelFunc
-	listener net.Listener
-	dialer   net.Dialer
+	ctx       context.Context // life-line of socket
+	cancel    context.CancelFunc
+	listeners []*boundListener // listeners, in the order of Listen
+	dialer    net.Dialer
 
 	closedConns   []*Conn
 	reaperCond    *sync.Cond
 	reaperStarted bool
 	isClosed      bool // tracks if socket has been closed
 
+	teardownOnce sync.Once
+	teardownErr  error
+
+	wg       sync.WaitGroup // accepting and reaping goroutines
+	done     chan struct{}  // closed once the socket is torn down
+	doneOnce sync.Once
+
 	monitor *socketMonitor // socket monitor for events
 }
 
@@ -70,22 +183,30 @@ func newDefaultSocket(ctx context.Context, sockType SocketType) *socket {
 		ctx = context.Background()
 	}
 	ctx, cancel := context.WithCancel(ctx)
-	return &socket{
+	sck := &socket{
 		typ:           sockType,
-		retry:         defaultRetry,
+		backoff:       ConstantBackoff(defaultRetry),
 		maxRetries:    defaultMaxRetries,
 		timeout:       defaultTimeout,
+		maxFrames:     defaultMaxFrames,
+		keepalive:     tcpKeepalive{mode: -1},
 		autoReconnect: true,
 		sec:           nullSecurity{},
 		conns:         nil,
-		r:             newQReader(ctx),
 		w:             newMWriter(ctx),
 		props:         make(map[string]interface{}),
 		ctx:           ctx,
 		cancel:        cancel,
 		dialer:        net.Dialer{Timeout: defaultTimeout},
 		reaperCond:    sync.NewCond(&sync.Mutex{}),
+		recv:          recvConfig{qsize: defaultRecvQueue},
+		subHWM:        DefaultSendHwm,
+		monitor:       newSocketMonitor(),
+		stats:         newSocketStats(),
+		done:          make(chan struct{}),
 	}
+	context.AfterFunc(ctx, sck.stop)
+	return sck
 }
 
 func newSocket(ctx context.Context, sockType SocketType, opts ...Option) *socket {
@@ -93,9 +214,7 @@ func newSocket(ctx context.Context, sockType SocketType, opts ...Option) *socket
 	for _, opt := range opts {
 		opt(sck)
 	}
-	if len(sck.id) == 0 {
-		sck.id = SocketIdentity(newUUID())
-	}
+	sck.r = newQReader(sck.ctx, sck.recv)
 	if sck.log == nil {
 		sck.log = log.New(os.Stderr, "zmq4: ", 0)
 	}
@@ -133,8 +252,11 @@ func (sck *socket) Close() error {
 		return fmt.Errorf("zmq4: socket already closed")
 	}
 	sck.isClosed = true
+	linger := sck.linger
 	sck.mu.Unlock()
 
+	sck.drain(linger)
+
 	// The Lock around Signal ensures the connReaper is running
 	// and is in sck.reaperCond.Wait()
 	sck.reaperCond.L.Lock()
@@ -142,28 +264,212 @@ func (sck *socket) Close() error {
 	sck.reaperCond.Signal()
 	sck.reaperCond.L.Unlock()
 
-	if sck.listener != nil {
-		defer sck.listener.Close()
+	err := sck.teardown()
+	sck.wg.Wait()
+	sck.closeDone()
+	return err
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context the socket was created with, has completed the teardown of the
+// socket: its listener and connections are closed and its goroutines
+// accepting and reaping connections have returned.
+func (sck *socket) Done() <-chan struct{} {
+	return sck.done
+}
+
+// closeDone closes the Done channel of the socket, once.
+func (sck *socket) closeDone() {
+	sck.doneOnce.Do(func() { close(sck.done) })
+}
+
+// drain waits up to linger for the messages queued for sending to be written
+// to the connected peers.
+func (sck *socket) drain(linger time.Duration) {
+	f, ok := sck.w.(flusher)
+	if !ok || linger == 0 {
+		return
+	}
+	ctx := sck.ctx
+	if linger > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, linger)
+		defer cancel()
+	}
+	_ = f.flush(ctx)
+}
+
+// Context returns the life-line of the socket.
+// The returned context is done once the socket is closed or once the
+// context the socket was created with is done.
+func (sck *socket) Context() context.Context {
+	return sck.ctx
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (sck *socket) MessageTrace() <-chan TraceEvent {
+	if sck.tracer == nil {
+		return nil
 	}
+	return sck.tracer.c
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (sck *socket) Stats() Stats {
+	stats := sck.stats.snapshot()
+	if q, ok := sck.r.(queuer); ok {
+		stats.RecvQueued = q.queued()
+	}
+	if q, ok := sck.w.(queuer); ok {
+		stats.SendQueued = q.queued()
+	}
+	return stats
+}
 
+// Connections returns the live connections of the socket.
+func (sck *socket) Connections() []ConnInfo {
 	sck.mu.RLock()
-	var err error
-	for _, conn := range sck.conns {
-		e := conn.Close()
-		if e != nil && err == nil {
-			err = e
+	defer sck.mu.RUnlock()
+	infos := make([]ConnInfo, 0, len(sck.conns))
+	for _, c := range sck.conns {
+		if c.Closed() {
+			continue
 		}
+		infos = append(infos, c.info())
 	}
+	return infos
+}
 
-	// Remove the unix socket file if created by net.Listen
-	ep := sck.ep
-	sck.mu.RUnlock()
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (sck *socket) Monitor(events EventType) <-chan SocketEvent {
+	return sck.monitor.enable(events)
+}
 
-	if sck.listener != nil && strings.HasPrefix(ep, "ipc://") {
-		os.Remove(ep[len("ipc://"):])
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (sck *socket) GetMonitorChannel() <-chan SocketEvent {
+	return sck.monitor.channel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (sck *socket) MonitorDropped() uint64 {
+	return sck.monitor.dropped.Load()
+}
+
+// recvOrEvent waits for a message to be ready on the socket and receives it
+// with recv, or for an event on the monitor channel.
+func (sck *socket) recvOrEvent(ctx context.Context, recv func() (Msg, error)) (Msg, *SocketEvent, error) {
+	var (
+		events = sck.monitor.channel()
+		ready  = make(chan struct{}, 1) // signaled once the socket may have become readable
+	)
+	unwatch := sck.watch(func() {
+		select {
+		case ready <- struct{}{}:
+		default:
+		}
+	})
+	defer unwatch()
+
+	for {
+		select {
+		case ev := <-events:
+			return Msg{}, &ev, nil
+		default:
+		}
+		if sck.events()&Readable != 0 {
+			msg, err := recv()
+			return msg, nil, err
+		}
+
+		select {
+		case ev := <-events:
+			return Msg{}, &ev, nil
+		case <-ready:
+		case <-ctx.Done():
+			return Msg{}, nil, ctx.Err()
+		case <-sck.ctx.Done():
+			return Msg{}, nil, sck.ctx.Err()
+		}
 	}
+}
 
-	return err
+// emitEvent reports a lifecycle event of the socket to its monitor.
+func (sck *socket) emitEvent(typ EventType, addr string, err error) {
+	sck.monitor.emit(sck.ctx, SocketEvent{Type: typ, Addr: addr, Err: err})
+}
+
+// events returns the events the socket is ready for.
+func (sck *socket) events() State {
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
+	if sck.isClosed || sck.ctx.Err() != nil {
+		return 0
+	}
+	var ev State
+	if q, ok := sck.r.(queuer); ok && q.queued() > 0 {
+		ev |= Readable
+	}
+	if len(sck.conns) > 0 {
+		ev |= Writable
+	}
+	return ev
+}
+
+// watch registers fn, called once the events of the socket may have changed,
+// until the returned function is called.
+func (sck *socket) watch(fn func()) func() {
+	return sck.watchers.add(fn)
+}
+
+// stop tears the socket down once its context is done.
+func (sck *socket) stop() {
+	sck.reaperCond.L.Lock()
+	sck.reaperCond.Signal()
+	sck.reaperCond.L.Unlock()
+
+	_ = sck.teardown()
+	sck.wg.Wait()
+	sck.closeDone()
+}
+
+// teardown closes the listener, the ZMTP connections and the message pools
+// of the socket, stopping all the goroutines attached to them.
+// teardown is only performed once, by Close or when the socket's context is
+// done, whichever comes first.
+func (sck *socket) teardown() error {
+	sck.teardownOnce.Do(func() {
+		sck.mu.RLock()
+		var err error
+		for _, conn := range sck.conns {
+			e := conn.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}
+		listeners := sck.listeners
+		sck.mu.RUnlock()
+
+		for _, bl := range listeners {
+			sck.closeListener(bl)
+		}
+
+		// the connections have been closed above: only the goroutines
+		// serving the pools are of interest here.
+		if sck.r != nil {
+			_ = sck.r.Close()
+		}
+		if sck.w != nil {
+			_ = sck.w.Close()
+		}
+		sck.teardownErr = err
+		sck.watchers.notify()
+	})
+	return sck.teardownErr
 }
 
 // Send puts the message on the outbound send queue.
@@ -175,8 +481,11 @@ func (sck *socket) Send(msg Msg) error {
 		return fmt.Errorf("zmq4: socket is closed")
 	}
 	sck.mu.RUnlock()
+	if err := sck.checkFrames(msg); err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(sck.ctx, sck.Timeout())
+	ctx, cancel := sck.sendContext()
 	defer cancel()
 	return sck.w.write(ctx, msg)
 }
@@ -191,15 +500,181 @@ func (sck *socket) SendMulti(msg Msg) error {
 		return fmt.Errorf("zmq4: socket is closed")
 	}
 	sck.mu.RUnlock()
+	if err := sck.checkFrames(msg); err != nil {
+		return err
+	}
 
 	msg.multipart = true
-	ctx, cancel := context.WithTimeout(sck.ctx, sck.Timeout())
+	ctx, cancel := sck.sendContext()
 	defer cancel()
 	return sck.w.write(ctx, msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (sck *socket) SendNB(msg Msg) error {
+	sck.mu.RLock()
+	if sck.isClosed {
+		sck.mu.RUnlock()
+		return fmt.Errorf("zmq4: socket is closed")
+	}
+	sck.mu.RUnlock()
+	if err := sck.checkFrames(msg); err != nil {
+		return err
+	}
+	if sck.events()&Writable == 0 {
+		return ErrWouldBlock
+	}
+	return sck.trySend(msg)
+}
+
+// trySend hands msg off to the writer of the socket without waiting for its
+// queues to have room.
+func (sck *socket) trySend(msg Msg) error {
+	_, queued := sck.w.(flusher)
+	_, acked := sck.w.(*ackWriter) // bounds the unacknowledged messages
+	if !queued && !acked {
+		// messages are written to the wire by the writer itself: there is
+		// no queue to wait for.
+		ctx, cancel := sck.sendContext()
+		defer cancel()
+		return sck.w.write(ctx, msg)
+	}
+	ctx := sck.tryCtx()
+	return wouldBlock(ctx, sck.w.write(ctx, msg))
+}
+
+// sendContext returns the context of a send operation, done once the
+// timeout of the socket expired or its send deadline passed.
+func (sck *socket) sendContext() (context.Context, context.CancelFunc) {
+	sck.mu.RLock()
+	timeout, deadline := sck.timeout, sck.sendDeadline
+	sck.mu.RUnlock()
+	return withDeadline(sck.ctx, timeout, deadline)
+}
+
+// recvContext returns the context of a receive operation, done once the
+// receive timeout of the socket expired or its receive deadline passed.
+func (sck *socket) recvContext() (context.Context, context.CancelFunc) {
+	sck.mu.RLock()
+	timeout, deadline := sck.recvTimeout, sck.recvDeadline
+	sck.mu.RUnlock()
+	return withDeadline(sck.ctx, timeout, deadline)
+}
+
+// withDeadline returns a context derived from ctx, done after timeout or at
+// deadline, whichever comes first. A zero timeout or deadline is ignored.
+func withDeadline(ctx context.Context, timeout time.Duration, deadline time.Time) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		if t := time.Now().Add(timeout); deadline.IsZero() || t.Before(deadline) {
+			deadline = t
+		}
+	}
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (sck *socket) SetSendDeadline(t time.Time) error {
+	sck.mu.Lock()
+	defer sck.mu.Unlock()
+	sck.sendDeadline = t
+	return nil
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (sck *socket) SetRecvDeadline(t time.Time) error {
+	sck.mu.Lock()
+	defer sck.mu.Unlock()
+	sck.recvDeadline = t
+	return nil
+}
+
+// tryCtx returns a context that is already done, for the pools of the socket
+// to complete only the operations that are ready.
+func (sck *socket) tryCtx() context.Context {
+	ctx, cancel := context.WithCancelCause(sck.ctx)
+	cancel(ErrWouldBlock)
+	return ctx
+}
+
+// wouldBlock maps the context error returned by a pool operation run with
+// tryCtx to ErrWouldBlock, unless the socket itself is done.
+func wouldBlock(ctx context.Context, err error) error {
+	if err != nil && err == ctx.Err() {
+		return context.Cause(ctx)
+	}
+	return err
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+// Unlike lingering on Close, Flush leaves the socket open.
+func (sck *socket) Flush(ctx context.Context) error {
+	sck.mu.RLock()
+	if sck.isClosed {
+		sck.mu.RUnlock()
+		return fmt.Errorf("zmq4: socket is closed")
+	}
+	sck.mu.RUnlock()
+
+	f, ok := sck.w.(flusher)
+	if !ok {
+		// messages are written to the wire by Send itself.
+		return nil
+	}
+	return f.flush(ctx)
+}
+
 // Recv receives a complete message.
 func (sck *socket) Recv() (Msg, error) {
+	return withoutConn(sck.recvConn())
+}
+
+// recvConn receives a complete message, still referencing the connection it was
+// received from.
+func (sck *socket) recvConn() (Msg, error) {
+	ctx, cancel := sck.recvContext()
+	defer cancel()
+	return sck.read(ctx)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (sck *socket) RecvNB() (Msg, error) {
+	return withoutConn(sck.recvConnNB())
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (sck *socket) RecvMulti() (Msg, error) {
+	return recvMulti(sck.Recv)
+}
+
+// recvMulti calls recv until it returns a complete message: the frames of
+// a message cut short by the failure of its connection, which recv reports
+// with io.ErrUnexpectedEOF, are discarded.
+func recvMulti(recv func() (Msg, error)) (Msg, error) {
+	for {
+		msg, err := recv()
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			return msg, err
+		}
+	}
+}
+
+// recvConnNB is the non-blocking version of recvConn.
+func (sck *socket) recvConnNB() (Msg, error) {
+	ctx := sck.tryCtx()
+	msg, err := sck.read(ctx)
+	return msg, wouldBlock(ctx, err)
+}
+
+// read receives a complete message from the reader of the socket.
+func (sck *socket) read(ctx context.Context) (Msg, error) {
 	sck.mu.RLock()
 	if sck.isClosed {
 		sck.mu.RUnlock()
@@ -207,8 +682,6 @@ func (sck *socket) Recv() (Msg, error) {
 	}
 	sck.mu.RUnlock()
 
-	ctx, cancel := context.WithCancel(sck.ctx)
-	defer cancel()
 	var msg Msg
 	err := sck.r.read(ctx, &msg)
 	return msg, err
@@ -221,11 +694,10 @@ func (sck *socket) Listen(endpoint string) error {
 		sck.mu.Unlock()
 		return fmt.Errorf("zmq4: socket is closed")
 	}
-	if sck.listener != nil {
+	if sck.listenerOf(endpoint) >= 0 {
 		sck.mu.Unlock()
-		return fmt.Errorf("zmq4: socket already listening on %q", sck.ep)
+		return fmt.Errorf("zmq4: socket already listening on %q", endpoint)
 	}
-	sck.ep = endpoint
 	sck.mu.Unlock()
 	network, addr, err := splitAddr(endpoint)
 	if err != nil {
@@ -237,48 +709,151 @@ func (sck *socket) Listen(endpoint string) error {
 		return UnknownTransportError{Name: network}
 	}
 
-	l, err := trans.Listen(sck.ctx, addr)
+	l, err := sck.listen(trans, endpoint, network, addr)
 	if err != nil {
-		return fmt.Errorf("zmq4: could not listen to %q: %w", endpoint, err)
+		sck.emitEvent(EventBindFailed, endpoint, err)
+		return err
 	}
 
+	ctx, cancel := context.WithCancel(sck.ctx)
+	bl := &boundListener{ep: endpoint, l: l, cancel: cancel, done: make(chan struct{})}
 	sck.mu.Lock()
-	sck.listener = l
+	sck.listeners = append(sck.listeners, bl)
+	sck.lastEndpoint = endpoint
+	if network == "tcp" || network == "tls" {
+		// e.g. with the port picked for "tcp://127.0.0.1:0".
+		sck.lastEndpoint = network + "://" + l.Addr().String()
+	}
 	sck.mu.Unlock()
+	sck.emitEvent(EventListening, endpoint, nil)
 
-	go sck.accept()
-	if !sck.reaperStarted {
-		sck.reaperCond.L.Lock()
-		go sck.connReaper()
-		sck.reaperStarted = true
-	}
+	sck.wg.Add(1)
+	go sck.accept(ctx, bl)
+	sck.startReaper()
 
 	return nil
 }
 
-func (sck *socket) accept() {
-	ctx, cancel := context.WithCancel(sck.ctx)
-	defer cancel()
+// listen listens to addr with the transport of the end-point.
+func (sck *socket) listen(trans transport.Transport, endpoint, network, addr string) (net.Listener, error) {
+	var (
+		l   net.Listener
+		err error
+	)
+	if _, port, _ := net.SplitHostPort(addr); (network == "tcp" || network == "tls") && port == "0" && sck.portMin != 0 {
+		l, err = sck.listenPortRange(trans, endpoint, addr)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if network == "ipc" {
+			removeStaleIPC(addr)
+		}
+		l, err = trans.Listen(sck.ctx, addr)
+		if err != nil {
+			return nil, fmt.Errorf("zmq4: could not listen to %q: %w", endpoint, err)
+		}
+	}
+	if network == "ipc" {
+		if err := sck.chmodIPC(endpoint, addr); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	if sck.backlog > 0 {
+		err = transport.SetBacklog(l, sck.backlog)
+		if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			l.Close()
+			return nil, fmt.Errorf("zmq4: could not listen to %q: %w", endpoint, err)
+		}
+	}
+	if network == "tls" {
+		tl, err := sck.tlsListener(endpoint, l)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		l = tl
+	}
+	return l, nil
+}
+
+// listenPortRange listens to addr, whose port is ephemeral, on the first
+// free port of the range set with WithPortRange.
+func (sck *socket) listenPortRange(trans transport.Transport, endpoint, addr string) (net.Listener, error) {
+	lo, hi := sck.portMin, sck.portMax
+	if lo < 1 || hi > 65535 || lo > hi {
+		return nil, fmt.Errorf("zmq4: invalid port range [%d, %d] to listen to %q", lo, hi, endpoint)
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	var err error
+	for port := lo; port <= hi; port++ {
+		var l net.Listener
+		l, err = trans.Listen(sck.ctx, net.JoinHostPort(host, strconv.Itoa(port)))
+		if err == nil {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("zmq4: no free port in range [%d, %d] to listen to %q: %w", lo, hi, endpoint, err)
+}
+
+// accept accepts the connections of the listener bl until ctx is done.
+func (sck *socket) accept(ctx context.Context, bl *boundListener) {
+	defer sck.wg.Done()
+	defer close(bl.done)
+	failures := 0 // consecutive failures of Accept
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			conn, err := sck.listener.Accept()
+			conn, err := bl.l.Accept()
 			if err != nil {
-				// FIXME(sbinet): maybe bubble up this error to application code?
-				//sck.log.Printf("error accepting connection from %q: %+v", sck.ep, err)
+				if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+					return
+				}
+				sck.emitEvent(EventAcceptFailed, bl.ep, err)
+				timer := time.NewTimer(acceptBackoff.Next(failures))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+				failures++
 				continue
 			}
+			failures = 0
 
-			zconn, err := Open(conn, sck.sec, sck.typ, sck.id, true, sck.scheduleRmConn)
+			if err := sck.setKeepalive(conn); err != nil {
+				conn.Close()
+				sck.emitEvent(EventAcceptFailed, bl.ep, err)
+				continue
+			}
+
+			// do not let a stalled handshake outlive the socket.
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			zconn, err := openConn(conn, sck.sec, sck.typ, sck.identity(), sck.zmtpServer(true), sck.zapDomain, sck.scheduleRmConn)
+			stop()
+			if err != nil {
+				conn.Close()
+			}
+			if err == nil {
+				err = sck.checkPeer(zconn)
+			}
+			if err == nil {
+				err = sck.filterPeer(zconn)
+			}
 			if err != nil {
 				// FIXME(sbinet): maybe bubble up this error to application code?
-				sck.log.Printf("could not open a ZMTP connection with %q: %+v", sck.ep, err)
+				sck.log.Printf("could not open a ZMTP connection with %q: %+v", bl.ep, err)
+				sck.emitEvent(EventHandshakeFailed, conn.RemoteAddr().String(), err)
 				continue
 			}
 
+			zconn.endpoint = bl.ep
 			sck.addConn(zconn)
+			sck.emitEvent(EventAccepted, conn.RemoteAddr().String(), nil)
 		}
 	}
 }
@@ -290,21 +865,70 @@ func (sck *socket) Dial(endpoint string) error {
 		sck.mu.Unlock()
 		return fmt.Errorf("zmq4: socket is closed")
 	}
-	sck.ep = endpoint
 	sck.mu.Unlock()
 
-	network, addr, err := splitAddr(endpoint)
+	if network, addr, err := splitAddr(endpoint); err == nil && network == "inproc" && !inproc.Listening(addr) {
+		// as with libzmq, the connection completes once the end-point is
+		// bound.
+		sck.startReaper()
+		sck.mu.Lock()
+		defer sck.mu.Unlock()
+		if sck.isClosed {
+			return fmt.Errorf("zmq4: socket is closed")
+		}
+		if !slices.Contains(sck.dialed, endpoint) {
+			sck.dialed = append(sck.dialed, endpoint)
+		}
+		sck.lastEndpoint = endpoint
+		if w, ok := sck.w.(connAwaiter); ok {
+			w.awaitConn()
+		}
+		sck.wg.Add(1)
+		go sck.dialAsync(endpoint)
+		return nil
+	}
+
+	zconn, err := sck.dial(endpoint)
 	if err != nil {
 		return err
 	}
 
+	sck.startReaper()
+	sck.mu.Lock()
+	if !slices.Contains(sck.dialed, endpoint) {
+		sck.dialed = append(sck.dialed, endpoint)
+	}
+	sck.lastEndpoint = endpoint
+	sck.mu.Unlock()
+	return sck.connected(zconn)
+}
+
+// startReaper starts the goroutine removing the closed connections of the
+// socket, unless it is already running.
+func (sck *socket) startReaper() {
+	if !sck.reaperStarted {
+		sck.reaperCond.L.Lock()
+		sck.wg.Add(1)
+		go sck.connReaper()
+		sck.reaperStarted = true
+	}
+}
+
+// dial opens a ZMTP connection to endpoint, retrying the failed dials as
+// configured by WithBackoff and WithDialerMaxRetries.
+func (sck *socket) dial(endpoint string) (*Conn, error) {
+	network, addr, err := splitAddr(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
 		conn      net.Conn
 		trans, ok = drivers.get(network)
 		retries   = 0
 	)
 	if !ok {
-		return UnknownTransportError{Name: network}
+		return nil, UnknownTransportError{Name: network}
 	}
 
 connect:
@@ -312,45 +936,190 @@ connect:
 	if err != nil {
 		// retry if retry count is lower than maximum retry count and context has not been canceled
 		if (sck.maxRetries == -1 || retries < sck.maxRetries) && sck.ctx.Err() == nil {
+			sck.emitEvent(EventConnectDelayed, endpoint, err)
+			sck.mu.RLock()
+			backoff := sck.backoff
+			sck.mu.RUnlock()
+			timer := time.NewTimer(backoff.Next(retries))
+			select {
+			case <-timer.C:
+			case <-sck.ctx.Done():
+				timer.Stop()
+			}
 			retries++
-			time.Sleep(sck.retry)
+			sck.emitEvent(EventConnectRetried, endpoint, nil)
 			goto connect
 		}
-		return fmt.Errorf("zmq4: could not dial to %q (retry=%v): %w", endpoint, sck.retry, err)
+		return nil, fmt.Errorf("zmq4: could not dial to %q (retries=%d): %w", endpoint, retries, err)
 	}
 
 	if conn == nil {
-		return fmt.Errorf("zmq4: got a nil dial-conn to %q", endpoint)
+		return nil, fmt.Errorf("zmq4: got a nil dial-conn to %q", endpoint)
+	}
+	if err := sck.setKeepalive(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if network == "tls" {
+		tc, err := sck.tlsClient(sck.ctx, conn, addr)
+		if err != nil {
+			conn.Close()
+			sck.emitEvent(EventHandshakeFailed, endpoint, err)
+			return nil, fmt.Errorf("zmq4: could not dial to %q: %w", endpoint, err)
+		}
+		conn = tc
 	}
 
-	zconn, err := Open(conn, sck.sec, sck.typ, sck.id, false, sck.scheduleRmConn)
+	// do not let a stalled handshake outlive the socket.
+	stop := context.AfterFunc(sck.ctx, func() { conn.Close() })
+	zconn, err := openConn(conn, sck.sec, sck.typ, sck.identity(), sck.zmtpServer(false), sck.zapDomain, sck.scheduleRmConn)
+	stop()
+	if err == nil {
+		err = sck.checkPeer(zconn)
+	}
 	if err != nil {
-		return fmt.Errorf("zmq4: could not open a ZMTP connection: %w", err)
+		conn.Close()
+		sck.emitEvent(EventHandshakeFailed, endpoint, err)
+		return nil, fmt.Errorf("zmq4: could not open a ZMTP connection: %w", err)
 	}
 	if zconn == nil {
-		return fmt.Errorf("zmq4: got a nil ZMTP connection to %q", endpoint)
+		return nil, fmt.Errorf("zmq4: got a nil ZMTP connection to %q", endpoint)
 	}
+	zconn.endpoint = endpoint
+	zconn.dialed = true
+	return zconn, nil
+}
 
-	if !sck.reaperStarted {
-		sck.reaperCond.L.Lock()
-		go sck.connReaper()
-		sck.reaperStarted = true
-	}
+// connected adds the freshly dialed connection zconn to the socket, and runs
+// the callback set with WithOnReconnect.
+func (sck *socket) connected(zconn *Conn) error {
 	sck.addConn(zconn)
+	sck.emitEvent(EventConnected, zconn.endpoint, nil)
+
+	if sck.onReconnect != nil {
+		if err := sck.onReconnect(sck.self); err != nil {
+			sck.log.Printf("connection callback failed for %q: %+v", zconn.endpoint, err)
+			return fmt.Errorf("zmq4: connection callback failed for %q: %w", zconn.endpoint, err)
+		}
+	}
+	return nil
+}
+
+// dialAsync dials ep in the background, after its connection was lost or,
+// for inproc end-points, until it is bound. It returns once the connection
+// is established, the retries are exhausted, the socket is closed or ep is
+// disconnected.
+// The identity of the socket is announced by the handshake, and the
+// subscriptions of SUB and XSUB sockets are sent by addConn.
+func (sck *socket) dialAsync(ep string) {
+	defer sck.wg.Done()
+
+	zconn, err := sck.dial(ep)
+	switch {
+	case sck.ctx.Err() != nil || !sck.isDialed(ep):
+		if zconn != nil {
+			_ = zconn.drop()
+		}
+		return
+	case err != nil:
+		sck.log.Printf("could not connect to %q: %+v", ep, err)
+		return
+	}
+	_ = sck.connected(zconn)
+
+	// ep may have been disconnected while the connection was added:
+	// Disconnect did not see it.
+	if !sck.isDialed(ep) {
+		sck.rmConn(zconn)
+		_ = zconn.drop()
+	}
+}
+
+// setKeepalive configures the TCP keepalive of conn, see OptionTCPKeepalive.
+func (sck *socket) setKeepalive(conn net.Conn) error {
+	sck.mu.RLock()
+	ka := sck.keepalive
+	sck.mu.RUnlock()
+	return ka.apply(conn)
+}
+
+// zmtpServer returns whether the socket is the ZMTP server of a connection
+// it accepted, or dialed.
+func (sck *socket) zmtpServer(accepted bool) bool {
+	if sck.asServer != nil {
+		return *sck.asServer
+	}
+	return accepted
+}
+
+// checkPeer closes the freshly opened connection c if its peer is not
+// acceptable.
+func (sck *socket) checkPeer(c *Conn) error {
+	if sck.requirePeerID && c.Peer.Meta[sysSockID] == "" {
+		c.Close()
+		return fmt.Errorf("zmq4: rejecting %s peer %v: %w", c.Peer.Meta[sysSockType], c.rw.RemoteAddr(), ErrPeerIdentity)
+	}
+	return nil
+}
+
+// filterPeer runs the accept filter of the socket on the accepted connection
+// c, and closes c if the filter rejects it.
+func (sck *socket) filterPeer(c *Conn) error {
+	if sck.acceptFilter == nil {
+		return nil
+	}
+	v, err := sck.acceptFilter(c.info())
+	if err != nil {
+		c.Close()
+		return fmt.Errorf("zmq4: accept filter rejected %s peer %v: %w", c.Peer.Meta[sysSockType], c.rw.RemoteAddr(), err)
+	}
+	c.value = v
 	return nil
 }
 
 func (sck *socket) addConn(c *Conn) {
 	sck.mu.Lock()
 	sck.conns = append(sck.conns, c)
+	c.pooled = sck.recvPool
+	c.notify = sck.watchers.notify
+	if sck.recvZeroCopy && sck.typ != Stream && c.cipher == nil && c.sec.Type() == NullSecurity {
+		c.zc = newZCReader(c.rw)
+	}
+	c.tracer = sck.tracer
+	c.stats = sck.stats
+	c.maxFrames = sck.maxFrames
+	if sck.coalesce.enabled() {
+		c.cw = newCoalescer(c.rw, sck.coalesce)
+	}
+	if id := c.Peer.Meta[PeerCertIdentity]; sck.certRouting && id != "" {
+		c.Peer.Meta[sysSockID] = id
+	}
 	if len(c.Peer.Meta[sysSockID]) == 0 {
 		switch c.typ {
-		case Router: // TODO: STREAM type when implemented
+		case Router, Stream:
 			// if empty Identity metadata is received from some client
 			// need to assign an uuid such that router socket can reply to the correct client
 			c.Peer.Meta[sysSockID] = newUUID()
 		}
 	}
+	var stale []*Conn
+	if id := c.Peer.Meta[sysSockID]; sck.handover && id != "" {
+		// the peer reconnected before its former connection was found
+		// dead: the new connection takes its identity over.
+		for _, old := range sck.conns {
+			if old != c && old.Peer.Meta[sysSockID] == id {
+				stale = append(stale, old)
+			}
+		}
+		for _, old := range stale {
+			sck.removeConn(old)
+		}
+	}
+	c.hb = sck.heartbeat
+	if c.hb.ivl > 0 && c.typ != Stream && !sck.isClosed {
+		sck.wg.Add(1)
+		go c.heartbeat(sck.ctx, &sck.wg)
+	}
 	if sck.w != nil {
 		sck.w.addConn(c)
 	}
@@ -358,22 +1127,38 @@ func (sck *socket) addConn(c *Conn) {
 		sck.r.addConn(c)
 	}
 	// Get topics to resend while holding the lock
-	var topics []string
+	var topics, groups []string
 	if sck.subTopics != nil {
 		topics = sck.subTopics()
 	}
+	if sck.dishGroups != nil {
+		groups = sck.dishGroups()
+	}
 	sck.mu.Unlock()
+	sck.watchers.notify()
+
+	for _, old := range stale {
+		_ = old.drop()
+	}
 
 	// resend subscriptions for topics if there are any (without holding the lock)
 	for _, topic := range topics {
-		_ = sck.Send(NewMsg(append([]byte{1}, topic...)))
+		_ = sck.Send(NewMsg(SubscribeFrame([]byte(topic))))
+	}
+	for _, group := range groups {
+		_ = c.SendCmd(CmdJoin, []byte(group))
 	}
 }
 
 func (sck *socket) rmConn(c *Conn) {
 	sck.mu.Lock()
 	defer sck.mu.Unlock()
+	sck.removeConn(c)
+}
 
+// removeConn removes c from the connections of the socket and from its
+// pools. removeConn must be called with sck.mu held.
+func (sck *socket) removeConn(c *Conn) {
 	cur := -1
 	for i := range sck.conns {
 		if sck.conns[i] == c {
@@ -393,17 +1178,149 @@ func (sck *socket) rmConn(c *Conn) {
 	if sck.w != nil {
 		sck.w.rmConn(c)
 	}
+	sck.watchers.notify()
 }
 
 func (sck *socket) scheduleRmConn(c *Conn) {
+	sck.emitEvent(EventDisconnected, c.rw.RemoteAddr().String(), nil)
+
 	sck.reaperCond.L.Lock()
 	sck.closedConns = append(sck.closedConns, c)
 	sck.reaperCond.Signal()
 	sck.reaperCond.L.Unlock()
 
-	if sck.autoReconnect {
-		sck.Dial(sck.ep)
+	if !sck.autoReconnect || !c.dialed || c.endpoint == "" {
+		return
+	}
+	// the connection may be torn down by a writer holding the lock of its
+	// pool: reconnect in the background. Close marks the socket closed
+	// with sck.mu held before waiting for its goroutines.
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
+	if sck.isClosed || !slices.Contains(sck.dialed, c.endpoint) {
+		return
+	}
+	sck.wg.Add(1)
+	go sck.dialAsync(c.endpoint)
+}
+
+// isDialed returns whether ep is one of the endpoints the socket is
+// connected to with Dial.
+func (sck *socket) isDialed(ep string) bool {
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
+	return ep != "" && slices.Contains(sck.dialed, ep)
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (sck *socket) Endpoints() []string {
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
+	return slices.Clone(sck.dialed)
+}
+
+// Disconnect closes the connections to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (sck *socket) Disconnect(ep string) error {
+	sck.mu.Lock()
+	i := slices.Index(sck.dialed, ep)
+	if i < 0 {
+		sck.mu.Unlock()
+		return fmt.Errorf("zmq4: socket is not connected to %q", ep)
+	}
+	sck.dialed = slices.Delete(sck.dialed, i, i+1)
+	var conns []*Conn
+	for _, c := range sck.conns {
+		if c.dialed && c.endpoint == ep {
+			conns = append(conns, c)
+		}
 	}
+	sck.mu.Unlock()
+
+	var err error
+	for _, c := range conns {
+		sck.rmConn(c)
+		if e := c.drop(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// boundListener is a listener of a socket, with the end-point it listens to.
+type boundListener struct {
+	ep     string
+	l      net.Listener
+	cancel context.CancelFunc // stops the accept loop of the listener
+	done   chan struct{}      // closed once the accept loop returned
+}
+
+// listenerOf returns the index of the listener of ep, or -1 if the socket
+// does not listen to ep. ep is either the end-point passed to Listen, or the
+// end-point resolved by the listener, e.g. with the port picked for
+// "tcp://127.0.0.1:0".
+// listenerOf must be called with sck.mu held.
+func (sck *socket) listenerOf(ep string) int {
+	return slices.IndexFunc(sck.listeners, func(bl *boundListener) bool {
+		if bl.ep == ep {
+			return true
+		}
+		network, _, _ := strings.Cut(bl.ep, "://")
+		return network+"://"+bl.l.Addr().String() == ep
+	})
+}
+
+// closeListener closes the listener bl.
+func (sck *socket) closeListener(bl *boundListener) {
+	bl.cancel()
+	bl.l.Close()
+	sck.emitEvent(EventClosed, bl.ep, nil)
+	// Remove the unix socket file if created by net.Listen
+	if path, ok := strings.CutPrefix(bl.ep, "ipc://"); ok && !ipcAbstract(path) {
+		os.Remove(path)
+	}
+}
+
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it. The other listeners and connections of the socket are
+// left intact.
+func (sck *socket) Unbind(ep string) error {
+	sck.mu.Lock()
+	if sck.isClosed {
+		sck.mu.Unlock()
+		return fmt.Errorf("zmq4: socket is closed")
+	}
+	i := sck.listenerOf(ep)
+	if i < 0 {
+		sck.mu.Unlock()
+		return fmt.Errorf("zmq4: socket is not listening on %q", ep)
+	}
+	bl := sck.listeners[i]
+	sck.listeners = slices.Delete(sck.listeners, i, i+1)
+	sck.mu.Unlock()
+
+	sck.closeListener(bl)
+	// wait for a handshake in progress to be done with, so that its
+	// connection is closed below.
+	<-bl.done
+
+	sck.mu.RLock()
+	var conns []*Conn
+	for _, c := range sck.conns {
+		if !c.dialed && c.endpoint == bl.ep {
+			conns = append(conns, c)
+		}
+	}
+	sck.mu.RUnlock()
+
+	var err error
+	for _, c := range conns {
+		sck.rmConn(c)
+		if e := c.drop(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
 }
 
 // Type returns the type of this Socket (PUB, SUB, ...)
@@ -411,20 +1328,85 @@ func (sck *socket) Type() SocketType {
 	return sck.typ
 }
 
-// Addr returns the listener's address.
+// Addr returns the listener's address, the address of the first one if
+// the socket listens to several end-points.
 // Addr returns nil if the socket isn't a listener.
 func (sck *socket) Addr() net.Addr {
-	if sck.listener == nil {
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
+	if len(sck.listeners) == 0 {
 		return nil
 	}
-	return sck.listener.Addr()
+	return sck.listeners[0].l.Addr()
+}
+
+// LastEndpoint returns the end-point the socket last listened to or dialed,
+// as resolved by its listener.
+func (sck *socket) LastEndpoint() (string, error) {
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
+	if sck.isClosed {
+		return "", fmt.Errorf("zmq4: socket is closed")
+	}
+	return sck.lastEndpoint, nil
 }
 
 // GetOption is used to retrieve an option for a socket.
 func (sck *socket) GetOption(name string) (interface{}, error) {
+	switch name {
+	case OptionLinger:
+		sck.mu.RLock()
+		defer sck.mu.RUnlock()
+		return sck.linger, nil
+	case OptionTimeout:
+		return sck.Timeout(), nil
+	case OptionIdentity:
+		return sck.identity().String(), nil
+	case OptionHWM, OptionSndHWM:
+		if w, ok := sck.w.(hwmWriter); ok {
+			return w.sendHWM(), nil
+		}
+		sck.mu.RLock()
+		defer sck.mu.RUnlock()
+		return sck.sndHWM, nil
+	case OptionRcvHWM:
+		return sck.recv.qsize, nil
+	case OptionReconnectIvl:
+		sck.mu.RLock()
+		defer sck.mu.RUnlock()
+		return sck.backoff.Initial, nil
+	case OptionReconnectIvlMax:
+		sck.mu.RLock()
+		defer sck.mu.RUnlock()
+		return sck.backoff.Max, nil
+	case OptionTCPKeepalive, OptionTCPKeepaliveIdle, OptionTCPKeepaliveInterval, OptionTCPKeepaliveCount:
+		sck.mu.RLock()
+		defer sck.mu.RUnlock()
+		switch name {
+		case OptionTCPKeepalive:
+			return sck.keepalive.mode, nil
+		case OptionTCPKeepaliveIdle:
+			return sck.keepalive.idle, nil
+		case OptionTCPKeepaliveInterval:
+			return sck.keepalive.interval, nil
+		default:
+			return sck.keepalive.count, nil
+		}
+	case OptionHeartbeatIvl, OptionHeartbeatTimeout, OptionHeartbeatTTL:
+		sck.mu.RLock()
+		defer sck.mu.RUnlock()
+		switch name {
+		case OptionHeartbeatIvl:
+			return sck.heartbeat.ivl, nil
+		case OptionHeartbeatTimeout:
+			return sck.heartbeat.timeout, nil
+		default:
+			return sck.heartbeat.ttl, nil
+		}
+	}
 	v, ok := sck.props[name]
 	if !ok {
-		return nil, ErrBadProperty
+		return nil, fmt.Errorf("zmq4: option %s is not set: %w", name, ErrBadProperty)
 	}
 	return v, nil
 }
@@ -432,11 +1414,119 @@ func (sck *socket) GetOption(name string) (interface{}, error) {
 // SetOption is used to set an option for a socket.
 func (sck *socket) SetOption(name string, value interface{}) error {
 	// FIXME(sbinet) different socket types support different options.
+	value, err := optionValue(name, value)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case OptionLinger:
+		sck.mu.Lock()
+		sck.linger = value.(time.Duration)
+		sck.mu.Unlock()
+		return nil
+	case OptionTimeout:
+		sck.mu.Lock()
+		sck.timeout = value.(time.Duration)
+		sck.recvTimeout = sck.timeout
+		sck.mu.Unlock()
+		return nil
+	case OptionIdentity:
+		sck.mu.Lock()
+		defer sck.mu.Unlock()
+		// peers learn the identity during the handshake: it cannot change
+		// under their feet.
+		if len(sck.conns) > 0 {
+			return fmt.Errorf("zmq4: could not set %s socket identity: %w", sck.typ, ErrIdentityChange)
+		}
+		sck.id = SocketIdentity(value.(string))
+		return nil
+	case OptionHWM, OptionSndHWM:
+		if w, ok := sck.w.(*mwriter); ok && w.lb {
+			// the messages are written within Send: nothing is queued.
+			return fmt.Errorf("zmq4: %s only applies to %s sockets created with WithSendHWM: %w", name, sck.typ, ErrBadProperty)
+		}
+		sck.mu.Lock()
+		sck.sndHWM = value.(int)
+		sck.mu.Unlock()
+		if w, ok := sck.w.(hwmWriter); ok {
+			w.setSendHWM(value.(int))
+		}
+		return nil
+	case OptionJoin, OptionLeave:
+		if sck.typ != Dish {
+			return fmt.Errorf("zmq4: %s only applies to DISH sockets, not %s: %w", name, sck.typ, ErrBadProperty)
+		}
+		return nil
+	case OptionRcvHWM:
+		// the receive queues are allocated with the socket.
+		return fmt.Errorf("zmq4: %s can only be set with WithRecvHWM: %w", name, ErrBadProperty)
+	case OptionReconnectIvl:
+		sck.mu.Lock()
+		sck.backoff.Initial = value.(time.Duration)
+		sck.mu.Unlock()
+		return nil
+	case OptionReconnectIvlMax:
+		sck.mu.Lock()
+		defer sck.mu.Unlock()
+		sck.backoff.Max = value.(time.Duration)
+		switch {
+		case sck.backoff.Max == 0:
+			sck.backoff.Multiplier = 0
+		case sck.backoff.Multiplier <= 1:
+			sck.backoff.Multiplier = 2
+		}
+		return nil
+	case OptionTCPKeepalive, OptionTCPKeepaliveIdle, OptionTCPKeepaliveInterval, OptionTCPKeepaliveCount:
+		sck.mu.Lock()
+		defer sck.mu.Unlock()
+		switch name {
+		case OptionTCPKeepalive:
+			sck.keepalive.mode = value.(int)
+		case OptionTCPKeepaliveIdle:
+			sck.keepalive.idle = value.(time.Duration)
+		case OptionTCPKeepaliveInterval:
+			sck.keepalive.interval = value.(time.Duration)
+		default:
+			sck.keepalive.count = value.(int)
+		}
+		return nil
+	case OptionHeartbeatIvl, OptionHeartbeatTimeout, OptionHeartbeatTTL:
+		sck.mu.Lock()
+		defer sck.mu.Unlock()
+		switch name {
+		case OptionHeartbeatIvl:
+			sck.heartbeat.ivl = value.(time.Duration)
+		case OptionHeartbeatTimeout:
+			sck.heartbeat.timeout = value.(time.Duration)
+		default:
+			sck.heartbeat.ttl = value.(time.Duration)
+		}
+		return nil
+	}
 	sck.props[name] = value
 	return nil
 }
 
+// identity returns the identity announced by the socket to its peers.
+func (sck *socket) identity() SocketIdentity {
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
+	return sck.id
+}
+
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (sck *socket) Reader() SocketReader {
+	return socketReader{sck}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (sck *socket) Writer() SocketWriter {
+	return socketWriter{sck}
+}
+
 func (sck *socket) Timeout() time.Duration {
+	sck.mu.RLock()
+	defer sck.mu.RUnlock()
 	return sck.timeout
 }
 
@@ -446,6 +1536,7 @@ func (sck *socket) connReaper() {
 	// That would ensure that sck.reaperCond.Signal()
 	// would be delivered only when reaper goroutine is really started
 	// and is in sck.reaperCond.Wait()
+	defer sck.wg.Done()
 	defer sck.reaperCond.L.Unlock()
 
 	for {
@@ -10,11 +10,18 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/luxfi/zmq/v4"
+	"github.com/luxfi/zmq/v4/security/plain"
 	"github.com/luxfi/zmq/v4/transport"
 	"golang.org/x/sync/errgroup"
 )
@@ -389,3 +396,1297 @@ func TestSocketAutomaticReconnect(t *testing.T) {
 	sendMessages(pub2)
 	checkConnectionWorking(sub)
 }
+
+func TestSocketReconnectResubscribes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	sub := zmq4.NewSub(ctx, zmq4.WithAutomaticReconnect(true), zmq4.WithDialerRetry(10*time.Millisecond))
+	defer sub.Close()
+	if err := sub.SetOption(zmq4.OptionSubscribe, "a"); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+
+	// publish sends messages on both topics until pub is closed.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	publish := func(pub zmq4.Socket) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				topic := []string{"a", "b"}[i%2]
+				if err := pub.Send(zmq4.NewMsgString(topic)); err != nil && ctx.Err() != nil {
+					return
+				}
+				select {
+				case <-pub.Done():
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		}()
+	}
+
+	// check receives a few messages, all of them on the subscribed topic.
+	check := func() {
+		t.Helper()
+		for n := 0; n < 5; {
+			msg, err := sub.Recv()
+			if errors.Is(err, io.EOF) {
+				// the connection to the former publisher was lost.
+				continue
+			}
+			if err != nil {
+				t.Fatalf("could not recv: %+v", err)
+			}
+			if got := string(msg.Frames[0]); got != "a" {
+				t.Fatalf("invalid topic: got=%q, want=%q", got, "a")
+			}
+			n++
+		}
+	}
+
+	pub := zmq4.NewPub(ctx)
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	publish(pub)
+	check()
+
+	pub.Close()
+
+	pub = zmq4.NewPub(ctx)
+	defer pub.Close()
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen again: %+v", err)
+	}
+	publish(pub)
+	check()
+}
+
+func TestSocketReconnectIdentity(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	const id = "worker"
+
+	dealer := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity(id)), zmq4.WithDialerRetry(10*time.Millisecond))
+	defer dealer.Close()
+
+	// check sends messages from the dealer until one of them is received
+	// by router, from the identity of the dealer.
+	check := func(router zmq4.Socket) {
+		t.Helper()
+		got := make(chan zmq4.Msg, 1)
+		go func() {
+			msg, err := router.Recv()
+			if err == nil {
+				got <- msg
+			}
+		}()
+		for {
+			_ = dealer.Send(zmq4.NewMsgString("ping"))
+			select {
+			case msg := <-got:
+				if got := string(msg.Frames[0]); got != id {
+					t.Fatalf("invalid identity: got=%q, want=%q", got, id)
+				}
+				return
+			case <-ctx.Done():
+				t.Fatalf("no message received: %+v", ctx.Err())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	router := zmq4.NewRouter(ctx)
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := dealer.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	check(router)
+
+	router.Close()
+
+	router = zmq4.NewRouter(ctx)
+	defer router.Close()
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen again: %+v", err)
+	}
+	check(router)
+}
+
+func TestSocketInvalidOperation(t *testing.T) {
+	// sockets are created with a done context so allowed operations return
+	// right away.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	check := func(t *testing.T, op string, allowed bool, err error) {
+		t.Helper()
+		switch {
+		case allowed && errors.Is(err, zmq4.ErrInvalidOperation):
+			t.Fatalf("%s: unexpected invalid operation error: %v", op, err)
+		case !allowed && !errors.Is(err, zmq4.ErrInvalidOperation):
+			t.Fatalf("%s: invalid error: got=%v, want=%v", op, err, zmq4.ErrInvalidOperation)
+		}
+	}
+
+	for _, tc := range []struct {
+		sck  zmq4.Socket
+		send bool // whether the socket can send
+		recv bool // whether the socket can receive
+	}{
+		{zmq4.NewPair(ctx), true, true},
+		{zmq4.NewPub(ctx), true, false},
+		{zmq4.NewSub(ctx), false, true},
+		{zmq4.NewReq(ctx), true, true},
+		{zmq4.NewRep(ctx), true, true},
+		{zmq4.NewDealer(ctx), true, true},
+		{zmq4.NewRouter(ctx), true, true},
+		{zmq4.NewPull(ctx), false, true},
+		{zmq4.NewPush(ctx), true, false},
+		{zmq4.NewXPub(ctx), true, true},
+		{zmq4.NewXSub(ctx), true, true},
+		{zmq4.NewStream(ctx), true, true},
+	} {
+		t.Run(string(tc.sck.Type()), func(t *testing.T) {
+			defer tc.sck.Close()
+
+			msg := zmq4.NewMsgString("data")
+			check(t, "Send", tc.send, tc.sck.Send(msg))
+			check(t, "SendMulti", tc.send, tc.sck.SendMulti(msg))
+			_, err := tc.sck.Recv()
+			check(t, "Recv", tc.recv, err)
+		})
+	}
+
+	// the error message names the socket type and the operation.
+	pub := zmq4.NewPub(ctx)
+	defer pub.Close()
+	_, err := pub.Recv()
+	if got, want := err.Error(), "zmq4: PUB sockets cannot receive"; !strings.HasPrefix(got, want) {
+		t.Fatalf("invalid error message: got=%q, want prefix %q", got, want)
+	}
+}
+
+func TestSocketSendPeerDisconnected(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	pull := zmq4.NewPull(ctx)
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	push := zmq4.NewPush(ctx, zmq4.WithDialerRetry(50*time.Millisecond))
+	defer push.Close()
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := push.Send(zmq4.NewMsgString("first")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+
+	// kill the peer: sends fail once the connection is reset.
+	pull.Close()
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		err = push.Send(zmq4.NewMsgString("lost"))
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !errors.Is(err, zmq4.ErrPeerDisconnected) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrPeerDisconnected)
+	}
+
+	// the socket reconnects to a new peer on the same end-point.
+	pull = zmq4.NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen again: %+v", err)
+	}
+
+	got := make(chan string, 1)
+	go func() {
+		msg, err := pull.Recv()
+		if err == nil {
+			got <- string(msg.Frames[0])
+		}
+	}()
+	for {
+		err := push.Send(zmq4.NewMsgString("retried"))
+		if err != nil && !errors.Is(err, zmq4.ErrPeerDisconnected) && !errors.Is(err, zmq4.ErrClosedConn) {
+			t.Fatalf("could not send: %+v", err)
+		}
+		select {
+		case msg := <-got:
+			if msg != "retried" {
+				t.Fatalf("invalid message: got=%q, want=%q", msg, "retried")
+			}
+			return
+		case <-ctx.Done():
+			t.Fatalf("socket did not reconnect")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestSocketLingerOption(t *testing.T) {
+	sck := zmq4.NewPair(context.Background(), zmq4.WithLinger(time.Second))
+	defer sck.Close()
+
+	for _, tc := range []struct {
+		value interface{}
+		want  time.Duration
+	}{
+		{nil, time.Second}, // set with WithLinger
+		{250, 250 * time.Millisecond},
+		{-1, -1},
+		{2 * time.Second, 2 * time.Second},
+		{0, 0},
+	} {
+		if tc.value != nil {
+			if err := sck.SetOption(zmq4.OptionLinger, tc.value); err != nil {
+				t.Fatalf("could not set linger to %v: %+v", tc.value, err)
+			}
+		}
+		v, err := sck.GetOption(zmq4.OptionLinger)
+		if err != nil {
+			t.Fatalf("could not get linger: %+v", err)
+		}
+		if got := v.(time.Duration); got != tc.want {
+			t.Fatalf("invalid linger: got=%v, want=%v", got, tc.want)
+		}
+	}
+
+	if err := sck.SetOption(zmq4.OptionLinger, "1s"); err == nil {
+		t.Fatalf("expected an error setting an invalid linger")
+	}
+}
+
+func TestSocketOptionValidation(t *testing.T) {
+	ctx := context.Background()
+	sub := zmq4.NewSub(ctx)
+	defer sub.Close()
+
+	for _, tc := range []struct {
+		name  string
+		value interface{}
+	}{
+		{zmq4.OptionSubscribe, 42},
+		{zmq4.OptionSubscribe, nil},
+		{zmq4.OptionUnsubscribe, 3.14},
+		{zmq4.OptionJoin, []string{"group"}},
+		{zmq4.OptionLeave, true},
+		{zmq4.OptionIdentity, 1},
+		{zmq4.OptionIdentity, ""},
+		{zmq4.OptionIdentity, strings.Repeat("x", 256)},
+		{zmq4.OptionHWM, "10"},
+		{zmq4.OptionHWM, -1},
+		{zmq4.OptionHWM, int64(10)},
+		{zmq4.OptionTimeout, 10},
+		{zmq4.OptionTimeout, -time.Second},
+		{zmq4.OptionLinger, "1s"},
+	} {
+		t.Run(fmt.Sprintf("%s-%T", tc.name, tc.value), func(t *testing.T) {
+			err := sub.SetOption(tc.name, tc.value)
+			if err == nil {
+				t.Fatalf("expected an error setting %s to %v", tc.name, tc.value)
+			}
+			if !errors.Is(err, zmq4.ErrBadProperty) {
+				t.Fatalf("invalid error: got=%+v, want=%+v", err, zmq4.ErrBadProperty)
+			}
+			if !strings.Contains(err.Error(), tc.name) {
+				t.Fatalf("error %q does not name option %s", err, tc.name)
+			}
+		})
+	}
+
+	for _, v := range []interface{}{"topic", []byte("bytes")} {
+		if err := sub.SetOption(zmq4.OptionSubscribe, v); err != nil {
+			t.Fatalf("could not subscribe to %v: %+v", v, err)
+		}
+	}
+	if err := sub.SetOption(zmq4.OptionUnsubscribe, []byte("bytes")); err != nil {
+		t.Fatalf("could not unsubscribe: %+v", err)
+	}
+	if got := sub.(zmq4.Topics).Topics(); len(got) != 1 || got[0] != "topic" {
+		t.Fatalf("invalid topics: got=%q, want=%q", got, []string{"topic"})
+	}
+
+	dealer := zmq4.NewDealer(ctx, zmq4.WithSendHWM(5))
+	defer dealer.Close()
+
+	for _, tc := range []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{zmq4.OptionIdentity, []byte("dealer-id"), "dealer-id"},
+		{zmq4.OptionHWM, 10, 10},
+		{zmq4.OptionTimeout, 2 * time.Second, 2 * time.Second},
+		{zmq4.OptionTimeout, time.Duration(0), time.Duration(0)},
+	} {
+		if err := dealer.SetOption(tc.name, tc.value); err != nil {
+			t.Fatalf("could not set %s to %v: %+v", tc.name, tc.value, err)
+		}
+		v, err := dealer.GetOption(tc.name)
+		if err != nil {
+			t.Fatalf("could not get %s: %+v", tc.name, err)
+		}
+		if v != tc.want {
+			t.Fatalf("invalid %s: got=%v, want=%v", tc.name, v, tc.want)
+		}
+	}
+
+	// without WithSendHWM, PUSH and DEALER sockets queue nothing to bound.
+	for _, sck := range []zmq4.Socket{zmq4.NewPush(ctx), zmq4.NewDealer(ctx)} {
+		defer sck.Close()
+		for _, name := range []string{zmq4.OptionHWM, zmq4.OptionSndHWM} {
+			if err := sck.SetOption(name, 10); !errors.Is(err, zmq4.ErrBadProperty) {
+				t.Fatalf("%s: invalid error setting %s: got=%v, want=%v", sck.Type(), name, err, zmq4.ErrBadProperty)
+			}
+		}
+	}
+}
+
+func TestSocketOptionTypes(t *testing.T) {
+	ctx := context.Background()
+	sub := zmq4.NewSub(ctx)
+	defer sub.Close()
+
+	for _, tc := range []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{zmq4.OptionHWM, "10", `HWM expects non-negative int, got string ("10")`},
+		{zmq4.OptionHWM, 10.0, `HWM expects non-negative int, got float64 (10)`},
+		{zmq4.OptionIdentity, 42, `IDENTITY expects string or []byte, got int (42)`},
+		{zmq4.OptionSubscribe, 42, `SUBSCRIBE expects string or []byte, got int (42)`},
+		{zmq4.OptionLinger, "1s", `LINGER expects time.Duration or int, got string ("1s")`},
+		{zmq4.OptionReconnectIvl, 100, `RECONNECT_IVL expects positive time.Duration, got int (100)`},
+		{zmq4.OptionReconnectIvlMax, "1m", `RECONNECT_IVL_MAX expects non-negative time.Duration, got string ("1m")`},
+	} {
+		t.Run(fmt.Sprintf("%s-%T", tc.name, tc.value), func(t *testing.T) {
+			err := sub.SetOption(tc.name, tc.value)
+			if !errors.Is(err, zmq4.ErrBadProperty) {
+				t.Fatalf("invalid error: got=%+v, want=%+v", err, zmq4.ErrBadProperty)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("invalid error message: got=%q, want=%q", err, tc.want)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{zmq4.OptionHWM, 10, 10},
+		{zmq4.OptionIdentity, []byte("sub-id"), "sub-id"},
+		{zmq4.OptionSubscribe, []byte("topic"), "topic"},
+		{zmq4.OptionLinger, 1500, 1500 * time.Millisecond},
+		{zmq4.OptionLinger, -1, time.Duration(-1)},
+		{zmq4.OptionReconnectIvl, 50 * time.Millisecond, 50 * time.Millisecond},
+		{zmq4.OptionReconnectIvlMax, time.Second, time.Second},
+	} {
+		t.Run(fmt.Sprintf("get-%s-%T", tc.name, tc.value), func(t *testing.T) {
+			if err := sub.SetOption(tc.name, tc.value); err != nil {
+				t.Fatalf("could not set %s to %v: %+v", tc.name, tc.value, err)
+			}
+			got, err := sub.GetOption(tc.name)
+			if err != nil {
+				t.Fatalf("could not get %s: %+v", tc.name, err)
+			}
+			if got != tc.want {
+				t.Fatalf("invalid %s: got=%#v (%T), want=%#v (%T)", tc.name, got, got, tc.want, tc.want)
+			}
+		})
+	}
+
+	if _, err := sub.GetOption("NO_SUCH_OPTION"); !errors.Is(err, zmq4.ErrBadProperty) || !strings.Contains(err.Error(), "NO_SUCH_OPTION") {
+		t.Fatalf("invalid error getting an unknown option: %v", err)
+	}
+}
+
+func TestSocketOptionsTyped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pub := zmq4.NewPub(ctx)
+	defer pub.Close()
+	sub := zmq4.NewSub(ctx)
+	defer sub.Close()
+	opts := zmq4.Options(sub)
+
+	if err := opts.SetHWM(42); err != nil {
+		t.Fatalf("could not set HWM: %+v", err)
+	}
+	if hwm, err := opts.HWM(); err != nil || hwm != 42 {
+		t.Fatalf("invalid HWM: got=(%d, %v), want=(42, nil)", hwm, err)
+	}
+	if err := opts.SetHWM(-1); !errors.Is(err, zmq4.ErrBadProperty) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrBadProperty)
+	}
+
+	if id := opts.Identity(); id != nil {
+		t.Fatalf("invalid default identity: got=%q, want nil", id)
+	}
+	if err := opts.SetIdentity([]byte("sub-id")); err != nil {
+		t.Fatalf("could not set identity: %+v", err)
+	}
+	if got, want := string(opts.Identity()), "sub-id"; got != want {
+		t.Fatalf("invalid identity: got=%q, want=%q", got, want)
+	}
+	if err := opts.SetIdentity(nil); !errors.Is(err, zmq4.ErrBadProperty) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrBadProperty)
+	}
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	for _, topic := range []string{"a", "b"} {
+		if err := opts.Subscribe([]byte(topic)); err != nil {
+			t.Fatalf("could not subscribe to %q: %+v", topic, err)
+		}
+	}
+	if err := opts.Unsubscribe([]byte("b")); err != nil {
+		t.Fatalf("could not unsubscribe: %+v", err)
+	}
+	if got := sub.(zmq4.Topics).Topics(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("invalid topics: got=%q, want=%q", got, []string{"a"})
+	}
+	// let the subscriptions reach the publisher.
+	time.Sleep(200 * time.Millisecond)
+
+	for _, topic := range []string{"b", "a"} {
+		if err := pub.Send(zmq4.NewMsgString(topic)); err != nil {
+			t.Fatalf("could not send %q: %+v", topic, err)
+		}
+	}
+	msg, err := sub.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Bytes()), "a"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+}
+
+func TestSocketCloseLinger(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		linger time.Duration
+	}{
+		{"discard", 0},
+		{"timeout", 200 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ep := must(EndPoint("tcp"))
+
+			// the XPUB peer stops reading once its receive queue is full.
+			xpub := zmq4.NewXPub(ctx, zmq4.WithRecvQueueSize(1))
+			defer xpub.Close()
+			if err := xpub.Listen(ep); err != nil {
+				t.Fatalf("could not listen: %+v", err)
+			}
+
+			xsub := zmq4.NewXSub(ctx, zmq4.WithLinger(-1))
+			if err := xsub.Dial(ep); err != nil {
+				t.Fatalf("could not dial: %+v", err)
+			}
+			payload := make([]byte, 1<<20)
+			for i := 0; i < 64; i++ {
+				if err := xsub.Send(zmq4.NewMsgFrom([]byte("data"), payload)); err != nil {
+					t.Fatalf("could not send: %+v", err)
+				}
+			}
+
+			// the most recent linger wins over WithLinger.
+			if err := xsub.SetOption(zmq4.OptionLinger, tc.linger); err != nil {
+				t.Fatalf("could not set linger: %+v", err)
+			}
+			start := time.Now()
+			if err := xsub.Close(); err != nil {
+				t.Fatalf("could not close: %+v", err)
+			}
+			elapsed := time.Since(start)
+			if elapsed < tc.linger || elapsed > tc.linger+time.Second {
+				t.Fatalf("invalid close duration: got=%v, want=%v", elapsed, tc.linger)
+			}
+		})
+	}
+}
+
+func TestSocketConnections(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		sec  zmq4.Security
+		want zmq4.SecurityType
+	}{
+		{"null", nil, zmq4.NullSecurity},
+		{"plain", plain.Security("user", "secret"), zmq4.PlainSecurity},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			var opts []zmq4.Option
+			if tc.sec != nil {
+				opts = append(opts, zmq4.WithSecurity(tc.sec))
+			}
+			ep := must(EndPoint("tcp"))
+			srv := zmq4.NewPair(ctx, append(opts, zmq4.WithID(zmq4.SocketIdentity("srv")))...)
+			defer srv.Close()
+			cli := zmq4.NewPair(ctx, append(opts, zmq4.WithID(zmq4.SocketIdentity("cli")))...)
+			defer cli.Close()
+
+			if err := srv.Listen(ep); err != nil {
+				t.Fatalf("could not listen: %+v", err)
+			}
+			if err := cli.Dial(ep); err != nil {
+				t.Fatalf("could not dial: %+v", err)
+			}
+
+			var conns []zmq4.ConnInfo
+			for len(conns) == 0 {
+				select {
+				case <-ctx.Done():
+					t.Fatalf("no server connection")
+				case <-time.After(10 * time.Millisecond):
+				}
+				conns = srv.(zmq4.ConnectionLister).Connections()
+			}
+			got := conns[0]
+			if got.Mechanism != tc.want || got.Identity != "cli" || got.PeerType != zmq4.Pair || !got.Server || got.Remote == "" {
+				t.Fatalf("invalid server connection: %+v", got)
+			}
+
+			conns = cli.(zmq4.ConnectionLister).Connections()
+			if len(conns) != 1 {
+				t.Fatalf("invalid number of client connections: %d", len(conns))
+			}
+			got = conns[0]
+			if got.Mechanism != tc.want || got.Identity != "srv" || got.Server || got.Remote != srv.Addr().String() {
+				t.Fatalf("invalid client connection: %+v", got)
+			}
+		})
+	}
+}
+
+func TestSocketDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv := zmq4.NewRouter(ctx)
+	if err := srv.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep := "tcp://" + srv.Addr().String()
+
+	cli := zmq4.NewDealer(ctx)
+	defer cli.Close()
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	select {
+	case <-srv.Done():
+		t.Fatalf("socket done before Close")
+	default:
+	}
+
+	go srv.Close()
+	select {
+	case <-srv.Done():
+	case <-ctx.Done():
+		t.Fatalf("socket not done after Close")
+	}
+
+	// the endpoint is released once the socket is done.
+	srv = zmq4.NewRouter(ctx)
+	defer srv.Close()
+	if err := srv.Listen(ep); err != nil {
+		t.Fatalf("could not listen again on %q: %+v", ep, err)
+	}
+}
+
+func TestSocketDoneContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sctx, scancel := context.WithCancel(ctx)
+	srv := zmq4.NewRouter(sctx)
+	defer srv.Close()
+	if err := srv.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep := "tcp://" + srv.Addr().String()
+
+	cli := zmq4.NewDealer(ctx)
+	defer cli.Close()
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	// a socket torn down by its context is done, without Close.
+	scancel()
+	select {
+	case <-srv.Done():
+	case <-ctx.Done():
+		t.Fatalf("socket not done after the end of its context")
+	}
+
+	// the endpoint is released once the socket is done.
+	srv2 := zmq4.NewRouter(ctx)
+	defer srv2.Close()
+	if err := srv2.Listen(ep); err != nil {
+		t.Fatalf("could not listen again on %q: %+v", ep, err)
+	}
+
+	// closing the socket afterwards is still fine.
+	if err := srv.Close(); err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+}
+
+func TestSocketMaxFrames(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	push := zmq4.NewPush(ctx, zmq4.WithMaxFrames(4))
+	defer push.Close()
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	frames := func(n int) zmq4.Msg {
+		msg := zmq4.NewMsgFrom()
+		for i := 0; i < n; i++ {
+			msg.Frames = append(msg.Frames, []byte{byte(i)})
+		}
+		return msg
+	}
+
+	err := push.SendMulti(frames(5))
+	if !errors.Is(err, zmq4.ErrTooManyFrames) {
+		t.Fatalf("invalid error sending 5 frames: got=%v, want=%v", err, zmq4.ErrTooManyFrames)
+	}
+
+	if err := push.SendMulti(frames(4)); err != nil {
+		t.Fatalf("could not send 4 frames: %+v", err)
+	}
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := len(msg.Frames), 4; got != want {
+		t.Fatalf("invalid number of frames: got=%d, want=%d", got, want)
+	}
+}
+
+// freePortRange returns the first port of a range of n free TCP ports.
+func freePortRange(t *testing.T, n int) int {
+	t.Helper()
+loop:
+	for i := 0; i < 100; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not find a free port: %+v", err)
+		}
+		lo := l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		if lo+n > 65536 {
+			continue
+		}
+		for port := lo; port < lo+n; port++ {
+			l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			if err != nil {
+				continue loop
+			}
+			l.Close()
+		}
+		return lo
+	}
+	t.Fatalf("could not find %d free ports", n)
+	return 0
+}
+
+func TestSocketPortRange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const n = 4
+	lo := freePortRange(t, n)
+	hi := lo + n - 1
+
+	// the first port of the range is taken: sockets must skip it.
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", lo))
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	defer l.Close()
+
+	ports := make(map[int]bool)
+	for i := 0; i < n-1; i++ {
+		sck := zmq4.NewPull(ctx, zmq4.WithPortRange(lo, hi))
+		defer sck.Close()
+		if err := sck.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen #%d in range [%d, %d]: %+v", i, lo, hi, err)
+		}
+		port := sck.Addr().(*net.TCPAddr).Port
+		if port <= lo || port > hi || ports[port] {
+			t.Fatalf("socket #%d listens to invalid port %d (range=[%d, %d], used=%v)", i, port, lo, hi, ports)
+		}
+		ports[port] = true
+	}
+
+	sck := zmq4.NewPull(ctx, zmq4.WithPortRange(lo, hi))
+	defer sck.Close()
+	if err := sck.Listen("tcp://127.0.0.1:0"); err == nil {
+		t.Fatalf("listened to %v with no free port in range", sck.Addr())
+	}
+
+	// explicit ports are not constrained by the range.
+	port := freePortRange(t, 1)
+	sck = zmq4.NewPull(ctx, zmq4.WithPortRange(lo, hi))
+	defer sck.Close()
+	if err := sck.Listen(fmt.Sprintf("tcp://127.0.0.1:%d", port)); err != nil {
+		t.Fatalf("could not listen to explicit port %d: %+v", port, err)
+	}
+}
+
+func TestSocketListenBacklog(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := zmq4.NewPull(ctx, zmq4.WithListenBacklog(4))
+	defer pull.Close()
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen with backlog: %+v", err)
+	}
+	ep := "tcp://" + pull.Addr().String()
+
+	const n = 16
+	for i := 0; i < n; i++ {
+		push := zmq4.NewPush(ctx)
+		defer push.Close()
+		if err := push.Dial(ep); err != nil {
+			t.Fatalf("could not dial #%d: %+v", i, err)
+		}
+		if err := push.Send(zmq4.NewMsgString("hello")); err != nil {
+			t.Fatalf("could not send #%d: %+v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if _, err := pull.Recv(); err != nil {
+			t.Fatalf("could not recv #%d: %+v", i, err)
+		}
+	}
+
+	// transports without kernel sockets ignore the backlog.
+	inp := zmq4.NewPull(ctx, zmq4.WithListenBacklog(4))
+	defer inp.Close()
+	if err := inp.Listen("inproc://listen-backlog"); err != nil {
+		t.Fatalf("could not listen to inproc with backlog: %+v", err)
+	}
+}
+
+func TestSocketEndpoints(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		pulls [2]zmq4.Socket
+		eps   [2]string
+	)
+	for i := range pulls {
+		pulls[i] = zmq4.NewPull(ctx)
+		defer pulls[i].Close()
+		if err := pulls[i].Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen #%d: %+v", i, err)
+		}
+		eps[i] = "tcp://" + pulls[i].Addr().String()
+	}
+
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	for i, ep := range eps {
+		if err := push.Dial(ep); err != nil {
+			t.Fatalf("could not dial #%d: %+v", i, err)
+		}
+	}
+	mgr := push.(zmq4.EndpointManager)
+	if got, want := mgr.Endpoints(), eps[:]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid endpoints: got=%q, want=%q", got, want)
+	}
+
+	// messages are dealt to the connections in turn.
+	const n = 10
+	for i := 0; i < n; i++ {
+		if err := push.Send(zmq4.NewMsgString(strconv.Itoa(i))); err != nil {
+			t.Fatalf("could not send #%d: %+v", i, err)
+		}
+	}
+	var parity [2]int
+	for i, pull := range pulls {
+		for j := 0; j < n/2; j++ {
+			msg, err := pull.Recv()
+			if err != nil {
+				t.Fatalf("could not recv #%d from pull #%d: %+v", j, i, err)
+			}
+			v, err := strconv.Atoi(string(msg.Bytes()))
+			if err != nil {
+				t.Fatalf("invalid message %q: %+v", msg.Bytes(), err)
+			}
+			if j == 0 {
+				parity[i] = v % 2
+			}
+			if v%2 != parity[i] {
+				t.Fatalf("pull #%d received messages #%d and #%d: not dealt in turn", i, parity[i], v)
+			}
+		}
+	}
+	if parity[0] == parity[1] {
+		t.Fatalf("both pulls received the same messages")
+	}
+
+	if err := mgr.Disconnect(eps[0]); err != nil {
+		t.Fatalf("could not disconnect: %+v", err)
+	}
+	if got, want := mgr.Endpoints(), eps[1:]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid endpoints: got=%q, want=%q", got, want)
+	}
+	if err := mgr.Disconnect(eps[0]); err == nil {
+		t.Fatalf("disconnected twice from %q", eps[0])
+	}
+
+	// the remaining connection gets all the messages.
+	for i := 0; i < n; i++ {
+		if err := push.Send(zmq4.NewMsgString(strconv.Itoa(i))); err != nil {
+			t.Fatalf("could not send #%d: %+v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		msg, err := pulls[1].Recv()
+		if err != nil {
+			t.Fatalf("could not recv #%d: %+v", i, err)
+		}
+		if got, want := string(msg.Bytes()), strconv.Itoa(i); got != want {
+			t.Fatalf("invalid message: got=%q, want=%q", got, want)
+		}
+	}
+
+	// messages are not silently dropped once no connection is left.
+	if err := mgr.Disconnect(eps[1]); err != nil {
+		t.Fatalf("could not disconnect: %+v", err)
+	}
+	if err := push.Send(zmq4.NewMsgString("lost")); !errors.Is(err, zmq4.ErrPeerDisconnected) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrPeerDisconnected)
+	}
+}
+
+func TestSocketUnbind(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	for _, ep := range []string{
+		"tcp://127.0.0.1:0",
+		"ipc://" + filepath.Join(t.TempDir(), "unbind.sock"),
+	} {
+		if err := pull.Listen(ep); err != nil {
+			t.Fatalf("could not listen to %q: %+v", ep, err)
+		}
+	}
+	ep0 := "tcp://" + pull.Addr().String()
+
+	push0 := zmq4.NewPush(ctx)
+	defer push0.Close()
+	if err := push0.Dial(ep0); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep0, err)
+	}
+
+	unbinder := pull.(zmq4.Unbinder)
+	if err := unbinder.Unbind(ep0); err != nil {
+		t.Fatalf("could not unbind %q: %+v", ep0, err)
+	}
+	if err := unbinder.Unbind(ep0); err == nil {
+		t.Fatalf("unbound %q twice", ep0)
+	}
+
+	// the connection accepted on the unbound end-point is closed, and the
+	// end-point does not accept connections anymore.
+	for {
+		if len(pull.(zmq4.ConnectionLister).Connections()) == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("connection accepted on %q not closed", ep0)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	lost := zmq4.NewPush(ctx, zmq4.WithDialerMaxRetries(0))
+	defer lost.Close()
+	if err := lost.Dial(ep0); err == nil {
+		t.Fatalf("could dial unbound %q", ep0)
+	}
+
+	// the other end-point is still listening.
+	ep1 := "ipc://" + pull.Addr().String()
+	if ep1 == ep0 {
+		t.Fatalf("unbound listener still reported by Addr")
+	}
+	push1 := zmq4.NewPush(ctx)
+	defer push1.Close()
+	if err := push1.Dial(ep1); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep1, err)
+	}
+	if err := push1.Send(zmq4.NewMsgString("hello")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Bytes()), "hello"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+}
+
+func TestSocketSendHWM(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const hwm = 4
+	pull := zmq4.NewPull(ctx, zmq4.WithRecvHWM(1))
+	defer pull.Close()
+	if err := pull.Listen("inproc://send-hwm"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	push := zmq4.NewPush(ctx, zmq4.WithSendHWM(hwm))
+	defer push.Close()
+	if err := push.Dial("inproc://send-hwm"); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		sck  zmq4.Socket
+		name string
+		want int
+	}{
+		{push, zmq4.OptionSndHWM, hwm},
+		{push, zmq4.OptionHWM, hwm},
+		{pull, zmq4.OptionRcvHWM, 1},
+	} {
+		v, err := tc.sck.GetOption(tc.name)
+		if err != nil {
+			t.Fatalf("could not get %s: %+v", tc.name, err)
+		}
+		if v != tc.want {
+			t.Fatalf("invalid %s: got=%v, want=%v", tc.name, v, tc.want)
+		}
+	}
+	if err := pull.SetOption(zmq4.OptionRcvHWM, 10); !errors.Is(err, zmq4.ErrBadProperty) {
+		t.Fatalf("invalid error setting %s: got=%v, want=%v", zmq4.OptionRcvHWM, err, zmq4.ErrBadProperty)
+	}
+
+	// the pull socket does not read: the messages pile up in its receive
+	// queue, then in the send queue, until the push socket pushes back.
+	sent := 0
+	for ; sent < 1000; sent++ {
+		if err := push.SetSendDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+			t.Fatalf("could not set send deadline: %+v", err)
+		}
+		err := push.Send(zmq4.NewMsgString("msg"))
+		if errors.Is(err, zmq4.ErrTimeout) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("could not send #%d: %+v", sent, err)
+		}
+	}
+	if sent == 1000 {
+		t.Fatalf("send queue not bounded")
+	}
+	if got := push.(zmq4.StatsReporter).Stats().SendQueued; got != hwm {
+		t.Fatalf("invalid number of queued messages: got=%d, want=%d", got, hwm)
+	}
+	if err := push.SendNB(zmq4.NewMsgString("blocked")); !errors.Is(err, zmq4.ErrWouldBlock) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrWouldBlock)
+	}
+
+	// all the queued messages are delivered once the pull socket reads.
+	for i := 0; i < sent; i++ {
+		if _, err := pull.Recv(); err != nil {
+			t.Fatalf("could not recv #%d: %+v", i, err)
+		}
+	}
+}
+
+func TestSocketReconnectInterval(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	push := zmq4.NewPush(ctx, zmq4.WithAutomaticReconnect(true), zmq4.WithDialerMaxRetries(-1))
+	defer push.Close()
+	for _, tc := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{zmq4.OptionReconnectIvl, 10 * time.Millisecond},
+		{zmq4.OptionReconnectIvlMax, 80 * time.Millisecond},
+	} {
+		if err := push.SetOption(tc.name, tc.value); err != nil {
+			t.Fatalf("could not set %s: %+v", tc.name, err)
+		}
+		v, err := push.GetOption(tc.name)
+		if err != nil {
+			t.Fatalf("could not get %s: %+v", tc.name, err)
+		}
+		if v != tc.value {
+			t.Fatalf("invalid %s: got=%v, want=%v", tc.name, v, tc.value)
+		}
+	}
+	if err := push.SetOption(zmq4.OptionReconnectIvl, time.Duration(0)); !errors.Is(err, zmq4.ErrBadProperty) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrBadProperty)
+	}
+
+	// transfer sends messages until one of them is received by pull.
+	transfer := func(pull zmq4.Socket) {
+		t.Helper()
+		got := make(chan error, 1)
+		go func() {
+			_, err := pull.Recv()
+			got <- err
+		}()
+		for {
+			_ = push.Send(zmq4.NewMsgString("ping"))
+			select {
+			case err := <-got:
+				if err != nil {
+					t.Fatalf("could not recv: %+v", err)
+				}
+				return
+			case <-ctx.Done():
+				t.Fatalf("no message received: %+v", ctx.Err())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	pull := zmq4.NewPull(ctx)
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	transfer(pull)
+
+	// kill the listener, and let the dialer back off for a while.
+	pull.Close()
+	time.Sleep(300 * time.Millisecond)
+
+	pull = zmq4.NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen again: %+v", err)
+	}
+	transfer(pull)
+}
+
+func TestSocketIPCFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket files have no permissions on windows")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "mode.sock")
+	ep := "ipc://" + path
+
+	// leave a stale socket file behind, as a crashed process would.
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	pull := zmq4.NewPull(ctx, zmq4.WithIPCFileMode(0o640))
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen over a stale socket file: %+v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat socket file: %+v", err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(0o640); got != want {
+		t.Fatalf("invalid socket file mode: got=%v, want=%v", got, want)
+	}
+
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := push.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+
+	if err := pull.Close(); err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("socket file not removed on close: %v", err)
+	}
+}
+
+func TestSocketInprocDialBeforeListen(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := "inproc://dial-before-listen-" + strconv.Itoa(int(time.Now().UnixNano()))
+
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial unbound end-point: %+v", err)
+	}
+	if got, want := push.(zmq4.EndpointManager).Endpoints(), []string{ep}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid endpoints: got=%q, want=%q", got, want)
+	}
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	if err := push.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "ping"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+}
+
+func TestSocketInprocDialPendingClose(t *testing.T) {
+	// a socket closed while its inproc connection waits for a listener
+	// does not hang.
+	push := zmq4.NewPush(context.Background())
+	if err := push.Dial("inproc://never-bound"); err != nil {
+		t.Fatalf("could not dial unbound end-point: %+v", err)
+	}
+	select {
+	case <-closeAsync(push):
+	case <-time.After(5 * time.Second):
+		t.Fatalf("close did not return")
+	}
+}
+
+// closeAsync closes sck, and returns a channel closed once Close returned.
+func closeAsync(sck zmq4.Socket) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = sck.Close()
+	}()
+	return done
+}
+
+func TestSocketLastEndpoint(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	if ep, err := pull.LastEndpoint(); err != nil || ep != "" {
+		t.Fatalf("invalid end-point before listen: got=(%q, %v), want=(\"\", nil)", ep, err)
+	}
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep, err := pull.LastEndpoint()
+	if err != nil {
+		t.Fatalf("could not get last end-point: %+v", err)
+	}
+	if got, want := ep, "tcp://"+pull.Addr().String(); got != want {
+		t.Fatalf("invalid end-point: got=%q, want=%q", got, want)
+	}
+	if strings.HasSuffix(ep, ":0") {
+		t.Fatalf("end-point %q not resolved", ep)
+	}
+
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep, err)
+	}
+	if got, err := push.LastEndpoint(); err != nil || got != ep {
+		t.Fatalf("invalid dialed end-point: got=(%q, %v), want=(%q, nil)", got, err, ep)
+	}
+	if err := push.Send(zmq4.NewMsgString("hello")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Bytes()), "hello"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+
+	if err := pull.Close(); err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+	if _, err := pull.LastEndpoint(); err == nil {
+		t.Fatalf("expected an error on a closed socket")
+	}
+}
@@ -20,6 +20,8 @@ const (
 	XPub   SocketType = "XPUB"   // a ZMQ_XPUB socket
 	XSub   SocketType = "XSUB"   // a ZMQ_XSUB socket
 	Stream SocketType = "STREAM" // a ZMQ_STREAM socket
+	Radio  SocketType = "RADIO"  // a ZMQ_RADIO socket
+	Dish   SocketType = "DISH"   // a ZMQ_DISH socket
 )
 
 // IsCompatible checks whether two sockets are compatible and thus
@@ -86,6 +88,14 @@ func (sck SocketType) IsCompatible(peer SocketType) bool {
 		if peer == Stream {
 			return true
 		}
+	case Radio:
+		if peer == Dish {
+			return true
+		}
+	case Dish:
+		if peer == Radio {
+			return true
+		}
 	default:
 		panic("unknown socket-type: \"" + string(sck) + "\"")
 	}
@@ -0,0 +1,68 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+// SocketOptions gives typed access to the options of a Socket.
+//
+// Its methods are checked at compile time, and delegate to the string-keyed
+// GetOption and SetOption of the Socket.
+type SocketOptions struct {
+	sck Socket
+}
+
+// Options returns the typed options of sck.
+func Options(sck Socket) SocketOptions {
+	return SocketOptions{sck: sck}
+}
+
+// SetHWM sets the high water mark of the outbound messages, see OptionHWM.
+func (o SocketOptions) SetHWM(hwm int) error {
+	return o.sck.SetOption(OptionHWM, hwm)
+}
+
+// HWM returns the high water mark of the outbound messages, see OptionHWM.
+func (o SocketOptions) HWM() (int, error) {
+	v, err := o.sck.GetOption(OptionHWM)
+	if err != nil {
+		return 0, err
+	}
+	hwm, ok := v.(int)
+	if !ok {
+		return 0, invalidOption(OptionHWM, v, "int")
+	}
+	return hwm, nil
+}
+
+// SetIdentity sets the identity the socket announces to its peers, see
+// OptionIdentity.
+func (o SocketOptions) SetIdentity(id []byte) error {
+	return o.sck.SetOption(OptionIdentity, id)
+}
+
+// Identity returns the identity the socket announces to its peers, or nil
+// if it has none.
+func (o SocketOptions) Identity() []byte {
+	v, err := o.sck.GetOption(OptionIdentity)
+	if err != nil {
+		return nil
+	}
+	id, _ := v.(string)
+	if id == "" {
+		return nil
+	}
+	return []byte(id)
+}
+
+// Subscribe subscribes the socket to the messages starting with prefix, see
+// OptionSubscribe.
+func (o SocketOptions) Subscribe(prefix []byte) error {
+	return o.sck.SetOption(OptionSubscribe, prefix)
+}
+
+// Unsubscribe cancels a subscription of the socket to the messages starting
+// with prefix, see OptionUnsubscribe.
+func (o SocketOptions) Unsubscribe(prefix []byte) error {
+	return o.sck.SetOption(OptionUnsubscribe, prefix)
+}
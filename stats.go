@@ -0,0 +1,141 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"sync/atomic"
+)
+
+// frameSizeBounds are the inclusive upper bounds, in bytes, of the buckets
+// of the frame-size histogram. Larger frames fall in a last, unbounded,
+// bucket.
+var frameSizeBounds = []int{
+	64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20,
+}
+
+// StatsReporter is implemented by sockets that count the messages they
+// exchange with their peers.
+type StatsReporter interface {
+	// Stats returns a snapshot of the traffic counters of the socket.
+	Stats() Stats
+}
+
+// Stats holds the traffic counters of a socket.
+// Messages are counted once per peer they are written to or read from.
+type Stats struct {
+	MsgsSent  uint64 // number of messages written to peers
+	MsgsRecv  uint64 // number of messages read from peers
+	BytesSent uint64 // total size of the frames written to peers
+	BytesRecv uint64 // total size of the frames read from peers
+
+	// SendQueued is the number of messages queued for sending, summed over
+	// the peers, and RecvQueued the number of received messages waiting to
+	// be read by Recv.
+	// Sockets writing messages to their peers directly have no send queue.
+	SendQueued int
+	RecvQueued int
+
+	// FrameSizes is the distribution of the sizes of the frames written
+	// to and read from peers, or nil if the socket was not created with
+	// WithFrameSizeHistogram.
+	FrameSizes *FrameSizeHistogram
+}
+
+// FrameSizeHistogram is a distribution of frame sizes.
+type FrameSizeHistogram struct {
+	// Bounds are the inclusive upper bounds, in bytes, of the buckets.
+	Bounds []int
+
+	// Counts are the number of frames in each bucket.
+	// Counts has one more element than Bounds: the number of frames larger
+	// than the last bound.
+	Counts []uint64
+
+	// Max is the size of the largest frame, in bytes.
+	Max int
+}
+
+// socketStats counts the traffic of the connections of a socket.
+type socketStats struct {
+	msgsSent  atomic.Uint64
+	msgsRecv  atomic.Uint64
+	bytesSent atomic.Uint64
+	bytesRecv atomic.Uint64
+
+	sizes []atomic.Uint64 // frame-size buckets, nil if disabled
+	max   atomic.Int64
+}
+
+func newSocketStats() *socketStats {
+	return &socketStats{}
+}
+
+func (st *socketStats) enableHistogram(enable bool) {
+	st.sizes = nil
+	if enable {
+		st.sizes = make([]atomic.Uint64, len(frameSizeBounds)+1)
+	}
+}
+
+// record counts msg, written to or read from a peer.
+func (st *socketStats) record(dir TraceDirection, msg Msg) {
+	if st == nil {
+		return
+	}
+	size := uint64(msg.Size())
+	switch dir {
+	case TraceSend:
+		st.msgsSent.Add(1)
+		st.bytesSent.Add(size)
+	case TraceRecv:
+		st.msgsRecv.Add(1)
+		st.bytesRecv.Add(size)
+	}
+	if st.sizes == nil {
+		return
+	}
+	for _, frame := range msg.Frames {
+		n := len(frame)
+		st.sizes[frameSizeBucket(n)].Add(1)
+		for {
+			cur := st.max.Load()
+			if int64(n) <= cur || st.max.CompareAndSwap(cur, int64(n)) {
+				break
+			}
+		}
+	}
+}
+
+// frameSizeBucket returns the index of the histogram bucket of a frame of n
+// bytes.
+func frameSizeBucket(n int) int {
+	for i, bound := range frameSizeBounds {
+		if n <= bound {
+			return i
+		}
+	}
+	return len(frameSizeBounds)
+}
+
+func (st *socketStats) snapshot() Stats {
+	stats := Stats{
+		MsgsSent:  st.msgsSent.Load(),
+		MsgsRecv:  st.msgsRecv.Load(),
+		BytesSent: st.bytesSent.Load(),
+		BytesRecv: st.bytesRecv.Load(),
+	}
+	if st.sizes != nil {
+		h := &FrameSizeHistogram{
+			Bounds: append([]int(nil), frameSizeBounds...),
+			Counts: make([]uint64, len(st.sizes)),
+			Max:    int(st.max.Load()),
+		}
+		for i := range st.sizes {
+			h.Counts[i] = st.sizes[i].Load()
+		}
+		stats.FrameSizes = h
+	}
+	return stats
+}
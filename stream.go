@@ -7,14 +7,20 @@ package zmq4
 import (
 	"context"
 	"net"
+	"time"
 )
 
 // NewStream returns a new STREAM ZeroMQ socket.
 // The returned socket value is initially unbound.
 // STREAM sockets are used to send and receive TCP data
 // from a non-ZeroMQ peer when using the tcp:// transport.
+//
+// Messages are sent to the peer identified by their first frame, which is
+// stripped. The identities of the peers are listed by Connections.
 func NewStream(ctx context.Context, opts ...Option) Socket {
 	stream := &streamSocket{sck: newSocket(ctx, Stream, opts...)}
+	stream.sck.self = stream
+	stream.sck.w = newRouterMWriter(stream.sck.ctx, stream.sck.deadLetter)
 	return stream
 }
 
@@ -28,6 +34,77 @@ func (stream *streamSocket) Close() error {
 	return stream.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (stream *streamSocket) Context() context.Context {
+	return stream.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (stream *streamSocket) Done() <-chan struct{} {
+	return stream.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (stream *streamSocket) MessageTrace() <-chan TraceEvent {
+	return stream.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (stream *streamSocket) Stats() Stats {
+	return stream.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (stream *streamSocket) Connections() []ConnInfo {
+	return stream.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (stream *streamSocket) Endpoints() []string {
+	return stream.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (stream *streamSocket) Disconnect(ep string) error {
+	return stream.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (stream *streamSocket) Monitor(events EventType) <-chan SocketEvent {
+	return stream.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (stream *streamSocket) GetMonitorChannel() <-chan SocketEvent {
+	return stream.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (stream *streamSocket) MonitorDropped() uint64 {
+	return stream.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (stream *streamSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return stream.sck.recvOrEvent(ctx, stream.Recv)
+}
+
+func (stream *streamSocket) events() State {
+	return stream.sck.events()
+}
+
+func (stream *streamSocket) watch(fn func()) func() {
+	return stream.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (stream *streamSocket) Send(msg Msg) error {
@@ -41,16 +118,63 @@ func (stream *streamSocket) SendMulti(msg Msg) error {
 	return stream.sck.SendMulti(msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (stream *streamSocket) SendNB(msg Msg) error {
+	return stream.sck.SendNB(msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (stream *streamSocket) Flush(ctx context.Context) error {
+	return stream.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (stream *streamSocket) Recv() (Msg, error) {
 	return stream.sck.Recv()
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (stream *streamSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(stream.sck.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (stream *streamSocket) RecvNB() (Msg, error) {
+	return stream.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (stream *streamSocket) RecvMulti() (Msg, error) {
+	return recvMulti(stream.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (stream *streamSocket) SetSendDeadline(t time.Time) error {
+	return stream.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (stream *streamSocket) SetRecvDeadline(t time.Time) error {
+	return stream.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (stream *streamSocket) Listen(ep string) error {
 	return stream.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (stream *streamSocket) Unbind(ep string) error {
+	return stream.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (stream *streamSocket) Dial(ep string) error {
 	return stream.sck.Dial(ep)
@@ -67,6 +191,11 @@ func (stream *streamSocket) Addr() net.Addr {
 	return stream.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (stream *streamSocket) LastEndpoint() (string, error) {
+	return stream.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (stream *streamSocket) GetOption(name string) (interface{}, error) {
 	return stream.sck.GetOption(name)
@@ -77,6 +206,23 @@ func (stream *streamSocket) SetOption(name string, value interface{}) error {
 	return stream.sck.SetOption(name, value)
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (stream *streamSocket) Reader() SocketReader {
+	return socketReader{stream}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (stream *streamSocket) Writer() SocketWriter {
+	return socketWriter{stream}
+}
+
 var (
-	_ Socket = (*streamSocket)(nil)
+	_ Socket           = (*streamSocket)(nil)
+	_ MessageTracer    = (*streamSocket)(nil)
+	_ ConnectionLister = (*streamSocket)(nil)
+	_ EndpointManager  = (*streamSocket)(nil)
+	_ PeerReceiver     = (*streamSocket)(nil)
+	_ Monitored        = (*streamSocket)(nil)
+	_ EventReceiver    = (*streamSocket)(nil)
+	_ StatsReporter    = (*streamSocket)(nil)
 )
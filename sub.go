@@ -8,16 +8,19 @@ import (
 	"context"
 	"net"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 // NewSub returns a new SUB ZeroMQ socket.
 // The returned socket value is initially unbound.
 func NewSub(ctx context.Context, opts ...Option) Socket {
 	sub := &subSocket{sck: newSocket(ctx, Sub, opts...)}
-	sub.sck.r = newQReader(sub.sck.ctx)
+	sub.sck.self = sub
+	sub.sck.r = newQReader(sub.sck.ctx, sub.sck.recv)
 	sub.sck.subTopics = sub.Topics
-	sub.topics = make(map[string]struct{})
+	sub.topics = make(map[string]int)
 	return sub
 }
 
@@ -26,7 +29,7 @@ type subSocket struct {
 	sck *socket
 
 	mu     sync.RWMutex
-	topics map[string]struct{}
+	topics map[string]int // number of subscriptions per topic
 }
 
 // Close closes the open Socket
@@ -34,22 +37,154 @@ func (sub *subSocket) Close() error {
 	return sub.sck.Close()
 }
 
-// Send puts the message on the outbound send queue.
-// Send blocks until the message can be queued or the send deadline expires.
-func (sub *subSocket) Send(msg Msg) error {
-	return sub.sck.Send(msg)
+// Context returns the life-line of the socket.
+func (sub *subSocket) Context() context.Context {
+	return sub.sck.Context()
 }
 
-// SendMulti puts the message on the outbound send queue.
-// SendMulti blocks until the message can be queued or the send deadline expires.
-// The message will be sent as a multipart message.
-func (sub *subSocket) SendMulti(msg Msg) error {
-	return sub.sck.SendMulti(msg)
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (sub *subSocket) Done() <-chan struct{} {
+	return sub.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (sub *subSocket) MessageTrace() <-chan TraceEvent {
+	return sub.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (sub *subSocket) Stats() Stats {
+	return sub.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (sub *subSocket) Connections() []ConnInfo {
+	return sub.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (sub *subSocket) Endpoints() []string {
+	return sub.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (sub *subSocket) Disconnect(ep string) error {
+	return sub.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (sub *subSocket) Monitor(events EventType) <-chan SocketEvent {
+	return sub.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (sub *subSocket) GetMonitorChannel() <-chan SocketEvent {
+	return sub.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (sub *subSocket) MonitorDropped() uint64 {
+	return sub.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (sub *subSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return sub.sck.recvOrEvent(ctx, sub.Recv)
+}
+
+func (sub *subSocket) events() State {
+	return sub.sck.events()
+}
+
+func (sub *subSocket) watch(fn func()) func() {
+	return sub.sck.watch(fn)
+}
+
+// Send returns ErrInvalidOperation: SUB sockets can't send messages.
+// Subscriptions are managed with SetOption.
+func (*subSocket) Send(msg Msg) error {
+	return errInvalidOp(Sub, "send")
+}
+
+// SendMulti returns ErrInvalidOperation: SUB sockets can't send messages.
+func (*subSocket) SendMulti(msg Msg) error {
+	return errInvalidOp(Sub, "send")
+}
+
+// SendNB is an invalid operation for a SUB socket.
+func (*subSocket) SendNB(msg Msg) error {
+	return errInvalidOp(Sub, "send")
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (sub *subSocket) Flush(ctx context.Context) error {
+	return sub.sck.Flush(ctx)
 }
 
 // Recv receives a complete message.
 func (sub *subSocket) Recv() (Msg, error) {
-	return sub.sck.Recv()
+	return withoutConn(sub.recvConn())
+}
+
+// recvConn receives a complete message, still referencing the connection it was
+// received from.
+func (sub *subSocket) recvConn() (Msg, error) {
+	return sub.filter(sub.sck.recvConn)
+}
+
+// filter receives messages with recv until one matches the subscriptions of
+// the socket.
+func (sub *subSocket) filter(recv func() (Msg, error)) (Msg, error) {
+	for {
+		msg, err := recv()
+		if err != nil {
+			return msg, err
+		}
+		if sub.sck.drainUnsub && !sub.subscribed(msg) {
+			// queued before an unsubscription.
+			msg.Release()
+			continue
+		}
+		return msg, nil
+	}
+}
+
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (sub *subSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(sub.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (sub *subSocket) RecvNB() (Msg, error) {
+	return withoutConn(sub.filter(sub.sck.recvConnNB))
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (sub *subSocket) RecvMulti() (Msg, error) {
+	return recvMulti(sub.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (sub *subSocket) SetSendDeadline(t time.Time) error {
+	return sub.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (sub *subSocket) SetRecvDeadline(t time.Time) error {
+	return sub.sck.SetRecvDeadline(t)
 }
 
 // Listen connects a local endpoint to the Socket.
@@ -57,6 +192,12 @@ func (sub *subSocket) Listen(ep string) error {
 	return sub.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (sub *subSocket) Unbind(ep string) error {
+	return sub.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (sub *subSocket) Dial(ep string) error {
 	err := sub.sck.Dial(ep)
@@ -77,6 +218,11 @@ func (sub *subSocket) Addr() net.Addr {
 	return sub.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (sub *subSocket) LastEndpoint() (string, error) {
+	return sub.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (sub *subSocket) GetOption(name string) (interface{}, error) {
 	return sub.sck.GetOption(name)
@@ -90,32 +236,47 @@ func (sub *subSocket) SetOption(name string, value interface{}) error {
 	}
 
 	var (
-		topic []byte
+		topic   []byte
+		changed bool
+		k, _    = optionString(name, value) // validated by sck.SetOption
 	)
 
 	switch name {
 	case OptionSubscribe:
-		k := value.(string)
-		sub.subscribe(k, 1)
-		topic = append([]byte{1}, k...)
+		changed = sub.subscribe(k, +1)
+		topic = SubscribeFrame([]byte(k))
 
 	case OptionUnsubscribe:
-		k := value.(string)
-		topic = append([]byte{0}, k...)
-		sub.subscribe(k, 0)
+		changed = sub.subscribe(k, -1)
+		topic = UnsubscribeFrame([]byte(k))
 
 	default:
-		return ErrBadProperty
+		// a socket option, set by sck.SetOption.
+		return nil
+	}
+	if !changed {
+		// the publishers already know about this subscription.
+		return nil
 	}
 
 	sub.sck.mu.RLock()
 	if len(sub.sck.conns) > 0 {
-		err = sub.Send(NewMsg(topic))
+		err = sub.sck.Send(NewMsg(topic))
 	}
 	sub.sck.mu.RUnlock()
 	return err
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (sub *subSocket) Reader() SocketReader {
+	return socketReader{sub}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (sub *subSocket) Writer() SocketWriter {
+	return socketWriter{sub}
+}
+
 // Topics returns the sorted list of topics a socket is subscribed to.
 func (sub *subSocket) Topics() []string {
 	sub.mu.RLock()
@@ -128,18 +289,57 @@ func (sub *subSocket) Topics() []string {
 	return topics
 }
 
-func (sub *subSocket) subscribe(topic string, v int) {
+// subscribe subscribes (delta=+1) to, or unsubscribes (delta=-1) from, a
+// topic, and reports whether the topic became, or stopped being, subscribed
+// to. Subscriptions are reference counted: a topic subscribed to n times
+// stays subscribed to until it is unsubscribed from n times.
+func (sub *subSocket) subscribe(topic string, delta int) bool {
 	sub.mu.Lock()
-	switch v {
-	case 0:
-		delete(sub.topics, topic)
-	case 1:
-		sub.topics[topic] = struct{}{}
+	defer sub.mu.Unlock()
+	return refCount(sub.topics, topic, delta)
+}
+
+// refCount adds delta to the count of k, and reports whether k was added to,
+// or removed from, counts.
+func refCount(counts map[string]int, k string, delta int) bool {
+	n := counts[k] + delta
+	switch {
+	case n < 0:
+		return false
+	case n == 0:
+		delete(counts, k)
+		return true
+	default:
+		counts[k] = n
+		return n == 1 && delta > 0
+	}
+}
+
+// subscribed returns whether a received message matches an active
+// subscription.
+func (sub *subSocket) subscribed(msg Msg) bool {
+	var topic string
+	if len(msg.Frames) > 0 {
+		topic = string(msg.Frames[0])
+	}
+	sub.mu.RLock()
+	defer sub.mu.RUnlock()
+	for k := range sub.topics {
+		if strings.HasPrefix(topic, k) {
+			return true
+		}
 	}
-	sub.mu.Unlock()
+	return false
 }
 
 var (
-	_ Socket = (*subSocket)(nil)
-	_ Topics = (*subSocket)(nil)
+	_ Socket           = (*subSocket)(nil)
+	_ MessageTracer    = (*subSocket)(nil)
+	_ ConnectionLister = (*subSocket)(nil)
+	_ EndpointManager  = (*subSocket)(nil)
+	_ PeerReceiver     = (*subSocket)(nil)
+	_ Monitored        = (*subSocket)(nil)
+	_ EventReceiver    = (*subSocket)(nil)
+	_ StatsReporter    = (*subSocket)(nil)
+	_ Topics           = (*subSocket)(nil)
 )
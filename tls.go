@@ -0,0 +1,51 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// errNoTLSConfig reports a tls:// end-point used by a socket created without
+// WithTLS.
+var errNoTLSConfig = errors.New("zmq4: tls end-points require a TLS configuration, see WithTLS")
+
+// tlsListener wraps the listener l of the tls:// end-point endpoint, so the
+// connections it accepts are served with the TLS configuration of the
+// socket. The handshake is performed by the first read of the greeting.
+func (sck *socket) tlsListener(endpoint string, l net.Listener) (net.Listener, error) {
+	if sck.tls == nil {
+		return nil, fmt.Errorf("zmq4: could not listen to %q: %w", endpoint, errNoTLSConfig)
+	}
+	return tls.NewListener(l, sck.tls), nil
+}
+
+// tlsClient performs the TLS handshake of the connection conn, dialed to
+// addr, with the TLS configuration of the socket.
+// The server certificate is verified against the name of the host of addr,
+// unless the configuration sets ServerName.
+func (sck *socket) tlsClient(ctx context.Context, conn net.Conn, addr string) (net.Conn, error) {
+	if sck.tls == nil {
+		return nil, errNoTLSConfig
+	}
+	cfg := sck.tls
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg = cfg.Clone()
+		cfg.ServerName = host
+	}
+	tc := tls.Client(conn, cfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("zmq4: TLS handshake with %v failed: %w", conn.RemoteAddr(), err)
+	}
+	return tc, nil
+}
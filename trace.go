@@ -0,0 +1,74 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"time"
+)
+
+// traceQueueSize is the capacity of the message trace channel of a socket.
+const traceQueueSize = 1024
+
+// MessageTracer is implemented by sockets that can trace the messages they
+// exchange with their peers.
+type MessageTracer interface {
+	// MessageTrace returns the channel of the trace events of the socket,
+	// or nil if the socket was not created with WithMessageTrace.
+	MessageTrace() <-chan TraceEvent
+}
+
+// TraceDirection is the direction of a traced message.
+type TraceDirection int
+
+const (
+	TraceSend TraceDirection = iota // message written to a peer
+	TraceRecv                       // message read from a peer
+)
+
+func (dir TraceDirection) String() string {
+	switch dir {
+	case TraceSend:
+		return "send"
+	case TraceRecv:
+		return "recv"
+	default:
+		return "unknown"
+	}
+}
+
+// TraceEvent describes a message written to or read from a peer.
+// Payloads are not copied: only the shape of the message is recorded.
+type TraceEvent struct {
+	Dir    TraceDirection
+	Frames int       // number of frames of the message
+	Size   int       // total size of the frames, in bytes
+	Time   time.Time // time the message was written or read
+}
+
+// messageTracer emits the trace events of a socket.
+type messageTracer struct {
+	c chan TraceEvent
+}
+
+func newMessageTracer() *messageTracer {
+	return &messageTracer{c: make(chan TraceEvent, traceQueueSize)}
+}
+
+// emit records msg, dropping the event if the trace channel is full.
+func (t *messageTracer) emit(dir TraceDirection, msg Msg) {
+	if t == nil {
+		return
+	}
+	ev := TraceEvent{
+		Dir:    dir,
+		Frames: len(msg.Frames),
+		Size:   msg.Size(),
+		Time:   time.Now(),
+	}
+	select {
+	case t.c <- ev:
+	default:
+	}
+}
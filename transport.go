@@ -85,6 +85,9 @@ func init() {
 
 	must(RegisterTransport("ipc", transport.New("unix")))
 	must(RegisterTransport("tcp", transport.New("tcp")))
+	// tls end-points are TCP end-points whose connections are secured
+	// with the TLS configuration of the socket, see WithTLS.
+	must(RegisterTransport("tls", transport.New("tcp")))
 	must(RegisterTransport("udp", transport.New("udp")))
 	must(RegisterTransport("inproc", inproc.Transport{}))
 }
@@ -0,0 +1,22 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+// +build !unix
+
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// SetBacklog sets the maximum length of the queue of the pending connections
+// of the listener l to n.
+// SetBacklog is not supported on this platform: it returns an error
+// wrapping errors.ErrUnsupported.
+func SetBacklog(l net.Listener, n int) error {
+	return fmt.Errorf("zmq4: could not set backlog of %T listener: %w", l, errors.ErrUnsupported)
+}
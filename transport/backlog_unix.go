@@ -0,0 +1,45 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+// +build unix
+
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// SetBacklog sets the maximum length of the queue of the pending connections
+// of the listener l to n, calling listen(2) again on its socket: neither
+// net.Listen nor net.ListenConfig let callers choose it, and use the
+// system maximum instead.
+// The kernel silently caps n to its own limit, e.g. net.core.somaxconn on
+// Linux or kern.ipc.somaxconn on BSDs and macOS.
+// SetBacklog returns an error wrapping errors.ErrUnsupported if l has no
+// underlying socket, e.g. for inproc listeners.
+func SetBacklog(l net.Listener, n int) error {
+	sc, ok := l.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("zmq4: could not set backlog of %T listener: %w", l, errors.ErrUnsupported)
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("zmq4: could not access listener socket: %w", err)
+	}
+	var lerr error
+	err = rc.Control(func(fd uintptr) {
+		lerr = syscall.Listen(int(fd), n)
+	})
+	if err == nil {
+		err = lerr
+	}
+	if err != nil {
+		return fmt.Errorf("zmq4: could not set listener backlog to %d: %w", n, err)
+	}
+	return nil
+}
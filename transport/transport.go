@@ -49,7 +49,8 @@ func (trans netTransport) Dial(ctx context.Context, dialer Dialer, addr string)
 
 // Listen announces on the provided network address.
 func (trans netTransport) Listen(ctx context.Context, addr string) (net.Listener, error) {
-	return net.Listen(trans.prot, addr)
+	var lc net.ListenConfig
+	return lc.Listen(ctx, trans.prot, addr)
 }
 
 // Addr returns the end-point address.
@@ -0,0 +1,57 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package transport_test
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4/transport"
+)
+
+type fakeListener struct{ net.Listener }
+
+func TestSetBacklog(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("accept queue overflows not observable on %s", runtime.GOOS)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	defer l.Close()
+
+	const backlog = 1
+	if err := transport.SetBacklog(l, backlog); err != nil {
+		t.Fatalf("could not set backlog: %+v", err)
+	}
+
+	// Linux queues up to backlog+1 connections nobody accepts, and drops
+	// the SYN of the next ones.
+	const n = 8
+	var conns int
+	for i := 0; i < n; i++ {
+		c, err := net.DialTimeout("tcp", l.Addr().String(), 250*time.Millisecond)
+		if err != nil {
+			continue
+		}
+		defer c.Close()
+		conns++
+	}
+	if conns > backlog+1 {
+		t.Fatalf("backlog not applied: %d connections queued, want at most %d", conns, backlog+1)
+	}
+}
+
+func TestSetBacklogUnsupported(t *testing.T) {
+	err := transport.SetBacklog(fakeListener{}, 1)
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, errors.ErrUnsupported)
+	}
+}
@@ -12,7 +12,7 @@ import (
 )
 
 func TestTransport(t *testing.T) {
-	if got, want := Transports(), []string{"inproc", "ipc", "tcp", "udp"}; !reflect.DeepEqual(got, want) {
+	if got, want := Transports(), []string{"inproc", "ipc", "tcp", "tls", "udp"}; !reflect.DeepEqual(got, want) {
 		t.Fatalf("invalid list of transports.\ngot= %q\nwant=%q", got, want)
 	}
 
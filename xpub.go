@@ -7,14 +7,30 @@ package zmq4
 import (
 	"context"
 	"net"
+	"time"
 )
 
 // NewXPub returns a new XPUB ZeroMQ socket.
 // The returned socket value is initially unbound.
+//
+// Recv returns the subscription messages of the subscribers, so they can be
+// forwarded upstream, e.g. by a Proxy to a XSUB socket. Subscriptions apply
+// even when they are not received: they are dropped from the receive queue
+// when it is full. Only the first subscription to a topic and its last
+// unsubscription are received, unless OptionXPubVerbose is set; the topics
+// of a disconnected subscriber are unsubscribed.
+// With OptionXPubManual, the subscriptions are applied by the application.
+// With OptionXPubWelcomeMsg, each subscriber is sent a welcome message upon
+// its first subscription.
 func NewXPub(ctx context.Context, opts ...Option) Socket {
 	xpub := &xpubSocket{newSocket(ctx, XPub, opts...)}
-	xpub.sck.w = newPubMWriter(xpub.sck.ctx)
-	xpub.sck.r = newPubQReader(xpub.sck.ctx)
+	xpub.sck.self = xpub
+	w := newPubMWriter(xpub.sck.ctx, xpub.sck.sndHWM)
+	xpub.sck.w = w
+	r := newPubQReader(xpub.sck.ctx, xpub.sck.recv.qsize)
+	r.forward = true
+	r.w = w
+	xpub.sck.r = r
 	return xpub
 }
 
@@ -28,6 +44,77 @@ func (xpub *xpubSocket) Close() error {
 	return xpub.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (xpub *xpubSocket) Context() context.Context {
+	return xpub.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (xpub *xpubSocket) Done() <-chan struct{} {
+	return xpub.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (xpub *xpubSocket) MessageTrace() <-chan TraceEvent {
+	return xpub.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (xpub *xpubSocket) Stats() Stats {
+	return xpub.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (xpub *xpubSocket) Connections() []ConnInfo {
+	return xpub.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (xpub *xpubSocket) Endpoints() []string {
+	return xpub.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (xpub *xpubSocket) Disconnect(ep string) error {
+	return xpub.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (xpub *xpubSocket) Monitor(events EventType) <-chan SocketEvent {
+	return xpub.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (xpub *xpubSocket) GetMonitorChannel() <-chan SocketEvent {
+	return xpub.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (xpub *xpubSocket) MonitorDropped() uint64 {
+	return xpub.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (xpub *xpubSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return xpub.sck.recvOrEvent(ctx, xpub.Recv)
+}
+
+func (xpub *xpubSocket) events() State {
+	return xpub.sck.events()
+}
+
+func (xpub *xpubSocket) watch(fn func()) func() {
+	return xpub.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (xpub *xpubSocket) Send(msg Msg) error {
@@ -41,16 +128,75 @@ func (xpub *xpubSocket) SendMulti(msg Msg) error {
 	return xpub.sck.SendMulti(msg)
 }
 
+// SendNB puts the message on the outbound send queue.
+// Like Send, SendNB never blocks: it drops the message for the subscribers
+// whose queue is full.
+func (xpub *xpubSocket) SendNB(msg Msg) error {
+	if err := xpub.sck.checkFrames(msg); err != nil {
+		return err
+	}
+	return xpub.sck.trySend(msg)
+}
+
+// SendBatch puts a batch of messages on the outbound send queue.
+// SendBatch blocks until the batch can be queued or the send deadline expires.
+func (xpub *xpubSocket) SendBatch(msgs []TopicMsg) error {
+	ctx, cancel := xpub.sck.sendContext()
+	defer cancel()
+	return xpub.sck.w.(*pubMWriter).writeBatch(ctx, msgs)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (xpub *xpubSocket) Flush(ctx context.Context) error {
+	return xpub.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (xpub *xpubSocket) Recv() (Msg, error) {
 	return xpub.sck.Recv()
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (xpub *xpubSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(xpub.sck.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (xpub *xpubSocket) RecvNB() (Msg, error) {
+	return xpub.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (xpub *xpubSocket) RecvMulti() (Msg, error) {
+	return recvMulti(xpub.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (xpub *xpubSocket) SetSendDeadline(t time.Time) error {
+	return xpub.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (xpub *xpubSocket) SetRecvDeadline(t time.Time) error {
+	return xpub.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (xpub *xpubSocket) Listen(ep string) error {
 	return xpub.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (xpub *xpubSocket) Unbind(ep string) error {
+	return xpub.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (xpub *xpubSocket) Dial(ep string) error {
 	return xpub.sck.Dial(ep)
@@ -67,6 +213,11 @@ func (xpub *xpubSocket) Addr() net.Addr {
 	return xpub.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (xpub *xpubSocket) LastEndpoint() (string, error) {
+	return xpub.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (xpub *xpubSocket) GetOption(name string) (interface{}, error) {
 	return xpub.sck.GetOption(name)
@@ -74,7 +225,36 @@ func (xpub *xpubSocket) GetOption(name string) (interface{}, error) {
 
 // SetOption is used to set an option for a socket.
 func (xpub *xpubSocket) SetOption(name string, value interface{}) error {
-	return xpub.sck.SetOption(name, value)
+	err := xpub.sck.SetOption(name, value)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case OptionXPubVerbose:
+		xpub.sck.r.(*pubQReader).setVerbose(value.(bool))
+	case OptionXPubManual:
+		xpub.sck.r.(*pubQReader).setManual(value.(bool))
+	case OptionXPubWelcomeMsg:
+		msg, _ := optionString(name, value)
+		xpub.sck.w.(*pubMWriter).setWelcome([]byte(msg))
+	case OptionSubscribe:
+		topic, _ := optionString(name, value)
+		xpub.sck.r.(*pubQReader).subscribeLast(NewMsg(SubscribeFrame([]byte(topic))))
+	case OptionUnsubscribe:
+		topic, _ := optionString(name, value)
+		xpub.sck.r.(*pubQReader).subscribeLast(NewMsg(UnsubscribeFrame([]byte(topic))))
+	}
+	return nil
+}
+
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (xpub *xpubSocket) Reader() SocketReader {
+	return socketReader{xpub}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (xpub *xpubSocket) Writer() SocketWriter {
+	return socketWriter{xpub}
 }
 
 func (xpub *xpubSocket) Topics() []string {
@@ -82,5 +262,13 @@ func (xpub *xpubSocket) Topics() []string {
 }
 
 var (
-	_ Socket = (*xpubSocket)(nil)
+	_ Socket           = (*xpubSocket)(nil)
+	_ MessageTracer    = (*xpubSocket)(nil)
+	_ ConnectionLister = (*xpubSocket)(nil)
+	_ EndpointManager  = (*xpubSocket)(nil)
+	_ PeerReceiver     = (*xpubSocket)(nil)
+	_ Monitored        = (*xpubSocket)(nil)
+	_ EventReceiver    = (*xpubSocket)(nil)
+	_ StatsReporter    = (*xpubSocket)(nil)
+	_ BatchSender      = (*xpubSocket)(nil)
 )
@@ -7,12 +7,22 @@ package zmq4
 import (
 	"context"
 	"net"
+	"sort"
+	"sync"
+	"time"
 )
 
 // NewXSub returns a new XSUB ZeroMQ socket.
 // The returned socket value is initially unbound.
+//
+// The subscription messages sent on the socket are replayed to the
+// publishers it connects to later on.
 func NewXSub(ctx context.Context, opts ...Option) Socket {
 	xsub := &xsubSocket{newSocket(ctx, XSub, opts...)}
+	xsub.sck.self = xsub
+	w := newXSubMWriter(xsub.sck.ctx, xsub.sck.subHWM)
+	xsub.sck.w = w
+	xsub.sck.subTopics = w.topics
 	return xsub
 }
 
@@ -26,6 +36,77 @@ func (xsub *xsubSocket) Close() error {
 	return xsub.sck.Close()
 }
 
+// Context returns the life-line of the socket.
+func (xsub *xsubSocket) Context() context.Context {
+	return xsub.sck.Context()
+}
+
+// Done returns a channel that is closed once Close, or the end of the
+// context of the socket, has completed the teardown of the socket.
+func (xsub *xsubSocket) Done() <-chan struct{} {
+	return xsub.sck.Done()
+}
+
+// MessageTrace returns the channel of the trace events of the socket,
+// or nil if the socket was not created with WithMessageTrace.
+func (xsub *xsubSocket) MessageTrace() <-chan TraceEvent {
+	return xsub.sck.MessageTrace()
+}
+
+// Stats returns a snapshot of the traffic counters of the socket.
+func (xsub *xsubSocket) Stats() Stats {
+	return xsub.sck.Stats()
+}
+
+// Connections returns the live connections of the socket.
+func (xsub *xsubSocket) Connections() []ConnInfo {
+	return xsub.sck.Connections()
+}
+
+// Endpoints returns the endpoints the socket is connected to with Dial.
+func (xsub *xsubSocket) Endpoints() []string {
+	return xsub.sck.Endpoints()
+}
+
+// Disconnect closes the connection to the endpoint ep, dialed previously,
+// and stops reconnecting to it.
+func (xsub *xsubSocket) Disconnect(ep string) error {
+	return xsub.sck.Disconnect(ep)
+}
+
+// Monitor starts reporting the given events on the monitor channel of the
+// socket, replacing the previously monitored events, and returns that
+// channel. The channel is never closed.
+func (xsub *xsubSocket) Monitor(events EventType) <-chan SocketEvent {
+	return xsub.sck.Monitor(events)
+}
+
+// GetMonitorChannel returns the monitor channel of the socket, or nil if
+// Monitor was never called.
+func (xsub *xsubSocket) GetMonitorChannel() <-chan SocketEvent {
+	return xsub.sck.GetMonitorChannel()
+}
+
+// MonitorDropped returns the number of events dropped because the monitor
+// channel was full.
+func (xsub *xsubSocket) MonitorDropped() uint64 {
+	return xsub.sck.MonitorDropped()
+}
+
+// RecvOrEvent waits for a message to be received or for a monitored
+// lifecycle event of the socket, whichever comes first (see EventReceiver).
+func (xsub *xsubSocket) RecvOrEvent(ctx context.Context) (Msg, *SocketEvent, error) {
+	return xsub.sck.recvOrEvent(ctx, xsub.Recv)
+}
+
+func (xsub *xsubSocket) events() State {
+	return xsub.sck.events()
+}
+
+func (xsub *xsubSocket) watch(fn func()) func() {
+	return xsub.sck.watch(fn)
+}
+
 // Send puts the message on the outbound send queue.
 // Send blocks until the message can be queued or the send deadline expires.
 func (xsub *xsubSocket) Send(msg Msg) error {
@@ -39,16 +120,63 @@ func (xsub *xsubSocket) SendMulti(msg Msg) error {
 	return xsub.sck.SendMulti(msg)
 }
 
+// SendNB puts the message on the outbound send queue if it can do so without
+// blocking, and returns ErrWouldBlock otherwise.
+func (xsub *xsubSocket) SendNB(msg Msg) error {
+	return xsub.sck.SendNB(msg)
+}
+
+// Flush blocks until all the messages queued for sending have been written
+// to the connected peers, or until ctx is done.
+func (xsub *xsubSocket) Flush(ctx context.Context) error {
+	return xsub.sck.Flush(ctx)
+}
+
 // Recv receives a complete message.
 func (xsub *xsubSocket) Recv() (Msg, error) {
 	return xsub.sck.Recv()
 }
 
+// RecvFrom receives a complete message, and describes the connection it was
+// received from.
+func (xsub *xsubSocket) RecvFrom() (Msg, ConnInfo, error) {
+	return recvFrom(xsub.sck.recvConn)
+}
+
+// RecvNB receives a complete message if one is ready, and returns
+// ErrWouldBlock otherwise.
+func (xsub *xsubSocket) RecvNB() (Msg, error) {
+	return xsub.sck.RecvNB()
+}
+
+// RecvMulti receives a complete multipart message, atomically.
+func (xsub *xsubSocket) RecvMulti() (Msg, error) {
+	return recvMulti(xsub.Recv)
+}
+
+// SetSendDeadline sets the deadline of the subsequent send operations.
+// A zero t clears the deadline.
+func (xsub *xsubSocket) SetSendDeadline(t time.Time) error {
+	return xsub.sck.SetSendDeadline(t)
+}
+
+// SetRecvDeadline sets the deadline of the subsequent receive operations.
+// A zero t clears the deadline.
+func (xsub *xsubSocket) SetRecvDeadline(t time.Time) error {
+	return xsub.sck.SetRecvDeadline(t)
+}
+
 // Listen connects a local endpoint to the Socket.
 func (xsub *xsubSocket) Listen(ep string) error {
 	return xsub.sck.Listen(ep)
 }
 
+// Unbind stops listening to the end-point ep, and closes the connections
+// accepted on it.
+func (xsub *xsubSocket) Unbind(ep string) error {
+	return xsub.sck.Unbind(ep)
+}
+
 // Dial connects a remote endpoint to the Socket.
 func (xsub *xsubSocket) Dial(ep string) error {
 	return xsub.sck.Dial(ep)
@@ -65,6 +193,11 @@ func (xsub *xsubSocket) Addr() net.Addr {
 	return xsub.sck.Addr()
 }
 
+// LastEndpoint returns the end-point the socket last listened to or dialed.
+func (xsub *xsubSocket) LastEndpoint() (string, error) {
+	return xsub.sck.LastEndpoint()
+}
+
 // GetOption is used to retrieve an option for a socket.
 func (xsub *xsubSocket) GetOption(name string) (interface{}, error) {
 	return xsub.sck.GetOption(name)
@@ -75,6 +208,175 @@ func (xsub *xsubSocket) SetOption(name string, value interface{}) error {
 	return xsub.sck.SetOption(name, value)
 }
 
+// Reader returns a handle restricted to the receiving methods of the socket.
+func (xsub *xsubSocket) Reader() SocketReader {
+	return socketReader{xsub}
+}
+
+// Writer returns a handle restricted to the sending methods of the socket.
+func (xsub *xsubSocket) Writer() SocketWriter {
+	return socketWriter{xsub}
+}
+
+// xsubMWriter writes the messages of a XSUB socket, subscription frames in
+// particular, to its publishers.
+//
+// Messages are queued per publisher and written in order by a background
+// goroutine: at most hwm messages are in flight per publisher, and write
+// blocks while the queue of a publisher is full. Unlike PUB, nothing is
+// dropped: a slow publisher pushes back on the application instead.
+type xsubMWriter struct {
+	ctx context.Context
+	hwm int
+	sem *semaphore // ready when a connection is live.
+	out *outbox
+
+	mu    sync.RWMutex
+	peers map[*Conn]chan Msg
+
+	smu  sync.Mutex
+	subs map[string]struct{} // topics subscribed to through the writer
+}
+
+func newXSubMWriter(ctx context.Context, hwm int) *xsubMWriter {
+	return &xsubMWriter{
+		ctx:   ctx,
+		hwm:   hwm,
+		sem:   newSemaphore(),
+		out:   newOutbox(),
+		peers: make(map[*Conn]chan Msg),
+		subs:  make(map[string]struct{}),
+	}
+}
+
+func (w *xsubMWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for conn, c := range w.peers {
+		_ = conn.Close()
+		close(c)
+	}
+	w.peers = nil
+	return nil
+}
+
+func (w *xsubMWriter) addConn(conn *Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.peers == nil {
+		// writer already closed.
+		_ = conn.Close()
+		return
+	}
+
+	c := make(chan Msg, w.hwm)
+	w.peers[conn] = c
+	w.sem.enable()
+	go func() {
+		for msg := range c {
+			// errors are reported by the connection reaper.
+			_ = conn.SendMsg(msg)
+			w.out.done()
+		}
+	}()
+}
+
+func (w *xsubMWriter) rmConn(conn *Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if c, ok := w.peers[conn]; ok {
+		delete(w.peers, conn)
+		close(c)
+	}
+}
+
+func (w *xsubMWriter) write(ctx context.Context, msg Msg) error {
+	w.sem.lock(ctx)
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	w.track(msg)
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, c := range w.peers {
+		w.out.add()
+		select {
+		case c <- msg:
+			continue
+		default:
+		}
+		select {
+		case c <- msg:
+		case <-ctx.Done():
+			w.out.done()
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// track records the subscription carried by msg, if any.
+func (w *xsubMWriter) track(msg Msg) {
+	if len(msg.Frames) != 1 {
+		return
+	}
+	subscribe, topic, ok := ParseSubscription(msg.Frames[0])
+	if !ok {
+		return
+	}
+	w.smu.Lock()
+	if subscribe {
+		w.subs[string(topic)] = struct{}{}
+	} else {
+		delete(w.subs, string(topic))
+	}
+	w.smu.Unlock()
+}
+
+// topics returns the topics subscribed to through the writer, to be replayed
+// to new publishers.
+func (w *xsubMWriter) topics() []string {
+	w.smu.Lock()
+	defer w.smu.Unlock()
+	topics := make([]string, 0, len(w.subs))
+	for topic := range w.subs {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (w *xsubMWriter) flush(ctx context.Context) error {
+	return w.out.wait(ctx)
+}
+
+// queued returns the number of messages waiting to be written to the
+// publishers.
+func (w *xsubMWriter) queued() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	n := 0
+	for _, c := range w.peers {
+		n += len(c)
+	}
+	return n
+}
+
 var (
-	_ Socket = (*xsubSocket)(nil)
+	_ Socket           = (*xsubSocket)(nil)
+	_ MessageTracer    = (*xsubSocket)(nil)
+	_ ConnectionLister = (*xsubSocket)(nil)
+	_ EndpointManager  = (*xsubSocket)(nil)
+	_ PeerReceiver     = (*xsubSocket)(nil)
+	_ Monitored        = (*xsubSocket)(nil)
+	_ EventReceiver    = (*xsubSocket)(nil)
+	_ StatsReporter    = (*xsubSocket)(nil)
+	_ wpool            = (*xsubMWriter)(nil)
+	_ flusher          = (*xsubMWriter)(nil)
+	_ queuer           = (*xsubMWriter)(nil)
 )
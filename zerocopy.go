@@ -0,0 +1,116 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// zcChunkSize is the size of the read buffers of the zero-copy connections.
+// Frames larger than a chunk are read into storage of their own.
+const zcChunkSize = 64 << 10
+
+// zcChunk is a reference counted read buffer of a zero-copy connection.
+// The frames of the received messages are sub-slices of the chunk they were
+// read into: the chunk is handed back to the pool once the reader moved on
+// to another chunk and all the messages referencing it were released.
+type zcChunk struct {
+	data [zcChunkSize]byte
+	refs atomic.Int32
+}
+
+var zcChunkPool = sync.Pool{
+	New: func() any { return new(zcChunk) },
+}
+
+// newZCChunk returns a chunk holding one reference, owned by the caller.
+func newZCChunk() *zcChunk {
+	c := zcChunkPool.Get().(*zcChunk)
+	c.refs.Store(1)
+	return c
+}
+
+func (c *zcChunk) retain() {
+	c.refs.Add(1)
+}
+
+func (c *zcChunk) release() {
+	if c.refs.Add(-1) == 0 {
+		zcChunkPool.Put(c)
+	}
+}
+
+// zcReader reads the frames of a connection in place, into chunks.
+type zcReader struct {
+	r     io.Reader
+	chunk *zcChunk // current chunk, referenced by the reader
+	rd    int      // offset of the first unread byte of chunk
+	wr    int      // offset of the first free byte of chunk
+}
+
+func newZCReader(r io.Reader) *zcReader {
+	return &zcReader{r: r, chunk: newZCChunk()}
+}
+
+// fill buffers at least n contiguous unread bytes, n <= zcChunkSize.
+func (z *zcReader) fill(n int) error {
+	if z.wr-z.rd >= n {
+		return nil
+	}
+	if z.rd+n > zcChunkSize {
+		// the bytes don't fit in the current chunk: carry the unread
+		// bytes over to a new one.
+		old := z.chunk
+		z.chunk = newZCChunk()
+		z.wr = copy(z.chunk.data[:], old.data[z.rd:z.wr])
+		z.rd = 0
+		old.release()
+	}
+	for z.wr-z.rd < n {
+		m, err := z.r.Read(z.chunk.data[z.wr:])
+		z.wr += m
+		if err != nil && z.wr-z.rd < n {
+			if err == io.EOF && z.wr > z.rd {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// next returns the next n unread bytes, n <= zcChunkSize, in place.
+// The returned bytes belong to z.chunk, which may differ from the chunk
+// before the call.
+func (z *zcReader) next(n int) ([]byte, error) {
+	if err := z.fill(n); err != nil {
+		return nil, err
+	}
+	p := z.chunk.data[z.rd : z.rd+n : z.rd+n]
+	z.rd += n
+	return p, nil
+}
+
+// ReadFull reads exactly len(p) bytes into p, with the semantics of
+// io.ReadFull. Unlike next, it copies: it reads the headers, the commands,
+// and the frames larger than a chunk.
+func (z *zcReader) ReadFull(p []byte) (int, error) {
+	if len(p) <= zcChunkSize {
+		b, err := z.next(len(p))
+		if err != nil {
+			return 0, err
+		}
+		return copy(p, b), nil
+	}
+	n := copy(p, z.chunk.data[z.rd:z.wr])
+	z.rd += n
+	m, err := io.ReadFull(z.r, p[n:])
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n + m, err
+}
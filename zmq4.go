@@ -7,7 +7,11 @@
 // For more informations, see http://zeromq.org.
 package zmq4
 
-import "net"
+import (
+	"context"
+	"net"
+	"time"
+)
 
 // Socket represents a ZeroMQ socket.
 type Socket interface {
@@ -25,12 +29,67 @@ type Socket interface {
 	// expires. The message will be sent as a multipart message.
 	SendMulti(msg Msg) error
 
+	// SendNB puts the message on the outbound send queue if it can do so
+	// without blocking, and returns ErrWouldBlock otherwise, e.g. when the
+	// socket has no peer yet or the queue of a peer is full.
+	// Sockets writing messages to their peers directly may still block
+	// while the message is written.
+	SendNB(msg Msg) error
+
+	// Flush blocks until all the messages queued for sending have been
+	// written to the connected peers, or until ctx is done.
+	//
+	// Unlike lingering on Close, Flush leaves the socket open.
+	Flush(ctx context.Context) error
+
 	// Recv receives a complete message.
 	Recv() (Msg, error)
 
+	// RecvNB receives a complete message if one is ready, and returns
+	// ErrWouldBlock otherwise.
+	RecvNB() (Msg, error)
+
+	// RecvMulti receives a complete multipart message: it blocks until all
+	// the frames of a message, sent with the MORE flag set on all of them
+	// but the last, have been received, and returns them in Frames.
+	// Messages are assembled by the connection they are received from
+	// before being handed off, so concurrent receivers never get frames of
+	// different messages.
+	// Frames sent as separate messages are received by separate calls.
+	//
+	// Unlike Recv, which returns the frames of a message cut short by the
+	// disconnection of its peer along with io.ErrUnexpectedEOF, RecvMulti
+	// discards such partial messages and waits for the next complete one.
+	RecvMulti() (Msg, error)
+
+	// SetSendDeadline sets the deadline of the subsequent send operations:
+	// they fail with ErrTimeout once t has passed, even if the timeout of
+	// the Socket has not expired. A zero t clears the deadline.
+	SetSendDeadline(t time.Time) error
+
+	// SetRecvDeadline sets the deadline of the subsequent receive
+	// operations: they fail with ErrTimeout once t has passed, even if the
+	// timeout of the Socket has not expired. A zero t clears the deadline.
+	SetRecvDeadline(t time.Time) error
+
+	// Context returns the life-line of the Socket.
+	//
+	// The returned context is done once the Socket is closed or once the
+	// context the Socket was created with is done.
+	// Cancelling the latter closes the listener and all the connections of
+	// the Socket, and makes pending and subsequent Send and Recv calls return
+	// the context's error. Close should still be called to release the Socket.
+	Context() context.Context
+
+	// Done returns a channel that is closed once Close, or the end of the
+	// context the Socket was created with, has completed the teardown of
+	// the Socket, e.g. once its endpoint is released.
+	Done() <-chan struct{}
+
 	// Listen connects a local endpoint to the Socket.
 	//
-	// In ZeroMQ's terminology, it binds.
+	// In ZeroMQ's terminology, it binds. Listen can be called repeatedly,
+	// to listen to several endpoints.
 	Listen(ep string) error
 
 	// Dial connects a remote endpoint to the Socket.
@@ -42,12 +101,33 @@ type Socket interface {
 	Type() SocketType
 
 	// Addr returns the listener's address. It returns nil if the socket isn't a
-	// listener.
+	// listener, and the address of the first listener if there are several.
 	Addr() net.Addr
 
+	// LastEndpoint returns the end-point the Socket last listened to or
+	// dialed, with the port picked by the system when listening to port 0,
+	// e.g. "tcp://127.0.0.1:54321". It returns an empty string if the
+	// Socket never listened nor dialed.
+	LastEndpoint() (string, error)
+
 	// GetOption retrieves an option for a socket.
+	// The value of a known option has the type documented with its name,
+	// e.g. an int for OptionHWM and a string for OptionIdentity.
 	GetOption(name string) (interface{}, error)
 
 	// SetOption sets an option for a socket.
+	// A value of the wrong type for a known option is rejected with an
+	// error wrapping ErrBadProperty.
 	SetOption(name string, value interface{}) error
+
+	// Reader returns a handle restricted to the receiving methods of the
+	// Socket.
+	Reader() SocketReader
+
+	// Writer returns a handle restricted to the sending methods of the
+	// Socket.
+	//
+	// The Reader and Writer handles of a Socket share its state and may be
+	// used from different goroutines.
+	Writer() SocketWriter
 }
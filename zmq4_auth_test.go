@@ -5,7 +5,11 @@
 package zmq4_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/luxfi/zmq/v4"
 )
@@ -70,6 +74,67 @@ func TestAuthAllowDeny(t *testing.T) {
 	zmq4.AuthCurveRemove("domain1", public)
 }
 
+func TestAuthConnect(t *testing.T) {
+	err := zmq4.AuthStart()
+	if err != nil {
+		t.Fatal("AuthStart:", err)
+	}
+	defer zmq4.AuthStop()
+
+	zmq4.AuthDeny("denied", "127.0.0.1")
+	zmq4.AuthAllow("allowed", "127.0.0.1")
+	zmq4.AuthAllow("others", "10.0.0.0/8")
+	zmq4.AuthSetMetadataHandler(func(domain, address string) map[string]string {
+		return map[string]string{"X-Domain": domain}
+	})
+
+	for _, tc := range []struct {
+		domain string
+		allow  bool
+	}{
+		{domain: "denied", allow: false},
+		{domain: "allowed", allow: true},
+		{domain: "others", allow: false},
+		{domain: "", allow: true}, // NULL peers are not authenticated without domain
+	} {
+		t.Run(tc.domain, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			ep := must(EndPoint("tcp"))
+			rep := zmq4.NewRep(ctx, zmq4.WithZAPDomain(tc.domain))
+			defer rep.Close()
+			req := zmq4.NewReq(ctx, zmq4.WithDialerMaxRetries(0))
+			defer req.Close()
+
+			if err := rep.Listen(ep); err != nil {
+				t.Fatalf("could not listen: %+v", err)
+			}
+			err := req.Dial(ep)
+			if !tc.allow {
+				if !errors.Is(err, zmq4.ErrAuthFailed) {
+					t.Fatalf("denied peer: got err=%v, want %v", err, zmq4.ErrAuthFailed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not dial: %+v", err)
+			}
+
+			if err := req.Send(zmq4.NewMsgString("ping")); err != nil {
+				t.Fatalf("could not send: %+v", err)
+			}
+			msg, err := rep.Recv()
+			if err != nil {
+				t.Fatalf("could not recv: %+v", err)
+			}
+			if got, want := string(msg.Frames[0]), "ping"; got != want {
+				t.Fatalf("invalid message: got=%q, want=%q", got, want)
+			}
+		})
+	}
+}
+
 func TestNewCurveKeypair(t *testing.T) {
 	public, secret, err := zmq4.NewCurveKeypair()
 	if err != nil {
@@ -90,23 +155,48 @@ func TestNewCurveKeypair(t *testing.T) {
 }
 
 func TestZ85EncodeDecode(t *testing.T) {
-	original := []byte("Hello, World!")
-
-	// Encode
-	encoded := zmq4.Z85encode(original)
-	if encoded == "" {
-		t.Fatal("Empty encoded string")
-	}
-
-	// Decode
-	decoded, err := zmq4.Z85decode(encoded)
+	for _, tc := range []struct {
+		data []byte
+		text string
+	}{
+		{nil, ""},
+		// test vector of https://rfc.zeromq.org/spec/32/.
+		{[]byte{0x86, 0x4f, 0xd2, 0x6f, 0xb5, 0x59, 0xf7, 0x5b}, "HelloWorld"},
+		{[]byte{0, 0, 0, 0}, "00000"},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, "%nSc0"},
+	} {
+		if got := zmq4.Z85encode(tc.data); got != tc.text {
+			t.Fatalf("invalid encoding of %x: got=%q, want=%q", tc.data, got, tc.text)
+		}
+		got, err := zmq4.Z85decode(tc.text)
+		if err != nil {
+			t.Fatalf("could not decode %q: %+v", tc.text, err)
+		}
+		if !bytes.Equal(got, tc.data) {
+			t.Fatalf("invalid decoding of %q: got=%x, want=%x", tc.text, got, tc.data)
+		}
+	}
+
+	// a CURVE public key, as printed by libzmq's curve_keygen.
+	const key = "Yne@$w-vo<fVvi]a<NY6T1ed:M$fCG*[IaLV{hID"
+	raw, err := zmq4.Z85decode(key)
 	if err != nil {
-		t.Fatal("Z85decode:", err)
+		t.Fatalf("could not decode key: %+v", err)
+	}
+	if len(raw) != 32 {
+		t.Fatalf("invalid key length: got=%d, want=32", len(raw))
+	}
+	if got := zmq4.Z85encode(raw); got != key {
+		t.Fatalf("invalid key round-trip: got=%q, want=%q", got, key)
 	}
 
-	// Compare
-	if string(decoded) != string(original) {
-		t.Fatalf("Mismatch: got %q, want %q", decoded, original)
+	if got := zmq4.Z85encode([]byte("Hello, World!")); got != "" {
+		t.Fatalf("encoded data of invalid length: %q", got)
+	}
+	for _, text := range []string{"Hell", "Hel o", "%nSc1"} {
+		if _, err := zmq4.Z85decode(text); err == nil {
+			t.Fatalf("decoded invalid text %q", text)
+		}
 	}
 }
 
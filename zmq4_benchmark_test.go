@@ -5,6 +5,9 @@ package zmq4_test
 
 import (
 	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
 	"testing"
 	"time"
 
@@ -35,6 +38,18 @@ func BenchmarkPureGoPubSub(b *testing.B) {
 	})
 }
 
+func BenchmarkPureGoPubSubBatch(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("Send-10k", func(b *testing.B) {
+		benchmarkPubSubUpdates(b, ctx, false)
+	})
+
+	b.Run("SendBatch-10k", func(b *testing.B) {
+		benchmarkPubSubUpdates(b, ctx, true)
+	})
+}
+
 func BenchmarkPureGoReqRep(b *testing.B) {
 	ctx := context.Background()
 
@@ -49,6 +64,10 @@ func BenchmarkPureGoReqRep(b *testing.B) {
 	b.Run("RoundTrip-Large", func(b *testing.B) {
 		benchmarkReqRepRoundTrip(b, ctx, 10*1024)
 	})
+
+	b.Run("RoundTrip-Small-Release", func(b *testing.B) {
+		benchmarkReqRepRoundTripRelease(b, ctx, 64)
+	})
 }
 
 func BenchmarkPureGoPushPull(b *testing.B) {
@@ -63,6 +82,30 @@ func BenchmarkPureGoPushPull(b *testing.B) {
 	})
 }
 
+func BenchmarkPureGoWriteCoalesce(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("Off", func(b *testing.B) {
+		benchmarkPushPullBurst(b, ctx)
+	})
+
+	b.Run("On-100us-64KB", func(b *testing.B) {
+		benchmarkPushPullBurst(b, ctx, zmq4.WithWriteCoalesce(100*time.Microsecond, 64*1024))
+	})
+}
+
+func BenchmarkPureGoRecvZeroCopy(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("Copy", func(b *testing.B) {
+		benchmarkPushPullRecv(b, ctx, false)
+	})
+
+	b.Run("ZeroCopy", func(b *testing.B) {
+		benchmarkPushPullRecv(b, ctx, true)
+	})
+}
+
 func BenchmarkPureGoRouterDealer(b *testing.B) {
 	ctx := context.Background()
 
@@ -71,6 +114,36 @@ func BenchmarkPureGoRouterDealer(b *testing.B) {
 	})
 }
 
+// BenchmarkPureGoRecvTuning reports the one-way latency (p50, p99) and the
+// throughput (ns/op) of the messages received by a PULL socket, for various
+// receive queue configurations.
+// The PUSH peer keeps either one ("Idle") or up to 256 ("Loaded") messages
+// in flight.
+//
+// Larger receive queues trade latency for throughput under load: messages
+// wait behind the ones already queued. The dispatcher adds a goroutine
+// hand-off per message, in exchange for fair-queuing between peers.
+func BenchmarkPureGoRecvTuning(b *testing.B) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		opts []zmq4.Option
+	}{
+		{"Queue-0", []zmq4.Option{zmq4.WithRecvQueueSize(0)}},
+		{"Queue-10", nil}, // default
+		{"Queue-1000", []zmq4.Option{zmq4.WithRecvQueueSize(1000)}},
+		{"Dispatcher", []zmq4.Option{zmq4.WithRecvDispatcher(true)}},
+	} {
+		b.Run(tc.name+"/Idle", func(b *testing.B) {
+			benchmarkRecvLatency(b, ctx, 1, tc.opts...)
+		})
+		b.Run(tc.name+"/Loaded", func(b *testing.B) {
+			benchmarkRecvLatency(b, ctx, 256, tc.opts...)
+		})
+	}
+}
+
 // Helper functions for benchmarks
 
 func benchmarkPubSubThroughput(b *testing.B, ctx context.Context, msgSize int) {
@@ -111,6 +184,61 @@ func benchmarkPubSubThroughput(b *testing.B, ctx context.Context, msgSize int) {
 	}
 }
 
+// benchmarkPubSubUpdates publishes 10k small topic updates per iteration,
+// one by one or as a single batch, and waits for their delivery.
+func benchmarkPubSubUpdates(b *testing.B, ctx context.Context, batched bool) {
+	const n = 10000
+
+	pub := zmq4.NewPub(ctx)
+	defer pub.Close()
+	sub := zmq4.NewSub(ctx)
+	defer sub.Close()
+	sub.SetOption(zmq4.OptionSubscribe, "")
+	// updates sent one by one must not be dropped.
+	pub.SetOption(zmq4.OptionHWM, 2*n)
+
+	endpoint := must(EndPoint("tcp"))
+	if err := pub.Listen(endpoint); err != nil {
+		b.Fatal(err)
+	}
+	if err := sub.Dial(endpoint); err != nil {
+		b.Fatal(err)
+	}
+
+	// Allow connection to establish
+	time.Sleep(100 * time.Millisecond)
+
+	updates := make([]zmq4.TopicMsg, n)
+	for i := range updates {
+		updates[i] = zmq4.TopicMsg{
+			Topic:   []byte(fmt.Sprintf("md.%04d", i)),
+			Payload: make([]byte, 16),
+		}
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if batched {
+			if err := pub.(zmq4.BatchSender).SendBatch(updates); err != nil {
+				b.Fatal(err)
+			}
+		} else {
+			for _, u := range updates {
+				if err := pub.Send(zmq4.NewMsgFrom(u.Topic, u.Payload)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		for j := 0; j < n; j++ {
+			if _, err := sub.Recv(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
 func benchmarkPubSubLatency(b *testing.B, ctx context.Context) {
 	pub := zmq4.NewPub(ctx)
 	defer pub.Close()
@@ -188,6 +316,56 @@ func benchmarkReqRepRoundTrip(b *testing.B, ctx context.Context, msgSize int) {
 	}
 }
 
+// benchmarkReqRepRoundTripRelease is like benchmarkReqRepRoundTrip but
+// hands the received messages back with Msg.Release.
+func benchmarkReqRepRoundTripRelease(b *testing.B, ctx context.Context, msgSize int) {
+	req := zmq4.NewReq(ctx, zmq4.WithRecvBufferPool(true))
+	defer req.Close()
+	rep := zmq4.NewRep(ctx, zmq4.WithRecvBufferPool(true))
+	defer rep.Close()
+
+	endpoint := must(EndPoint("tcp"))
+	if err := rep.Listen(endpoint); err != nil {
+		b.Fatal(err)
+	}
+	if err := req.Dial(endpoint); err != nil {
+		b.Fatal(err)
+	}
+
+	reply := zmq4.NewMsg(make([]byte, msgSize))
+	go func() {
+		for {
+			msg, err := rep.Recv()
+			if err != nil {
+				return
+			}
+			// the reply doesn't share the request frames: the request
+			// can be released before the (queued) reply is sent.
+			msg.Release()
+			if err := rep.Send(reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	msg := zmq4.NewMsg(make([]byte, msgSize))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.SetBytes(int64(msgSize * 2)) // Request + Reply
+
+	for i := 0; i < b.N; i++ {
+		if err := req.Send(msg); err != nil {
+			b.Fatal(err)
+		}
+		rmsg, err := req.Recv()
+		if err != nil {
+			b.Fatal(err)
+		}
+		rmsg.Release()
+	}
+}
+
 func benchmarkPushPullThroughput(b *testing.B, ctx context.Context) {
 	push := zmq4.NewPush(ctx)
 	defer push.Close()
@@ -216,6 +394,81 @@ func benchmarkPushPullThroughput(b *testing.B, ctx context.Context) {
 	}
 }
 
+// benchmarkPushPullBurst sends bursts of small messages, waiting for each
+// burst to be received.
+func benchmarkPushPullBurst(b *testing.B, ctx context.Context, opts ...zmq4.Option) {
+	const burst = 1000
+
+	push := zmq4.NewPush(ctx, opts...)
+	defer push.Close()
+	pull := zmq4.NewPull(ctx, zmq4.WithRecvQueueSize(burst))
+	defer pull.Close()
+
+	endpoint := must(EndPoint("tcp"))
+	if err := pull.Listen(endpoint); err != nil {
+		b.Fatal(err)
+	}
+	if err := push.Dial(endpoint); err != nil {
+		b.Fatal(err)
+	}
+
+	// Allow connection to establish
+	time.Sleep(100 * time.Millisecond)
+
+	msg := zmq4.NewMsg(make([]byte, 32))
+
+	b.ResetTimer()
+	b.SetBytes(burst * 32)
+
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < burst; j++ {
+			if err := push.Send(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for j := 0; j < burst; j++ {
+			if _, err := pull.Recv(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkPushPullRecv measures the allocations of receiving small
+// messages, released once received when zeroCopy is enabled.
+func benchmarkPushPullRecv(b *testing.B, ctx context.Context, zeroCopy bool) {
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	pull := zmq4.NewPull(ctx, zmq4.WithRecvZeroCopy(zeroCopy))
+	defer pull.Close()
+
+	endpoint := must(EndPoint("tcp"))
+	if err := pull.Listen(endpoint); err != nil {
+		b.Fatal(err)
+	}
+	if err := push.Dial(endpoint); err != nil {
+		b.Fatal(err)
+	}
+
+	msg := zmq4.NewMsg(make([]byte, 64))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := push.Send(msg); err != nil {
+			b.Fatal(err)
+		}
+		got, err := pull.Recv()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if zeroCopy {
+			got.Release()
+		}
+	}
+}
+
 func benchmarkPushPullFanOut(b *testing.B, ctx context.Context, workers int) {
 	push := zmq4.NewPush(ctx)
 	defer push.Close()
@@ -299,3 +552,53 @@ func benchmarkRouterDealerPerformance(b *testing.B, ctx context.Context) {
 		}
 	}
 }
+
+func benchmarkRecvLatency(b *testing.B, ctx context.Context, inflight int, opts ...zmq4.Option) {
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	pull := zmq4.NewPull(ctx, opts...)
+	defer pull.Close()
+
+	endpoint := must(EndPoint("tcp"))
+	if err := pull.Listen(endpoint); err != nil {
+		b.Fatal(err)
+	}
+	if err := push.Dial(endpoint); err != nil {
+		b.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	var (
+		lats   = make([]time.Duration, b.N)
+		tokens = make(chan struct{}, inflight)
+	)
+
+	b.ResetTimer()
+
+	go func() {
+		for i := 0; i < b.N; i++ {
+			tokens <- struct{}{}
+			var ts [8]byte
+			binary.LittleEndian.PutUint64(ts[:], uint64(time.Now().UnixNano()))
+			if err := push.Send(zmq4.NewMsg(ts[:])); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < b.N; i++ {
+		msg, err := pull.Recv()
+		if err != nil {
+			b.Fatal(err)
+		}
+		sent := int64(binary.LittleEndian.Uint64(msg.Frames[0]))
+		lats[i] = time.Duration(time.Now().UnixNano() - sent)
+		<-tokens
+	}
+
+	b.StopTimer()
+	sort.Slice(lats, func(i, j int) bool { return lats[i] < lats[j] })
+	b.ReportMetric(float64(lats[len(lats)/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(lats[len(lats)*99/100].Nanoseconds()), "p99-ns")
+}
@@ -8,11 +8,15 @@ package zmq4_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/luxfi/zmq/v4"
+	"github.com/luxfi/zmq/v4/security/null"
 )
 
 // Test all socket types and their basic operations
@@ -391,9 +395,212 @@ func TestRecvWithTimeout(t *testing.T) {
 	}
 }
 
+func TestRecvSendNB(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+
+	if err := push.SendNB(zmq4.NewMsgString("early")); !errors.Is(err, zmq4.ErrWouldBlock) {
+		t.Fatalf("SendNB without peer: got %v, want ErrWouldBlock", err)
+	}
+	if _, err := pull.RecvNB(); !errors.Is(err, zmq4.ErrWouldBlock) {
+		t.Fatalf("RecvNB without peer: got %v, want ErrWouldBlock", err)
+	}
+
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := push.Dial("tcp://" + pull.Addr().String()); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	for {
+		err := push.SendNB(zmq4.NewMsgString("hello"))
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, zmq4.ErrWouldBlock) {
+			t.Fatalf("could not send: %+v", err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("push socket never became writable")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	for {
+		msg, err := pull.RecvNB()
+		if err == nil {
+			if got, want := msg.String(), zmq4.NewMsgString("hello").String(); got != want {
+				t.Fatalf("invalid message: got %q, want %q", got, want)
+			}
+			break
+		}
+		if !errors.Is(err, zmq4.ErrWouldBlock) {
+			t.Fatalf("could not receive: %+v", err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("message never received")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	if _, err := pull.RecvNB(); !errors.Is(err, zmq4.ErrWouldBlock) {
+		t.Fatalf("RecvNB on drained socket: got %v, want ErrWouldBlock", err)
+	}
+
+	pull.Close()
+	if _, err := pull.RecvNB(); err == nil || errors.Is(err, zmq4.ErrWouldBlock) {
+		t.Fatalf("RecvNB on closed socket: got %v, want a socket error", err)
+	}
+}
+
+// Test RecvMulti discarding the messages truncated by a disconnection
+func TestRecvMultiTruncated(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	// truncate sends the first frame of a multipart message, with the MORE
+	// flag set, and disconnects.
+	truncate := func() {
+		t.Helper()
+		raw, err := net.Dial("tcp", pull.Addr().String())
+		if err != nil {
+			t.Fatalf("could not dial: %+v", err)
+		}
+		defer raw.Close()
+		if _, err := zmq4.Open(raw, null.Security(), zmq4.Push, nil, false, nil); err != nil {
+			t.Fatalf("could not open connection: %+v", err)
+		}
+		if _, err := raw.Write([]byte{0x01, 4, 'p', 'a', 'r', 't'}); err != nil {
+			t.Fatalf("could not write frame: %+v", err)
+		}
+		raw.Close()
+		if ok, err := zmq4.WaitReadable(pull, 5*time.Second); err != nil || !ok {
+			t.Fatalf("truncated message not queued: ok=%v, err=%v", ok, err)
+		}
+	}
+
+	// Recv returns the frames of the truncated message.
+	truncate()
+	msg, err := pull.Recv()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, io.ErrUnexpectedEOF)
+	}
+	if len(msg.Frames) != 1 || string(msg.Frames[0]) != "part" {
+		t.Fatalf("invalid truncated message: %q", msg.Frames)
+	}
+
+	// RecvMulti skips it, and returns the next complete message.
+	truncate()
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	if err := push.Dial("tcp://" + pull.Addr().String()); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	frames := []string{"part-1", "part-2", "part-3"}
+	if err := push.SendMulti(zmq4.NewMsgFromString(frames)); err != nil {
+		t.Fatalf("could not send multipart message: %+v", err)
+	}
+	msg, err = pull.RecvMulti()
+	if err != nil {
+		t.Fatalf("could not receive multipart message: %+v", err)
+	}
+	if got := msg.Frames; len(got) != 3 || string(got[0]) != frames[0] || string(got[2]) != frames[2] {
+		t.Fatalf("invalid multipart message: got=%q, want=%q", got, frames)
+	}
+}
+
 // Test multiple recv with timeout
 func TestMultipleRecvWithTimeout(t *testing.T) {
-	t.Skip("RecvMulti not available in simplified API")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	if err := push.Dial("tcp://" + pull.Addr().String()); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	frames := []string{"part-1", "part-2", "part-3"}
+	if err := push.SendMulti(zmq4.NewMsgFromString(frames)); err != nil {
+		t.Fatalf("could not send multipart message: %+v", err)
+	}
+	msg, err := pull.RecvMulti()
+	if err != nil {
+		t.Fatalf("could not receive multipart message: %+v", err)
+	}
+	if got := msg.Frames; len(got) != 3 || string(got[0]) != frames[0] || string(got[1]) != frames[1] || string(got[2]) != frames[2] {
+		t.Fatalf("invalid multipart message: got=%q, want=%q", got, frames)
+	}
+
+	// separately sent frames are separate messages.
+	for _, frame := range frames[:2] {
+		if err := push.Send(zmq4.NewMsgString(frame)); err != nil {
+			t.Fatalf("could not send %q: %+v", frame, err)
+		}
+	}
+	for _, frame := range frames[:2] {
+		msg, err := pull.RecvMulti()
+		if err != nil {
+			t.Fatalf("could not receive %q: %+v", frame, err)
+		}
+		if len(msg.Frames) != 1 || string(msg.Frames[0]) != frame {
+			t.Fatalf("invalid message: got=%q, want=%q", msg.Frames, frame)
+		}
+	}
+
+	// concurrent receivers get whole messages.
+	const n = 100
+	errc := make(chan error, n)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for {
+				msg, err := pull.RecvMulti()
+				if err != nil {
+					return
+				}
+				if len(msg.Frames) != 3 || string(msg.Frames[0]) != string(msg.Frames[2]) {
+					errc <- fmt.Errorf("interleaved message: %q", msg.Frames)
+					continue
+				}
+				errc <- nil
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("msg-%d", i)
+		if err := push.SendMulti(zmq4.NewMsgFromString([]string{id, "body", id})); err != nil {
+			t.Fatalf("could not send message #%d: %+v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timeout waiting for message #%d", i)
+		}
+	}
 }
 
 // Test context cancellation
@@ -430,6 +637,104 @@ func TestContextCancellation(t *testing.T) {
 	}
 }
 
+func TestContextCancellationAllTypes(t *testing.T) {
+	type ctor func(context.Context, ...zmq4.Option) zmq4.Socket
+	for _, tc := range []struct {
+		name string
+		sck  ctor
+		peer ctor
+		recv bool // whether the socket can receive
+		send bool // whether the socket can send
+	}{
+		{"pair", zmq4.NewPair, zmq4.NewPair, true, true},
+		{"pub", zmq4.NewPub, zmq4.NewSub, false, true},
+		{"sub", zmq4.NewSub, zmq4.NewPub, true, false},
+		{"req", zmq4.NewReq, zmq4.NewRep, true, true},
+		{"rep", zmq4.NewRep, zmq4.NewReq, true, true},
+		{"dealer", zmq4.NewDealer, zmq4.NewRouter, true, true},
+		{"router", zmq4.NewRouter, zmq4.NewDealer, true, true},
+		{"pull", zmq4.NewPull, zmq4.NewPush, true, false},
+		{"push", zmq4.NewPush, zmq4.NewPull, false, true},
+		{"xpub", zmq4.NewXPub, zmq4.NewXSub, true, true},
+		{"xsub", zmq4.NewXSub, zmq4.NewXPub, true, true},
+		{"stream", zmq4.NewStream, nil, true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sck := tc.sck(ctx)
+			defer sck.Close()
+
+			if err := sck.Listen("tcp://127.0.0.1:0"); err != nil {
+				t.Fatalf("could not listen: %+v", err)
+			}
+
+			if tc.peer != nil {
+				peer := tc.peer(context.Background())
+				defer peer.Close()
+				if err := peer.Dial("tcp://" + sck.Addr().String()); err != nil {
+					t.Fatalf("could not dial: %+v", err)
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			if tc.name == "req" {
+				// a pending REQ receive waits for the reply to a request.
+				if err := sck.Send(zmq4.NewMsgString("ping")); err != nil {
+					t.Fatalf("could not send request: %+v", err)
+				}
+			}
+
+			done := make(chan error, 1)
+			if tc.recv {
+				go func() {
+					_, err := sck.Recv()
+					done <- err
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			cancel()
+
+			select {
+			case <-sck.Context().Done():
+			case <-time.After(time.Second):
+				t.Fatalf("socket context not done after cancellation")
+			}
+			if err := sck.Context().Err(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("invalid socket context error: got=%v", err)
+			}
+
+			if tc.recv {
+				select {
+				case err := <-done:
+					if !errors.Is(err, context.Canceled) {
+						t.Fatalf("invalid pending Recv error: got=%v, want=%v", err, context.Canceled)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("pending Recv did not return after cancellation")
+				}
+
+				if _, err := sck.Recv(); !errors.Is(err, context.Canceled) {
+					t.Fatalf("invalid Recv error: got=%v, want=%v", err, context.Canceled)
+				}
+			}
+
+			if tc.send {
+				msg := zmq4.NewMsgFrom([]byte("id"), []byte("data"))
+				if err := sck.Send(msg); !errors.Is(err, context.Canceled) {
+					t.Fatalf("invalid Send error: got=%v, want=%v", err, context.Canceled)
+				}
+			}
+
+			if err := sck.Close(); err != nil {
+				t.Fatalf("could not close socket after cancellation: %+v", err)
+			}
+		})
+	}
+}
+
 // Test proxy functionality
 func TestProxyBasic(t *testing.T) {
 	ctx := context.Background()
@@ -452,7 +757,7 @@ func TestProxyBasic(t *testing.T) {
 
 	// Start proxy in background
 	go func() {
-		zmq4.Proxy(frontend, backend)
+		zmq4.Proxy(frontend, backend, nil)
 	}()
 
 	// Allow proxy to start
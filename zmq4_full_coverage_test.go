@@ -161,7 +161,7 @@ func TestProxyFunction(t *testing.T) {
 	// Start proxy in goroutine with timeout
 	done := make(chan error, 1)
 	go func() {
-		err := zmq4.Proxy(frontend, backend)
+		err := zmq4.Proxy(frontend, backend, nil)
 		done <- err
 	}()
 
@@ -487,7 +487,7 @@ func TestErrorHandling(t *testing.T) {
 	ctx := context.Background()
 
 	// Test nil socket in Proxy
-	err := zmq4.Proxy(nil, nil)
+	err := zmq4.Proxy(nil, nil, nil)
 	if err == nil {
 		t.Error("Expected error for nil sockets in Proxy")
 	}
@@ -0,0 +1,147 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+)
+
+// stallWriter writes to w until stalled is set, then discards the writes,
+// as a peer that stopped responding.
+type stallWriter struct {
+	w       io.Writer
+	stalled *atomic.Bool
+}
+
+func (sw stallWriter) Write(p []byte) (int, error) {
+	if sw.stalled.Load() {
+		return len(p), nil
+	}
+	return sw.w.Write(p)
+}
+
+// stallProxy forwards the first connection accepted on a local TCP address
+// to addr, until stalled is set. It returns the end-point of the proxy.
+func stallProxy(t *testing.T, addr string, stalled *atomic.Bool) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		_ = ln.Close()
+		wg.Wait()
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cli, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer cli.Close()
+		srv, err := net.Dial("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer srv.Close()
+		done := make(chan struct{}, 2)
+		go func() {
+			_, _ = io.Copy(stallWriter{srv, stalled}, cli)
+			done <- struct{}{}
+		}()
+		go func() {
+			_, _ = io.Copy(stallWriter{cli, stalled}, srv)
+			done <- struct{}{}
+		}()
+		<-done
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const (
+		ivl     = 50 * time.Millisecond
+		timeout = 100 * time.Millisecond
+	)
+
+	srv := zmq4.NewPair(ctx)
+	defer srv.Close()
+	if err := srv.Listen(must(EndPoint("tcp"))); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	var stalled atomic.Bool
+	ep := stallProxy(t, srv.Addr().String(), &stalled)
+
+	cli := zmq4.NewPair(ctx, zmq4.WithAutomaticReconnect(false))
+	defer cli.Close()
+	for _, opt := range []struct {
+		name  string
+		value interface{}
+	}{
+		{zmq4.OptionHeartbeatIvl, ivl},
+		{zmq4.OptionHeartbeatTimeout, timeout},
+		{zmq4.OptionHeartbeatTTL, time.Second},
+	} {
+		if err := cli.SetOption(opt.name, opt.value); err != nil {
+			t.Fatalf("could not set %s: %+v", opt.name, err)
+		}
+	}
+	if err := cli.SetOption(zmq4.OptionHeartbeatTTL, 2*time.Hour); !errors.Is(err, zmq4.ErrBadProperty) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrBadProperty)
+	}
+	events := cli.(zmq4.Monitored).Monitor(zmq4.EventDisconnected)
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	// the heartbeats of a live peer keep the connection up, and are not
+	// received as messages.
+	select {
+	case ev := <-events:
+		t.Fatalf("live peer disconnected: %+v", ev)
+	case <-time.After(5 * (ivl + timeout)):
+	}
+	for _, pair := range [][2]zmq4.Socket{{cli, srv}, {srv, cli}} {
+		if err := pair[0].Send(zmq4.NewMsgString("hello")); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+		msg, err := pair[1].Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		if got, want := string(msg.Frames[0]), "hello"; got != want {
+			t.Fatalf("invalid message: got=%q, want=%q", got, want)
+		}
+	}
+
+	stalled.Store(true)
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		t.Fatalf("dead peer not detected: %+v", ctx.Err())
+	case <-events:
+	}
+	// the peer is found dead at most a PING interval, and the timeout,
+	// after it stopped responding.
+	if elapsed := time.Since(start); elapsed > 2*(ivl+timeout) {
+		t.Fatalf("dead peer detected after %v, want at most %v", elapsed, 2*(ivl+timeout))
+	}
+}
@@ -171,3 +171,135 @@ func TestPair(t *testing.T) {
 		})
 	}
 }
+
+func TestPairReaderWriter(t *testing.T) {
+	ctx, timeout := context.WithTimeout(context.Background(), 20*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	srv := zmq4.NewPair(ctx)
+	defer srv.Close()
+	cli := zmq4.NewPair(ctx)
+	defer cli.Close()
+
+	if err := srv.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	// echo every message back to the client.
+	go func() {
+		for {
+			msg, err := srv.Recv()
+			if err != nil {
+				return
+			}
+			if err := srv.Send(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	const n = 10
+	var (
+		grp, _ = errgroup.WithContext(ctx)
+		r      = cli.Reader()
+		w      = cli.Writer()
+	)
+	if _, ok := r.(zmq4.SocketWriter); ok {
+		t.Fatalf("reader handle can send")
+	}
+	if _, ok := w.(zmq4.SocketReader); ok {
+		t.Fatalf("writer handle can receive")
+	}
+	if r.Context() != cli.Context() || w.Context() != cli.Context() {
+		t.Fatalf("handles do not share the socket life-line")
+	}
+
+	grp.Go(func() error {
+		for i := 0; i < n; i++ {
+			if err := w.Send(zmq4.NewMsgString(fmt.Sprintf("msg-%d", i))); err != nil {
+				return fmt.Errorf("could not send #%d msg: %w", i, err)
+			}
+		}
+		return nil
+	})
+	grp.Go(func() error {
+		for i := 0; i < n; i++ {
+			msg, err := r.Recv()
+			if err != nil {
+				return fmt.Errorf("could not recv #%d msg: %w", i, err)
+			}
+			if got, want := string(msg.Bytes()), fmt.Sprintf("msg-%d", i); got != want {
+				return fmt.Errorf("invalid #%d msg: got=%q, want=%q", i, got, want)
+			}
+		}
+		return nil
+	})
+
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("error: %+v", err)
+	}
+}
+
+func TestSendRecvParts(t *testing.T) {
+	ctx, timeout := context.WithTimeout(context.Background(), 20*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	srv := zmq4.NewPair(ctx)
+	defer srv.Close()
+	cli := zmq4.NewPair(ctx)
+	defer cli.Close()
+
+	if err := srv.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		parts [][]byte
+		msg   zmq4.Msg
+	}{
+		{"empty", nil, zmq4.NewMsg(nil)},
+		{"single", [][]byte{[]byte("hello")}, zmq4.NewMsgString("hello")},
+		{"empty-frame", [][]byte{{}}, zmq4.NewMsg([]byte{})},
+		{"multi", [][]byte{[]byte("a"), {}, []byte("c")}, zmq4.NewMsgFrom([]byte("a"), []byte{}, []byte("c"))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := zmq4.SendParts(cli, tc.parts...); err != nil {
+				t.Fatalf("could not send parts: %+v", err)
+			}
+			got, err := zmq4.RecvParts(srv)
+			if err != nil {
+				t.Fatalf("could not recv parts: %+v", err)
+			}
+
+			if err := cli.Send(tc.msg); err != nil {
+				t.Fatalf("could not send msg: %+v", err)
+			}
+			msg, err := srv.Recv()
+			if err != nil {
+				t.Fatalf("could not recv msg: %+v", err)
+			}
+
+			if len(got) != len(msg.Frames) {
+				t.Fatalf("invalid number of parts: got=%d, want=%d", len(got), len(msg.Frames))
+			}
+			for i := range got {
+				if got[i] == nil || !bytes.Equal(got[i], msg.Frames[i]) {
+					t.Fatalf("invalid part #%d: got=%q, want=%q", i, got[i], msg.Frames[i])
+				}
+			}
+		})
+	}
+}
@@ -8,7 +8,9 @@ package zmq4_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,176 +18,677 @@ import (
 )
 
 func TestProxy(t *testing.T) {
-	t.Skip("Simplified proxy needs updated test")
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Create frontend and backend sockets
 	frontend := zmq4.NewRouter(ctx)
 	defer frontend.Close()
 	backend := zmq4.NewDealer(ctx)
 	defer backend.Close()
+	capture := zmq4.NewPush(ctx)
+	defer capture.Close()
 
-	// Bind sockets
-	err := frontend.Listen("tcp://127.0.0.1:0")
-	if err != nil {
-		t.Fatal("frontend.Listen:", err)
-	}
-
-	err = backend.Listen("tcp://127.0.0.1:0")
-	if err != nil {
-		t.Fatal("backend.Listen:", err)
+	for _, sck := range []zmq4.Socket{frontend, backend, capture} {
+		if err := sck.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen on %v: %+v", sck.Type(), err)
+		}
 	}
 
-	// Create client and worker
 	client := zmq4.NewReq(ctx)
 	defer client.Close()
+	if err := client.Dial(fmt.Sprintf("tcp://%s", frontend.Addr())); err != nil {
+		t.Fatalf("could not dial frontend: %+v", err)
+	}
 	worker := zmq4.NewRep(ctx)
 	defer worker.Close()
-
-	// Connect client and worker
-	frontAddr := frontend.Addr()
-	err = client.Dial(fmt.Sprintf("tcp://%s", frontAddr))
-	if err != nil {
-		t.Fatal("client.Dial:", err)
+	if err := worker.Dial(fmt.Sprintf("tcp://%s", backend.Addr())); err != nil {
+		t.Fatalf("could not dial backend: %+v", err)
 	}
-
-	backAddr := backend.Addr()
-	err = worker.Dial(fmt.Sprintf("tcp://%s", backAddr))
-	if err != nil {
-		t.Fatal("worker.Dial:", err)
+	observer := zmq4.NewPull(ctx)
+	defer observer.Close()
+	if err := observer.Dial(fmt.Sprintf("tcp://%s", capture.Addr())); err != nil {
+		t.Fatalf("could not dial capture: %+v", err)
 	}
 
-	// Start proxy in background
 	done := make(chan error, 1)
 	go func() {
-		err := zmq4.Proxy(frontend, backend)
-		done <- err
+		done <- zmq4.Proxy(frontend, backend, capture)
 	}()
 
-	// Worker echo loop
+	const n = 3
 	go func() {
-		for i := 0; i < 3; i++ {
+		for range n {
 			msg, err := worker.Recv()
 			if err != nil {
-				t.Error("worker.Recv:", err)
+				t.Errorf("could not recv request: %+v", err)
 				return
 			}
-
-			// Echo back with prefix
-			reply := zmq4.NewMsg([]byte("Reply: " + string(msg.Frames[0])))
-			err = worker.Send(reply)
-			if err != nil {
-				t.Error("worker.Send:", err)
+			if err := worker.Send(zmq4.NewMsgString("Reply: " + string(msg.Frames[0]))); err != nil {
+				t.Errorf("could not send reply: %+v", err)
 				return
 			}
 		}
 	}()
 
-	// Send requests through proxy
-	for i := 0; i < 3; i++ {
-		msg := zmq4.NewMsg([]byte(fmt.Sprintf("Request %d", i)))
-		err = client.Send(msg)
+	for i := range n {
+		if err := client.Send(zmq4.NewMsgString(fmt.Sprintf("Request %d", i))); err != nil {
+			t.Fatalf("could not send request: %+v", err)
+		}
+		reply, err := client.Recv()
 		if err != nil {
-			t.Fatal("client.Send:", err)
+			t.Fatalf("could not recv reply: %+v", err)
 		}
+		if got, want := string(reply.Frames[0]), fmt.Sprintf("Reply: Request %d", i); got != want {
+			t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+		}
+	}
 
-		reply, err := client.Recv()
+	// each request and reply is copied to the capture socket, with its
+	// routing envelope.
+	for i := range 2 * n {
+		msg, err := observer.Recv()
 		if err != nil {
-			t.Fatal("client.Recv:", err)
+			t.Fatalf("could not recv captured message %d: %+v", i, err)
+		}
+		payload := string(msg.Frames[len(msg.Frames)-1])
+		if !strings.HasPrefix(payload, "Request ") && !strings.HasPrefix(payload, "Reply: ") {
+			t.Fatalf("invalid captured message %d: %q", i, msg.Frames)
 		}
+	}
 
-		expected := fmt.Sprintf("Reply: Request %d", i)
-		if string(reply.Frames[0]) != expected {
-			t.Errorf("Got %q, want %q", reply.Frames[0], expected)
+	// the proxy returns nil once either socket is closed.
+	if err := frontend.Close(); err != nil {
+		t.Fatalf("could not close frontend: %+v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("proxy failed: %+v", err)
 		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("proxy did not return once its frontend was closed")
 	}
 }
 
-func TestProxyWithCapture(t *testing.T) {
-	t.Skip("Temporarily disabled - proxy implementation needs work")
-	ctx := context.Background()
+func TestProxyWithPeerCheck(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Create sockets
-	frontend := zmq4.NewPub(ctx)
+	frontend := zmq4.NewRouter(ctx)
 	defer frontend.Close()
-	backend := zmq4.NewSub(ctx)
+	backend := zmq4.NewDealer(ctx)
 	defer backend.Close()
-	capture := zmq4.NewPub(ctx)
-	defer capture.Close()
 
-	// Bind frontend and capture
-	err := frontend.Listen("tcp://127.0.0.1:0")
+	if err := frontend.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen on frontend: %+v", err)
+	}
+	if err := backend.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen on backend: %+v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- zmq4.ProxyWithPeerCheck(frontend, backend, zmq4.ProxyPeers{
+			Frontend: []zmq4.SocketType{zmq4.Req, zmq4.Dealer},
+			Backend:  []zmq4.SocketType{zmq4.Rep},
+		})
+	}()
+
+	client := zmq4.NewReq(ctx)
+	defer client.Close()
+	if err := client.Dial("tcp://" + frontend.Addr().String()); err != nil {
+		t.Fatalf("could not dial frontend: %+v", err)
+	}
+
+	// a well-wired peer is accepted.
+	select {
+	case err := <-done:
+		t.Fatalf("proxy stopped: %+v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// a DEALER worker is compatible with the backend, but not expected.
+	worker := zmq4.NewDealer(ctx)
+	defer worker.Close()
+	if err := worker.Dial("tcp://" + backend.Addr().String()); err != nil {
+		t.Fatalf("could not dial backend: %+v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, zmq4.ErrProxyWiring) {
+			t.Fatalf("invalid error: got=%+v, want=%+v", err, zmq4.ErrProxyWiring)
+		}
+		if !strings.Contains(err.Error(), "backend peer") || !strings.Contains(err.Error(), "DEALER") {
+			t.Fatalf("error does not describe the wiring mistake: %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("proxy did not detect the invalid wiring")
+	}
+
+	// the proxy stopped forwarding to the misconfigured peer.
+	if err := client.Send(zmq4.NewMsgString("late")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	ready, err := zmq4.WaitReadable(worker, 200*time.Millisecond)
 	if err != nil {
-		t.Fatal("frontend.Listen:", err)
+		t.Fatalf("could not wait for the worker: %+v", err)
+	}
+	if ready {
+		msg, _ := worker.Recv()
+		t.Fatalf("message forwarded after the wiring error: %q", msg.Frames)
+	}
+}
+
+func TestProxyTransform(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	frontend := zmq4.NewPair(ctx)
+	defer frontend.Close()
+	backend := zmq4.NewPair(ctx)
+	defer backend.Close()
+
+	if err := frontend.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen on frontend: %+v", err)
 	}
+	if err := backend.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen on backend: %+v", err)
+	}
+
+	client := zmq4.NewPair(ctx)
+	defer client.Close()
+	if err := client.Dial("tcp://" + frontend.Addr().String()); err != nil {
+		t.Fatalf("could not dial frontend: %+v", err)
+	}
+	worker := zmq4.NewPair(ctx)
+	defer worker.Close()
+	if err := worker.Dial("tcp://" + backend.Addr().String()); err != nil {
+		t.Fatalf("could not dial backend: %+v", err)
+	}
+
+	go func() {
+		_ = zmq4.ProxyTransform(frontend, backend, func(dir zmq4.Direction, msg zmq4.Msg) (zmq4.Msg, bool) {
+			switch dir {
+			case zmq4.FrontendToBackend:
+				if string(msg.Frames[0]) == "drop" {
+					return msg, false
+				}
+				return zmq4.NewMsgString(strings.ToUpper(string(msg.Frames[0]))), true
+			default:
+				return zmq4.NewMsgFrom(append([][]byte{[]byte("header")}, msg.Frames...)...), true
+			}
+		})
+	}()
 
-	err = capture.Listen("tcp://127.0.0.1:0")
+	for _, s := range []string{"drop", "hello"} {
+		if err := client.Send(zmq4.NewMsgString(s)); err != nil {
+			t.Fatalf("could not send %q: %+v", s, err)
+		}
+	}
+	msg, err := worker.Recv()
 	if err != nil {
-		t.Fatal("capture.Listen:", err)
+		t.Fatalf("could not recv request: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "HELLO"; got != want {
+		t.Fatalf("invalid request: got=%q, want=%q", got, want)
 	}
 
-	// Connect backend to frontend
-	frontAddr := frontend.Addr()
-	err = backend.Dial(fmt.Sprintf("tcp://%s", frontAddr))
+	if err := worker.Send(zmq4.NewMsgString("world")); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	msg, err = client.Recv()
 	if err != nil {
-		t.Fatal("backend.Dial:", err)
+		t.Fatalf("could not recv reply: %+v", err)
+	}
+	if len(msg.Frames) != 2 || string(msg.Frames[0]) != "header" || string(msg.Frames[1]) != "world" {
+		t.Fatalf("invalid reply: %q", msg.Frames)
 	}
-	backend.SetOption(zmq4.OptionSubscribe, "")
+}
+
+func TestProxyTransformContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Create capture subscriber
-	captureSub := zmq4.NewSub(ctx)
-	defer captureSub.Close()
-	captureAddr := capture.Addr()
-	err = captureSub.Dial(fmt.Sprintf("tcp://%s", captureAddr))
+	frontend := zmq4.NewPair(ctx)
+	defer frontend.Close()
+	backend := zmq4.NewPair(ctx)
+	defer backend.Close()
+	for _, sck := range []zmq4.Socket{frontend, backend} {
+		if err := sck.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen on %v: %+v", sck.Type(), err)
+		}
+	}
+	client := zmq4.NewPair(ctx)
+	defer client.Close()
+	if err := client.Dial("tcp://" + frontend.Addr().String()); err != nil {
+		t.Fatalf("could not dial frontend: %+v", err)
+	}
+	worker := zmq4.NewPair(ctx)
+	defer worker.Close()
+	if err := worker.Dial("tcp://" + backend.Addr().String()); err != nil {
+		t.Fatalf("could not dial backend: %+v", err)
+	}
+
+	pctx, pcancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- zmq4.ProxyTransformContext(pctx, frontend, backend, func(dir zmq4.Direction, msg zmq4.Msg) (zmq4.Msg, bool) {
+			return zmq4.NewMsgString(dir.String() + ":" + string(msg.Frames[0])), true
+		})
+	}()
+
+	if err := client.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := worker.Recv()
 	if err != nil {
-		t.Fatal("captureSub.Dial:", err)
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "frontend->backend:ping"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+
+	pcancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("proxy did not return once its context was canceled")
+	}
+
+	// neither direction forwards messages once the proxy returned.
+	if err := client.Send(zmq4.NewMsgString("late")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if err := worker.Send(zmq4.NewMsgString("late")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	for _, sck := range []zmq4.Socket{worker, client} {
+		ready, err := zmq4.WaitReadable(sck, 100*time.Millisecond)
+		if err != nil {
+			t.Fatalf("could not wait: %+v", err)
+		}
+		if ready {
+			msg, _ := sck.Recv()
+			t.Fatalf("message forwarded after the proxy returned: %q", msg.Frames)
+		}
 	}
-	captureSub.SetOption(zmq4.OptionSubscribe, "")
+}
+
+func TestProxyContextCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	frontend := zmq4.NewPair(ctx)
+	defer frontend.Close()
+	backend := zmq4.NewPair(ctx)
+	defer backend.Close()
 
-	// Start proxy with capture
+	pctx, pcancel := context.WithCancel(ctx)
 	done := make(chan error, 1)
 	go func() {
-		// Simplified proxy doesn't support capture
-		err := zmq4.Proxy(frontend, backend)
-		done <- err
+		done <- zmq4.ProxyContext(pctx, frontend, backend)
 	}()
 
-	// Give proxy time to start
-	time.Sleep(100 * time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	pcancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("proxy did not return once its context was canceled")
+	}
 
-	// Send message
-	msg := zmq4.NewMsg([]byte("Test message"))
-	err = frontend.Send(msg)
-	if err != nil {
-		t.Fatal("frontend.Send:", err)
+	// the sockets are left intact.
+	if err := frontend.Context().Err(); err != nil {
+		t.Fatalf("frontend closed with the proxy: %+v", err)
 	}
+}
 
-	// Receive on backend
-	received, err := backend.Recv()
-	if err != nil {
-		t.Fatal("backend.Recv:", err)
+func TestProxySteerable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	frontend := zmq4.NewPair(ctx)
+	defer frontend.Close()
+	backend := zmq4.NewPair(ctx)
+	defer backend.Close()
+	control := zmq4.NewPair(ctx)
+	defer control.Close()
+
+	for _, sck := range []zmq4.Socket{frontend, backend, control} {
+		if err := sck.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen on %v: %+v", sck.Type(), err)
+		}
+	}
+
+	client := zmq4.NewPair(ctx)
+	defer client.Close()
+	worker := zmq4.NewPair(ctx)
+	defer worker.Close()
+	steer := zmq4.NewPair(ctx)
+	defer steer.Close()
+	for _, v := range []struct {
+		sck zmq4.Socket
+		ep  zmq4.Socket
+	}{{client, frontend}, {worker, backend}, {steer, control}} {
+		if err := v.sck.Dial("tcp://" + v.ep.Addr().String()); err != nil {
+			t.Fatalf("could not dial: %+v", err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- zmq4.ProxySteerable(frontend, backend, nil, control)
+	}()
+
+	command := func(cmd string) {
+		t.Helper()
+		if err := steer.Send(zmq4.NewMsgString(cmd)); err != nil {
+			t.Fatalf("could not send %s: %+v", cmd, err)
+		}
+		// let the proxy handle the command.
+		time.Sleep(50 * time.Millisecond)
+	}
+	send := func(s string) {
+		t.Helper()
+		if err := client.Send(zmq4.NewMsgString(s)); err != nil {
+			t.Fatalf("could not send %q: %+v", s, err)
+		}
+	}
+	recv := func(want string) {
+		t.Helper()
+		msg, err := worker.Recv()
+		if err != nil {
+			t.Fatalf("could not recv %q: %+v", want, err)
+		}
+		if got := string(msg.Frames[0]); got != want {
+			t.Fatalf("invalid message: got=%q, want=%q", got, want)
+		}
 	}
 
-	if string(received.Frames[0]) != "Test message" {
-		t.Errorf("Backend got %q, want %q", received.Frames[0], "Test message")
+	send("msg-1")
+	recv("msg-1")
+
+	command(zmq4.ProxyPause)
+	send("msg-2")
+	time.Sleep(100 * time.Millisecond)
+	if msg, err := worker.RecvNB(); err == nil {
+		t.Fatalf("paused proxy forwarded %q", msg.Frames)
 	}
 
-	// Should also receive on capture
+	command(zmq4.ProxyResume)
+	recv("msg-2")
+
+	command(zmq4.ProxyTerminate)
 	select {
-	case <-time.After(100 * time.Millisecond):
-		// Capture might not work in simplified implementation
-		t.Log("Capture not received (simplified implementation)")
-	default:
-		captured, err := captureSub.Recv()
-		if err == nil {
-			t.Logf("Captured: %s", captured.Frames[0])
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("proxy failed: %+v", err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("proxy did not terminate")
+	}
+}
+
+func TestProxyHandleClose(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// newPipeline returns a PULL frontend and a PUSH backend, with a PUSH
+	// client feeding the frontend and a PULL worker draining the backend.
+	newPipeline := func(t *testing.T) (frontend, backend, client, worker zmq4.Socket) {
+		t.Helper()
+		frontend = zmq4.NewPull(ctx)
+		backend = zmq4.NewPush(ctx)
+		client = zmq4.NewPush(ctx)
+		worker = zmq4.NewPull(ctx)
+		t.Cleanup(func() {
+			client.Close()
+			worker.Close()
+		})
+		if err := frontend.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen on frontend: %+v", err)
+		}
+		if err := backend.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen on backend: %+v", err)
+		}
+		if err := client.Dial("tcp://" + frontend.Addr().String()); err != nil {
+			t.Fatalf("could not dial frontend: %+v", err)
+		}
+		if err := worker.Dial("tcp://" + backend.Addr().String()); err != nil {
+			t.Fatalf("could not dial backend: %+v", err)
+		}
+		return frontend, backend, client, worker
+	}
+
+	wait := func(t *testing.T, h *zmq4.ProxyHandle) {
+		t.Helper()
+		select {
+		case <-h.Done():
+		case <-ctx.Done():
+			t.Fatalf("proxy did not stop")
+		}
+		if err := h.Wait(); err != nil {
+			t.Fatalf("proxy did not stop cleanly: %+v", err)
+		}
+	}
+
+	t.Run("close-handle", func(t *testing.T) {
+		frontend, backend, client, worker := newPipeline(t)
+		h := zmq4.StartProxy(ctx, frontend, backend, true)
+
+		if err := client.Send(zmq4.NewMsgString("hello")); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+		msg, err := worker.Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		if got, want := string(msg.Frames[0]), "hello"; got != want {
+			t.Fatalf("invalid message: got=%q, want=%q", got, want)
+		}
+
+		if err := h.Close(); err != nil {
+			t.Fatalf("could not close proxy: %+v", err)
+		}
+		wait(t, h)
+		for _, sck := range []zmq4.Socket{frontend, backend} {
+			select {
+			case <-sck.Done():
+			default:
+				t.Fatalf("%v socket not closed with the proxy", sck.Type())
+			}
+		}
+		if err := h.Close(); err != nil {
+			t.Fatalf("could not close proxy twice: %+v", err)
+		}
+	})
+
+	t.Run("close-socket", func(t *testing.T) {
+		frontend, backend, _, _ := newPipeline(t)
+		defer frontend.Close()
+		h := zmq4.StartProxy(ctx, frontend, backend, false)
+
+		if err := backend.Close(); err != nil {
+			t.Fatalf("could not close backend: %+v", err)
+		}
+		wait(t, h)
+		if err := h.Close(); err != nil {
+			t.Fatalf("could not close proxy: %+v", err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		frontend, backend, _, _ := newPipeline(t)
+		defer frontend.Close()
+		defer backend.Close()
+
+		pctx, pcancel := context.WithCancel(ctx)
+		h := zmq4.StartProxy(pctx, frontend, backend, false)
+		pcancel()
+		wait(t, h)
+	})
+}
+
+func TestProxySubscriptions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// PUB -> XSUB/XPUB proxy -> SUB, with a PUB connecting to the proxy
+	// before the subscription and another one after it.
+	xsub := zmq4.NewXSub(ctx)
+	xpub := zmq4.NewXPub(ctx)
+	if err := xpub.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen on backend: %+v", err)
+	}
+	h := zmq4.StartProxy(ctx, xsub, xpub, true)
+	defer h.Close()
+
+	newPub := func() zmq4.Socket {
+		pub := zmq4.NewPub(ctx)
+		t.Cleanup(func() { pub.Close() })
+		if err := pub.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen on pub: %+v", err)
+		}
+		if err := xsub.Dial("tcp://" + pub.Addr().String()); err != nil {
+			t.Fatalf("could not dial pub: %+v", err)
+		}
+		return pub
+	}
+	waitTopics := func(pub zmq4.Socket) {
+		t.Helper()
+		for {
+			topics := pub.(zmq4.Topics).Topics()
+			if len(topics) == 1 && topics[0] == "x" {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("subscription did not reach the publisher: topics=%q", topics)
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+
+	early := newPub()
+
+	sub := zmq4.NewSub(ctx)
+	defer sub.Close()
+	if err := sub.Dial("tcp://" + xpub.Addr().String()); err != nil {
+		t.Fatalf("could not dial proxy: %+v", err)
+	}
+	if err := sub.SetOption(zmq4.OptionSubscribe, "x"); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+	waitTopics(early)
+
+	late := newPub()
+	waitTopics(late)
+
+	for _, pub := range []zmq4.Socket{early, late} {
+		for _, topic := range []string{"y", "x"} {
+			if err := pub.Send(zmq4.NewMsgFrom([]byte(topic), []byte("data"))); err != nil {
+				t.Fatalf("could not send: %+v", err)
+			}
+		}
+		if err := pub.Flush(ctx); err != nil {
+			t.Fatalf("could not flush: %+v", err)
+		}
+		if got, want := pub.(zmq4.StatsReporter).Stats().MsgsSent, uint64(1); got != want {
+			t.Fatalf("publisher sent unsubscribed topics: got=%d messages, want=%d", got, want)
+		}
+
+		msg, err := sub.Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		if got, want := string(msg.Frames[0]), "x"; got != want {
+			t.Fatalf("invalid topic: got=%q, want=%q", got, want)
+		}
+	}
+}
+
+func TestProxyWithCapture(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	frontend := zmq4.NewPair(ctx)
+	defer frontend.Close()
+	backend := zmq4.NewPair(ctx)
+	defer backend.Close()
+	capture := zmq4.NewPub(ctx)
+	defer capture.Close()
+
+	for _, sck := range []zmq4.Socket{frontend, backend, capture} {
+		if err := sck.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen on %v: %+v", sck.Type(), err)
+		}
+	}
+
+	client := zmq4.NewPair(ctx)
+	defer client.Close()
+	if err := client.Dial("tcp://" + frontend.Addr().String()); err != nil {
+		t.Fatalf("could not dial frontend: %+v", err)
+	}
+	worker := zmq4.NewPair(ctx)
+	defer worker.Close()
+	if err := worker.Dial("tcp://" + backend.Addr().String()); err != nil {
+		t.Fatalf("could not dial backend: %+v", err)
+	}
+
+	spy := zmq4.NewSub(ctx)
+	defer spy.Close()
+	if err := spy.Dial("tcp://" + capture.Addr().String()); err != nil {
+		t.Fatalf("could not dial capture: %+v", err)
+	}
+	if err := spy.SetOption(zmq4.OptionSubscribe, ""); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+	// messages published before the subscription reached capture are lost.
+	for len(capture.(zmq4.Topics).Topics()) == 0 {
+		if ctx.Err() != nil {
+			t.Fatalf("capture did not receive the subscription")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		_ = zmq4.Proxy(frontend, backend, capture)
+	}()
+
+	if err := client.Send(zmq4.NewMsgString("request")); err != nil {
+		t.Fatalf("could not send request: %+v", err)
+	}
+	if msg, err := worker.Recv(); err != nil || string(msg.Frames[0]) != "request" {
+		t.Fatalf("could not recv request: msg=%q, err=%+v", msg.Frames, err)
+	}
+	if err := worker.Send(zmq4.NewMsgString("reply")); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	if msg, err := client.Recv(); err != nil || string(msg.Frames[0]) != "reply" {
+		t.Fatalf("could not recv reply: msg=%q, err=%+v", msg.Frames, err)
+	}
+
+	for _, want := range []string{"request", "reply"} {
+		msg, err := spy.Recv()
+		if err != nil {
+			t.Fatalf("could not recv captured %q: %+v", want, err)
+		}
+		if got := string(msg.Frames[0]); got != want {
+			t.Fatalf("invalid captured message: got=%q, want=%q", got, want)
 		}
 	}
 }
 
 func TestDevice(t *testing.T) {
-	t.Skip("Device removed - use Proxy instead")
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
 	// Create frontend and backend
 	frontend := zmq4.NewPull(ctx)
@@ -223,7 +726,16 @@ func TestDevice(t *testing.T) {
 		t.Fatal("consumer.Dial:", err)
 	}
 
-	// Test removed - Device no longer exists
+	// Sockets of the wrong type are rejected.
+	err = zmq4.Device(zmq4.DeviceForwarder, frontend, backend)
+	if !errors.Is(err, zmq4.ErrProxyWiring) || !strings.Contains(err.Error(), "FORWARDER") {
+		t.Fatalf("invalid error for mismatched sockets: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- zmq4.Device(zmq4.DeviceStreamer, frontend, backend)
+	}()
 
 	// Send and receive through device
 	for i := 0; i < 3; i++ {
@@ -243,4 +755,121 @@ func TestDevice(t *testing.T) {
 			t.Errorf("Got %q, want %q", received.Frames[0], expected)
 		}
 	}
+
+	frontend.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("device failed: %+v", err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("device did not stop once its frontend was closed")
+	}
+}
+
+func TestQueueDevice(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	frontend := zmq4.NewRouter(ctx)
+	defer frontend.Close()
+	backend := zmq4.NewRouter(ctx)
+	defer backend.Close()
+
+	if err := frontend.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen on frontend: %+v", err)
+	}
+	if err := backend.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen on backend: %+v", err)
+	}
+
+	dev, err := zmq4.NewQueueDevice(frontend, backend)
+	if err != nil {
+		t.Fatalf("could not create queue device: %+v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- dev.Run() }()
+
+	const (
+		nclients  = 4
+		nworkers  = 2
+		nrequests = 5
+	)
+
+	served := make(chan string, nclients*nrequests)
+	for i := 0; i < nworkers; i++ {
+		name := fmt.Sprintf("worker-%d", i)
+		worker := zmq4.NewReq(ctx)
+		defer worker.Close()
+		if err := worker.Dial("tcp://" + backend.Addr().String()); err != nil {
+			t.Fatalf("could not dial backend: %+v", err)
+		}
+		go func() {
+			msg := zmq4.NewMsgString("READY")
+			for {
+				if err := worker.Send(msg); err != nil {
+					return
+				}
+				req, err := worker.Recv()
+				if err != nil {
+					return
+				}
+				served <- name
+				time.Sleep(5 * time.Millisecond) // keep the worker busy.
+				msg = zmq4.NewMsgFrom([]byte(name), req.Frames[0])
+			}
+		}()
+	}
+
+	errc := make(chan error, nclients)
+	for i := 0; i < nclients; i++ {
+		client := zmq4.NewReq(ctx)
+		defer client.Close()
+		if err := client.Dial("tcp://" + frontend.Addr().String()); err != nil {
+			t.Fatalf("could not dial frontend: %+v", err)
+		}
+		go func(i int) {
+			for j := 0; j < nrequests; j++ {
+				req := fmt.Sprintf("client-%d-request-%d", i, j)
+				if err := client.Send(zmq4.NewMsgString(req)); err != nil {
+					errc <- fmt.Errorf("client %d could not send: %w", i, err)
+					return
+				}
+				rep, err := client.Recv()
+				if err != nil {
+					errc <- fmt.Errorf("client %d could not recv: %w", i, err)
+					return
+				}
+				if len(rep.Frames) != 2 || string(rep.Frames[1]) != req {
+					errc <- fmt.Errorf("client %d: invalid reply to %q: %q", i, req, rep.Frames)
+					return
+				}
+			}
+			errc <- nil
+		}(i)
+	}
+
+	for i := 0; i < nclients; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case err := <-done:
+			t.Fatalf("queue device stopped: %+v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout waiting for clients")
+		}
+	}
+
+	workers := make(map[string]int)
+	for i := 0; i < nclients*nrequests; i++ {
+		workers[<-served]++
+	}
+	if len(workers) != nworkers {
+		t.Fatalf("requests were not load-balanced across workers: %v", workers)
+	}
+	if n := dev.Busy(); n != 0 {
+		t.Fatalf("invalid number of busy workers: got=%d, want=0", n)
+	}
 }
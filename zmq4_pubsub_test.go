@@ -5,6 +5,7 @@
 package zmq4_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -531,6 +532,10 @@ func TestPubOptionHWM(t *testing.T) {
 	if err := pub.SetOption(zmq4.OptionHWM, hwm); err != nil {
 		t.Fatalf("unable to set HWM")
 	}
+	// the other socket options apply to PUB sockets as well.
+	if err := pub.SetOption(zmq4.OptionLinger, time.Second); err != nil {
+		t.Fatalf("could not set %s: %+v", zmq4.OptionLinger, err)
+	}
 
 	ep := must(EndPoint("tcp"))
 	cleanUp(ep)
@@ -610,6 +615,226 @@ func TestPubOptionHWM(t *testing.T) {
 	}
 }
 
+func TestPubFlush(t *testing.T) {
+	ep := must(EndPoint("inproc"))
+	defer cleanUp(ep)
+
+	pub := zmq4.NewPub(bkg)
+	defer pub.Close()
+	sub := zmq4.NewSub(bkg)
+	defer sub.Close()
+
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen on %q: %+v", ep, err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep, err)
+	}
+	if err := sub.SetOption(zmq4.OptionSubscribe, ""); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+	for len(pub.(zmq4.Topics).Topics()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the subscriber does not read anything yet: the inproc pipe and the
+	// subscriber's receive queue fill up and the publisher stalls.
+	const nmsgs = 64
+	for i := 0; i < nmsgs; i++ {
+		if err := pub.Send(zmq4.NewMsgString(fmt.Sprintf("msg-%d", i))); err != nil {
+			t.Fatalf("could not send message %d: %+v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(bkg, 100*time.Millisecond)
+	defer cancel()
+	if err := pub.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("flush on a stalled peer: got=%v, want=%v", err, context.DeadlineExceeded)
+	}
+
+	recvd := make(chan int)
+	go func() {
+		n := 0
+		for ; n < nmsgs; n++ {
+			if _, err := sub.Recv(); err != nil {
+				break
+			}
+		}
+		recvd <- n
+	}()
+
+	ctx, cancel = context.WithTimeout(bkg, 5*time.Second)
+	defer cancel()
+	if err := pub.Flush(ctx); err != nil {
+		t.Fatalf("could not flush: %+v", err)
+	}
+
+	select {
+	case n := <-recvd:
+		if n != nmsgs {
+			t.Fatalf("invalid number of messages: got=%d, want=%d", n, nmsgs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for flushed messages")
+	}
+}
+
+func TestSubDrainOnUnsubscribe(t *testing.T) {
+	ep := must(EndPoint("inproc"))
+	defer cleanUp(ep)
+
+	pub := zmq4.NewPub(bkg)
+	defer pub.Close()
+	sub := zmq4.NewSub(bkg, zmq4.WithDrainOnUnsubscribe(true))
+	defer sub.Close()
+
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen on %q: %+v", ep, err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep, err)
+	}
+	for _, topic := range []string{"a", "b"} {
+		if err := sub.SetOption(zmq4.OptionSubscribe, topic); err != nil {
+			t.Fatalf("could not subscribe to %q: %+v", topic, err)
+		}
+	}
+	for len(pub.(zmq4.Topics).Topics()) != 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	send := func(frames ...string) {
+		t.Helper()
+		for _, frame := range frames {
+			if err := pub.Send(zmq4.NewMsgString(frame)); err != nil {
+				t.Fatalf("could not send %q: %+v", frame, err)
+			}
+		}
+	}
+	recv := func(want string) {
+		t.Helper()
+		msg, err := sub.Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		if got := string(msg.Frames[0]); got != want {
+			t.Fatalf("invalid message: got=%q, want=%q", got, want)
+		}
+	}
+
+	send("a-0", "b-0")
+	recv("a-0")
+	recv("b-0")
+
+	// queue messages for both topics, then drop the "a" subscription
+	// before they are received.
+	send("a-1", "b-1", "a-2", "ab-1", "b-2")
+	if err := pub.Flush(context.Background()); err != nil {
+		t.Fatalf("could not flush: %+v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := sub.SetOption(zmq4.OptionUnsubscribe, "a"); err != nil {
+		t.Fatalf("could not unsubscribe: %+v", err)
+	}
+	for len(pub.(zmq4.Topics).Topics()) != 1 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	send("a-3", "b-3")
+
+	recv("b-1")
+	recv("b-2")
+	recv("b-3")
+}
+
+func TestSubRefCountedSubscriptions(t *testing.T) {
+	ep := must(EndPoint("inproc"))
+	defer cleanUp(ep)
+
+	pub := zmq4.NewPub(bkg)
+	defer pub.Close()
+	sub := zmq4.NewSub(bkg)
+	defer sub.Close()
+
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen on %q: %+v", ep, err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep, err)
+	}
+
+	// set changes the subscriptions of the SUB socket, and checks the
+	// number of subscription frames it sent to the PUB socket so far.
+	set := func(opt, topic string, frames uint64) {
+		t.Helper()
+		if err := sub.SetOption(opt, topic); err != nil {
+			t.Fatalf("could not set %s %q: %+v", opt, topic, err)
+		}
+		if got := sub.(zmq4.StatsReporter).Stats().MsgsSent; got != frames {
+			t.Fatalf("invalid number of subscription frames after %s %q: got=%d, want=%d", opt, topic, got, frames)
+		}
+	}
+	waitTopics := func(want ...string) {
+		t.Helper()
+		for {
+			got := pub.(zmq4.Topics).Topics()
+			if len(got) == len(want) && (len(want) == 0 || reflect.DeepEqual(got, want)) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	send := func(frames ...string) {
+		t.Helper()
+		for _, frame := range frames {
+			if err := pub.Send(zmq4.NewMsgString(frame)); err != nil {
+				t.Fatalf("could not send %q: %+v", frame, err)
+			}
+		}
+	}
+	recv := func(want string) {
+		t.Helper()
+		msg, err := sub.Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		if got := string(msg.Frames[0]); got != want {
+			t.Fatalf("invalid message: got=%q, want=%q", got, want)
+		}
+	}
+
+	set(zmq4.OptionSubscribe, "a", 1)
+	set(zmq4.OptionSubscribe, "ab", 2)
+	set(zmq4.OptionSubscribe, "ab", 2)
+	set(zmq4.OptionSubscribe, "a", 2)
+	if got, want := sub.(zmq4.Topics).Topics(), []string{"a", "ab"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid topics: got=%q, want=%q", got, want)
+	}
+	waitTopics("a", "ab")
+
+	// messages matching both overlapping prefixes are delivered once.
+	send("ab-0", "b-0", "a-0", "abc-0")
+	recv("ab-0")
+	recv("a-0")
+	recv("abc-0")
+
+	// "a" was subscribed to twice: it is still subscribed to.
+	set(zmq4.OptionUnsubscribe, "a", 2)
+	set(zmq4.OptionUnsubscribe, "a", 3)
+	set(zmq4.OptionUnsubscribe, "a", 3) // not subscribed to anymore
+	waitTopics("ab")
+
+	send("a-1", "ab-1")
+	recv("ab-1")
+
+	set(zmq4.OptionUnsubscribe, "ab", 3)
+	set(zmq4.OptionUnsubscribe, "ab", 4)
+	waitTopics()
+	if got := sub.(zmq4.Topics).Topics(); len(got) != 0 {
+		t.Fatalf("invalid topics: got=%q, want none", got)
+	}
+}
+
 func BenchmarkPubSub(b *testing.B) {
 	topic := "msg"
 	msgs := make([][]byte, 10)
@@ -718,3 +943,163 @@ func (p *pubSubSync) SubscriptionComplete() {
 func (p *pubSubSync) WaitForSubscriptions() {
 	p.wg2.Wait()
 }
+
+func TestPubSendBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	pub := zmq4.NewPub(ctx)
+	defer pub.Close()
+	sub := zmq4.NewSub(ctx)
+	defer sub.Close()
+
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen on %q: %+v", ep, err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep, err)
+	}
+	if err := sub.SetOption(zmq4.OptionSubscribe, "a"); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+	for len(pub.(zmq4.Topics).Topics()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	batch := []zmq4.TopicMsg{
+		{Topic: []byte("a.1"), Payload: []byte("first")},
+		{Topic: []byte("b.1"), Payload: []byte("filtered")},
+		{Topic: []byte("a.2"), Payload: []byte("")},
+		{Topic: []byte("a.3"), Payload: bytes.Repeat([]byte("x"), 1024)},
+	}
+	if err := pub.(zmq4.BatchSender).SendBatch(batch); err != nil {
+		t.Fatalf("could not send batch: %+v", err)
+	}
+	if err := pub.(zmq4.BatchSender).SendBatch(nil); err != nil {
+		t.Fatalf("could not send empty batch: %+v", err)
+	}
+
+	for _, want := range []zmq4.TopicMsg{batch[0], batch[2], batch[3]} {
+		msg, err := sub.Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		if len(msg.Frames) != 2 || !bytes.Equal(msg.Frames[0], want.Topic) || !bytes.Equal(msg.Frames[1], want.Payload) {
+			t.Fatalf("invalid message: got=%q, want=[%q %q]", msg.Frames, want.Topic, want.Payload)
+		}
+	}
+}
+
+func TestPubSubQueueStats(t *testing.T) {
+	ep := must(EndPoint("inproc"))
+	defer cleanUp(ep)
+
+	pub := zmq4.NewPub(bkg)
+	defer pub.Close()
+	sub := zmq4.NewSub(bkg)
+	defer sub.Close()
+
+	if hwm, err := pub.GetOption(zmq4.OptionHWM); err != nil || hwm != zmq4.DefaultSendHwm {
+		t.Fatalf("invalid HWM: got=%v, want=%v (err=%v)", hwm, zmq4.DefaultSendHwm, err)
+	}
+
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen on %q: %+v", ep, err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep, err)
+	}
+	if err := sub.SetOption(zmq4.OptionSubscribe, ""); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+	for len(pub.(zmq4.Topics).Topics()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the subscriber does not read anything: its receive queue fills up,
+	// and then the publisher's send queue.
+	const nmsgs = 64
+	for i := 0; i < nmsgs; i++ {
+		if err := pub.Send(zmq4.NewMsgString(fmt.Sprintf("msg-%d", i))); err != nil {
+			t.Fatalf("could not send msg #%d: %+v", i, err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var (
+			sent = pub.(zmq4.StatsReporter).Stats().SendQueued
+			recv = sub.(zmq4.StatsReporter).Stats().RecvQueued
+		)
+		if sent > 0 && recv > 0 {
+			if sent+recv > nmsgs {
+				t.Fatalf("too many queued messages: send=%d, recv=%d", sent, recv)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queues did not back up: send=%d, recv=%d", sent, recv)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for i := 0; i < nmsgs; i++ {
+		if _, err := sub.Recv(); err != nil {
+			t.Fatalf("could not recv msg #%d: %+v", i, err)
+		}
+	}
+	if got := pub.(zmq4.StatsReporter).Stats().SendQueued; got != 0 {
+		t.Fatalf("messages still queued after being received: %d", got)
+	}
+}
+
+func TestPubSubTopicMsg(t *testing.T) {
+	ep := must(EndPoint("inproc"))
+	defer cleanUp(ep)
+
+	pub := zmq4.NewPub(bkg)
+	defer pub.Close()
+	sub := zmq4.NewSub(bkg)
+	defer sub.Close()
+
+	if err := pub.Listen(ep); err != nil {
+		t.Fatalf("could not listen on %q: %+v", ep, err)
+	}
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial %q: %+v", ep, err)
+	}
+	if err := sub.SetOption(zmq4.OptionSubscribe, "news"); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+	for len(pub.(zmq4.Topics).Topics()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// only the topic frame is matched, not the payload.
+	for _, msg := range []zmq4.Msg{
+		zmq4.NewTopicMsg("weather", []byte("news: sunny")),
+		zmq4.NewTopicMsg("news", []byte("hello")),
+	} {
+		if err := pub.Send(msg); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+	}
+
+	msg, err := sub.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	topic, payload, ok := msg.TopicPayload()
+	if !ok {
+		t.Fatalf("invalid topic message: %q", msg.Frames)
+	}
+	if string(topic) != "news" || string(payload) != "hello" {
+		t.Fatalf("invalid topic message: got=%q/%q, want=%q/%q", topic, payload, "news", "hello")
+	}
+
+	if _, _, ok := zmq4.NewMsgString("news hello").TopicPayload(); ok {
+		t.Fatalf("single frame message split into topic and payload")
+	}
+}
@@ -5,11 +5,18 @@
 package zmq4_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/luxfi/zmq/v4"
 	"golang.org/x/sync/errgroup"
@@ -129,3 +136,462 @@ func TestPushPull(t *testing.T) {
 		})
 	}
 }
+
+func TestPushPullRecvDispatcher(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	pull := zmq4.NewPull(ctx, zmq4.WithRecvDispatcher(true), zmq4.WithRecvQueueSize(16))
+	defer pull.Close()
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	send := func(name string, n int) {
+		push := zmq4.NewPush(ctx)
+		t.Cleanup(func() { push.Close() })
+		if err := push.Dial(ep); err != nil {
+			t.Fatalf("could not dial %s: %+v", name, err)
+		}
+		for i := 0; i < n; i++ {
+			if err := push.Send(zmq4.NewMsgString(name)); err != nil {
+				t.Fatalf("could not send %s: %+v", name, err)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// the busy peer queues its messages before the quiet one connects.
+	send("busy", 20)
+	send("quiet", 4)
+
+	// the messages waiting in the queues of the connections are reported:
+	// at least the full queue of the busy peer, and the quiet messages.
+	if ok, err := zmq4.WaitReadable(pull, 0); err != nil || !ok {
+		t.Fatalf("dispatched socket not readable: ok=%v, err=%v", ok, err)
+	}
+	if got := pull.(zmq4.StatsReporter).Stats().RecvQueued; got < 16+4 {
+		t.Fatalf("invalid number of queued messages: got=%d, want at least %d", got, 16+4)
+	}
+
+	var got []string
+	for i := 0; i < 24; i++ {
+		msg, err := pull.Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		got = append(got, string(msg.Frames[0]))
+	}
+
+	quiet := 0
+	for _, name := range got[:10] {
+		if name == "quiet" {
+			quiet++
+		}
+	}
+	if quiet != 4 {
+		t.Fatalf("quiet peer starved by busy peer: %q", got)
+	}
+}
+
+func TestPushPullMessageTrace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	pull := zmq4.NewPull(ctx, zmq4.WithMessageTrace(true))
+	defer pull.Close()
+	push := zmq4.NewPush(ctx, zmq4.WithMessageTrace(true))
+	defer push.Close()
+
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	msg := zmq4.NewMsgFrom([]byte("hello"), []byte("world!"))
+	if err := push.Send(msg); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		sck zmq4.Socket
+		dir zmq4.TraceDirection
+	}{
+		{push, zmq4.TraceSend},
+		{pull, zmq4.TraceRecv},
+	} {
+		select {
+		case ev := <-tc.sck.(zmq4.MessageTracer).MessageTrace():
+			if ev.Dir != tc.dir || ev.Frames != 2 || ev.Size != 11 || ev.Time.IsZero() {
+				t.Fatalf("invalid %v trace event: %+v", tc.dir, ev)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no %v trace event", tc.dir)
+		}
+	}
+
+	// tracing is off by default.
+	quiet := zmq4.NewPull(ctx)
+	defer quiet.Close()
+	if c := quiet.(zmq4.MessageTracer).MessageTrace(); c != nil {
+		t.Fatalf("message trace enabled by default")
+	}
+}
+
+func TestPushPullStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	pull := zmq4.NewPull(ctx, zmq4.WithFrameSizeHistogram(true))
+	defer pull.Close()
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	msgs := []zmq4.Msg{
+		zmq4.NewMsg(make([]byte, 10)),
+		zmq4.NewMsgFrom(make([]byte, 100), make([]byte, 2000)),
+		zmq4.NewMsg(make([]byte, 2<<20)),
+	}
+	for _, msg := range msgs {
+		if err := push.Send(msg); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+		if _, err := pull.Recv(); err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+	}
+	const size = 10 + 100 + 2000 + 2<<20
+
+	stats := pull.(zmq4.StatsReporter).Stats()
+	if stats.MsgsRecv != 3 || stats.BytesRecv != size || stats.MsgsSent != 0 {
+		t.Fatalf("invalid pull stats: %+v", stats)
+	}
+	h := stats.FrameSizes
+	if h == nil {
+		t.Fatalf("no frame-size histogram")
+	}
+	if len(h.Counts) != len(h.Bounds)+1 {
+		t.Fatalf("invalid number of buckets: got=%d, want=%d", len(h.Counts), len(h.Bounds)+1)
+	}
+	want := make([]uint64, len(h.Counts))
+	want[0] = 1           // 10B
+	want[1] = 1           // 100B
+	want[3] = 1           // 2000B
+	want[len(want)-1] = 1 // 2MB
+	if !reflect.DeepEqual(h.Counts, want) {
+		t.Fatalf("invalid histogram:\ngot= %v\nwant=%v", h.Counts, want)
+	}
+	if h.Max != 2<<20 {
+		t.Fatalf("invalid max frame size: got=%d, want=%d", h.Max, 2<<20)
+	}
+
+	// messages are counted once written to the peer.
+	for {
+		stats = push.(zmq4.StatsReporter).Stats()
+		if stats.MsgsSent == 3 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("invalid push stats: %+v", stats)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if stats.BytesSent != size || stats.FrameSizes != nil {
+		t.Fatalf("invalid push stats: %+v", stats)
+	}
+}
+
+func TestPushPullRecvZeroCopy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, zeroCopy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("zero-copy=%v", zeroCopy), func(t *testing.T) {
+			ep := must(EndPoint("tcp"))
+
+			pull := zmq4.NewPull(ctx, zmq4.WithRecvZeroCopy(zeroCopy))
+			defer pull.Close()
+			push := zmq4.NewPush(ctx)
+			defer push.Close()
+
+			if err := pull.Listen(ep); err != nil {
+				t.Fatalf("could not listen: %+v", err)
+			}
+			if err := push.Dial(ep); err != nil {
+				t.Fatalf("could not dial: %+v", err)
+			}
+
+			recv := func(want string) zmq4.Msg {
+				t.Helper()
+				if err := push.Send(zmq4.NewMsgString(want)); err != nil {
+					t.Fatalf("could not send: %+v", err)
+				}
+				msg, err := pull.Recv()
+				if err != nil {
+					t.Fatalf("could not recv: %+v", err)
+				}
+				if got := string(msg.Frames[0]); got != want {
+					t.Fatalf("invalid message: got=%q, want=%q", got, want)
+				}
+				return msg
+			}
+
+			// received messages stay intact until they are released.
+			msgs := make([]zmq4.Msg, 10)
+			for i := range msgs {
+				msgs[i] = recv(fmt.Sprintf("msg-%d", i))
+			}
+			for i, msg := range msgs {
+				if got, want := string(msg.Frames[0]), fmt.Sprintf("msg-%d", i); got != want {
+					t.Fatalf("message modified by later receives: got=%q, want=%q", got, want)
+				}
+			}
+
+			// in zero-copy mode, the frames are not copied out of the read
+			// buffer: consecutive frames are laid out as on the wire, each
+			// after the 2 bytes header of the next one.
+			inPlace := true
+			for i := 1; i < len(msgs); i++ {
+				prev, next := msgs[i-1].Frames[0], msgs[i].Frames[0]
+				end := uintptr(unsafe.Pointer(unsafe.SliceData(prev))) + uintptr(len(prev))
+				if uintptr(unsafe.Pointer(unsafe.SliceData(next))) != end+2 {
+					inPlace = false
+				}
+			}
+			if inPlace != zeroCopy {
+				t.Fatalf("invalid frames layout: in-place=%v, want=%v", inPlace, zeroCopy)
+			}
+
+			for i := range msgs {
+				msgs[i].Release()
+				if zeroCopy && msgs[i].Frames != nil {
+					t.Fatalf("released message still references its frames")
+				}
+			}
+
+			// a message held while many more are received, reading past the
+			// end of its read buffer, keeps its content.
+			want := strings.Repeat("x", 4096)
+			keep := recv(want)
+			for i := 0; i < 100; i++ {
+				msg := recv(fmt.Sprintf("msg-%d-%s", i, want))
+				msg.Release()
+			}
+			if got := string(keep.Frames[0]); got != want {
+				t.Fatalf("held message modified by later receives")
+			}
+			keep.Release()
+		})
+	}
+}
+
+func TestPushPullAck(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const timeout = 100 * time.Millisecond
+
+	push := zmq4.NewPush(ctx, zmq4.WithPullAck(timeout))
+	defer push.Close()
+	if err := push.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep := "tcp://" + push.Addr().String()
+
+	waitConns := func(n int) {
+		t.Helper()
+		for len(push.(zmq4.ConnectionLister).Connections()) != n {
+			if ctx.Err() != nil {
+				t.Fatalf("timeout waiting for %d connections", n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	recv := func(pull zmq4.Socket, want string) zmq4.Msg {
+		t.Helper()
+		msg, err := pull.Recv()
+		if err != nil {
+			t.Fatalf("could not recv %q: %+v", want, err)
+		}
+		if got := string(msg.Frames[0]); len(msg.Frames) != 1 || got != want {
+			t.Fatalf("invalid message: got=%q, want=%q", msg.Frames, want)
+		}
+		return msg
+	}
+
+	crash := zmq4.NewPull(ctx, zmq4.WithPullAck(timeout))
+	defer crash.Close()
+	if err := crash.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	waitConns(1)
+
+	if err := push.Send(zmq4.NewMsgString("job-1")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	// the worker crashes before acknowledging its message.
+	recv(crash, "job-1")
+	if err := crash.Close(); err != nil {
+		t.Fatalf("could not close crashed worker: %+v", err)
+	}
+
+	worker := zmq4.NewPull(ctx, zmq4.WithPullAck(timeout))
+	defer worker.Close()
+	if err := worker.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	msg := recv(worker, "job-1")
+	if err := worker.(zmq4.Acker).Ack(msg); err != nil {
+		t.Fatalf("could not ack: %+v", err)
+	}
+
+	if err := push.Send(zmq4.NewMsgString("job-2")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg = recv(worker, "job-2")
+	if err := worker.(zmq4.Acker).Ack(msg); err != nil {
+		t.Fatalf("could not ack: %+v", err)
+	}
+
+	// acknowledged messages are not redelivered.
+	time.Sleep(3 * timeout)
+	if msg, err := worker.RecvNB(); err == nil {
+		t.Fatalf("acknowledged message redelivered: %q", msg.Frames)
+	}
+
+	// messages received without acknowledgement mode cannot be acknowledged.
+	plain := zmq4.NewPull(ctx)
+	defer plain.Close()
+	if err := plain.(zmq4.Acker).Ack(zmq4.NewMsgString("job")); err == nil {
+		t.Fatalf("acknowledged a message received without acknowledgement mode")
+	}
+}
+
+func TestPushPullAckHWM(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	push := zmq4.NewPush(ctx, zmq4.WithPullAck(time.Minute), zmq4.WithSendHWM(2))
+	defer push.Close()
+	pull := zmq4.NewPull(ctx, zmq4.WithPullAck(time.Minute))
+	defer pull.Close()
+
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := push.Send(zmq4.NewMsgString(fmt.Sprintf("job-%d", i))); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+	}
+
+	// the unacknowledged messages reached the high water mark.
+	if err := push.SendNB(zmq4.NewMsgString("job-2")); !errors.Is(err, zmq4.ErrWouldBlock) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrWouldBlock)
+	}
+	if err := push.SetSendDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("could not set send deadline: %+v", err)
+	}
+	if err := push.Send(zmq4.NewMsgString("job-2")); err == nil {
+		t.Fatalf("send beyond the high water mark did not block")
+	}
+	if err := push.SetSendDeadline(time.Time{}); err != nil {
+		t.Fatalf("could not clear send deadline: %+v", err)
+	}
+
+	// an acknowledgement makes room for the next message.
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if err := pull.(zmq4.Acker).Ack(msg); err != nil {
+		t.Fatalf("could not ack: %+v", err)
+	}
+	if err := push.Send(zmq4.NewMsgString("job-2")); err != nil {
+		t.Fatalf("could not send after ack: %+v", err)
+	}
+
+	// the high water mark defaults to DefaultSendHwm.
+	dflt := zmq4.NewPush(ctx, zmq4.WithPullAck(time.Minute))
+	defer dflt.Close()
+	if hwm, err := zmq4.Options(dflt).HWM(); err != nil || hwm != zmq4.DefaultSendHwm {
+		t.Fatalf("invalid default HWM: got=(%d, %v), want=(%d, nil)", hwm, err, zmq4.DefaultSendHwm)
+	}
+}
+
+func TestPushPullStream(t *testing.T) {
+	ctx, timeout := context.WithTimeout(context.Background(), 20*time.Second)
+	defer timeout()
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	const size = 5 << 20
+	for _, tc := range []struct {
+		name string
+		size int64
+	}{
+		{"sized", size},
+		{"until-eof", -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src := io.LimitReader(rand.New(rand.NewSource(42)), size)
+			want := sha256.New()
+			if err := zmq4.SendReader(push, io.TeeReader(src, want), tc.size); err != nil {
+				t.Fatalf("could not send reader: %+v", err)
+			}
+
+			got := sha256.New()
+			n, err := zmq4.RecvWriter(pull, got)
+			if err != nil {
+				t.Fatalf("could not recv writer: %+v", err)
+			}
+			if n != size {
+				t.Fatalf("invalid number of bytes: got=%d, want=%d", n, size)
+			}
+			if !bytes.Equal(got.Sum(nil), want.Sum(nil)) {
+				t.Fatalf("invalid checksum: got=%x, want=%x", got.Sum(nil), want.Sum(nil))
+			}
+		})
+	}
+
+	if err := zmq4.SendReader(push, strings.NewReader("short"), 10); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("invalid error sending a short reader: got=%v, want=%v", err, io.ErrUnexpectedEOF)
+	}
+}
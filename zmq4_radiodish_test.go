@@ -0,0 +1,122 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/luxfi/zmq/v4"
+)
+
+func TestRadioDish(t *testing.T) {
+	for _, transport := range []string{"inproc", "tcp"} {
+		t.Run(transport, func(t *testing.T) {
+			ep := must(EndPoint(transport))
+			defer cleanUp(ep)
+
+			radio := zmq4.NewRadio(bkg)
+			defer radio.Close()
+			dish := zmq4.NewDish(bkg)
+			defer dish.Close()
+
+			if err := radio.Listen(ep); err != nil {
+				t.Fatalf("could not listen on %q: %+v", ep, err)
+			}
+			if err := dish.Dial(ep); err != nil {
+				t.Fatalf("could not dial %q: %+v", ep, err)
+			}
+
+			for _, group := range []string{"", strings.Repeat("g", 256)} {
+				if err := dish.SetOption(zmq4.OptionJoin, group); !errors.Is(err, zmq4.ErrBadProperty) {
+					t.Fatalf("invalid error joining a group of %d bytes: got=%v, want=%v", len(group), err, zmq4.ErrBadProperty)
+				}
+			}
+			for _, group := range []string{"grp", "sync"} {
+				if err := dish.SetOption(zmq4.OptionJoin, group); err != nil {
+					t.Fatalf("could not join %q: %+v", group, err)
+				}
+			}
+
+			// wait for the radio to know about the joined groups.
+			for {
+				if err := radio.Send(zmq4.Msg{Frames: [][]byte{[]byte("ping")}, Group: "sync"}); err != nil {
+					t.Fatalf("could not send: %+v", err)
+				}
+				msg, err := dish.RecvNB()
+				if err == nil && msg.Group == "sync" {
+					break
+				}
+				if err != nil && !errors.Is(err, zmq4.ErrWouldBlock) {
+					t.Fatalf("could not recv: %+v", err)
+				}
+			}
+			if err := dish.SetOption(zmq4.OptionLeave, "sync"); err != nil {
+				t.Fatalf("could not leave group: %+v", err)
+			}
+
+			payload := []byte{0x00, 0x01, 0x02}
+			for _, msg := range []zmq4.Msg{
+				{Frames: [][]byte{[]byte("other")}, Group: "grp-other"},
+				{Frames: [][]byte{[]byte("left")}, Group: "sync"},
+				{Frames: [][]byte{payload}, Group: "grp"},
+				{Frames: [][]byte{[]byte("end")}, Group: "grp"},
+			} {
+				if err := radio.Send(msg); err != nil {
+					t.Fatalf("could not send %v: %+v", msg, err)
+				}
+			}
+
+			for _, want := range [][]byte{payload, []byte("end")} {
+				msg, err := dish.Recv()
+				if err != nil {
+					t.Fatalf("could not recv: %+v", err)
+				}
+				if msg.Group != "grp" || !reflect.DeepEqual(msg.Frames, [][]byte{want}) {
+					t.Fatalf("invalid message: got=%q (group=%q), want=%q (group=%q)", msg.Frames, msg.Group, want, "grp")
+				}
+			}
+		})
+	}
+}
+
+func TestRadioDishInvalid(t *testing.T) {
+	radio := zmq4.NewRadio(bkg)
+	defer radio.Close()
+	dish := zmq4.NewDish(bkg)
+	defer dish.Close()
+	sub := zmq4.NewSub(bkg)
+	defer sub.Close()
+
+	for _, tc := range []struct {
+		name string
+		msg  zmq4.Msg
+		want error
+	}{
+		{"no-group", zmq4.NewMsgString("data"), zmq4.ErrInvalidGroup},
+		{"long-group", zmq4.Msg{Frames: [][]byte{[]byte("data")}, Group: strings.Repeat("g", 256)}, zmq4.ErrInvalidGroup},
+		{"multipart", zmq4.Msg{Frames: [][]byte{[]byte("a"), []byte("b")}, Group: "grp"}, zmq4.ErrTooManyFrames},
+	} {
+		if err := radio.Send(tc.msg); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: invalid error: got=%v, want=%v", tc.name, err, tc.want)
+		}
+	}
+
+	if _, err := radio.Recv(); !errors.Is(err, zmq4.ErrInvalidOperation) {
+		t.Fatalf("invalid RADIO recv error: got=%v, want=%v", err, zmq4.ErrInvalidOperation)
+	}
+	if err := dish.Send(zmq4.Msg{Frames: [][]byte{[]byte("data")}, Group: "grp"}); !errors.Is(err, zmq4.ErrInvalidOperation) {
+		t.Fatalf("invalid DISH send error: got=%v, want=%v", err, zmq4.ErrInvalidOperation)
+	}
+
+	// groups are only joined by DISH sockets.
+	for _, name := range []string{zmq4.OptionJoin, zmq4.OptionLeave} {
+		if err := sub.SetOption(name, "grp"); !errors.Is(err, zmq4.ErrBadProperty) {
+			t.Fatalf("invalid SUB %s error: got=%v, want=%v", name, err, zmq4.ErrBadProperty)
+		}
+	}
+}
@@ -0,0 +1,658 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+	"golang.org/x/sync/errgroup"
+)
+
+// newPushPull returns a connected PUSH/PULL pair of sockets.
+func newPushPull(t *testing.T, ctx context.Context) (push, pull zmq4.Socket) {
+	t.Helper()
+	ep := must(EndPoint("tcp"))
+
+	pull = zmq4.NewPull(ctx)
+	t.Cleanup(func() { pull.Close() })
+	if err := pull.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	push = zmq4.NewPush(ctx)
+	t.Cleanup(func() { push.Close() })
+	if err := push.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	return push, pull
+}
+
+func TestPoller(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	push, pull := newPushPull(t, ctx)
+
+	poller := zmq4.NewPoller()
+	poller.Add(pull, zmq4.Readable)
+
+	ready, err := poller.Poll(0)
+	if err != nil {
+		t.Fatalf("could not poll: %+v", err)
+	}
+	if len(ready) != 0 {
+		t.Fatalf("idle socket reported ready: %v", ready)
+	}
+
+	ready, err = poller.Poll(50 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("could not poll: %+v", err)
+	}
+	if len(ready) != 0 {
+		t.Fatalf("idle socket reported ready: %v", ready)
+	}
+
+	if err := push.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	ready, err = poller.Poll(-1)
+	if err != nil {
+		t.Fatalf("could not poll: %+v", err)
+	}
+	if len(ready) != 1 || ready[0].Socket != pull || ready[0].Events != zmq4.Readable {
+		t.Fatalf("invalid poll results: %v", ready)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+
+	all, err := poller.PollAll(0)
+	if err != nil {
+		t.Fatalf("could not poll: %+v", err)
+	}
+	if len(all) != 1 || all[0].Events != 0 {
+		t.Fatalf("invalid poll results: %v", all)
+	}
+
+	if err := poller.Remove(1); err == nil {
+		t.Fatalf("expected an error removing an invalid index")
+	}
+	if err := poller.Remove(0); err != nil {
+		t.Fatalf("could not remove socket: %+v", err)
+	}
+}
+
+func TestPollerReq(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	rep := zmq4.NewRep(ctx)
+	defer rep.Close()
+	if err := rep.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	req := zmq4.NewReq(ctx)
+	defer req.Close()
+	if err := req.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	poller := zmq4.NewPoller()
+	poller.Add(req, zmq4.Readable)
+
+	if err := req.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send request: %+v", err)
+	}
+	if ready, err := poller.Poll(20 * time.Millisecond); err != nil || len(ready) != 0 {
+		t.Fatalf("REQ socket ready before the reply: ready=%v, err=%v", ready, err)
+	}
+
+	if _, err := rep.Recv(); err != nil {
+		t.Fatalf("could not recv request: %+v", err)
+	}
+	if err := rep.Send(zmq4.NewMsgString("pong")); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	ready, err := poller.Poll(-1)
+	if err != nil {
+		t.Fatalf("could not poll: %+v", err)
+	}
+	if len(ready) != 1 || ready[0].Events != zmq4.Readable {
+		t.Fatalf("invalid poll results: %v", ready)
+	}
+	msg, err := req.Recv()
+	if err != nil {
+		t.Fatalf("could not recv reply: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "pong"; got != want {
+		t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+	}
+	if ready, err := poller.Poll(0); err != nil || len(ready) != 0 {
+		t.Fatalf("REQ socket ready after reading the reply: ready=%v, err=%v", ready, err)
+	}
+}
+
+func TestWaitReadable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	push, pull := newPushPull(t, ctx)
+
+	ok, err := zmq4.WaitReadable(pull, 20*time.Millisecond)
+	if err != nil || ok {
+		t.Fatalf("idle socket reported readable: ok=%v, err=%v", ok, err)
+	}
+
+	if err := push.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	ok, err = zmq4.WaitReadable(pull, -1)
+	if err != nil || !ok {
+		t.Fatalf("socket not readable: ok=%v, err=%v", ok, err)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+
+	// closing the socket interrupts the wait.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		pull.Close()
+	}()
+	if _, err := zmq4.WaitReadable(pull, -1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("invalid error: got=%+v, want=%+v", err, context.Canceled)
+	}
+}
+
+func TestPollerClose(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, pull := newPushPull(t, ctx)
+
+	poller := zmq4.NewPoller()
+	defer poller.Close()
+	poller.Add(pull, zmq4.Readable)
+
+	// closing a polled socket interrupts the wait, as for WaitReadable.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		pull.Close()
+	}()
+	errc := make(chan error, 1)
+	go func() {
+		_, err := poller.Poll(-1)
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("invalid error: got=%+v, want=%+v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("poll not interrupted by the closing of the socket")
+	}
+}
+
+func TestReactorRunContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	push, pull := newPushPull(t, ctx)
+
+	got := make(chan string)
+	reactor := zmq4.NewReactor()
+	reactor.AddSocket(pull, zmq4.Readable, func(zmq4.State) {
+		msg, err := pull.Recv()
+		if err != nil {
+			return
+		}
+		got <- string(msg.Frames[0])
+	})
+
+	rctx, rcancel := context.WithCancel(ctx)
+	var grp errgroup.Group
+	grp.Go(func() error { return reactor.RunContext(rctx) })
+
+	if err := push.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	select {
+	case msg := <-got:
+		if msg != "ping" {
+			t.Fatalf("invalid message: got=%q, want=%q", msg, "ping")
+		}
+	case <-ctx.Done():
+		t.Fatalf("handler not called")
+	}
+
+	rcancel()
+	if err := grp.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+	}
+
+	// Stop also ends RunContext, without error.
+	grp = errgroup.Group{}
+	grp.Go(func() error { return reactor.RunContext(ctx) })
+	reactor.Stop()
+	reactor.Stop() // no-op
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("could not stop reactor: %+v", err)
+	}
+}
+
+func TestReactorReplace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	const n = 200
+	push1, pull1 := newPushPull(t, ctx)
+	push2, pull2 := newPushPull(t, ctx)
+
+	var (
+		reactor = zmq4.NewReactor()
+		got1    = make(chan string, 2*n)
+		got2    = make(chan string, 2*n)
+	)
+	recv := func(sck zmq4.Socket, got chan string) func(zmq4.State) {
+		return func(zmq4.State) {
+			msg, err := sck.Recv()
+			if err != nil {
+				return
+			}
+			got <- string(msg.Frames[0])
+		}
+	}
+	reactor.AddSocket(pull1, zmq4.Readable, recv(pull1, got1))
+
+	if err := reactor.Replace(pull2, pull1, zmq4.Readable, nil); err == nil {
+		t.Fatalf("expected an error replacing an unregistered socket")
+	}
+
+	var grp errgroup.Group
+	grp.Go(func() error { return reactor.RunContext(ctx) })
+
+	// both peers keep sending while the sockets are swapped.
+	var senders errgroup.Group
+	for _, push := range []zmq4.Socket{push1, push2} {
+		senders.Go(func() error {
+			for i := 0; i < n; i++ {
+				if err := push.Send(zmq4.NewMsgString("msg")); err != nil {
+					return err
+				}
+				time.Sleep(100 * time.Microsecond)
+			}
+			return nil
+		})
+	}
+
+	// wait for the first socket to be served before swapping it out.
+	select {
+	case <-got1:
+	case <-ctx.Done():
+		t.Fatalf("first socket not served")
+	}
+	if err := reactor.Replace(pull1, pull2, zmq4.Readable, recv(pull2, got2)); err != nil {
+		t.Fatalf("could not replace socket: %+v", err)
+	}
+
+	if err := senders.Wait(); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+
+	// every message of the replacement socket is dispatched.
+	for i := 0; i < n; i++ {
+		select {
+		case <-got2:
+		case <-ctx.Done():
+			t.Fatalf("replacement socket served %d/%d messages", i, n)
+		}
+	}
+
+	reactor.Stop()
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("could not run reactor: %+v", err)
+	}
+}
+
+func TestReactorWorkers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	const (
+		nsocks = 4
+		n      = 50
+	)
+
+	var (
+		reactor = zmq4.NewReactor(zmq4.WithReactorWorkers(nsocks))
+		active  atomic.Int32 // handlers being called, over all sockets
+		maxed   atomic.Int32 // maximum of active
+		got     = make([]chan int, nsocks)
+		pushes  = make([]zmq4.Socket, nsocks)
+	)
+	for i := range nsocks {
+		push, pull := newPushPull(t, ctx)
+		pushes[i] = push
+		got[i] = make(chan int, n)
+
+		var calls atomic.Int32 // handlers of pull being called
+		reactor.AddSocket(pull, zmq4.Readable, func(zmq4.State) {
+			if calls.Add(1) != 1 {
+				t.Errorf("socket %d: concurrent handler calls", i)
+			}
+			defer calls.Add(-1)
+			cur := active.Add(1)
+			defer active.Add(-1)
+			for {
+				prev := maxed.Load()
+				if cur <= prev || maxed.CompareAndSwap(prev, cur) {
+					break
+				}
+			}
+
+			msg, err := pull.Recv()
+			if err != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+			seq, err := strconv.Atoi(string(msg.Frames[0]))
+			if err != nil {
+				t.Errorf("socket %d: invalid message %q", i, msg.Frames[0])
+				return
+			}
+			got[i] <- seq
+		})
+	}
+
+	var grp errgroup.Group
+	grp.Go(func() error { return reactor.RunContext(ctx) })
+
+	var senders errgroup.Group
+	for _, push := range pushes {
+		senders.Go(func() error {
+			for seq := range n {
+				if err := push.Send(zmq4.NewMsgString(strconv.Itoa(seq))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := senders.Wait(); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+
+	for i := range nsocks {
+		for want := range n {
+			select {
+			case seq := <-got[i]:
+				if seq != want {
+					t.Fatalf("socket %d: invalid order: got=%d, want=%d", i, seq, want)
+				}
+			case <-ctx.Done():
+				t.Fatalf("socket %d: served %d/%d messages", i, want, n)
+			}
+		}
+	}
+
+	reactor.Stop()
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("could not run reactor: %+v", err)
+	}
+	if got := maxed.Load(); got < 2 {
+		t.Fatalf("handlers were not called concurrently: max=%d", got)
+	}
+}
+
+func TestReactorTimer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const ivl = 10 * time.Millisecond
+	var (
+		reactor = zmq4.NewReactor()
+		shots   atomic.Int32
+		ticks   atomic.Int32
+		done    = make(chan struct{})
+	)
+	reactor.AddTimer(ivl, 3, func() {
+		if shots.Add(1) == 3 {
+			close(done)
+		}
+	})
+	forever := reactor.AddTimer(ivl, 0, func() { ticks.Add(1) })
+	removed := reactor.AddTimer(ivl, 0, func() { t.Errorf("removed timer fired") })
+	reactor.RemoveTimer(removed)
+
+	var grp errgroup.Group
+	grp.Go(func() error { return reactor.RunContext(ctx) })
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatalf("timer fired %d/3 times", shots.Load())
+	}
+	// the timer is gone after its last shot, while the other one repeats.
+	before := ticks.Load()
+	time.Sleep(10 * ivl)
+	if got := shots.Load(); got != 3 {
+		t.Fatalf("invalid number of shots: got=%d, want=3", got)
+	}
+	if ticks.Load() <= before {
+		t.Fatalf("repeating timer stopped firing")
+	}
+
+	for _, tc := range []struct {
+		interval time.Duration
+		times    int
+	}{
+		{0, 1},
+		{-ivl, 1},
+		{ivl, -1},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("no panic for interval=%v, times=%d", tc.interval, tc.times)
+				}
+			}()
+			reactor.AddTimer(tc.interval, tc.times, func() {})
+		}()
+	}
+
+	reactor.RemoveTimer(forever)
+	time.Sleep(2 * ivl) // let a firing in progress complete
+	before = ticks.Load()
+	time.Sleep(5 * ivl)
+	if got := ticks.Load(); got != before {
+		t.Fatalf("removed timer fired %d more times", got-before)
+	}
+
+	reactor.Stop()
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("could not run reactor: %+v", err)
+	}
+}
+
+func TestReactorChannel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	push, pull := newPushPull(t, ctx)
+
+	var (
+		reactor = zmq4.NewReactor()
+		events  = make(chan interface{})
+		got     = make(chan string, 10)
+		loop    int // handlers run sequentially in the reactor loop
+	)
+	reactor.AddSocket(pull, zmq4.Readable, func(zmq4.State) {
+		msg, err := pull.Recv()
+		if err != nil {
+			return
+		}
+		loop++
+		got <- "socket:" + string(msg.Frames[0])
+	})
+	reactor.AddChannel(events, func(v interface{}) {
+		loop++
+		got <- "channel:" + v.(string)
+	})
+
+	var grp errgroup.Group
+	grp.Go(func() error { return reactor.RunContext(ctx) })
+
+	if err := push.Send(zmq4.NewMsgString("msg")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	for _, v := range []string{"a", "b", "c"} {
+		select {
+		case events <- v:
+		case <-ctx.Done():
+			t.Fatalf("channel value %q not received", v)
+		}
+	}
+	want := map[string]bool{"socket:msg": true, "channel:a": true, "channel:b": true, "channel:c": true}
+	for len(want) > 0 {
+		select {
+		case v := <-got:
+			if !want[v] {
+				t.Fatalf("unexpected event %q", v)
+			}
+			delete(want, v)
+		case <-ctx.Done():
+			t.Fatalf("events not dispatched: %v", want)
+		}
+	}
+
+	// values sent to an idle reactor are dispatched as they arrive, not
+	// when the reactor next polls its sockets.
+	const latency = 50 * time.Millisecond
+	for _, v := range []string{"d", "e", "f"} {
+		time.Sleep(20 * time.Millisecond)
+		start := time.Now()
+		events <- v
+		select {
+		case got := <-got:
+			if got != "channel:"+v {
+				t.Fatalf("unexpected event %q", got)
+			}
+			if elapsed := time.Since(start); elapsed > latency {
+				t.Fatalf("channel value %q dispatched after %v", v, elapsed)
+			}
+		case <-time.After(latency):
+			t.Fatalf("channel value %q not dispatched after %v", v, latency)
+		}
+	}
+
+	// a removed channel is no longer received from.
+	reactor.RemoveChannel(events)
+	select {
+	case events <- "removed":
+		t.Fatalf("removed channel received from")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// a closed channel is unregistered, and its handler not called.
+	closed := make(chan interface{})
+	reactor.AddChannel(closed, func(v interface{}) {
+		t.Errorf("handler of closed channel called with %v", v)
+	})
+	close(closed)
+	time.Sleep(50 * time.Millisecond)
+
+	reactor.Stop()
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("could not run reactor: %+v", err)
+	}
+	if loop != 7 {
+		t.Fatalf("invalid number of handler calls: got=%d, want=7", loop)
+	}
+}
+
+func TestReactorHandlerError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []zmq4.ReactorOption
+	}{
+		{"loop", nil},
+		{"workers", []zmq4.ReactorOption{zmq4.WithReactorWorkers(2)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			push, pull := newPushPull(t, ctx)
+
+			var (
+				reactor = zmq4.NewReactor(tc.opts...)
+				errBoom = errors.New("boom")
+				calls   atomic.Int32
+			)
+			reactor.AddSocketErr(pull, zmq4.Readable, func(zmq4.State) error {
+				calls.Add(1)
+				if _, err := pull.Recv(); err != nil {
+					return err
+				}
+				return errBoom
+			})
+
+			var grp errgroup.Group
+			grp.Go(func() error { return reactor.RunContext(ctx) })
+
+			for range 2 {
+				if err := push.Send(zmq4.NewMsgString("msg")); err != nil {
+					t.Fatalf("could not send: %+v", err)
+				}
+			}
+			if err := grp.Wait(); !errors.Is(err, errBoom) {
+				t.Fatalf("invalid error: got=%v, want=%v", err, errBoom)
+			}
+			if got := calls.Load(); got != 1 {
+				t.Fatalf("handler called %d times after failing", got)
+			}
+		})
+	}
+}
+
+func TestReactorStop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, pull := newPushPull(t, ctx)
+
+	reactor := zmq4.NewReactor()
+	reactor.AddSocket(pull, zmq4.Readable, func(zmq4.State) {})
+
+	var grp errgroup.Group
+	grp.Go(func() error { return reactor.RunContext(ctx) })
+
+	// concurrent and repeated calls to Stop do not panic.
+	var stoppers errgroup.Group
+	for range 4 {
+		stoppers.Go(func() error {
+			reactor.Stop()
+			reactor.Stop()
+			return nil
+		})
+	}
+	_ = stoppers.Wait()
+	if err := grp.Wait(); err != nil {
+		t.Fatalf("could not stop reactor: %+v", err)
+	}
+
+	// a stopped reactor returns right away.
+	if err := reactor.RunContext(ctx); err != nil {
+		t.Fatalf("could not run stopped reactor: %+v", err)
+	}
+	reactor.Stop()
+}
@@ -6,6 +6,7 @@ package zmq4_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -327,3 +328,164 @@ func TestMultiReqRepIssue70(t *testing.T) {
 		})
 	}
 }
+
+func TestReqRepRawEnvelope(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		req     zmq4.Socket
+		rep     zmq4.Socket
+		request zmq4.Msg // as sent by REQ
+		recvd   zmq4.Msg // as received by REP
+		reply   zmq4.Msg // as sent by REP
+		answer  zmq4.Msg // as received by REQ
+	}{
+		{
+			name:    "raw-rep",
+			req:     zmq4.NewReq(bkg),
+			rep:     zmq4.NewRep(bkg, zmq4.WithRawEnvelope(true)),
+			request: zmq4.NewMsgString("ping"),
+			recvd:   zmq4.NewMsgFrom([]byte{}, []byte("ping")),
+			reply:   zmq4.NewMsgFrom(nil, []byte("pong")),
+			answer:  zmq4.NewMsgString("pong"),
+		},
+		{
+			name:    "raw-req",
+			req:     zmq4.NewReq(bkg, zmq4.WithRawEnvelope(true)),
+			rep:     zmq4.NewRep(bkg),
+			request: zmq4.NewMsgFrom(nil, []byte("ping")),
+			recvd:   zmq4.NewMsgString("ping"),
+			reply:   zmq4.NewMsgString("pong"),
+			answer:  zmq4.NewMsgFrom([]byte{}, []byte("pong")),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer tc.req.Close()
+			defer tc.rep.Close()
+
+			ep := must(EndPoint("inproc"))
+			defer cleanUp(ep)
+
+			if err := tc.rep.Listen(ep); err != nil {
+				t.Fatalf("could not listen on %q: %+v", ep, err)
+			}
+			if err := tc.req.Dial(ep); err != nil {
+				t.Fatalf("could not dial %q: %+v", ep, err)
+			}
+
+			if err := tc.req.Send(tc.request); err != nil {
+				t.Fatalf("could not send request: %+v", err)
+			}
+			msg, err := tc.rep.Recv()
+			if err != nil {
+				t.Fatalf("could not recv request: %+v", err)
+			}
+			if got, want := msg.Frames, tc.recvd.Frames; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid request: got=%q, want=%q", got, want)
+			}
+
+			if err := tc.rep.Send(tc.reply); err != nil {
+				t.Fatalf("could not send reply: %+v", err)
+			}
+			msg, err = tc.req.Recv()
+			if err != nil {
+				t.Fatalf("could not recv reply: %+v", err)
+			}
+			if got, want := msg.Frames, tc.answer.Frames; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+			}
+		})
+	}
+}
+
+// reqRepPair returns a REQ socket connected to a REP socket, over tcp.
+func reqRepPair(t *testing.T, ctx context.Context, opts ...string) (req, rep zmq4.Socket) {
+	t.Helper()
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	rep = zmq4.NewRep(ctx)
+	t.Cleanup(func() { rep.Close() })
+	req = zmq4.NewReq(ctx)
+	t.Cleanup(func() { req.Close() })
+	for _, opt := range opts {
+		if err := req.SetOption(opt, true); err != nil {
+			t.Fatalf("could not set %s: %+v", opt, err)
+		}
+	}
+
+	if err := rep.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := req.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	return req, rep
+}
+
+func TestReqRepFSM(t *testing.T) {
+	ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeout()
+
+	req, rep := reqRepPair(t, ctx)
+
+	for _, step := range []struct {
+		name string
+		op   func() error
+		fsm  bool // whether the operation fails with ErrFSM
+	}{
+		{"req-recv-before-send", func() error { _, err := req.Recv(); return err }, true},
+		{"rep-send-before-recv", func() error { return rep.Send(zmq4.NewMsgString("reply")) }, true},
+		{"req-send", func() error { return req.Send(zmq4.NewMsgString("request")) }, false},
+		{"req-send-again", func() error { return req.Send(zmq4.NewMsgString("request")) }, true},
+		{"rep-recv", func() error { _, err := rep.Recv(); return err }, false},
+		{"rep-recv-again", func() error { _, err := rep.Recv(); return err }, true},
+		{"rep-send", func() error { return rep.Send(zmq4.NewMsgString("reply")) }, false},
+		{"rep-send-again", func() error { return rep.Send(zmq4.NewMsgString("reply")) }, true},
+		{"req-recv", func() error { _, err := req.Recv(); return err }, false},
+		{"req-recv-again", func() error { _, err := req.Recv(); return err }, true},
+		{"req-send-next", func() error { return req.Send(zmq4.NewMsgString("request")) }, false},
+	} {
+		err := step.op()
+		switch {
+		case step.fsm && !errors.Is(err, zmq4.ErrFSM):
+			t.Fatalf("%s: invalid error: got=%v, want=%v", step.name, err, zmq4.ErrFSM)
+		case !step.fsm && err != nil:
+			t.Fatalf("%s: %+v", step.name, err)
+		}
+	}
+}
+
+func TestReqRelaxed(t *testing.T) {
+	ctx, timeout := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeout()
+
+	req, rep := reqRepPair(t, ctx, zmq4.OptionReqRelaxed, zmq4.OptionReqCorrelate)
+
+	// the first request is resent before its reply arrives.
+	for _, request := range []string{"first", "second"} {
+		if err := req.Send(zmq4.NewMsgString(request)); err != nil {
+			t.Fatalf("could not send %s request: %+v", request, err)
+		}
+	}
+	for _, request := range []string{"first", "second"} {
+		msg, err := rep.Recv()
+		if err != nil {
+			t.Fatalf("could not receive %s request: %+v", request, err)
+		}
+		if got := string(msg.Frames[0]); got != request {
+			t.Fatalf("invalid request: got=%q, want=%q", got, request)
+		}
+		if err := rep.Send(zmq4.NewMsgString("reply-" + request)); err != nil {
+			t.Fatalf("could not reply to %s request: %+v", request, err)
+		}
+	}
+
+	// the late reply to the first request is discarded.
+	msg, err := req.Recv()
+	if err != nil {
+		t.Fatalf("could not receive reply: %+v", err)
+	}
+	if got, want := msg.Frames, [][]byte{[]byte("reply-second")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+	}
+}
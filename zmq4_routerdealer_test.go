@@ -7,9 +7,12 @@ package zmq4_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -302,3 +305,682 @@ func TestRouterDealerClose(t *testing.T) {
 		})
 	}
 }
+
+func TestDealerRouterRepEnvelope(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		front = must(EndPoint("tcp"))
+		back  = must(EndPoint("tcp"))
+
+		client   = zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("client")))
+		frontend = zmq4.NewRouter(ctx)
+		backend  = zmq4.NewDealer(ctx)
+		worker   = zmq4.NewRep(ctx)
+	)
+	for _, sck := range []zmq4.Socket{client, frontend, backend, worker} {
+		defer sck.Close()
+	}
+
+	if err := frontend.Listen(front); err != nil {
+		t.Fatalf("could not listen on frontend: %+v", err)
+	}
+	if err := backend.Listen(back); err != nil {
+		t.Fatalf("could not listen on backend: %+v", err)
+	}
+	if err := client.Dial(front); err != nil {
+		t.Fatalf("could not dial frontend: %+v", err)
+	}
+	if err := worker.Dial(back); err != nil {
+		t.Fatalf("could not dial backend: %+v", err)
+	}
+
+	// body frames may themselves be empty: only the first empty frame
+	// delimits the envelope.
+	req := [][]byte{[]byte("hello"), {}, []byte("world")}
+	rep := [][]byte{[]byte("bye"), {}}
+
+	checkEnvelope := func(name string, msg zmq4.Msg, ids, body [][]byte) {
+		t.Helper()
+		gotIDs, gotBody, ok := zmq4.SplitEnvelope(msg)
+		if !ok {
+			t.Fatalf("%s: no delimiter frame: %v", name, msg)
+		}
+		if !reflect.DeepEqual(gotIDs, ids) || !reflect.DeepEqual(gotBody, body) {
+			t.Fatalf("%s: invalid envelope:\ngot= %q %q\nwant=%q %q", name, gotIDs, gotBody, ids, body)
+		}
+	}
+
+	// DEALER -> ROUTER: the DEALER adds the delimiter, the ROUTER the identity.
+	if err := client.Send(zmq4.NewEnvelopeMsg(nil, req...)); err != nil {
+		t.Fatalf("could not send request: %+v", err)
+	}
+	msg, err := frontend.Recv()
+	if err != nil {
+		t.Fatalf("could not recv request on frontend: %+v", err)
+	}
+	checkEnvelope("frontend", msg, [][]byte{[]byte("client")}, req)
+
+	// ROUTER -> DEALER -> REP: the envelope is forwarded as-is and stripped
+	// by the REP socket.
+	if err := backend.Send(msg); err != nil {
+		t.Fatalf("could not forward request: %+v", err)
+	}
+	msg, err = worker.Recv()
+	if err != nil {
+		t.Fatalf("could not recv request on worker: %+v", err)
+	}
+	if !reflect.DeepEqual(msg.Frames, req) {
+		t.Fatalf("invalid request: got=%q, want=%q", msg.Frames, req)
+	}
+
+	// REP -> DEALER -> ROUTER -> DEALER: the envelope comes back intact.
+	if err := worker.Send(zmq4.NewMsgFrom(rep...)); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	msg, err = backend.Recv()
+	if err != nil {
+		t.Fatalf("could not recv reply on backend: %+v", err)
+	}
+	checkEnvelope("backend", msg, [][]byte{[]byte("client")}, rep)
+
+	if err := frontend.Send(msg); err != nil {
+		t.Fatalf("could not route reply: %+v", err)
+	}
+	msg, err = client.Recv()
+	if err != nil {
+		t.Fatalf("could not recv reply on client: %+v", err)
+	}
+	checkEnvelope("client", msg, [][]byte{}, rep)
+}
+
+// waitConns waits for sck to have n live connections and returns them.
+func waitConns(t *testing.T, sck zmq4.Socket, n int) []zmq4.ConnInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conns := sck.(zmq4.ConnectionLister).Connections()
+		if len(conns) >= n {
+			return conns
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %d connections (got %d)", n, len(conns))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type deadLetter struct {
+	id  string
+	msg zmq4.Msg
+}
+
+func TestRouterDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	dead := make(chan deadLetter, 1)
+	router := zmq4.NewRouter(ctx, zmq4.WithDeadLetterHandler(func(id []byte, msg zmq4.Msg) {
+		dead <- deadLetter{string(id), msg}
+	}))
+	defer router.Close()
+	dealer := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("dealer")))
+	defer dealer.Close()
+
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := dealer.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	waitConns(t, router, 1)
+
+	if err := router.Send(zmq4.NewMsgFrom([]byte("unknown"), []byte("lost"))); err != nil {
+		t.Fatalf("could not send to unknown peer: %+v", err)
+	}
+	select {
+	case dl := <-dead:
+		if dl.id != "unknown" || len(dl.msg.Frames) != 1 || string(dl.msg.Frames[0]) != "lost" {
+			t.Fatalf("invalid dead letter: id=%q, msg=%q", dl.id, dl.msg.Frames)
+		}
+	case <-ctx.Done():
+		t.Fatalf("dead-letter handler was not called")
+	}
+
+	// routable messages are delivered, and not reported.
+	if err := router.Send(zmq4.NewMsgFrom([]byte("dealer"), []byte("found"))); err != nil {
+		t.Fatalf("could not send to dealer: %+v", err)
+	}
+	msg, err := dealer.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got := string(msg.Frames[0]); got != "found" {
+		t.Fatalf("invalid msg: got=%q, want=%q", got, "found")
+	}
+	select {
+	case dl := <-dead:
+		t.Fatalf("unexpected dead letter: %+v", dl)
+	default:
+	}
+}
+
+func TestRouterSendTo(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	router := zmq4.NewRouter(ctx)
+	defer router.Close()
+	req := zmq4.NewReq(ctx, zmq4.WithID(zmq4.SocketIdentity("req")))
+	defer req.Close()
+	dealer := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("dealer")))
+	defer dealer.Close()
+
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	for _, sck := range []zmq4.Socket{req, dealer} {
+		if err := sck.Dial(ep); err != nil {
+			t.Fatalf("could not dial: %+v", err)
+		}
+	}
+	waitConns(t, router, 2)
+
+	// the REQ peer gets the empty delimiter frame, as in a reply.
+	if err := req.Send(zmq4.NewMsgString("hello")); err != nil {
+		t.Fatalf("could not send request: %+v", err)
+	}
+	msg, err := router.Recv()
+	if err != nil {
+		t.Fatalf("could not recv request: %+v", err)
+	}
+	if got, want := msg.String(), zmq4.NewMsgFrom([]byte("req"), []byte{}, []byte("hello")).String(); got != want {
+		t.Fatalf("invalid request: got=%q, want=%q", got, want)
+	}
+	if err := router.(zmq4.RouterSender).SendTo([]byte("req"), zmq4.NewMsgString("world")); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	msg, err = req.Recv()
+	if err != nil {
+		t.Fatalf("could not recv reply: %+v", err)
+	}
+	if got, want := msg.String(), zmq4.NewMsgString("world").String(); got != want {
+		t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+	}
+
+	// the DEALER peer gets the frames as-is.
+	if err := router.(zmq4.RouterSender).SendTo([]byte("dealer"), zmq4.NewMsgFrom([]byte("a"), []byte("b"))); err != nil {
+		t.Fatalf("could not send to dealer: %+v", err)
+	}
+	msg, err = dealer.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := msg.String(), zmq4.NewMsgFrom([]byte("a"), []byte("b")).String(); got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+}
+
+func TestStreamDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dead := make(chan deadLetter, 1)
+	stream := zmq4.NewStream(ctx, zmq4.WithDeadLetterHandler(func(id []byte, msg zmq4.Msg) {
+		dead <- deadLetter{string(id), msg}
+	}))
+	defer stream.Close()
+
+	if err := stream.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	peer, err := net.Dial("tcp", stream.Addr().String())
+	if err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	defer peer.Close()
+	id := waitConns(t, stream, 1)[0].Identity
+
+	if err := stream.Send(zmq4.NewMsgFrom([]byte("unknown"), []byte("lost"))); err != nil {
+		t.Fatalf("could not send to unknown peer: %+v", err)
+	}
+	select {
+	case dl := <-dead:
+		if dl.id != "unknown" || string(dl.msg.Bytes()) != "lost" {
+			t.Fatalf("invalid dead letter: id=%q, msg=%q", dl.id, dl.msg.Frames)
+		}
+	case <-ctx.Done():
+		t.Fatalf("dead-letter handler was not called")
+	}
+
+	if err := stream.Send(zmq4.NewMsgFrom([]byte(id), []byte("raw data"))); err != nil {
+		t.Fatalf("could not send to peer: %+v", err)
+	}
+	buf := make([]byte, len("raw data"))
+	_ = peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(peer, buf); err != nil {
+		t.Fatalf("could not read raw data: %+v", err)
+	}
+	if got := string(buf); got != "raw data" {
+		t.Fatalf("invalid raw data: got=%q, want=%q", got, "raw data")
+	}
+}
+
+func TestDealerIdentityChange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	router := zmq4.NewRouter(ctx)
+	defer router.Close()
+	dealer := zmq4.NewDealer(ctx)
+	defer dealer.Close()
+
+	// the identity of an unconnected socket may be changed.
+	if err := dealer.SetOption(zmq4.OptionIdentity, "before"); err != nil {
+		t.Fatalf("could not set identity: %+v", err)
+	}
+
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := dealer.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	err := dealer.SetOption(zmq4.OptionIdentity, "after")
+	if !errors.Is(err, zmq4.ErrIdentityChange) {
+		t.Fatalf("invalid error: got=%+v, want=%+v", err, zmq4.ErrIdentityChange)
+	}
+	if v, err := dealer.GetOption(zmq4.OptionIdentity); err != nil || v != "before" {
+		t.Fatalf("invalid identity: got=%v (err=%v), want=%q", v, err, "before")
+	}
+
+	if err := dealer.Send(zmq4.NewMsgString("hello")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := router.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got := string(msg.Frames[0]); got != "before" {
+		t.Fatalf("invalid peer identity: got=%q, want=%q", got, "before")
+	}
+}
+
+func TestDealerSendAll(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dealer := zmq4.NewDealer(ctx)
+	defer dealer.Close()
+
+	const npeers = 3
+	peers := make([]zmq4.Socket, npeers)
+	for i := range peers {
+		ep := must(EndPoint("tcp"))
+		peers[i] = zmq4.NewDealer(ctx)
+		defer peers[i].Close()
+		if err := peers[i].Listen(ep); err != nil {
+			t.Fatalf("could not listen on peer %d: %+v", i, err)
+		}
+		if err := dealer.Dial(ep); err != nil {
+			t.Fatalf("could not dial peer %d: %+v", i, err)
+		}
+	}
+
+	if err := dealer.(zmq4.Broadcaster).SendAll(zmq4.NewMsgString("control")); err != nil {
+		t.Fatalf("could not broadcast: %+v", err)
+	}
+
+	for i, peer := range peers {
+		msg, err := peer.Recv()
+		if err != nil {
+			t.Fatalf("peer %d could not recv: %+v", i, err)
+		}
+		if got := string(msg.Bytes()); got != "control" {
+			t.Fatalf("peer %d: invalid msg: got=%q, want=%q", i, got, "control")
+		}
+	}
+}
+
+func TestRouterRequirePeerIdentity(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	router := zmq4.NewRouter(ctx, zmq4.WithRequirePeerIdentity(true))
+	defer router.Close()
+	events := router.(zmq4.Monitored).Monitor(zmq4.EventHandshakeFailed)
+
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	anon := zmq4.NewDealer(ctx, zmq4.WithAutomaticReconnect(false))
+	defer anon.Close()
+	if err := anon.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Type != zmq4.EventHandshakeFailed || !errors.Is(ev.Err, zmq4.ErrPeerIdentity) {
+			t.Fatalf("invalid event: %+v", ev)
+		}
+	case <-ctx.Done():
+		t.Fatalf("anonymous peer was not rejected")
+	}
+
+	worker := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("worker")))
+	defer worker.Close()
+	if err := worker.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := worker.Send(zmq4.NewMsgString("ready")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := router.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got := string(msg.Frames[0]); got != "worker" {
+		t.Fatalf("invalid peer identity: got=%q, want=%q", got, "worker")
+	}
+	if conns := router.(zmq4.ConnectionLister).Connections(); len(conns) != 1 {
+		t.Fatalf("invalid number of connections: got=%d, want=1", len(conns))
+	}
+}
+
+// TestRouterAnonymousPeers checks the expectation the sockets used to meet
+// with a default, random, identity: a ROUTER tells its anonymous peers apart,
+// and routes the replies back to each of them.
+func TestRouterAnonymousPeers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	router := zmq4.NewRouter(ctx)
+	defer router.Close()
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	dealers := make([]zmq4.Socket, 2)
+	for i := range dealers {
+		dealers[i] = zmq4.NewDealer(ctx)
+		defer dealers[i].Close()
+		if id, err := dealers[i].GetOption(zmq4.OptionIdentity); err != nil || id != "" {
+			t.Fatalf("invalid default identity: got=(%q, %v), want empty", id, err)
+		}
+		if err := dealers[i].Dial(ep); err != nil {
+			t.Fatalf("could not dial: %+v", err)
+		}
+	}
+
+	ids := make(map[string]string) // name of the dealer, by routing identity
+	for i, dealer := range dealers {
+		name := fmt.Sprintf("dealer-%d", i)
+		if err := dealer.Send(zmq4.NewMsgString(name)); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+		msg, err := router.Recv()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		id := string(msg.Frames[0])
+		if id == "" {
+			t.Fatalf("anonymous peer without routing identity")
+		}
+		if _, dup := ids[id]; dup {
+			t.Fatalf("anonymous peers share the routing identity %q", id)
+		}
+		ids[id] = string(msg.Frames[1])
+	}
+
+	for id, name := range ids {
+		if err := router.Send(zmq4.NewMsgFrom([]byte(id), []byte(name))); err != nil {
+			t.Fatalf("could not send reply: %+v", err)
+		}
+	}
+	for i, dealer := range dealers {
+		msg, err := dealer.Recv()
+		if err != nil {
+			t.Fatalf("could not recv reply: %+v", err)
+		}
+		if got, want := string(msg.Frames[0]), fmt.Sprintf("dealer-%d", i); got != want {
+			t.Fatalf("reply routed to the wrong peer: got=%q, want=%q", got, want)
+		}
+	}
+}
+
+func TestRouterAcceptFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	errTenant := errors.New("unknown tenant")
+	ep := must(EndPoint("tcp"))
+	router := zmq4.NewRouter(ctx, zmq4.WithAcceptFilter(func(info zmq4.ConnInfo) (interface{}, error) {
+		tenant, _, ok := strings.Cut(info.Identity, "/")
+		if !ok {
+			return nil, errTenant
+		}
+		return tenant, nil
+	}))
+	defer router.Close()
+	events := router.(zmq4.Monitored).Monitor(zmq4.EventHandshakeFailed)
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	anon := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("anonymous")), zmq4.WithAutomaticReconnect(false))
+	defer anon.Close()
+	if err := anon.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	select {
+	case ev := <-events:
+		if !errors.Is(ev.Err, errTenant) {
+			t.Fatalf("invalid event: %+v", ev)
+		}
+	case <-ctx.Done():
+		t.Fatalf("peer without tenant was not rejected")
+	}
+
+	for _, id := range []string{"acme/1", "globex/1"} {
+		dealer := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity(id)))
+		defer dealer.Close()
+		if err := dealer.Dial(ep); err != nil {
+			t.Fatalf("could not dial: %+v", err)
+		}
+		if err := dealer.Send(zmq4.NewMsgString(id)); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		msg, info, err := router.(zmq4.PeerReceiver).RecvFrom()
+		if err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		id := string(msg.Frames[0])
+		if info.Identity != id {
+			t.Fatalf("invalid connection identity: got=%q, want=%q", info.Identity, id)
+		}
+		if want, _, _ := strings.Cut(id, "/"); info.Value != want {
+			t.Fatalf("invalid tenant of %q: got=%v, want=%v", id, info.Value, want)
+		}
+	}
+
+	for _, info := range waitConns(t, router, 2) {
+		if want, _, _ := strings.Cut(info.Identity, "/"); info.Value != want {
+			t.Fatalf("invalid tenant of %q: got=%v, want=%v", info.Identity, info.Value, want)
+		}
+	}
+}
+
+func TestDealerOnReconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	worker := zmq4.NewDealer(ctx,
+		zmq4.WithID(zmq4.SocketIdentity("worker")),
+		zmq4.WithDialerRetry(10*time.Millisecond),
+		zmq4.WithOnReconnect(func(sck zmq4.Socket) error {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			return sck.Send(zmq4.NewMsgString("REGISTER"))
+		}),
+	)
+	defer worker.Close()
+
+	// register checks the broker receives the registration of the worker.
+	register := func(broker zmq4.Socket) {
+		t.Helper()
+		msg, err := broker.Recv()
+		if err != nil {
+			t.Fatalf("could not recv registration: %+v", err)
+		}
+		if len(msg.Frames) != 2 || string(msg.Frames[0]) != "worker" || string(msg.Frames[1]) != "REGISTER" {
+			t.Fatalf("invalid registration: %q", msg.Frames)
+		}
+	}
+
+	broker := zmq4.NewRouter(ctx)
+	if err := broker.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := worker.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	register(broker)
+
+	// restart the broker: the worker reconnects and registers again.
+	broker.Close()
+	<-broker.Done()
+
+	broker = zmq4.NewRouter(ctx)
+	defer broker.Close()
+	if err := broker.Listen(ep); err != nil {
+		t.Fatalf("could not listen again: %+v", err)
+	}
+	register(broker)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Fatalf("invalid number of callback calls: got=%d, want=2", calls)
+	}
+}
+
+func TestRouterMandatory(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	router := zmq4.NewRouter(ctx)
+	defer router.Close()
+	if err := router.SetOption(zmq4.OptionRouterMandatory, true); err != nil {
+		t.Fatalf("could not set %s: %+v", zmq4.OptionRouterMandatory, err)
+	}
+	if err := router.SetOption(zmq4.OptionRouterMandatory, 1); !errors.Is(err, zmq4.ErrBadProperty) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrBadProperty)
+	}
+
+	// no peer is connected yet.
+	err := router.Send(zmq4.NewMsgFrom([]byte("bogus"), []byte("lost")))
+	if !errors.Is(err, zmq4.ErrHostUnreachable) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrHostUnreachable)
+	}
+
+	dealer := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("dealer")))
+	defer dealer.Close()
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := dealer.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	waitConns(t, router, 1)
+
+	err = router.Send(zmq4.NewMsgFrom([]byte("bogus"), []byte("lost")))
+	if !errors.Is(err, zmq4.ErrHostUnreachable) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, zmq4.ErrHostUnreachable)
+	}
+
+	if err := router.Send(zmq4.NewMsgFrom([]byte("dealer"), []byte("found"))); err != nil {
+		t.Fatalf("could not send to connected peer: %+v", err)
+	}
+	msg, err := dealer.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "found"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+}
+
+func TestRouterHandover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	router := zmq4.NewRouter(ctx)
+	defer router.Close()
+	if err := router.SetOption(zmq4.OptionRouterHandover, true); err != nil {
+		t.Fatalf("could not set %s: %+v", zmq4.OptionRouterHandover, err)
+	}
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	// the first connection of the worker is not found dead by the router
+	// when the worker reconnects.
+	stale := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("worker")), zmq4.WithAutomaticReconnect(false))
+	defer stale.Close()
+	if err := stale.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	waitConns(t, router, 1)
+
+	fresh := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("worker")))
+	defer fresh.Close()
+	if err := fresh.Dial(ep); err != nil {
+		t.Fatalf("could not dial again: %+v", err)
+	}
+	if err := fresh.Send(zmq4.NewMsgString("ready")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if _, err := router.Recv(); err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got := len(router.(zmq4.ConnectionLister).Connections()); got != 1 {
+		t.Fatalf("invalid number of connections: got=%d, want=1", got)
+	}
+
+	if err := router.Send(zmq4.NewMsgFrom([]byte("worker"), []byte("job"))); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := fresh.Recv()
+	if err != nil {
+		t.Fatalf("could not recv on the new connection: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "job"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+
+	// the former connection was closed by the router.
+	if _, err := stale.Recv(); err == nil {
+		t.Fatalf("expected the former connection to be closed")
+	}
+}
@@ -8,6 +8,8 @@ package zmq4_test
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -120,7 +122,7 @@ func TestProxyWithCaptureSocket(t *testing.T) {
 	done := make(chan error, 1)
 	go func() {
 		// Proxy with capture socket (if available)
-		err := zmq4.Proxy(frontend, backend)
+		err := zmq4.Proxy(frontend, backend, nil)
 		done <- err
 	}()
 
@@ -414,6 +416,101 @@ func TestMessageOperationsDetailed(t *testing.T) {
 	}
 }
 
+func TestMsgPopPushFront(t *testing.T) {
+	msg := zmq4.NewMsgFrom([]byte("id"), []byte("body"))
+
+	id, rest := msg.Pop()
+	if got, want := string(id), "id"; got != want {
+		t.Fatalf("invalid popped frame: got=%q, want=%q", got, want)
+	}
+	if got, want := rest.String(), zmq4.NewMsgString("body").String(); got != want {
+		t.Fatalf("invalid remainder: got=%q, want=%q", got, want)
+	}
+	if len(msg.Frames) != 2 {
+		t.Fatalf("Pop modified the message: %q", msg.Frames)
+	}
+
+	routed := rest.PushFront([]byte("peer"))
+	if got, want := routed.String(), zmq4.NewMsgFrom([]byte("peer"), []byte("body")).String(); got != want {
+		t.Fatalf("invalid pushed message: got=%q, want=%q", got, want)
+	}
+	if len(rest.Frames) != 1 {
+		t.Fatalf("PushFront modified the message: %q", rest.Frames)
+	}
+
+	// the results do not share memory with their source.
+	id[0] = 'X'
+	rest.Frames[0][0] = 'X'
+	if got, want := string(msg.Frames[0])+string(msg.Frames[1]), "idbody"; got != want {
+		t.Fatalf("Pop results share memory with the message: got=%q, want=%q", got, want)
+	}
+	if got, want := string(routed.Frames[1]), "body"; got != want {
+		t.Fatalf("PushFront result shares memory with the message: got=%q, want=%q", got, want)
+	}
+
+	frame, empty := (&zmq4.Msg{}).Pop()
+	if frame != nil || len(empty.Frames) != 0 {
+		t.Fatalf("invalid Pop of an empty message: got=%q, %q", frame, empty.Frames)
+	}
+}
+
+func TestMsgJSON(t *testing.T) {
+	type payload struct {
+		Name  string            `json:"name"`
+		Count int               `json:"count"`
+		Tags  []string          `json:"tags"`
+		Meta  map[string]string `json:"meta,omitempty"`
+	}
+	want := payload{Name: "block", Count: 42, Tags: []string{"a", "b"}, Meta: map[string]string{"k": "v"}}
+
+	msg, err := zmq4.NewMsgJSON(want)
+	if err != nil {
+		t.Fatalf("could not create JSON message: %+v", err)
+	}
+	if len(msg.Frames) != 1 {
+		t.Fatalf("invalid number of frames: got=%d, want=1", len(msg.Frames))
+	}
+
+	var got payload
+	if err := msg.JSON(&got); err != nil {
+		t.Fatalf("could not decode JSON message: %+v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid round-trip: got=%+v, want=%+v", got, want)
+	}
+
+	if _, err := zmq4.NewMsgJSON(make(chan int)); err == nil {
+		t.Fatalf("expected an error encoding a channel")
+	}
+	if err := zmq4.NewMsgString("{malformed").JSON(&got); err == nil {
+		t.Fatalf("expected an error decoding a malformed payload")
+	}
+	if err := (zmq4.Msg{}).JSON(&got); !errors.Is(err, zmq4.ErrBadFrame) {
+		t.Fatalf("invalid error decoding a message without frames: got=%v, want=%v", err, zmq4.ErrBadFrame)
+	}
+}
+
+func TestMsgSize(t *testing.T) {
+	msg := zmq4.NewMsgFrom([]byte("topic"), nil, []byte("payload"), make([]byte, 1024))
+	if got, want := msg.NumFrames(), 4; got != want {
+		t.Fatalf("invalid number of frames: got=%d, want=%d", got, want)
+	}
+	if got, want := msg.Size(), len("topic")+len("payload")+1024; got != want {
+		t.Fatalf("invalid size: got=%d, want=%d", got, want)
+	}
+	if got, want := msg.Size(), len(msg.Bytes()); got != want {
+		t.Fatalf("size does not match Bytes: got=%d, want=%d", got, want)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _, _ = msg.Size(), msg.NumFrames() }); allocs != 0 {
+		t.Fatalf("Size and NumFrames allocate: %v allocs", allocs)
+	}
+
+	var empty zmq4.Msg
+	if empty.Size() != 0 || empty.NumFrames() != 0 {
+		t.Fatalf("invalid empty message: size=%d, frames=%d", empty.Size(), empty.NumFrames())
+	}
+}
+
 // Test socket options more thoroughly
 func TestSocketOptionsDetailed(t *testing.T) {
 	ctx := context.Background()
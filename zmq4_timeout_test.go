@@ -6,6 +6,7 @@ package zmq4
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -47,3 +48,138 @@ func TestPushTimeout(t *testing.T) {
 	}
 
 }
+
+func TestRecvTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	checkTimeout := func(t *testing.T, recv func() (Msg, error)) {
+		t.Helper()
+		start := time.Now()
+		_, err := recv()
+		if !errors.Is(err, ErrTimeout) {
+			t.Fatalf("invalid error: got=%+v, want=%+v", err, ErrTimeout)
+		}
+		if d := time.Since(start); d < timeout || d > 20*timeout {
+			t.Fatalf("recv timed out after %v, want about %v", d, timeout)
+		}
+	}
+
+	t.Run("no-peer", func(t *testing.T) {
+		pull := NewPull(ctx, WithTimeout(timeout))
+		defer pull.Close()
+		checkTimeout(t, pull.Recv)
+	})
+
+	t.Run("no-reply", func(t *testing.T) {
+		req := NewReq(ctx, WithTimeout(timeout))
+		defer req.Close()
+		if err := req.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen: %+v", err)
+		}
+
+		rep := NewRep(ctx)
+		defer rep.Close()
+		if err := rep.Dial("tcp://" + req.Addr().String()); err != nil {
+			t.Fatalf("could not dial: %+v", err)
+		}
+
+		if err := req.Send(NewMsgString("ping")); err != nil {
+			t.Fatalf("could not send request: %+v", err)
+		}
+		if _, err := rep.Recv(); err != nil {
+			t.Fatalf("could not recv request: %+v", err)
+		}
+		// the reply never comes.
+		checkTimeout(t, req.Recv)
+	})
+
+	t.Run("no-timeout", func(t *testing.T) {
+		pull := NewPull(ctx, WithTimeout(0))
+		defer pull.Close()
+		done := make(chan error, 1)
+		go func() {
+			_, err := pull.Recv()
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			t.Fatalf("recv without timeout returned: %+v", err)
+		case <-time.After(3 * timeout):
+		}
+	})
+}
+
+func TestDeadlines(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pull := NewPull(ctx)
+	defer pull.Close()
+
+	// a deadline in the past fails the next receive right away.
+	if err := pull.SetRecvDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("could not set recv deadline: %+v", err)
+	}
+	start := time.Now()
+	if _, err := pull.Recv(); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("invalid error: got=%+v, want=%+v", err, ErrTimeout)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("recv past its deadline returned after %v", d)
+	}
+
+	// the deadline applies to the subsequent receives, until it is cleared.
+	const delay = 100 * time.Millisecond
+	if err := pull.SetRecvDeadline(time.Now().Add(delay)); err != nil {
+		t.Fatalf("could not set recv deadline: %+v", err)
+	}
+	start = time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := pull.Recv(); !errors.Is(err, ErrTimeout) {
+			t.Fatalf("invalid error #%d: got=%+v, want=%+v", i, err, ErrTimeout)
+		}
+	}
+	if d := time.Since(start); d < delay || d > 20*delay {
+		t.Fatalf("recv timed out after %v, want about %v", d, delay)
+	}
+
+	if err := pull.SetRecvDeadline(time.Time{}); err != nil {
+		t.Fatalf("could not clear recv deadline: %+v", err)
+	}
+	if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	push := NewPush(ctx)
+	defer push.Close()
+	if err := push.Dial("tcp://" + pull.Addr().String()); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := push.Send(NewMsgString("hello")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatalf("could not recv without deadline: %+v", err)
+	}
+
+	// send deadlines bound the wait for a peer.
+	lonely := NewPush(ctx)
+	defer lonely.Close()
+	if err := lonely.SetSendDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("could not set send deadline: %+v", err)
+	}
+	if err := lonely.Send(NewMsgString("hello")); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("invalid error: got=%+v, want=%+v", err, ErrTimeout)
+	}
+
+	req := NewReq(ctx)
+	defer req.Close()
+	if err := req.SetSendDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("could not set send deadline: %+v", err)
+	}
+	if err := req.Send(NewMsgString("hello")); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("invalid REQ error: got=%+v, want=%+v", err, ErrTimeout)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+	"github.com/luxfi/zmq/v4/security/null"
+	"github.com/luxfi/zmq/v4/transport"
+)
+
+// testCA is a certificate authority issuing test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate CA key: %+v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "zmq4 test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create CA certificate: %+v", err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("could not parse CA certificate: %+v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns a certificate for the given common name, valid for
+// localhost.
+func (ca *testCA) issue(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %+v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %+v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key}
+}
+
+// mtlsTransport is a TCP transport secured with mutual TLS.
+type mtlsTransport struct {
+	tcp    transport.Transport
+	server *tls.Config
+	client *tls.Config
+}
+
+func (trans mtlsTransport) Dial(ctx context.Context, dialer transport.Dialer, addr string) (net.Conn, error) {
+	conn, err := trans.tcp.Dial(ctx, dialer, addr)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Client(conn, trans.client), nil
+}
+
+func (trans mtlsTransport) Listen(ctx context.Context, addr string) (net.Listener, error) {
+	l, err := trans.tcp.Listen(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return tls.NewListener(l, trans.server), nil
+}
+
+func (trans mtlsTransport) Addr(ep string) (string, error) {
+	return trans.tcp.Addr(ep)
+}
+
+func TestTLSCertIdentity(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ca := newTestCA(t)
+	err := zmq4.RegisterTransport("mtls-cert-identity", mtlsTransport{
+		tcp: transport.New("tcp"),
+		server: &tls.Config{
+			Certificates: []tls.Certificate{ca.issue(t, "router")},
+			ClientCAs:    ca.pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		},
+		client: &tls.Config{
+			Certificates: []tls.Certificate{ca.issue(t, "client-1")},
+			RootCAs:      ca.pool,
+			ServerName:   "localhost",
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not register transport: %+v", err)
+	}
+
+	ep := "mtls-cert-identity://127.0.0.1:0"
+	router := zmq4.NewRouter(ctx, zmq4.WithCertIdentityRouting(true))
+	defer router.Close()
+	if err := router.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep = "mtls-cert-identity://" + router.Addr().String()
+
+	dealer := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("announced")))
+	defer dealer.Close()
+	if err := dealer.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	if err := dealer.Send(zmq4.NewMsgString("hello")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := router.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "client-1"; got != want {
+		t.Fatalf("invalid routing identity: got=%q, want=%q", got, want)
+	}
+
+	// replies are routed by certificate identity.
+	if err := router.Send(zmq4.NewMsgFrom([]byte("client-1"), []byte("world"))); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	msg, err = dealer.Recv()
+	if err != nil {
+		t.Fatalf("could not recv reply: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "world"; got != want {
+		t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+	}
+}
+
+func TestOpenTLSCertIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	defer l.Close()
+	cli, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	srv, err := l.Accept()
+	if err != nil {
+		t.Fatalf("could not accept: %+v", err)
+	}
+	srvTLS := tls.Server(srv, &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "server")},
+		ClientCAs:    ca.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	cliTLS := tls.Client(cli, &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "")},
+		RootCAs:      ca.pool,
+		ServerName:   "localhost",
+	})
+
+	type result struct {
+		conn *zmq4.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := zmq4.Open(srvTLS, null.Security(), zmq4.Pair, zmq4.SocketIdentity("srv"), true, nil)
+		done <- result{conn, err}
+	}()
+
+	cconn, err := zmq4.Open(cliTLS, null.Security(), zmq4.Pair, zmq4.SocketIdentity("cli"), false, nil)
+	if err != nil {
+		t.Fatalf("could not open client conn: %+v", err)
+	}
+	defer cconn.Close()
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("could not open server conn: %+v", res.err)
+	}
+	defer res.conn.Close()
+
+	// the client certificate has no common name: its first SAN is used.
+	if got, want := res.conn.Peer.Meta[zmq4.PeerCertIdentity], "localhost"; got != want {
+		t.Fatalf("invalid client identity: got=%q, want=%q", got, want)
+	}
+	if got, want := cconn.Peer.Meta[zmq4.PeerCertIdentity], "server"; got != want {
+		t.Fatalf("invalid server identity: got=%q, want=%q", got, want)
+	}
+}
+
+// selfSigned returns a self-signed certificate valid for localhost, and the
+// pool of roots trusting it.
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %+v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %+v", err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %+v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key}, pool
+}
+
+func TestTLSTransport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cert, roots := selfSigned(t)
+
+	srv := zmq4.NewPair(ctx, zmq4.WithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}))
+	defer srv.Close()
+	if err := srv.Listen("tls://127.0.0.1:0"); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	ep := "tls://" + srv.Addr().String()
+
+	// the certificate of the server is not trusted by default.
+	untrusted := zmq4.NewPair(ctx, zmq4.WithTLS(&tls.Config{}), zmq4.WithDialerMaxRetries(0))
+	defer untrusted.Close()
+	if err := untrusted.Dial(ep); err == nil {
+		t.Fatalf("expected an error dialing with an untrusted certificate")
+	}
+	plain := zmq4.NewPair(ctx, zmq4.WithDialerMaxRetries(0))
+	defer plain.Close()
+	if err := plain.Dial(ep); err == nil {
+		t.Fatalf("expected an error dialing without a TLS configuration")
+	}
+
+	cli := zmq4.NewPair(ctx, zmq4.WithTLS(&tls.Config{RootCAs: roots}))
+	defer cli.Close()
+	if err := cli.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	if err := cli.Send(zmq4.NewMsgString("ping")); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err := srv.Recv()
+	if err != nil {
+		t.Fatalf("could not recv: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "ping"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+
+	if err := srv.Send(zmq4.NewMsgString("pong")); err != nil {
+		t.Fatalf("could not send reply: %+v", err)
+	}
+	msg, err = cli.Recv()
+	if err != nil {
+		t.Fatalf("could not recv reply: %+v", err)
+	}
+	if got, want := string(msg.Frames[0]), "pong"; got != want {
+		t.Fatalf("invalid reply: got=%q, want=%q", got, want)
+	}
+}
@@ -187,3 +187,301 @@ func TestXPubSub(t *testing.T) {
 		})
 	}
 }
+
+func TestXSubSubscriptionHWM(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	xpub := zmq4.NewXPub(ctx)
+	defer xpub.Close()
+	xsub := zmq4.NewXSub(ctx, zmq4.WithSubscriptionHWM(4))
+	defer xsub.Close()
+
+	if err := xpub.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	if err := xsub.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	const n = 500
+	var want []string
+	for i := 0; i < n; i++ {
+		topic := fmt.Sprintf("topic-%03d", i)
+		if err := xsub.Send(zmq4.NewMsg(zmq4.SubscribeFrame([]byte(topic)))); err != nil {
+			t.Fatalf("could not subscribe to %q: %+v", topic, err)
+		}
+		if i%2 == 1 {
+			if err := xsub.Send(zmq4.NewMsg(zmq4.UnsubscribeFrame([]byte(topic)))); err != nil {
+				t.Fatalf("could not unsubscribe from %q: %+v", topic, err)
+			}
+			continue
+		}
+		want = append(want, topic)
+	}
+
+	if err := xsub.Flush(ctx); err != nil {
+		t.Fatalf("could not flush subscriptions: %+v", err)
+	}
+
+	topics := xpub.(zmq4.Topics)
+	for !reflect.DeepEqual(topics.Topics(), want) {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("subscriptions lost or reordered:\ngot= %q\nwant=%q", topics.Topics(), want)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSubscriptionFrames(t *testing.T) {
+	for _, tc := range []struct {
+		topic []byte
+		sub   bool
+	}{
+		{[]byte("topic"), true},
+		{[]byte("topic"), false},
+		{nil, true}, // subscribe to everything
+		{nil, false},
+		{[]byte{0x00, 0x01, 0xff}, true},
+	} {
+		t.Run(fmt.Sprintf("%q-%v", tc.topic, tc.sub), func(t *testing.T) {
+			frame := zmq4.UnsubscribeFrame(tc.topic)
+			if tc.sub {
+				frame = zmq4.SubscribeFrame(tc.topic)
+			}
+			if got, want := len(frame), 1+len(tc.topic); got != want {
+				t.Fatalf("invalid frame length: got=%d, want=%d", got, want)
+			}
+
+			sub, topic, ok := zmq4.ParseSubscription(frame)
+			if !ok {
+				t.Fatalf("could not parse subscription frame %q", frame)
+			}
+			if sub != tc.sub {
+				t.Fatalf("invalid subscription kind: got=%v, want=%v", sub, tc.sub)
+			}
+			if string(topic) != string(tc.topic) {
+				t.Fatalf("invalid topic: got=%q, want=%q", topic, tc.topic)
+			}
+		})
+	}
+
+	for _, frame := range [][]byte{nil, {}, []byte("\x02topic"), []byte("topic")} {
+		if _, _, ok := zmq4.ParseSubscription(frame); ok {
+			t.Fatalf("frame %q parsed as a subscription", frame)
+		}
+	}
+}
+
+func TestXPubVerbose(t *testing.T) {
+	for _, tc := range []struct {
+		verbose bool
+		want    int
+	}{
+		{false, 1},
+		{true, 2},
+	} {
+		t.Run(fmt.Sprintf("verbose=%v", tc.verbose), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ep := must(EndPoint("tcp"))
+			cleanUp(ep)
+
+			xpub := zmq4.NewXPub(ctx)
+			defer xpub.Close()
+			if err := xpub.SetOption(zmq4.OptionXPubVerbose, tc.verbose); err != nil {
+				t.Fatalf("could not set %s: %+v", zmq4.OptionXPubVerbose, err)
+			}
+			if err := xpub.Listen(ep); err != nil {
+				t.Fatalf("could not listen: %+v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				xsub := zmq4.NewXSub(ctx)
+				defer xsub.Close()
+				if err := xsub.Dial(ep); err != nil {
+					t.Fatalf("could not dial: %+v", err)
+				}
+				if err := xsub.Send(zmq4.NewMsg(zmq4.SubscribeFrame([]byte("topic")))); err != nil {
+					t.Fatalf("could not subscribe: %+v", err)
+				}
+				if err := xsub.Flush(ctx); err != nil {
+					t.Fatalf("could not flush subscription: %+v", err)
+				}
+			}
+
+			topics := xpub.(zmq4.Topics)
+			for len(xpub.(zmq4.ConnectionLister).Connections()) != 2 || len(topics.Topics()) != 1 {
+				select {
+				case <-ctx.Done():
+					t.Fatalf("subscriptions not applied: %+v", ctx.Err())
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			// both subscriptions are applied once queued for Recv.
+			time.Sleep(50 * time.Millisecond)
+
+			got := 0
+			for {
+				msg, err := xpub.RecvNB()
+				if err != nil {
+					break
+				}
+				sub, topic, ok := zmq4.ParseSubscription(msg.Frames[0])
+				if !ok || !sub || string(topic) != "topic" {
+					t.Fatalf("invalid subscription message %q", msg.Frames[0])
+				}
+				got++
+			}
+			if got != tc.want {
+				t.Fatalf("invalid number of subscription messages: got=%d, want=%d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestXPubManual(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	xpub := zmq4.NewXPub(ctx)
+	defer xpub.Close()
+	if err := xpub.SetOption(zmq4.OptionXPubManual, true); err != nil {
+		t.Fatalf("could not set %s: %+v", zmq4.OptionXPubManual, err)
+	}
+	if err := xpub.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	sub := zmq4.NewSub(ctx)
+	defer sub.Close()
+	if err := sub.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if err := sub.SetOption(zmq4.OptionSubscribe, "topic"); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+
+	msg, err := xpub.Recv()
+	if err != nil {
+		t.Fatalf("could not receive subscription: %+v", err)
+	}
+	if sub, topic, ok := zmq4.ParseSubscription(msg.Frames[0]); !ok || !sub || string(topic) != "topic" {
+		t.Fatalf("invalid subscription message %q", msg.Frames[0])
+	}
+
+	// the subscription frame alone is not honored.
+	topics := xpub.(zmq4.Topics)
+	if got := topics.Topics(); len(got) != 0 {
+		t.Fatalf("subscription applied before the application did: %q", got)
+	}
+	if err := xpub.Send(zmq4.NewMsgFrom([]byte("topic"), []byte("dropped"))); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	if err := sub.SetRecvDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatalf("could not set recv deadline: %+v", err)
+	}
+	if msg, err := sub.Recv(); err == nil {
+		t.Fatalf("received %q before the application subscribed", msg.Frames)
+	}
+	if err := sub.SetRecvDeadline(time.Time{}); err != nil {
+		t.Fatalf("could not clear recv deadline: %+v", err)
+	}
+
+	if err := xpub.SetOption(zmq4.OptionSubscribe, "topic"); err != nil {
+		t.Fatalf("could not subscribe manually: %+v", err)
+	}
+	if got, want := topics.Topics(), []string{"topic"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid topics: got=%q, want=%q", got, want)
+	}
+	if err := xpub.Send(zmq4.NewMsgFrom([]byte("topic"), []byte("delivered"))); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+	msg, err = sub.Recv()
+	if err != nil {
+		t.Fatalf("could not receive: %+v", err)
+	}
+	if got, want := string(msg.Frames[1]), "delivered"; got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+}
+
+func TestXPubWelcomeMsg(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep := must(EndPoint("tcp"))
+	cleanUp(ep)
+
+	xpub := zmq4.NewXPub(ctx)
+	defer xpub.Close()
+	for _, opt := range []struct {
+		name  string
+		value interface{}
+	}{
+		{zmq4.OptionXPubWelcomeMsg, []byte("welcome")},
+		{zmq4.OptionXPubVerbose, true},
+	} {
+		if err := xpub.SetOption(opt.name, opt.value); err != nil {
+			t.Fatalf("could not set %s: %+v", opt.name, err)
+		}
+	}
+	if err := xpub.Listen(ep); err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	xsub := zmq4.NewXSub(ctx)
+	defer xsub.Close()
+	if err := xsub.Dial(ep); err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+
+	// unsubscriptions do not trigger the welcome message.
+	if err := xsub.Send(zmq4.NewMsg(zmq4.UnsubscribeFrame([]byte("topic")))); err != nil {
+		t.Fatalf("could not unsubscribe: %+v", err)
+	}
+	if _, err := xpub.Recv(); err != nil {
+		t.Fatalf("could not receive unsubscription: %+v", err)
+	}
+	if err := xsub.SetRecvDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatalf("could not set recv deadline: %+v", err)
+	}
+	if msg, err := xsub.Recv(); err == nil {
+		t.Fatalf("received %q upon unsubscription", msg.Frames)
+	}
+	if err := xsub.SetRecvDeadline(time.Time{}); err != nil {
+		t.Fatalf("could not clear recv deadline: %+v", err)
+	}
+
+	if err := xsub.Send(zmq4.NewMsg(zmq4.SubscribeFrame([]byte("topic")))); err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+	if _, err := xpub.Recv(); err != nil {
+		t.Fatalf("could not receive subscription: %+v", err)
+	}
+	if err := xpub.Send(zmq4.NewMsgFrom([]byte("topic"), []byte("data"))); err != nil {
+		t.Fatalf("could not send: %+v", err)
+	}
+
+	for _, want := range [][]string{{"welcome"}, {"topic", "data"}} {
+		msg, err := xsub.Recv()
+		if err != nil {
+			t.Fatalf("could not receive: %+v", err)
+		}
+		var got []string
+		for _, frame := range msg.Frames {
+			got = append(got, string(frame))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid message: got=%q, want=%q", got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package zmq4test provides helpers to test code using zmq4 sockets.
+package zmq4test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// leakTimeout is how long LeakCheck waits for the resources of closed
+// sockets to be released.
+var leakTimeout = 5 * time.Second
+
+// LeakCheck snapshots the goroutines and the open network sockets of the
+// process, and returns a function reporting a test failure if they did not
+// return to that baseline, e.g.:
+//
+//	func TestFoo(t *testing.T) {
+//		defer zmq4test.LeakCheck(t)()
+//		...
+//	}
+//
+// Closing a zmq4 socket releases its resources asynchronously: the returned
+// function waits for a while before reporting a leak.
+// Tests running in parallel with the checked test may be reported as leaking.
+//
+// Open network sockets are only counted on Linux.
+func LeakCheck(t testing.TB) func() {
+	t.Helper()
+	var (
+		ignore = goleak.IgnoreCurrent()
+		fds    = openSockets()
+	)
+	return func() {
+		t.Helper()
+		if err := goleak.Find(ignore); err != nil {
+			t.Errorf("zmq4test: leaked goroutines: %v", err)
+		}
+		if fds < 0 {
+			return
+		}
+		n := openSockets()
+		for deadline := time.Now().Add(leakTimeout); n > fds && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+			n = openSockets()
+		}
+		if n > fds {
+			t.Errorf("zmq4test: leaked %d network sockets (before=%d, after=%d)", n-fds, fds, n)
+		}
+	}
+}
+
+// openSockets returns the number of network sockets opened by the process,
+// or -1 if they can not be counted.
+func openSockets() int {
+	const dir = "/proc/self/fd"
+	fds, err := os.ReadDir(dir)
+	if err != nil {
+		return -1
+	}
+	n := 0
+	for _, fd := range fds {
+		link, err := os.Readlink(dir + "/" + fd.Name())
+		if err != nil {
+			// closed while reading the directory.
+			continue
+		}
+		if strings.HasPrefix(link, "socket:") {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 The go-zeromq Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zmq4test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/luxfi/zmq/v4"
+)
+
+// recorder is a testing.TB recording the failures it is reported.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()                       {}
+func (r *recorder) Errorf(string, ...interface{}) { r.failed = true }
+
+func TestLeakCheck(t *testing.T) {
+	t.Run("no-leak", func(t *testing.T) {
+		rec := &recorder{TB: t}
+		check := LeakCheck(rec)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		pull := zmq4.NewPull(ctx)
+		if err := pull.Listen("tcp://127.0.0.1:0"); err != nil {
+			t.Fatalf("could not listen: %+v", err)
+		}
+		push := zmq4.NewPush(ctx)
+		if err := push.Dial("tcp://" + pull.Addr().String()); err != nil {
+			t.Fatalf("could not dial: %+v", err)
+		}
+		if err := push.Send(zmq4.NewMsgString("hello")); err != nil {
+			t.Fatalf("could not send: %+v", err)
+		}
+		if _, err := pull.Recv(); err != nil {
+			t.Fatalf("could not recv: %+v", err)
+		}
+		push.Close()
+		pull.Close()
+
+		check()
+		if rec.failed {
+			t.Fatalf("closed sockets reported as leaking")
+		}
+	})
+
+	t.Run("goroutine", func(t *testing.T) {
+		rec := &recorder{TB: t}
+		check := LeakCheck(rec)
+
+		quit := make(chan struct{})
+		defer close(quit)
+		go func() { <-quit }()
+
+		check()
+		if !rec.failed {
+			t.Fatalf("leaked goroutine not reported")
+		}
+	})
+
+	t.Run("socket", func(t *testing.T) {
+		if openSockets() < 0 {
+			t.Skip("network sockets can not be counted on this platform")
+		}
+		defer func(v time.Duration) { leakTimeout = v }(leakTimeout)
+		leakTimeout = 100 * time.Millisecond
+
+		rec := &recorder{TB: t}
+		check := LeakCheck(rec)
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not listen: %+v", err)
+		}
+		defer l.Close()
+
+		check()
+		if !rec.failed {
+			t.Fatalf("leaked network socket not reported")
+		}
+	})
+}